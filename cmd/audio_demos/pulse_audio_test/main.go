@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"math"
 	"os"
@@ -21,17 +22,19 @@ var (
 	sampleRate     int
 	channelCount   int
 	frameDuration  int
+	wavFile        string
 	verboseLogging bool
 )
 
 func init() {
 	// 解析命令行参数
-	flag.StringVar(&mode, "mode", "sine", "运行模式: sine=生成1K正弦波, record=录音并播放, pulse=打印PulseAudio设备")
-	flag.StringVar(&inputDevice, "input", "", "输入设备名称（部分匹配）")
-	flag.StringVar(&outputDevice, "output", "", "输出设备名称（部分匹配）")
+	flag.StringVar(&mode, "mode", "sine", "运行模式: sine=生成1K正弦波, record=录音并播放, wav=播放WAV文件, pulse=打印PulseAudio设备")
+	flag.StringVar(&inputDevice, "input", "", "输入设备名称，可用 -mode pulse 列出")
+	flag.StringVar(&outputDevice, "output", "", "输出设备名称，可用 -mode pulse 列出")
 	flag.IntVar(&sampleRate, "rate", audio.DefaultSampleRate, "采样率")
 	flag.IntVar(&channelCount, "channels", audio.DefaultChannelCount, "通道数")
 	flag.IntVar(&frameDuration, "duration", audio.DefaultFrameDuration, "帧持续时间（毫秒）")
+	flag.StringVar(&wavFile, "file", "", "wav模式下播放的WAV文件路径")
 	flag.BoolVar(&verboseLogging, "verbose", false, "启用详细日志")
 }
 
@@ -64,6 +67,8 @@ func main() {
 		runSineWaveGenerator()
 	case "record":
 		runRecordAndPlayback()
+	case "wav":
+		runWAVPlayback()
 	default:
 		logrus.Fatalf("未知模式: %s", mode)
 	}
@@ -72,44 +77,29 @@ func main() {
 // 查找PulseAudio设备
 func findPulseAudioDevices() {
 	devices, err := audio.GetAudioDevices()
+	if errors.Is(err, audio.ErrDeviceEnumerationUnsupported) {
+		logrus.Info("当前平台不支持音频设备枚举")
+		return
+	}
 	if err != nil {
 		logrus.Errorf("获取音频设备失败: %v", err)
 		return
 	}
-	if devices == nil || len(devices) == 0 {
-		logrus.Info("当前平台不支持音频设备枚举")
+	if len(devices) == 0 {
+		logrus.Info("未找到任何PulseAudio设备")
 		return
 	}
 
 	logrus.Info("查找PulseAudio设备:")
-	found := false
-
-	for i, dev := range devices {
-		// 这里假设有 DeviceInfo 类型，否则直接跳过
-		info, ok := dev.(struct {
-			Name              string
-			MaxInputChannels  int
-			MaxOutputChannels int
-		})
-		if !ok {
-			logrus.Warnf("未知设备类型，跳过: %#v", dev)
-			continue
+	for i, info := range devices {
+		logrus.Infof("[%d] 找到PulseAudio设备: %s (%s)", i, info.Name, info.Description)
+		if info.IsInput {
+			logrus.Infof("    - 可用作输入设备（通道数: %d）", info.Channels)
 		}
-		if strings.Contains(strings.ToLower(info.Name), "pulse") {
-			logrus.Infof("[%d] 找到PulseAudio设备: %s", i, info.Name)
-			if info.MaxInputChannels > 0 {
-				logrus.Infof("    - 可用作输入设备（通道数: %d）", info.MaxInputChannels)
-			}
-			if info.MaxOutputChannels > 0 {
-				logrus.Infof("    - 可用作输出设备（通道数: %d）", info.MaxOutputChannels)
-			}
-			found = true
+		if info.IsOutput {
+			logrus.Infof("    - 可用作输出设备（通道数: %d）", info.Channels)
 		}
 	}
-
-	if !found {
-		logrus.Info("未找到任何PulseAudio设备")
-	}
 }
 
 // 运行正弦波生成器
@@ -252,6 +242,47 @@ func runRecordAndPlayback() {
 	logrus.Info("程序已退出")
 }
 
+// 播放WAV文件，用于在没有服务器的情况下检查输出设备
+func runWAVPlayback() {
+	if wavFile == "" {
+		logrus.Fatal("wav模式需要通过 -file 指定WAV文件")
+	}
+	logrus.Infof("开始播放WAV文件: %s", wavFile)
+
+	options := audio.AudioManagerOptions{
+		SampleRate:        sampleRate,
+		ChannelCount:      channelCount,
+		FrameDuration:     frameDuration,
+		OutputDeviceName:  outputDevice,
+		UseDefaultDevices: outputDevice == "",
+	}
+
+	manager, err := audio.NewAudioManagerWithOptions(options)
+	if err != nil {
+		logrus.Fatalf("创建音频管理器失败: %v", err)
+	}
+	defer manager.Close()
+
+	if err := manager.StartPlaying(); err != nil {
+		logrus.Fatalf("启动音频播放器失败: %v", err)
+	}
+
+	if err := manager.PlayWAVFile(wavFile); err != nil {
+		logrus.Errorf("播放WAV文件失败: %v", err)
+		return
+	}
+
+	// 等待队列中剩余的音频播放完毕
+	for manager.GetQueueLength() > 0 {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if err := manager.StopPlaying(); err != nil {
+		logrus.Errorf("停止音频播放器失败: %v", err)
+	}
+	logrus.Info("WAV文件播放完成")
+}
+
 // 录音并延迟播放
 func recordAndPlay(ctx context.Context, manager *audio.AudioManagerNew) {
 	// 设置PCM数据回调
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// configEnvPrefix 环境变量的前缀，参数名转为大写、"-"换成"_"，例如 -listen-mode 对应 XIAOZHI_LISTEN_MODE
+const configEnvPrefix = "XIAOZHI_"
+
+// cliConfig 命令行客户端的配置文件，JSON对象的键与命令行参数同名（不含"-"前缀），例如：
+//
+//	{"server": "wss://example.com/xiaozhi/v1/", "token": "...", "listen-mode": "auto", "barge-in-grace": "300ms"}
+//
+// 参数的优先级为：命令行 > 环境变量 > 配置文件 > 默认值
+type cliConfig struct {
+	path   string                     // 配置文件路径，状态文件（见 cliState）位于同一目录
+	values map[string]json.RawMessage // 配置文件中的参数
+}
+
+// defaultConfigPath 返回默认的配置文件路径：用户配置目录下的 xiaozhi/config.json
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "xiaozhi", "config.json")
+}
+
+// configEnvName 返回参数对应的环境变量名
+func configEnvName(name string) string {
+	return configEnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// loadCLIConfig 按 -config、XIAOZHI_CONFIG、默认路径的顺序确定配置文件并加载，再用环境变量和配置文件
+// 填充命令行未指定的参数。显式指定的配置文件不存在时返回错误，默认路径下没有配置文件时只使用环境变量
+func loadCLIConfig(flags *flag.FlagSet) (*cliConfig, error) {
+	var path string
+	if f := flags.Lookup("config"); f != nil {
+		path = f.Value.String()
+	}
+	required := path != ""
+	if !required {
+		path, required = os.Getenv(configEnvName("config")), true
+	}
+	if path == "" {
+		path, required = defaultConfigPath(), false
+	}
+
+	cfg := &cliConfig{path: path, values: make(map[string]json.RawMessage)}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(data, &cfg.values); err != nil {
+				return nil, fmt.Errorf("解析配置文件%s失败: %v", path, err)
+			}
+			logrus.Infof("已加载配置文件: %s", path)
+		case errors.Is(err, fs.ErrNotExist) && !required:
+		default:
+			return nil, fmt.Errorf("读取配置文件失败: %v", err)
+		}
+	}
+
+	if err := cfg.apply(flags); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// apply 用环境变量和配置文件填充命令行未指定的参数
+func (cfg *cliConfig) apply(flags *flag.FlagSet) error {
+	explicit := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	var applyErr error
+	flags.VisitAll(func(f *flag.Flag) {
+		if applyErr != nil || explicit[f.Name] || f.Name == "config" {
+			return
+		}
+		if value, ok := os.LookupEnv(configEnvName(f.Name)); ok {
+			if err := flags.Set(f.Name, value); err != nil {
+				applyErr = fmt.Errorf("环境变量%s无效: %v", configEnvName(f.Name), err)
+			}
+			return
+		}
+		if raw, ok := cfg.values[f.Name]; ok {
+			if err := flags.Set(f.Name, configValue(raw)); err != nil {
+				applyErr = fmt.Errorf("配置文件%s中的%s无效: %v", cfg.path, f.Name, err)
+			}
+		}
+	})
+	if applyErr != nil {
+		return applyErr
+	}
+
+	// 拼错的参数名不会生效，提示出来
+	var unknown []string
+	for name := range cfg.values {
+		if flags.Lookup(name) == nil {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		logrus.Warnf("配置文件%s中有未知的参数，已忽略: %s", cfg.path, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// configValue 将配置文件中的值转为命令行参数的写法：字符串取其内容，数字和布尔值保持原样
+func configValue(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return strings.TrimSpace(string(raw))
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// controlChannels 无头模式下命令要送达的主循环通道
+type controlChannels struct {
+	keyPress chan<- string
+	command  chan<- string
+	text     chan<- string
+}
+
+// dispatch 执行一行控制命令：start、stop、say <文本>、quit
+func (ch controlChannels) dispatch(line string) error {
+	name, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	switch strings.ToLower(name) {
+	case "":
+		return nil
+	case "start":
+		ch.keyPress <- "F2_PRESSED"
+	case "stop":
+		ch.keyPress <- "F2_RELEASED"
+	case "say":
+		text := strings.TrimSpace(arg)
+		if text == "" {
+			return errors.New("say 需要文本参数")
+		}
+		ch.text <- text
+	case "quit", "q":
+		ch.command <- "quit"
+	default:
+		return fmt.Errorf("未知命令: %s（支持 start、stop、say <文本>、quit）", name)
+	}
+	return nil
+}
+
+// readHeadlessInput 无头模式下逐行读取命令，不修改终端设置。
+// 标准输入结束（例如systemd下为/dev/null）时不退出，程序继续作为后台服务运行，可通过控制套接字或信号控制
+func readHeadlessInput(r io.Reader, ch controlChannels) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if err := ch.dispatch(scanner.Text()); err != nil {
+			logrus.Warnf("%v", err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		logrus.Errorf("读取输入失败: %v", err)
+		return
+	}
+	logrus.Debug("标准输入已结束，停止读取命令")
+}
+
+// serveControlSocket 在本地Unix套接字上接收命令，每个连接逐行发送命令，每条命令回复一行"ok"或"error: <原因>"
+// 套接字文件仅当前用户可访问，启动时删除上次遗留的文件
+func serveControlSocket(path string, ch controlChannels) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("删除遗留的控制套接字失败: %v", err)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("监听控制套接字失败: %v", err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return fmt.Errorf("设置控制套接字权限失败: %v", err)
+	}
+	logrus.Infof("控制套接字已启动: %s", path)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				logrus.Errorf("控制套接字停止接收连接: %v", err)
+				return
+			}
+			go handleControlConn(conn, ch)
+		}
+	}()
+	return nil
+}
+
+// handleControlConn 处理控制套接字上的一个连接
+func handleControlConn(conn net.Conn, ch controlChannels) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		reply := "ok"
+		if err := ch.dispatch(scanner.Text()); err != nil {
+			reply = "error: " + err.Error()
+		}
+		if _, err := fmt.Fprintln(conn, reply); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/justa-cai/xiaozhi-go/internal/client"
+	"github.com/justa-cai/xiaozhi-go/internal/protocol"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultHealthMaxIdle 默认的最长无消息时间，超过则认为连接已卡死
+const defaultHealthMaxIdle = 60 * time.Second
+
+// healthReport /healthz 返回的状态信息
+type healthReport struct {
+	Healthy     bool             `json:"healthy"`
+	Reason      string           `json:"reason,omitempty"`
+	Connected   bool             `json:"connected"`
+	ClientState string           `json:"client_state"`
+	LastMessage string           `json:"last_message,omitempty"`
+	LastPong    string           `json:"last_pong,omitempty"`
+	IdleSeconds float64          `json:"idle_seconds"`
+	Connection  healthConnection `json:"connection"`
+	Audio       healthAudio      `json:"audio"`
+}
+
+// healthConnection 连接收发统计
+type healthConnection struct {
+	BytesSent      uint64  `json:"bytes_sent"`
+	BytesReceived  uint64  `json:"bytes_received"`
+	FramesSent     uint64  `json:"frames_sent"`
+	FramesReceived uint64  `json:"frames_received"`
+	Reconnects     uint64  `json:"reconnects"`
+	RTTMillis      float64 `json:"rtt_ms"`
+}
+
+// healthAudio 音频设备状态
+type healthAudio struct {
+	Initialized     bool   `json:"initialized"`
+	Playing         bool   `json:"playing"`
+	DummyMode       bool   `json:"dummy_mode"`
+	Recording       bool   `json:"recording"`
+	QueueLength     int    `json:"queue_length"`
+	ConcealedFrames uint64 `json:"concealed_frames"`
+	// 播放抖动缓冲，未启用时只有深度有效
+	JitterDepthMillis float64 `json:"jitter_depth_ms"`
+	JitterUnderruns   uint64  `json:"jitter_underruns"`
+	JitterOverruns    uint64  `json:"jitter_overruns"`
+}
+
+// startHealthServer 在addr上启动健康检查HTTP服务
+// /healthz 在已连接且最近有消息或保活pong时返回200，否则返回503，供systemd或Kubernetes重启卡死的实例
+func startHealthServer(addr string, c *client.Client, proto protocol.Protocol) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("监听健康检查地址失败: %v", err)
+	}
+	ensureHealthKeepAlive(proto)
+
+	server := &http.Server{
+		Handler:           healthHandler(c, proto),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logrus.Errorf("健康检查服务异常退出: %v", err)
+		}
+	}()
+
+	logrus.Infof("健康检查服务已启动: http://%s/healthz", listener.Addr())
+	return nil
+}
+
+// healthHandler 返回提供 /healthz 的HTTP处理器
+func healthHandler(c *client.Client, proto protocol.Protocol) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		report := buildHealthReport(c, proto, time.Now())
+
+		w.Header().Set("Content-Type", "application/json")
+		if report.Healthy {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	})
+	return mux
+}
+
+// messageTimer 能报告最近一次收到消息时间的协议实现，如 *protocol.WebsocketProtocol
+type messageTimer interface {
+	LastMessageTime() time.Time
+}
+
+// pongTimer 能报告最近一次收到保活pong时间的协议实现，如 *protocol.WebsocketProtocol
+type pongTimer interface {
+	LastPongTime() time.Time
+}
+
+// keepAliver 可定时发送ping保活的协议实现，如 *protocol.WebsocketProtocol
+type keepAliver interface {
+	KeepAlive() time.Duration
+	SetKeepAlive(interval time.Duration)
+}
+
+// ensureHealthKeepAlive 确保协议以小于healthMaxIdle的间隔发送保活ping
+// 空闲会话里服务器可能很久不发消息，只有持续返回的pong能证明连接未卡死；
+// 须在建立连接前调用，保活间隔在连接时生效
+func ensureHealthKeepAlive(proto protocol.Protocol) {
+	ka, ok := proto.(keepAliver)
+	if !ok {
+		return
+	}
+	if current := ka.KeepAlive(); current > 0 && current < healthMaxIdle {
+		return
+	}
+	interval := healthMaxIdle / 3
+	if interval <= 0 {
+		return
+	}
+	ka.SetKeepAlive(interval)
+	logrus.Infof("健康检查依赖保活pong判断空闲连接，保活间隔设为%v", interval)
+}
+
+// buildHealthReport 根据客户端、连接和音频状态生成健康报告
+// 空闲时长按最近一次消息和最近一次保活pong中较晚的计算，协议两者都不报告时不检查空闲时长
+func buildHealthReport(c *client.Client, proto protocol.Protocol, now time.Time) healthReport {
+	report := healthReport{
+		Connected:   proto.IsConnected(),
+		ClientState: c.GetState(),
+	}
+
+	var active time.Time
+	if timer, ok := proto.(messageTimer); ok {
+		if last := timer.LastMessageTime(); !last.IsZero() {
+			report.LastMessage = last.Format(time.RFC3339)
+			active = last
+		}
+	}
+	if timer, ok := proto.(pongTimer); ok {
+		if pong := timer.LastPongTime(); !pong.IsZero() {
+			report.LastPong = pong.Format(time.RFC3339)
+			if pong.After(active) {
+				active = pong
+			}
+		}
+	}
+	if !active.IsZero() {
+		report.IdleSeconds = now.Sub(active).Seconds()
+	}
+
+	stats := c.Stats()
+	report.Connection = healthConnection{
+		BytesSent:      stats.BytesSent,
+		BytesReceived:  stats.BytesReceived,
+		FramesSent:     stats.FramesSent,
+		FramesReceived: stats.FramesReceived,
+		Reconnects:     stats.Reconnects,
+		RTTMillis:      float64(stats.RTT) / float64(time.Millisecond),
+	}
+
+	if audioManager != nil {
+		report.Audio.Initialized = true
+		report.Audio.Playing = audioManager.IsPlaying()
+		report.Audio.DummyMode = audioManager.IsDummyMode()
+		report.Audio.Recording = audioManager.IsRecording()
+		report.Audio.QueueLength = audioManager.GetQueueLength()
+		if player := audioManager.Player(); player != nil {
+			report.Audio.ConcealedFrames = player.ConcealedFrames()
+			jitter := player.JitterBufferStats()
+			report.Audio.JitterDepthMillis = float64(jitter.Depth) / float64(time.Millisecond)
+			report.Audio.JitterUnderruns = jitter.Underruns
+			report.Audio.JitterOverruns = jitter.Overruns
+		}
+	}
+
+	switch {
+	case !report.Connected:
+		report.Reason = "未连接到服务器"
+	case report.IdleSeconds > healthMaxIdle.Seconds():
+		report.Reason = fmt.Sprintf("超过%v未收到服务器消息或保活pong", healthMaxIdle)
+	default:
+		report.Healthy = true
+	}
+	return report
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/justa-cai/xiaozhi-go/internal/client"
+	"github.com/justa-cai/xiaozhi-go/internal/protocol"
+)
+
+// timedProtocol 为模拟协议补充最后一条消息和最后一次pong的时间，用于测试空闲判断
+type timedProtocol struct {
+	*protocol.MockProtocol
+	last      atomic.Int64
+	pong      atomic.Int64
+	keepAlive time.Duration
+}
+
+func (p *timedProtocol) LastMessageTime() time.Time { return time.Unix(0, p.last.Load()) }
+
+func (p *timedProtocol) LastPongTime() time.Time {
+	if at := p.pong.Load(); at != 0 {
+		return time.Unix(0, at)
+	}
+	return time.Time{}
+}
+
+func (p *timedProtocol) KeepAlive() time.Duration            { return p.keepAlive }
+func (p *timedProtocol) SetKeepAlive(interval time.Duration) { p.keepAlive = interval }
+
+// newHealthClient 返回已打开音频通道的客户端及其模拟协议，最后消息时间为当前时间
+func newHealthClient(t *testing.T) (*client.Client, *timedProtocol) {
+	t.Helper()
+	mock := protocol.NewMockProtocol()
+	mock.OnSendJSON = func(data []byte) {
+		if protocol.MessageType(data) == "hello" {
+			mock.InjectJSON(`{"type":"hello","version":1,"transport":"websocket"}`)
+		}
+	}
+	proto := &timedProtocol{MockProtocol: mock}
+	proto.last.Store(time.Now().UnixNano())
+	c := client.New(proto)
+	if err := c.OpenAudioChannel("ws://test"); err != nil {
+		t.Fatalf("打开音频通道失败: %v", err)
+	}
+	return c, proto
+}
+
+// getHealth 请求 /healthz，返回状态码和解析后的报告
+func getHealth(t *testing.T, url string) (int, healthReport) {
+	t.Helper()
+	resp, err := http.Get(url + "/healthz")
+	if err != nil {
+		t.Fatalf("请求健康检查失败: %v", err)
+	}
+	defer resp.Body.Close()
+	var report healthReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("解析健康报告失败: %v", err)
+	}
+	return resp.StatusCode, report
+}
+
+func TestHealthzReportsDisconnect(t *testing.T) {
+	oldMaxIdle := healthMaxIdle
+	healthMaxIdle = time.Minute
+	t.Cleanup(func() { healthMaxIdle = oldMaxIdle })
+
+	c, proto := newHealthClient(t)
+
+	srv := httptest.NewServer(healthHandler(c, proto))
+	defer srv.Close()
+
+	status, report := getHealth(t, srv.URL)
+	if status != http.StatusOK || !report.Healthy || !report.Connected {
+		t.Fatalf("连接正常时返回 %d %+v，期望200且健康", status, report)
+	}
+
+	proto.last.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+	status, report = getHealth(t, srv.URL)
+	if status != http.StatusServiceUnavailable || report.Healthy {
+		t.Errorf("长时间未收到消息时返回 %d %+v，期望503", status, report)
+	}
+	proto.last.Store(time.Now().UnixNano())
+
+	proto.InjectDisconnect(nil)
+	status, report = getHealth(t, srv.URL)
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("断线后返回 %d，期望503", status)
+	}
+	if report.Healthy || report.Connected || report.Reason != "未连接到服务器" {
+		t.Errorf("断线后的报告为 %+v", report)
+	}
+}
+
+func TestHealthzCountsKeepAlivePongs(t *testing.T) {
+	oldMaxIdle := healthMaxIdle
+	healthMaxIdle = time.Minute
+	t.Cleanup(func() { healthMaxIdle = oldMaxIdle })
+
+	c, proto := newHealthClient(t)
+	srv := httptest.NewServer(healthHandler(c, proto))
+	defer srv.Close()
+
+	// 空闲会话：服务器两分钟没有消息，但保活pong仍在返回
+	proto.last.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+	proto.pong.Store(time.Now().Add(-5 * time.Second).UnixNano())
+	status, report := getHealth(t, srv.URL)
+	if status != http.StatusOK || !report.Healthy {
+		t.Fatalf("最近有pong时返回 %d %+v，期望200", status, report)
+	}
+	if report.IdleSeconds > 10 || report.LastPong == "" {
+		t.Errorf("空闲时长应按最近pong计算，报告为 %+v", report)
+	}
+
+	proto.pong.Store(time.Now().Add(-90 * time.Second).UnixNano())
+	status, report = getHealth(t, srv.URL)
+	if status != http.StatusServiceUnavailable || report.Healthy {
+		t.Errorf("消息和pong都超时时返回 %d %+v，期望503", status, report)
+	}
+}
+
+func TestEnsureHealthKeepAlive(t *testing.T) {
+	oldMaxIdle := healthMaxIdle
+	healthMaxIdle = time.Minute
+	t.Cleanup(func() { healthMaxIdle = oldMaxIdle })
+
+	tests := []struct {
+		name    string
+		current time.Duration
+		want    time.Duration
+	}{
+		{"未开启时开启", 0, 20 * time.Second},
+		{"间隔过长时缩短", 2 * time.Minute, 20 * time.Second},
+		{"已有较短间隔时保留", 10 * time.Second, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proto := &timedProtocol{MockProtocol: protocol.NewMockProtocol(), keepAlive: tt.current}
+			ensureHealthKeepAlive(proto)
+			if proto.keepAlive != tt.want {
+				t.Errorf("保活间隔为 %v，期望 %v", proto.keepAlive, tt.want)
+			}
+		})
+	}
+}
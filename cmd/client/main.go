@@ -1,17 +1,18 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"crypto/md5"
 	"crypto/rand"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"net"
 	"os"
-	"os/exec"
 	"os/signal"
 	"strings"
-	"sync"
 	"syscall"
 	"time"
 	"runtime"
@@ -33,19 +34,67 @@ const (
 
 var (
 	// 命令行参数
-	serverURL     string
-	deviceID      string
-	token         string
-	boardType     string
-	appVersion    string
-	activateOnly  bool
-	logLevel      string
-	skipTLSVerify bool
-	httpProxy     string
+	configFile   string
+	serverURL    string
+	deviceID     string
+	clientID     string
+	resetState   bool
+	token        string
+	boardType    string
+	appVersion   string
+	activateOnly bool
+	// 激活轮询
+	activatePollInterval time.Duration
+	activateTimeout      time.Duration
+	logLevel             string
+	skipTLSVerify        bool
+	httpProxy            string
+	// 自定义TLS证书
+	caCertFile     string
+	clientCertFile string
+	clientKeyFile  string
+	// 连接和hello握手超时
+	connectTimeout time.Duration
+	helloTimeout   time.Duration
+	// 传输方式
+	transport string
+	// 断线重连：会话恢复和按住说话时暂存上行音频
+	sessionResume   bool
+	reconnectBuffer time.Duration
 	// 添加调试标志
 	debugEnabled bool
 	// 添加详细日志标志
 	verboseLogging bool
+	// 健康检查
+	healthAddr    string
+	healthMaxIdle time.Duration
+	// Prometheus指标
+	metricsAddr string
+	// 静音自动停止
+	vadAutoStop time.Duration
+	// 打断宽限期
+	bargeInGrace time.Duration
+	// 文本对话模式
+	textMode bool
+	// 无头模式和控制套接字
+	headless      bool
+	controlSocket string
+	// 不使用状态界面
+	noTUI bool
+	// 监听模式
+	listenMode string
+	// 回声消除
+	enableAEC bool
+	// 降噪强度
+	noiseSuppression int
+	// 录音自动增益目标电平
+	agcTarget float64
+	// 播放音频保存路径
+	saveTTSFile string
+	// 静音抑制阈值
+	silenceThreshold float64
+	// 播放抖动缓冲目标深度
+	jitterBuffer time.Duration
 )
 
 // 全局音频管理器
@@ -54,30 +103,53 @@ var (
 	audioPlayer  *audio.AudioPlayerNew
 )
 
-// 定义一个全局变量，用于追踪是否已恢复终端设置
-var terminalRestored bool = false
-var terminalMutex sync.Mutex
-
 // 全局音频数据通道
 var audioChan chan []byte
 
-var audioInited = false
-
 func init() {
 	// 解析命令行参数
 	flag.StringVar(&serverURL, "server", protocol.DefaultWebSocketURL, "WebSocket服务器地址")
-	flag.StringVar(&deviceID, "device-id", "", "设备ID (MAC地址)")
+	flag.StringVar(&configFile, "config", "", "配置文件路径(JSON，键与参数同名)，默认为用户配置目录下的xiaozhi/config.json；优先级: 命令行 > 环境变量XIAOZHI_* > 配置文件")
+	flag.StringVar(&deviceID, "device-id", "", "设备ID (MAC地址)，未指定时沿用上次的设备ID，首次运行时自动获取")
+	flag.StringVar(&clientID, "client-id", "", "客户端ID，未指定时沿用上次的客户端ID，首次运行时根据设备ID生成")
+	flag.BoolVar(&resetState, "reset-state", false, "清除保存的设备ID、客户端ID和激活状态后再启动")
 	flag.StringVar(&token, "token", "test-token", "API访问令牌")
+	flag.StringVar(&transport, "transport", transportAuto, "传输方式 (auto, websocket, mqtt)，auto在未指定-server且OTA只下发了MQTT配置时使用MQTT")
 	flag.StringVar(&boardType, "board", "generic", "设备板型号")
 	flag.StringVar(&appVersion, "version", "1.0.0", "应用版本号")
 	flag.BoolVar(&activateOnly, "activate-only", false, "只执行激活流程")
+	flag.DurationVar(&activatePollInterval, "activate-poll-interval", ota.DefaultActivationPollInterval, "激活流程中检查激活状态的间隔")
+	flag.DurationVar(&activateTimeout, "activate-timeout", 10*time.Minute, "激活流程等待用户输入激活码的最长时间")
 	flag.StringVar(&logLevel, "log-level", "info", "日志级别 (debug, info, warn, error, fatal, panic)")
 	flag.BoolVar(&skipTLSVerify, "skip-tls-verify", true, "跳过TLS证书验证")
+	flag.StringVar(&caCertFile, "ca-cert", "", "校验服务器证书使用的CA证书文件(PEM)，设置后忽略 -skip-tls-verify")
+	flag.StringVar(&clientCertFile, "client-cert", "", "双向TLS客户端证书文件(PEM)")
+	flag.StringVar(&clientKeyFile, "client-key", "", "双向TLS客户端私钥文件(PEM)")
 	flag.StringVar(&httpProxy, "http-proxy", "", "HTTP代理地址，例如: http://127.0.0.1:8080")
+	flag.DurationVar(&connectTimeout, "connect-timeout", client.DefaultConnectTimeout, "连接服务器（含TLS和WebSocket握手）的最长时间")
+	flag.DurationVar(&helloTimeout, "hello-timeout", client.DefaultHelloTimeout, "发送hello后等待服务器hello的最长时间")
+	flag.BoolVar(&sessionResume, "session-resume", false, "断线重连时请求服务器恢复原会话，需要服务器支持")
+	flag.DurationVar(&reconnectBuffer, "reconnect-buffer", 3*time.Second, "按住说话时断线，重连期间最多暂存多长的录音并在重连后补发 (为0则不暂存)")
 	// 添加调试标志
 	flag.BoolVar(&debugEnabled, "debug", false, "启用高级调试功能")
 	// 添加详细日志标志
 	flag.BoolVar(&verboseLogging, "verbose", false, "启用详细日志")
+	flag.DurationVar(&bargeInGrace, "barge-in-grace", 500*time.Millisecond, "播放时开始说话后延迟多久中断AI回复，期间停止说话则不中断")
+	flag.StringVar(&listenMode, "listen-mode", client.ListenModeManual, "监听模式 (manual, auto, realtime)，realtime为全双工，需回声消除或耳机")
+	flag.BoolVar(&enableAEC, "aec", false, "启用软件回声消除，外放时从录音中去除扬声器声音，配合realtime模式使用")
+	flag.IntVar(&noiseSuppression, "noise-suppression", 0, "录音降噪强度 (0关闭, 1轻度, 2中等, 3强)")
+	flag.Float64Var(&agcTarget, "agc", 0, "录音自动增益的目标RMS电平 (0..1，例如0.1)，为0则不启用")
+	flag.Float64Var(&silenceThreshold, "silence-threshold", 0, "静音抑制阈值，RMS电平 (0..1，例如0.01) 低于该值的录音帧不上传，为0则不启用")
+	flag.DurationVar(&jitterBuffer, "jitter-buffer", 0, "播放抖动缓冲深度，先缓冲这么长的TTS音频再开始播放并按网络抖动自适应加深，例如: 120ms (为0则不启用)")
+	flag.StringVar(&saveTTSFile, "save-tts", "", "将播放的TTS音频同时保存为WAV文件，用于排查音频问题 (为空则不保存)")
+	flag.BoolVar(&textMode, "text", false, "文本对话模式：逐行输入文字作为一轮对话，无需麦克风")
+	flag.BoolVar(&headless, "headless", false, "无头模式：从标准输入逐行读取命令(start, stop, say <文本>, quit)，不设置终端，适合作为后台服务运行；标准输入不是终端时自动启用")
+	flag.BoolVar(&noTUI, "no-tui", false, "按键模式下不使用全屏状态界面，沿用滚动的日志输出；标准输出不是终端时自动禁用")
+	flag.StringVar(&controlSocket, "control-socket", "", "无头模式下额外接收命令的本地Unix套接字路径 (为空则不启用)")
+	flag.DurationVar(&vadAutoStop, "vad-auto-stop", 0, "手动监听模式下说话结束后静音多久自动停止监听，例如: 800ms (为0则不启用)")
+	flag.StringVar(&healthAddr, "health-addr", "", "健康检查监听地址，例如: :8081 (为空则不启用)")
+	flag.DurationVar(&healthMaxIdle, "health-max-idle", defaultHealthMaxIdle, "超过该时长未收到服务器消息或保活pong时健康检查返回503")
+	flag.StringVar(&metricsAddr, "metrics-addr", "", "Prometheus指标监听地址，例如: :9100 (为空则不启用，需使用 -tags prometheus 构建)")
 
 	// 配置日志
 	logrus.SetFormatter(&logrus.TextFormatter{
@@ -121,101 +193,6 @@ func (hook *WebSocketLogHook) Fire(entry *logrus.Entry) error {
 	return nil
 }
 
-// safeExit 安全退出程序，确保恢复终端设置
-func safeExit(code int) {
-	terminalMutex.Lock()
-	defer terminalMutex.Unlock()
-
-	if !terminalRestored {
-		// 恢复终端设置
-		if err := exec.Command("stty", "-F", "/dev/tty", "echo").Run(); err != nil {
-			logrus.Errorf("退出时恢复终端回显失败: %v", err)
-		}
-		if err := exec.Command("stty", "-F", "/dev/tty", "-cbreak").Run(); err != nil {
-			logrus.Errorf("退出时恢复终端规范模式失败: %v", err)
-		}
-		terminalRestored = true
-		logrus.Debug("退出前已恢复终端设置")
-	}
-
-	os.Exit(code)
-}
-
-// cleanupAndExit 清理资源并安全退出
-func cleanupAndExit(c *client.Client, code int) {
-	// 直接强制退出，不等待资源清理
-	// 设置一个非常短的超时时间
-	forcedExit := make(chan struct{})
-	go func() {
-		select {
-		case <-forcedExit:
-			return
-		case <-time.After(1 * time.Second):
-			logrus.Warn("强制结束进程")
-			safeExit(1)
-		}
-	}()
-
-	// 快速清理核心资源
-	logrus.Debug("开始快速清理资源...")
-
-	// 使用goroutine并行处理所有清理工作
-	var wg sync.WaitGroup
-
-	// 关闭客户端连接 - 最优先处理
-	if c != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-
-			cleanDone := make(chan struct{})
-			go func() {
-				logrus.Debug("正在关闭音频通道...")
-				// 直接强制关闭连接，不调用客户端的方法
-				if proto := c.GetProtocol(); proto != nil {
-					if wp, ok := proto.(*protocol.WebsocketProtocol); ok {
-						wp.ForceDisconnect()
-					} else {
-						// 普通关闭
-						c.CloseAudioChannel()
-					}
-				}
-				close(cleanDone)
-			}()
-
-			// 最多等待200ms
-			select {
-			case <-cleanDone:
-				logrus.Debug("音频通道已关闭")
-			case <-time.After(200 * time.Millisecond):
-				logrus.Warn("关闭音频通道超时")
-			}
-		}()
-	}
-
-	// 等待所有清理工作完成或超时
-	waitChan := make(chan struct{})
-	go func() {
-		wg.Wait()
-		close(waitChan)
-	}()
-
-	// 最多等待500ms
-	select {
-	case <-waitChan:
-		// 所有工作完成
-	case <-time.After(500 * time.Millisecond):
-		logrus.Warn("资源清理超时")
-	}
-
-	// 关闭强制退出
-	close(forcedExit)
-
-	// 立即退出
-	logrus.Info("正在退出程序")
-	safeExit(code)
-}
-
 // analyzeConnectionError 分析连接错误
 func analyzeConnectionError(err error) {
 	logrus.Error("连接错误详细分析:")
@@ -245,6 +222,10 @@ func analyzeConnectionError(err error) {
 
 func main() {
 	flag.Parse()
+	cfg, cfgErr := loadCLIConfig(flag.CommandLine)
+	if cfgErr != nil {
+		logrus.Fatalf("加载配置失败: %v", cfgErr)
+	}
 
 	// 根据命令行参数设置日志级别
 	switch strings.ToLower(logLevel) {
@@ -265,16 +246,26 @@ func main() {
 		logrus.SetLevel(logrus.InfoLevel)
 	}
 
-	// 在程序退出时确保恢复终端设置
-	defer func() {
-		exec.Command("stty", "-F", "/dev/tty", "echo").Run()
-		exec.Command("stty", "-F", "/dev/tty", "-cbreak").Run()
-		logrus.Debug("已恢复终端设置")
-	}()
+	// 在程序退出时确保恢复终端设置，Fatal日志直接退出时同样恢复
+	defer restoreTerminal()
+	logrus.RegisterExitHandler(restoreTerminal)
 
 	logrus.Info("正在启动小智客户端...")
 
-	// 获取设备ID
+	// 读取上次运行保存的设备ID和激活状态
+	st := loadCLIState(statePath(cfg))
+	if resetState {
+		if err := resetCLIState(st.path); err != nil {
+			logrus.Fatalf("%v", err)
+		}
+		st = &cliState{path: st.path}
+		logrus.Info("已清除保存的设备ID和激活状态")
+	}
+
+	// 获取设备ID：优先使用参数，其次沿用上次的设备ID，首次运行时根据MAC地址生成
+	if deviceID == "" {
+		deviceID = st.DeviceID
+	}
 	if deviceID == "" {
 		var err error
 		deviceID, err = getMACAddress()
@@ -286,40 +277,57 @@ func main() {
 	}
 	logrus.Infof("使用设备ID: %s", deviceID)
 
+	// 使用基于设备ID生成的UUID作为客户端ID
+	if clientID == "" && st.DeviceID == deviceID {
+		clientID = st.ClientID
+	}
+	if clientID == "" {
+		clientID = generateUUID(deviceID)
+	}
+	if st.DeviceID != deviceID || st.ClientID != clientID {
+		st.useDevice(deviceID, clientID)
+		if err := st.save(); err != nil {
+			logrus.Warnf("保存设备ID失败，下次运行可能使用不同的ID: %v", err)
+		}
+	}
+
 	// 如果只执行激活流程
 	if activateOnly {
-		runActivation()
+		runActivation(st)
 		return
 	}
 
-	// 如果设备未激活，则返回
-	if !isDeviceActivated() {
-		logrus.Error("设备未激活，请先激活设备")
-		logrus.Error("跳过激活")
-		// return
+	// 检查激活状态，并使用OTA下发的服务器配置
+	var servers serverConfig
+	if activated, otaServers, err := isDeviceActivated(st); err != nil {
+		logrus.Errorf("检查设备激活状态失败: %v", err)
+	} else {
+		// 如果设备未激活，则返回
+		if !activated {
+			logrus.Error("设备未激活，请先激活设备")
+			logrus.Error("跳过激活")
+			// return
+		}
+		applyOTAWebsocketConfig(otaServers.Websocket)
+		servers = otaServers
 	}
 
 	// 初始化音频系统
 	initAudio()
 	defer cleanupAudio()
 
-	// 创建WebSocket协议实例
-	proto := protocol.NewWebsocketProtocol()
-
-	// 设置跳过TLS证书验证
-	proto.SetSkipTLSVerify(skipTLSVerify)
-	if skipTLSVerify {
-		logrus.Info("已设置跳过TLS证书验证")
-	} else {
-		logrus.Info("将验证TLS证书")
+	// 按 -transport 创建WebSocket或MQTT协议实例
+	proto, err := newProtocol(servers)
+	if err != nil {
+		logrus.Fatalf("%v", err)
 	}
 
 	// 创建客户端
 	c := client.New(proto)
+	if err := c.SetListenMode(listenMode); err != nil {
+		logrus.Fatalf("%v", err)
+	}
 	c.SetDeviceID(deviceID)
-
-	// 使用基于设备ID生成的UUID作为客户端ID
-	clientID := generateUUID(deviceID)
 	c.SetClientID(clientID)
 	logrus.Infof("使用客户端ID: %s", clientID)
 
@@ -327,6 +335,37 @@ func main() {
 		c.SetToken(token)
 	}
 
+	if err := c.SetConnectTimeout(connectTimeout); err != nil {
+		logrus.Fatalf("%v", err)
+	}
+	if err := c.SetHelloTimeout(helloTimeout); err != nil {
+		logrus.Fatalf("%v", err)
+	}
+
+	c.SetSessionResume(sessionResume)
+	c.SetReconnectAudioBuffer(reconnectBuffer, 0)
+
+	c.SetBargeInGrace(bargeInGrace)
+
+	if vadAutoStop > 0 {
+		c.SetVADAutoStop(true, vadAutoStop)
+		logrus.Infof("已启用静音自动停止，静音时长: %v", vadAutoStop)
+	}
+
+	// 启动健康检查服务
+	if healthAddr != "" {
+		if err := startHealthServer(healthAddr, c, proto); err != nil {
+			logrus.Errorf("启动健康检查服务失败: %v", err)
+		}
+	}
+
+	// 启动Prometheus指标服务
+	if metricsAddr != "" {
+		if err := startMetricsServer(metricsAddr, c); err != nil {
+			logrus.Errorf("启动指标服务失败: %v", err)
+		}
+	}
+
 	// 捕获中断信号
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -336,55 +375,33 @@ func main() {
 		sig := <-sigChan
 		logrus.Infof("接收到信号: %v, 立即退出...", sig)
 
-		// 使用cleanupAndExit功能进行资源清理和安全退出
-		cleanupAndExit(c, 0)
+		// 统一关闭所有子系统后退出
+		shutdownAndExit(c, 0)
 	}()
 
 	// 设置回调
 	setupCallbacks(c)
 
-	// 设置连接回调
-	proto.SetOnConnected(func() {
-		logrus.Info("✅ WebSocket连接成功!")
-
-		// 发送hello消息
-		helloMsg := map[string]interface{}{
-			"type":      "hello",
-			"version":   1,
-			"transport": "websocket",
-			"audio_params": map[string]interface{}{
-				"format":         "opus",
-				"sample_rate":    16000,
-				"channels":       1,
-				"frame_duration": 60,
-			},
-		}
-
-		if err := proto.SendJSON(helloMsg); err != nil {
-			logrus.Errorf("❌ 发送hello消息失败: %v", err)
-		} else {
-			logrus.Info("✅ hello消息发送成功")
-		}
+	c.SetOnServerClose(func(code int, text string) {
+		logrus.Warnf("服务器关闭了连接: 关闭码=%d（%s）, 原因=%q", code, protocol.CloseCodeMeaning(code), text)
 	})
 
-	proto.SetOnDisconnected(func(err error) {
-		if err != nil {
-			logrus.Errorf("❌ WebSocket断开连接: %v", err)
+	c.SetOnDisconnected(func(reason protocol.DisconnectReason, err error) {
+		if reason.ShouldReconnect() {
+			logrus.Errorf("❌ WebSocket断开连接（%s）: %v", reason, err)
 
 			// 延迟1秒后尝试重连
 			go func() {
 				logrus.Info("准备在1秒后尝试重新连接...")
 				time.Sleep(1 * time.Second)
+				if c.IsClosed() {
+					logrus.Info("客户端已关闭，不再重新连接")
+					return
+				}
 
 				logrus.Info("正在尝试重新连接...")
-				// 设置请求头
-				proto.SetHeader("Authorization", fmt.Sprintf("Bearer %s", token))
-				proto.SetHeader("Protocol-Version", "1")
-				proto.SetHeader("Device-Id", deviceID)
-				proto.SetHeader("Client-Id", generateUUID(deviceID))
-
-				// 连接
-				if err := proto.Connect(serverURL); err != nil {
+				// 由客户端重新握手，开启会话恢复时hello携带原会话ID，按住说话时恢复监听并补发暂存的音频
+				if err := c.OpenAudioChannel(serverURL); err != nil {
 					logrus.Errorf("重新连接失败: %v", err)
 					analyzeConnectionError(err)
 				} else {
@@ -396,8 +413,8 @@ func main() {
 		}
 	})
 
-	// 设置JSON消息回调
-	proto.SetOnJSONMessage(func(data []byte) {
+	// 原始JSON消息回调，在客户端处理消息之前记录日志；消息仍由客户端分发，状态和回调照常更新
+	c.SetOnRawJSON(func(data []byte) {
 		// 尝试解析JSON格式以便美观打印
 		var jsonData interface{}
 		if err := json.Unmarshal(data, &jsonData); err == nil {
@@ -441,51 +458,50 @@ func main() {
 		}
 	})
 
-	// 设置二进制消息回调
-	proto.SetOnBinaryMessage(func(data []byte) {
-		if verboseLogging {
-			logrus.Infof("📥 接收到二进制数据: %d字节", len(data))
-		}
-
-		// 处理Opus编码的音频数据
-		if audioManager != nil && audioManager.Player() != nil {
-			// 检查音频播放器状态
-			if !audioManager.Player().IsPlaying() {
-				// 播放器未运行，可能是因为刚初始化或之前有错误
-				logrus.Debug("音频播放器未运行，尝试启动...")
-				if err := audioManager.Player().Start(); err != nil {
-					logrus.Errorf("启动音频播放器失败: %v", err)
-				}
+	// 启动输入监听
+	keyPressCh := make(chan string)
+	commandCh := make(chan string)
+	textCh := make(chan string)
+	if !textMode && !headless && !stdinIsTerminal() {
+		logrus.Info("标准输入不是终端，自动切换到无头模式")
+		headless = true
+	}
+	if headless {
+		control := controlChannels{keyPress: keyPressCh, command: commandCh, text: textCh}
+		logrus.Info("无头模式: 逐行发送命令 start、stop、say <文本>、quit")
+		if controlSocket != "" {
+			if err := serveControlSocket(controlSocket, control); err != nil {
+				logrus.Fatalf("%v", err)
 			}
-
-			// 如果播放器在哑模式下运行，记录一下
-			if audioManager.Player().IsDummyMode() && verboseLogging {
-				logrus.Debug("音频播放器在哑模式下运行，可能无法实际播放音频")
+		}
+		go readHeadlessInput(os.Stdin, control)
+	} else if textMode {
+		fmt.Println("文本对话模式:")
+		fmt.Println("  输入文字后回车发送")
+		fmt.Println("  q - 退出程序")
+		go readTextInput(textCh, commandCh)
+	} else {
+		if !noTUI && stdoutIsTerminal() {
+			var err error
+			if ui, err = newStatusUI(); err != nil {
+				logrus.Warnf("%v，使用日志输出", err)
 			}
-
-			c.SetState(client.StateSpeaking)
-			// 将Opus编码的音频数据添加到播放队列
-			audioManager.Player().QueueAudio(data)
-
-			if verboseLogging {
-				logrus.Debugf("已将%d字节Opus编码音频数据添加到播放队列", len(data))
+		}
+		if ui != nil {
+			// 状态界面显示录音电平，并接管按键输入和日志输出
+			if audioManager != nil {
+				audioManager.SetLevelCallback(ui.setLevel)
 			}
+			ui.start(keyPressCh, commandCh)
 		} else {
-			logrus.Warn("音频播放器未初始化，无法播放收到的音频数据")
-			// 不再尝试 new audioPlayer，直接报错
+			// 显示按键操作说明
+			fmt.Println("按键操作:")
+			fmt.Println("  f - 开始录音")
+			fmt.Println("  s - 停止录音")
+			fmt.Println("  q - 退出程序")
+			go readInput(keyPressCh, commandCh)
 		}
-	})
-
-	// 显示按键操作说明
-	fmt.Println("按键操作:")
-	fmt.Println("  f - 开始录音")
-	fmt.Println("  s - 停止录音")
-	fmt.Println("  q - 退出程序")
-
-	// 启动按键监听
-	keyPressCh := make(chan string)
-	commandCh := make(chan string)
-	go readInput(keyPressCh, commandCh)
+	}
 
 	// 记录录音状态
 	isRecording := false
@@ -493,20 +509,8 @@ func main() {
 	// 连接服务器
 	logrus.Info("准备连接到服务器...")
 
-	// 添加请求头
-	proto.SetHeader("Authorization", fmt.Sprintf("Bearer %s", token))
-	proto.SetHeader("Protocol-Version", "1")
-	proto.SetHeader("Device-Id", deviceID)
-	proto.SetHeader("Client-Id", generateUUID(deviceID))
-
-	// 设置握手超时
-	proto.SetHandshakeTimeout(15 * time.Second)
-
-	// 	// 连接
-	err := proto.Connect(serverURL)
-	// connDone <- err
-	// }()
-	if err != nil {
+	// 由客户端设置请求头、连接并完成hello握手
+	if err := c.OpenAudioChannel(serverURL); err != nil {
 		logrus.Errorf("❌ 连接失败: %v", err)
 		analyzeConnectionError(err)
 		return
@@ -524,28 +528,34 @@ func main() {
 			// 直接处理简单的退出命令
 			if cmd == "quit" || cmd == "q" {
 				logrus.Info("收到退出命令，准备退出程序...")
-				c.CloseAudioChannel()
-				cleanupAndExit(c, 0)
+				shutdownAndExit(c, 0)
 			} else {
 				logrus.Warnf("不支持的命令: %s", cmd)
 			}
 
+		case text := <-textCh:
+			// 文本对话：以文字发起一轮对话，回复照常播放并打印
+			logrus.Infof("发送文本: %s", text)
+			if err := c.SendText(text); err != nil {
+				logrus.Errorf("发送文本失败: %v", err)
+			}
+
 		case key := <-keyPressCh:
 			// 处理按键事件
 			logrus.Debugf("主循环收到按键事件: %s", key)
 			handleKeyPress(c, key, &isRecording)
 
 		case <-pingTicker.C:
-			// 发送心跳包，保持连接
+			// 发送心跳包，保持连接并测量往返时延
 			if proto.IsConnected() {
-				pingMsg := map[string]interface{}{
-					"type": "ping",
-					"id":   time.Now().Unix(),
-				}
-
-				if err := proto.SendJSON(pingMsg); err != nil {
-					logrus.Warnf("发送心跳包失败: %v", err)
-				}
+				go func() {
+					rtt, err := c.Ping(context.Background())
+					if err != nil {
+						logrus.Warnf("发送心跳包失败: %v", err)
+						return
+					}
+					logrus.Debugf("心跳往返时延: %v", rtt)
+				}()
 			}
 		}
 	}
@@ -587,12 +597,9 @@ func handleKeyPress(c *client.Client, key string, isRecording *bool) {
 		currentState := c.GetState()
 		logrus.Info("当前客户端状态:", currentState)
 		if currentState == client.StateSpeaking {
-			logrus.Info("正在中断AI回复以开始录音...")
-			c.SendAbortSpeaking("start_recording")
-
-			// 停止音频播放
-			stopAudioPlayback(c)
-
+			// 宽限期结束后中断AI回复并停止播放
+			logrus.Info("准备中断AI回复以开始录音...")
+			c.BeginBargeIn("start_recording")
 		}
 
 		if currentState != client.StateListening {
@@ -600,7 +607,7 @@ func handleKeyPress(c *client.Client, key string, isRecording *bool) {
 			// 增加超时保护
 			commandDone := make(chan error, 1)
 			go func() {
-				err := c.SendStartListening(client.ListenModeManual)
+				err := c.SendStartListening("")
 				commandDone <- err
 			}()
 
@@ -649,7 +656,10 @@ func handleKeyPress(c *client.Client, key string, isRecording *bool) {
 	} else if key == "F2_RELEASED" {
 		// 检查客户端当前状态，如果是Speaking状态，则停止播放
 		currentState := c.GetState()
-		if currentState == client.StateSpeaking {
+		if c.CancelBargeIn() {
+			// 宽限期内停止说话，继续播放AI回复
+			logrus.Info("宽限期内停止说话，继续播放AI回复")
+		} else if currentState == client.StateSpeaking {
 			logrus.Info("正在中断AI回复...")
 			if err := c.SendAbortSpeaking("stop_speaking"); err != nil {
 				logrus.Errorf("发送停止讲话命令失败: %v", err)
@@ -669,6 +679,11 @@ func handleKeyPress(c *client.Client, key string, isRecording *bool) {
 				logrus.Error("客户端未连接到服务器，但尝试停止录音")
 				fmt.Println("⚠️ 连接已断开，无法正常停止录音")
 
+				// 等待重连期间松开按键，放弃这一轮发言和暂存的音频
+				if err := c.SendStopListening(); err != nil {
+					logrus.Debugf("停止监听: %v", err)
+				}
+
 				// 即使未连接，也要尝试停止本地录音设备
 				if audioManager != nil {
 					if err := audioManager.StopRecording(); err != nil {
@@ -724,6 +739,15 @@ func initAudio() {
 		logrus.Warnf("初始化音频管理器失败: %v，将无法录音", err)
 	} else {
 		logrus.Debug("音频管理器初始化成功")
+		if jitterBuffer > 0 {
+			audioManager.SetJitterBuffer(jitterBuffer, 0)
+			logrus.Infof("已启用播放抖动缓冲: %v", jitterBuffer)
+		}
+		if saveTTSFile != "" {
+			if err := audioManager.StartPlaybackToFile(saveTTSFile); err != nil {
+				logrus.Errorf("保存播放音频失败: %v", err)
+			}
+		}
 	}
 
 	// audioPlayer 的初始化全部移除，防止oto.NewContext多次调用
@@ -756,10 +780,6 @@ func cleanupAudio() {
 
 // stopAudioPlayback 停止音频播放
 func stopAudioPlayback(c *client.Client) {
-	// 先等待500毫秒，给音频播放器一些时间处理缓冲区中的数据
-	logrus.Debug("等待500毫秒后停止音频播放...")
-	time.Sleep(500 * time.Millisecond)
-
 	// 停止音频播放
 	if audioManager != nil && audioManager.Player() != nil && audioManager.Player().IsPlaying() {
 		if err := audioManager.Player().Stop(); err != nil {
@@ -770,8 +790,8 @@ func stopAudioPlayback(c *client.Client) {
 	}
 }
 
-// runActivation 运行激活流程
-func runActivation() {
+// runActivation 运行激活流程，激活成功后记录到状态文件
+func runActivation(st *cliState) {
 	logrus.Info("开始执行设备激活流程...")
 
 	// 创建OTA客户端
@@ -783,8 +803,30 @@ func runActivation() {
 		logrus.Fatalf("设备激活失败: %v", err)
 	}
 
-	logrus.Info("设备激活成功!")
-	logrus.Infof("激活码: %s", resp.Activation.Code)
+	if resp.Activation.Code != "" {
+		logrus.Infof("激活码: %s", resp.Activation.Code)
+		if resp.Activation.Message != "" {
+			logrus.Info(resp.Activation.Message)
+		}
+		logrus.Info("请在控制台输入激活码，正在等待激活...")
+
+		// 轮询直到激活、超时或收到终止信号
+		ctx, cancel := context.WithTimeout(context.Background(), activateTimeout)
+		defer cancel()
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		resp, err = otaClient.WaitForActivation(ctx, activatePollInterval)
+		if err != nil {
+			logrus.Fatalf("等待设备激活失败: %v", err)
+		}
+	}
+
+	logrus.Info("激活成功")
+	st.recordActivation(resp)
+	if err := st.save(); err != nil {
+		logrus.Warnf("保存激活状态失败: %v", err)
+	}
 	logrus.Infof("固件版本: %s", resp.Firmware.Version)
 	logrus.Infof("MQTT配置: 端点=%s, 客户端ID=%s",
 		resp.MQTT.Endpoint, resp.MQTT.ClientID)
@@ -795,13 +837,23 @@ func setupCallbacks(c *client.Client) {
 	// 状态变更回调
 	c.SetOnStateChanged(func(oldState, newState string) {
 		logrus.Infof("客户端状态变更: %s -> %s", oldState, newState)
+		ui.setState(newState)
 
 		// 处理不同的状态变更
+		// 全双工模式下录音在监听和播放期间都保持进行
+		fullDuplex := c.IsFullDuplex()
 		if oldState != StateListening && newState == StateListening {
-			// 进入监听状态，开始录音
-			startRecording(c)
+			// 进入监听状态，开始录音；全双工时从播放回到监听，录音一直在进行
+			if !(fullDuplex && oldState == StateSpeaking) {
+				startRecording(c)
+			}
 		} else if oldState == StateListening && newState != StateListening {
-			// 退出监听状态，停止录音
+			// 退出监听状态，停止录音；全双工时开始播放不停止录音，断线等待重连时录音继续
+			if !(fullDuplex && newState == StateSpeaking) && !c.IsReconnectBuffering() {
+				stopRecording(c)
+			}
+		} else if fullDuplex && oldState == StateSpeaking && audioManager != nil && audioManager.IsRecording() {
+			// 全双工时播放结束后不再监听（例如断线），停止录音
 			stopRecording(c)
 		}
 	})
@@ -814,6 +866,12 @@ func setupCallbacks(c *client.Client) {
 	// 识别文本回调
 	c.SetOnRecognizedText(func(text string) {
 		logrus.Infof("识别到文本: %s", text)
+		ui.addUserText(text)
+	})
+
+	// 中间识别结果回调，用于状态界面显示正在识别的文本
+	c.SetOnPartialText(func(text string) {
+		ui.setUserPartial(text)
 	})
 
 	// 朗读文本回调
@@ -821,12 +879,38 @@ func setupCallbacks(c *client.Client) {
 		logrus.Infof("AI回复: %s", text)
 	})
 
-	// 音频数据回调
-	c.SetOnAudioData(func(data []byte) {
-		// logrus.Debugf("收到音频数据: %d字节", len(data))
-		// 将音频数据添加到播放队列
+	// 回复拼接回调，用于状态界面显示本轮的完整回复
+	c.SetOnAssistantPartial(func(text string) {
+		ui.setReplyPartial(text)
+	})
+	c.SetOnAssistantResponse(func(text string) {
+		ui.addReply(text)
+		// 文本对话模式下把完整回复打印在输入行之间
+		if textMode {
+			fmt.Printf("小智: %s\n", text)
+		}
+	})
+
+	// 音频帧回调，客户端按半双工规则处理下行音频，监听期间的音频被缓冲，其余在这里加入播放队列
+	c.SetOnAudioFrame(func(frame protocol.AudioFrame) {
+		data := frame.Payload
+		if verboseLogging {
+			logrus.Infof("📥 接收到音频数据: %d字节", len(data))
+		}
 		if audioManager != nil && audioManager.Player() != nil {
-			audioManager.Player().QueueAudio(data)
+			// 播放器未运行，可能是因为刚初始化或之前有错误
+			if !audioManager.Player().IsPlaying() {
+				logrus.Debug("音频播放器未运行，尝试启动...")
+				if err := audioManager.Player().Start(); err != nil {
+					logrus.Errorf("启动音频播放器失败: %v", err)
+				}
+			}
+			if frame.Lost > 0 {
+				// 帧头模式下序号不连续，先补偿丢失的帧
+				audioManager.Player().QueueAudioAfterLoss(data, frame.Lost)
+			} else {
+				audioManager.Player().QueueAudio(data)
+			}
 			if audioManager.Player().IsDummyMode() {
 				// 如果是哑模式，简单记录一下
 				logrus.Debugf("音频在哑模式下处理")
@@ -840,21 +924,56 @@ func setupCallbacks(c *client.Client) {
 	})
 
 	// IoT命令回调
-	c.SetOnIoTCommand(func(commands []interface{}) {
-		logrus.Infof("收到IoT命令: %v", commands)
+	c.SetOnIoTCommands(func(commands []protocol.IoTCommand) {
+		for _, cmd := range commands {
+			logrus.Infof("收到IoT命令: %s.%s %v", cmd.Name, cmd.Method, cmd.Parameters)
+		}
 		// 这里可以实现IoT命令处理
 	})
 
-	// 音频通道打开回调
+	// 轮次延迟回调
+	c.SetOnTurnLatency(func(d time.Duration) {
+		logrus.Debugf("本轮响应延迟: %v", d)
+	})
+
+	// 本地播放队列播空时通知客户端，用于判断TTS是否真正播放完毕，文本模式下不录音也需要
+	if audioManager != nil {
+		audioManager.SetOnQueueDrained(c.PlaybackDrained)
+		c.SetAudioMetricsProvider(audioManager)
+	}
+
+	// TTS音频实际播放完毕回调
+	c.SetOnSpeakingFinished(func() {
+		logrus.Debug("TTS音频播放完毕")
+	})
+
+	// 打断生效回调，停止本地播放
+	c.SetOnBargeIn(func() {
+		stopAudioPlayback(c)
+	})
+
+	// 音频参数更新回调，重新配置本地编解码器和播放器
+	c.SetOnAudioParamsChanged(func(params protocol.AudioParams) error {
+		if audioManager == nil {
+			return nil
+		}
+		return audioManager.Reconfigure(params.SampleRate, params.Channels, params.FrameDuration)
+	})
+
+	// 音频通道打开回调，服务器已回复hello
 	c.SetOnAudioChannelOpen(func() {
-		logrus.Info("音频通道已打开")
+		logrus.Info("✅ WebSocket连接成功，音频通道已打开")
+		ui.setConnected(true)
 	})
 
 	// 音频通道关闭回调
 	c.SetOnAudioChannelClosed(func() {
 		logrus.Info("音频通道已关闭")
-		// 如果正在录音，停止录音
-		stopRecording(c)
+		ui.setConnected(false)
+		// 如果正在录音，停止录音；按住说话时断线，录音继续，重连后补发
+		if !c.IsReconnectBuffering() {
+			stopRecording(c)
+		}
 	})
 }
 
@@ -873,7 +992,7 @@ func startRecording(c *client.Client) {
 
 	// 如果客户端不在监听状态，确保先发送开始监听命令
 	if c != nil && c.GetState() != client.StateListening {
-		if err := c.SendStartListening(client.ListenModeManual); err != nil {
+		if err := c.SendStartListening(""); err != nil {
 			logrus.Errorf("发送开始监听命令失败: %v", err)
 			return
 		}
@@ -886,14 +1005,29 @@ func startRecording(c *client.Client) {
 		time.Sleep(50 * time.Millisecond)
 	}
 
+	if enableAEC {
+		audioManager.EnableAEC(true)
+		logrus.Info("已启用软件回声消除")
+	}
+	if noiseSuppression > 0 {
+		audioManager.EnableNoiseSuppression(noiseSuppression)
+		logrus.Infof("已启用录音降噪，强度: %d", noiseSuppression)
+	}
+	if agcTarget > 0 {
+		audioManager.EnableAGC(agcTarget)
+		logrus.Infof("已启用录音自动增益，目标电平: %.3f", agcTarget)
+	}
+	if silenceThreshold > 0 {
+		audioManager.SetSilenceThreshold(silenceThreshold)
+		logrus.Infof("已启用静音抑制，阈值: %.3f", silenceThreshold)
+	}
 	// 创建一个带缓冲的通道来接收音频数据
 	audioChan = make(chan []byte, 100) // 足够大的缓冲区
 
 	// 设置PCM数据回调
 	audioManager.SetPCMDataCallback(func(data []int16, size int) {
-		// 复制数据以避免竞争条件
-		dataCopy := make([]int16, size)
-		copy(dataCopy, data[:size])
+		// 送入语音活动检测，用于静音自动停止
+		c.ProcessPCM(data[:size])
 	})
 
 	// 启动一个单独的goroutine处理音频数据发送
@@ -1012,19 +1146,68 @@ func getMACAddress() (string, error) {
 	return "", fmt.Errorf("未找到有效的网络接口")
 }
 
-// isDeviceActivated 检查设备是否已激活
-func isDeviceActivated() bool {
+// requestOTA 向OTA服务器请求激活状态和服务器配置
+func requestOTA() (*ota.OTAResponse, error) {
 	// 创建OTA客户端
 	otaClient := ota.NewOTAClient(deviceID, appVersion, boardType)
 
-	// 检查激活状态
-	activated, err := otaClient.CheckActivationStatus()
-	if err != nil {
-		logrus.Errorf("检查设备激活状态失败: %v", err)
-		return false
+	return otaClient.RequestActivation()
+}
+
+// isFlagSet 返回参数是否通过命令行、环境变量或配置文件显式指定
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// applyOTAWebsocketConfig 未通过命令行显式指定时，使用OTA下发的WebSocket地址和令牌
+func applyOTAWebsocketConfig(cfg ota.WebsocketConfig) {
+	if cfg.URL != "" && !isFlagSet("server") {
+		serverURL = cfg.URL
+		logrus.Infof("使用OTA下发的WebSocket地址: %s", serverURL)
+	}
+	if cfg.Token != "" && !isFlagSet("token") {
+		token = cfg.Token
+		logrus.Info("使用OTA下发的访问令牌")
+	}
+}
+
+// configureTLS 根据命令行参数加载自定义CA证书和客户端证书
+func configureTLS(proto *protocol.WebsocketProtocol) error {
+	if caCertFile != "" {
+		pemBytes, err := os.ReadFile(caCertFile)
+		if err != nil {
+			return fmt.Errorf("读取CA证书失败: %v", err)
+		}
+		if err := proto.SetRootCAs(pemBytes); err != nil {
+			return err
+		}
+		logrus.Infof("使用CA证书校验服务器: %s", caCertFile)
 	}
 
-	return activated
+	if clientCertFile != "" || clientKeyFile != "" {
+		if clientCertFile == "" || clientKeyFile == "" {
+			return fmt.Errorf("-client-cert 和 -client-key 需要同时指定")
+		}
+		certPEM, err := os.ReadFile(clientCertFile)
+		if err != nil {
+			return fmt.Errorf("读取客户端证书失败: %v", err)
+		}
+		keyPEM, err := os.ReadFile(clientKeyFile)
+		if err != nil {
+			return fmt.Errorf("读取客户端私钥失败: %v", err)
+		}
+		if err := proto.SetClientCert(certPEM, keyPEM); err != nil {
+			return err
+		}
+		logrus.Infof("已加载双向TLS客户端证书: %s", clientCertFile)
+	}
+	return nil
 }
 
 // readInput 处理按键输入
@@ -1036,31 +1219,21 @@ func readInput(keyPressCh chan<- string, commandCh chan<- string) {
 		return
 	}
 	
-	// 设置终端为原始模式
-	if err := exec.Command("stty", "-F", "/dev/tty", "cbreak", "min", "1").Run(); err != nil {
-		logrus.Errorf("设置终端cbreak模式失败: %v", err)
-	}
-	// 关闭终端回显
-	if err := exec.Command("stty", "-F", "/dev/tty", "-echo").Run(); err != nil {
-		logrus.Errorf("关闭终端回显失败: %v", err)
+	// 关闭行缓冲和回显，逐个读取按键；退出时由 restoreTerminal 恢复
+	if err := enterKeyMode(); err != nil {
+		logrus.Errorf("设置终端按键模式失败: %v", err)
 	}
 
-	// 即使在goroutine中发生panic，也要尝试恢复终端设置
-	defer func() {
-		if err := exec.Command("stty", "-F", "/dev/tty", "echo").Run(); err != nil {
-			logrus.Errorf("恢复终端回显失败: %v", err)
-		}
-		if err := exec.Command("stty", "-F", "/dev/tty", "-cbreak").Run(); err != nil {
-			logrus.Errorf("恢复终端规范模式失败: %v", err)
-		}
-	}()
-
 	// 记录录音按键状态，防止重复触发
 	recordKeyPressed := false
 
 	for {
 		var b [1]byte
 		_, err := os.Stdin.Read(b[:])
+		if err == io.EOF {
+			logrus.Debug("标准输入已结束，停止读取按键")
+			return
+		}
 		if err != nil {
 			logrus.Errorf("读取输入失败: %v", err)
 			continue
@@ -1090,6 +1263,25 @@ func readInput(keyPressCh chan<- string, commandCh chan<- string) {
 	}
 }
 
+// readTextInput 文本对话模式下逐行读取输入，终端保持规范模式以便编辑
+func readTextInput(textCh chan<- string, commandCh chan<- string) {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch line {
+		case "":
+			continue
+		case "q", "Q", "quit":
+			commandCh <- "quit"
+		default:
+			textCh <- line
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		logrus.Errorf("读取输入失败: %v", err)
+	}
+}
+
 // 添加 Windows 专用的输入读取函数
 func readInputWindows(keyPressCh chan<- string, commandCh chan<- string) {
     // 使用 github.com/eiannone/keyboard 包
@@ -1097,7 +1289,8 @@ func readInputWindows(keyPressCh chan<- string, commandCh chan<- string) {
         logrus.Errorf("无法打开键盘: %v", err)
         return
     }
-    defer keyboard.Close()
+    // 键盘库修改了控制台模式，退出时由 restoreTerminal 关闭
+    onTerminalRestore(func() { keyboard.Close() })
 
     recordKeyPressed := false
 
@@ -1145,17 +1338,11 @@ func reinitializeOpusDecoder(sampleRate, channels, frameDuration int) {
 		return
 	}
 
-	if audioInited {
-		logrus.Warn("检测到服务器音频参数变化，Oto 不支持热切换采样率，请重启程序以应用新参数！")
-		return
-	}
-
 	err := audioManager.RecreatePlayer(sampleRate, channels, frameDuration)
 	if err != nil {
 		logrus.Errorf("重建播放器失败: %v", err)
 	} else {
 		audioManager.Player().Start()
 		logrus.Info("已根据服务器参数重建播放器")
-		audioInited = true
 	}
 }
@@ -0,0 +1,21 @@
+//go:build prometheus
+
+package main
+
+import (
+	"github.com/justa-cai/xiaozhi-go/internal/client"
+	"github.com/justa-cai/xiaozhi-go/internal/metrics"
+)
+
+// startMetricsServer 在addr上以 /metrics 导出客户端的Prometheus指标，包括Go运行时和进程指标
+func startMetricsServer(addr string, c *client.Client) error {
+	// 未开始录音前也导出音频统计
+	if audioManager != nil {
+		c.SetAudioMetricsProvider(audioManager)
+	}
+	if err := c.RegisterMetrics(nil); err != nil {
+		return err
+	}
+	_, err := metrics.Serve(addr, nil)
+	return err
+}
@@ -0,0 +1,14 @@
+//go:build !prometheus
+
+package main
+
+import (
+	"errors"
+
+	"github.com/justa-cai/xiaozhi-go/internal/client"
+)
+
+// startMetricsServer 未使用 -tags prometheus 构建时不支持导出指标
+func startMetricsServer(addr string, c *client.Client) error {
+	return errors.New("未包含Prometheus指标支持，请使用 -tags prometheus 构建")
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/justa-cai/xiaozhi-go/internal/client"
+	"github.com/sirupsen/logrus"
+)
+
+// shutdownTimeout 退出时等待各子系统关闭的最长时间
+const shutdownTimeout = 2 * time.Second
+
+var (
+	shutdownOnce sync.Once
+	shutdownDone = make(chan struct{})
+)
+
+// Shutdown 统一关闭客户端、音频系统并恢复终端设置
+// 先关闭客户端再关闭音频系统：客户端关闭后不再有服务器音频写入播放队列，排空队列才有意义，
+// 录音回调也不会再向已关闭的连接发送数据。全部完成或ctx到期时返回；重复调用会等待同一次关闭流程
+func Shutdown(ctx context.Context, c *client.Client) error {
+	shutdownOnce.Do(func() {
+		go func() {
+			defer close(shutdownDone)
+
+			shutdownClient(ctx, c)
+			shutdownAudio(ctx)
+			restoreTerminal()
+		}()
+	})
+
+	select {
+	case <-shutdownDone:
+		logrus.Debug("所有子系统已关闭")
+		return nil
+	case <-ctx.Done():
+		// 超时也要保证终端可用
+		restoreTerminal()
+		return ctx.Err()
+	}
+}
+
+// shutdownClient 停止录音后关闭客户端
+func shutdownClient(ctx context.Context, c *client.Client) {
+	if c == nil {
+		return
+	}
+
+	// 先停止采集，让尾部音频帧进入发送队列
+	if audioManager != nil && audioManager.IsRecording() {
+		if err := audioManager.StopRecording(); err != nil {
+			logrus.Warnf("停止录音失败: %v", err)
+		}
+	}
+
+	logrus.Debug("正在关闭客户端...")
+	if err := c.Close(ctx); err != nil {
+		logrus.Warnf("关闭客户端失败: %v", err)
+	}
+	logrus.Debug("客户端已关闭")
+}
+
+// shutdownAudio 等待播放队列排空后关闭音频管理器
+func shutdownAudio(ctx context.Context) {
+	if audioManager == nil {
+		return
+	}
+
+	if audioManager.IsPlaying() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+	drain:
+		for audioManager.GetQueueLength() > 0 {
+			select {
+			case <-ctx.Done():
+				logrus.Warn("等待播放队列排空超时")
+				break drain
+			case <-ticker.C:
+			}
+		}
+	}
+
+	if err := audioManager.Close(); err != nil {
+		logrus.Warnf("关闭音频管理器失败: %v", err)
+	}
+	logrus.Debug("音频管理器已关闭")
+}
+
+// shutdownAndExit 在限定时间内关闭所有子系统并退出
+func shutdownAndExit(c *client.Client, code int) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	err := Shutdown(ctx, c)
+	cancel()
+
+	if err != nil {
+		logrus.Warnf("资源清理未在%v内完成: %v", shutdownTimeout, err)
+		if code == 0 {
+			code = 1
+		}
+	}
+
+	logrus.Info("正在退出程序")
+	os.Exit(code)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/justa-cai/xiaozhi-go/internal/audio"
+	"github.com/justa-cai/xiaozhi-go/internal/client"
+	"github.com/justa-cai/xiaozhi-go/internal/protocol"
+)
+
+func TestShutdownClosesAllSubsystemsWithinDeadline(t *testing.T) {
+	shutdownOnce = sync.Once{}
+	shutdownDone = make(chan struct{})
+	t.Cleanup(func() { audioManager = nil })
+
+	mock := protocol.NewMockProtocol()
+	mock.OnSendJSON = func(data []byte) {
+		if protocol.MessageType(data) == "hello" {
+			mock.InjectJSON(`{"type":"hello","version":1,"transport":"websocket"}`)
+		}
+	}
+	c := client.New(mock)
+	if err := c.OpenAudioChannel("ws://test"); err != nil {
+		t.Fatalf("打开音频通道失败: %v", err)
+	}
+
+	recorder := audio.NewMockRecorder(audio.RecorderOptions{
+		SampleRate:    audio.DefaultSampleRate,
+		ChannelCount:  audio.DefaultChannelCount,
+		FrameDuration: audio.DefaultFrameDuration,
+	}, nil)
+	var err error
+	audioManager, err = audio.NewAudioManagerWithOptions(audio.AudioManagerOptions{Recorder: recorder, HeadlessOutput: true})
+	if err != nil {
+		t.Fatalf("创建音频管理器失败: %v", err)
+	}
+	audioManager.SetAudioDataCallback(func(data []byte) { c.SendAudioData(data) })
+	if err := c.SendStartListening(client.ListenModeManual); err != nil {
+		t.Fatalf("开始监听失败: %v", err)
+	}
+	if err := audioManager.StartRecording(); err != nil {
+		t.Fatalf("开始录音失败: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	start := time.Now()
+	if err := Shutdown(ctx, c); err != nil {
+		t.Fatalf("关闭未在%v内完成: %v", shutdownTimeout, err)
+	}
+	elapsed := time.Since(start)
+
+	select {
+	case <-shutdownDone:
+	default:
+		t.Error("Shutdown 返回后关闭流程仍未结束")
+	}
+	if !c.IsClosed() {
+		t.Error("客户端未关闭")
+	}
+	if mock.IsConnected() {
+		t.Error("关闭后协议仍处于连接状态")
+	}
+	if audioManager.IsRecording() {
+		t.Error("关闭后仍在录音")
+	}
+	if elapsed >= shutdownTimeout {
+		t.Errorf("关闭用时%v，超过期限%v", elapsed, shutdownTimeout)
+	}
+
+	// 重复调用等待同一次关闭流程，立即返回
+	if err := Shutdown(ctx, c); err != nil {
+		t.Errorf("重复调用 Shutdown 返回错误: %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/justa-cai/xiaozhi-go/internal/ota"
+	"github.com/sirupsen/logrus"
+)
+
+// activationCacheTTL 本地记录的激活状态在这段时间内直接使用，不再请求OTA服务器；
+// 超过后重新检查，检查失败时仍沿用本地记录
+const activationCacheTTL = 24 * time.Hour
+
+// cliState 命令行客户端在多次运行之间保存的状态，位于配置文件同目录下的 state.json，
+// 由程序自动维护，可通过 -reset-state 清除
+type cliState struct {
+	DeviceID  string              `json:"device_id,omitempty"` // 上次使用的设备ID
+	ClientID  string              `json:"client_id,omitempty"` // 上次使用的客户端ID
+	Activated bool                `json:"activated"`           // 设备最近一次确认时是否已激活
+	CheckedAt time.Time           `json:"checked_at"`          // 最近一次向OTA服务器确认激活状态的时间
+	Websocket ota.WebsocketConfig `json:"websocket"`           // 最近一次OTA下发的WebSocket配置
+	MQTT      ota.MQTTConfig      `json:"mqtt"`                // 最近一次OTA下发的MQTT配置
+
+	path string
+}
+
+// statePath 返回与配置文件同目录的状态文件路径
+func statePath(cfg *cliConfig) string {
+	if cfg.path == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(cfg.path), "state.json")
+}
+
+// loadCLIState 读取状态文件，文件不存在或已损坏时返回空状态
+func loadCLIState(path string) *cliState {
+	st := &cliState{path: path}
+	if path == "" {
+		return st
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logrus.Warnf("读取状态文件失败: %v", err)
+		}
+		return st
+	}
+	if err := json.Unmarshal(data, st); err != nil {
+		logrus.Warnf("状态文件%s已损坏，将重新生成: %v", path, err)
+		return &cliState{path: path}
+	}
+	return st
+}
+
+// resetCLIState 删除状态文件
+func resetCLIState(path string) error {
+	if path == "" {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("删除状态文件失败: %v", err)
+	}
+	return nil
+}
+
+// useDevice 记录本次使用的设备ID和客户端ID，设备ID变化时之前的激活记录不再适用
+func (st *cliState) useDevice(deviceID, clientID string) {
+	if st.DeviceID != deviceID {
+		st.Activated = false
+		st.CheckedAt = time.Time{}
+		st.Websocket = ota.WebsocketConfig{}
+		st.MQTT = ota.MQTTConfig{}
+	}
+	st.DeviceID = deviceID
+	st.ClientID = clientID
+}
+
+// recordActivation 记录OTA服务器返回的激活状态和服务器配置
+func (st *cliState) recordActivation(resp *ota.OTAResponse) {
+	st.Activated = resp.Activation.Code == ""
+	st.CheckedAt = time.Now()
+	st.Websocket = resp.Websocket
+	st.MQTT = resp.MQTT
+}
+
+// servers 返回记录的OTA下发的服务器配置
+func (st *cliState) servers() serverConfig {
+	return serverConfig{Websocket: st.Websocket, MQTT: st.MQTT}
+}
+
+// activationFresh 返回本地记录的激活状态是否仍在有效期内
+func (st *cliState) activationFresh() bool {
+	return st.Activated && time.Since(st.CheckedAt) < activationCacheTTL
+}
+
+// save 写入状态文件
+func (st *cliState) save() error {
+	if st.path == "" {
+		return errors.New("无法确定状态文件路径")
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("编码状态文件失败: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(st.path), 0o700); err != nil {
+		return fmt.Errorf("创建状态目录失败: %v", err)
+	}
+	// 状态文件包含OTA下发的访问令牌，只允许当前用户读写
+	if err := os.WriteFile(st.path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("写入状态文件失败: %v", err)
+	}
+	return nil
+}
+
+// serverConfig OTA下发的服务器配置，用于选择传输方式
+type serverConfig struct {
+	Websocket ota.WebsocketConfig
+	MQTT      ota.MQTTConfig
+}
+
+// isDeviceActivated 返回设备是否已激活，以及需要使用的OTA下发的服务器配置。
+// 本地记录在有效期内时直接使用，不请求OTA服务器；否则请求OTA服务器并更新记录，
+// 请求失败但本地记录过设备已激活时沿用记录，避免OTA服务器短暂不可用时误报设备未激活
+func isDeviceActivated(st *cliState) (bool, serverConfig, error) {
+	if st.activationFresh() {
+		logrus.Infof("设备已激活（本地记录于%s）", st.CheckedAt.Format("2006-01-02 15:04:05"))
+		return true, st.servers(), nil
+	}
+
+	resp, err := requestOTA()
+	if err != nil {
+		if st.Activated {
+			logrus.Warnf("检查设备激活状态失败，沿用本地记录: %v", err)
+			return true, st.servers(), nil
+		}
+		return false, serverConfig{}, err
+	}
+
+	st.recordActivation(resp)
+	if err := st.save(); err != nil {
+		logrus.Warnf("保存激活状态失败: %v", err)
+	}
+	return st.Activated, st.servers(), nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/term"
+)
+
+// 按键模式下标准输入关闭行缓冲和回显，逐个读取按键；修改前的终端设置保存在这里，
+// 退出时由 restoreTerminal 统一恢复
+var (
+	terminalMu    sync.Mutex
+	terminalState *term.State // 进入按键模式前的终端设置，nil表示未修改终端
+	terminalClose func()      // 额外的恢复操作，例如Windows下关闭键盘库
+)
+
+// stdinIsTerminal 返回标准输入是否为终端；在systemd、Docker或管道中运行时为false
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// stdoutIsTerminal 返回标准输出是否为终端
+func stdoutIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// enterKeyMode 将标准输入切换为按键模式：关闭行缓冲和回显，保留Ctrl+C等信号键，输出换行不受影响
+func enterKeyMode() error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return errors.New("标准输入不是终端")
+	}
+	state, err := term.GetState(fd)
+	if err != nil {
+		return err
+	}
+	if err := setCbreak(fd); err != nil {
+		return err
+	}
+
+	terminalMu.Lock()
+	terminalState = state
+	terminalMu.Unlock()
+	return nil
+}
+
+// onTerminalRestore 注册退出时恢复终端需要执行的额外操作
+func onTerminalRestore(fn func()) {
+	terminalMu.Lock()
+	defer terminalMu.Unlock()
+	terminalClose = fn
+}
+
+// restoreTerminal 恢复进入按键模式前的终端设置，未修改终端时什么也不做，可重复调用
+func restoreTerminal() {
+	terminalMu.Lock()
+	state, closeFn := terminalState, terminalClose
+	terminalState, terminalClose = nil, nil
+	terminalMu.Unlock()
+
+	if closeFn != nil {
+		closeFn()
+	}
+	if state != nil {
+		if err := term.Restore(int(os.Stdin.Fd()), state); err != nil {
+			logrus.Errorf("恢复终端设置失败: %v", err)
+			return
+		}
+		logrus.Debug("已恢复终端设置")
+	}
+}
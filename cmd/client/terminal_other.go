@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+
+package main
+
+import "errors"
+
+// setCbreak 在没有termios的平台上不可用，Windows改用键盘库读取按键
+func setCbreak(fd int) error {
+	return errors.New("当前平台不支持终端按键模式")
+}
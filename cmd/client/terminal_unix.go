@@ -0,0 +1,18 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package main
+
+import "golang.org/x/sys/unix"
+
+// setCbreak 关闭规范模式和回显，每次读取至少返回一个字节；保留ISIG和输出处理，
+// 因此Ctrl+C仍触发SIGINT，日志换行正常显示
+func setCbreak(fd int) error {
+	termios, err := unix.IoctlGetTermios(fd, ioctlReadTermios)
+	if err != nil {
+		return err
+	}
+	termios.Lflag &^= unix.ICANON | unix.ECHO
+	termios.Cc[unix.VMIN] = 1
+	termios.Cc[unix.VTIME] = 0
+	return unix.IoctlSetTermios(fd, ioctlWriteTermios, termios)
+}
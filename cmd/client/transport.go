@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/justa-cai/xiaozhi-go/internal/protocol"
+	"github.com/sirupsen/logrus"
+)
+
+// transportAuto -transport 的默认值，按OTA下发的配置选择传输方式
+const transportAuto = "auto"
+
+// selectTransport 按 -transport 决定使用的传输方式。auto 时只有在未显式指定 -server、
+// OTA没有下发WebSocket地址而下发了MQTT配置时才使用MQTT，其余情况使用WebSocket
+func selectTransport(name string, servers serverConfig, serverExplicit bool) (string, error) {
+	switch name {
+	case protocol.TransportWebSocket, protocol.TransportMQTT:
+		return name, nil
+	case transportAuto, "":
+		if !serverExplicit && servers.Websocket.URL == "" && servers.MQTT.Endpoint != "" {
+			return protocol.TransportMQTT, nil
+		}
+		return protocol.TransportWebSocket, nil
+	default:
+		return "", fmt.Errorf("未知的传输方式: %q，可选 auto、websocket、mqtt", name)
+	}
+}
+
+// newProtocol 创建并配置所选传输方式的协议实例；使用MQTT时把服务器地址换成OTA下发的MQTT端点
+func newProtocol(servers serverConfig) (protocol.Protocol, error) {
+	name, err := selectTransport(transport, servers, isFlagSet("server"))
+	if err != nil {
+		return nil, err
+	}
+
+	if name == protocol.TransportMQTT {
+		if servers.MQTT.Endpoint == "" {
+			return nil, errors.New("OTA未下发MQTT配置，无法使用MQTT传输")
+		}
+		if caCertFile != "" || clientCertFile != "" {
+			logrus.Warn("MQTT传输暂不支持自定义证书，忽略 -ca-cert 和 -client-cert")
+		}
+		mp := protocol.NewMQTTProtocol(servers.MQTT.ProtocolOptions())
+		mp.SetSkipTLSVerify(skipTLSVerify)
+		mp.SetTimeout(connectTimeout)
+		serverURL = servers.MQTT.Endpoint
+		logrus.Infof("使用MQTT传输: %s", serverURL)
+		return mp, nil
+	}
+
+	wp := protocol.NewWebsocketProtocol()
+
+	// 设置跳过TLS证书验证
+	wp.SetSkipTLSVerify(skipTLSVerify)
+	if skipTLSVerify {
+		logrus.Info("已设置跳过TLS证书验证")
+	} else {
+		logrus.Info("将验证TLS证书")
+	}
+	if err := configureTLS(wp); err != nil {
+		return nil, fmt.Errorf("配置TLS证书失败: %v", err)
+	}
+
+	// 握手超时与客户端的连接超时一致，两者中较小的一个生效
+	wp.SetHandshakeTimeout(connectTimeout)
+	// 空闲时定时ping保活，同时测量往返时延
+	wp.SetKeepAlive(10 * time.Second)
+	return wp, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/justa-cai/xiaozhi-go/internal/ota"
+	"github.com/justa-cai/xiaozhi-go/internal/protocol"
+)
+
+func TestSelectTransport(t *testing.T) {
+	wsOnly := serverConfig{Websocket: ota.WebsocketConfig{URL: "wss://example.com/xiaozhi/v1/"}}
+	mqttOnly := serverConfig{MQTT: ota.MQTTConfig{Endpoint: "mqtt.example.com:8883"}}
+	both := serverConfig{Websocket: wsOnly.Websocket, MQTT: mqttOnly.MQTT}
+
+	tests := []struct {
+		name           string
+		flag           string
+		servers        serverConfig
+		serverExplicit bool
+		want           string
+		wantErr        bool
+	}{
+		{"auto无OTA配置", transportAuto, serverConfig{}, false, protocol.TransportWebSocket, false},
+		{"auto只有WebSocket", transportAuto, wsOnly, false, protocol.TransportWebSocket, false},
+		{"auto只有MQTT", transportAuto, mqttOnly, false, protocol.TransportMQTT, false},
+		{"auto两者都有优先WebSocket", transportAuto, both, false, protocol.TransportWebSocket, false},
+		{"auto显式指定服务器", transportAuto, mqttOnly, true, protocol.TransportWebSocket, false},
+		{"空值按auto处理", "", mqttOnly, false, protocol.TransportMQTT, false},
+		{"强制websocket", protocol.TransportWebSocket, mqttOnly, false, protocol.TransportWebSocket, false},
+		{"强制mqtt", protocol.TransportMQTT, wsOnly, false, protocol.TransportMQTT, false},
+		{"未知传输方式", "udp", both, false, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectTransport(tt.flag, tt.servers, tt.serverExplicit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("错误为 %v，期望出错: %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("选择了 %q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
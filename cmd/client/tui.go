@@ -0,0 +1,465 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/justa-cai/xiaozhi-go/internal/client"
+	"github.com/mattn/go-runewidth"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// uiRefreshInterval 状态界面的最短刷新间隔，录音电平每帧都会更新，合并到一次刷新中
+	uiRefreshInterval = 50 * time.Millisecond
+	// uiTranscriptHistory 对话记录保留的条数
+	uiTranscriptHistory = 200
+	// uiLogHistory 日志区保留的行数，退出时输出到标准错误
+	uiLogHistory = 100
+	// uiMaxLogRows 日志区最多占用的行数
+	uiMaxLogRows = 8
+	// levelFloorDB 电平表的下限，低于该值显示为空
+	levelFloorDB = -60.0
+)
+
+// ui 按键模式下的状态界面，未启用时为nil，此时各方法什么也不做
+var ui *statusUI
+
+// transcriptEntry 对话记录中的一条
+type transcriptEntry struct {
+	user bool // 用户说的话，否则为AI回复
+	text string
+}
+
+// statusUI 全屏状态界面：顶部状态栏显示连接、录音和播放状态，下面依次是录音电平、对话记录和最近的日志，
+// 底部为按键提示。日志不再直接输出到终端，而是显示在日志区，退出时输出到标准错误
+type statusUI struct {
+	screen tcell.Screen
+
+	mu           sync.Mutex
+	connected    bool
+	state        string
+	level        float64 // 最近一帧录音的RMS电平，0..1
+	peak         float64 // 最近一帧录音的峰值电平，0..1
+	transcript   []transcriptEntry
+	userPartial  string // 尚未结束的识别结果
+	replyPartial string // 尚未结束的AI回复
+	logs         []string
+	dirty        bool
+	closed       bool
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// newStatusUI 初始化全屏界面，终端不支持时返回错误
+func newStatusUI() (*statusUI, error) {
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return nil, fmt.Errorf("创建终端界面失败: %v", err)
+	}
+	if err := screen.Init(); err != nil {
+		return nil, fmt.Errorf("初始化终端界面失败: %v", err)
+	}
+	return &statusUI{
+		screen: screen,
+		state:  client.StateIdle,
+		dirty:  true,
+		done:   make(chan struct{}),
+	}, nil
+}
+
+// start 接管日志输出并开始刷新界面、读取按键，按键含义与 readInput 相同；
+// 退出时由 restoreTerminal 关闭界面
+func (u *statusUI) start(keyPressCh chan<- string, commandCh chan<- string) {
+	logrus.SetOutput(u)
+	onTerminalRestore(u.close)
+	go u.renderLoop()
+	go u.readKeys(keyPressCh, commandCh)
+}
+
+// close 关闭界面，恢复终端和日志输出，并把日志区保留的日志输出到标准错误
+func (u *statusUI) close() {
+	u.closeOnce.Do(func() {
+		u.mu.Lock()
+		u.closed = true
+		logs := u.logs
+		u.screen.Fini()
+		u.mu.Unlock()
+		close(u.done)
+
+		logrus.SetOutput(os.Stderr)
+		for _, line := range logs {
+			fmt.Fprintln(os.Stderr, line)
+		}
+	})
+}
+
+// Write 实现io.Writer，接收logrus输出的日志
+func (u *statusUI) Write(p []byte) (int, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		u.logs = append(u.logs, strings.TrimRight(line, "\r"))
+	}
+	if len(u.logs) > uiLogHistory {
+		u.logs = append([]string(nil), u.logs[len(u.logs)-uiLogHistory:]...)
+	}
+	u.dirty = true
+	return len(p), nil
+}
+
+// setConnected 更新连接状态
+func (u *statusUI) setConnected(connected bool) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.connected = connected
+	u.dirty = true
+}
+
+// setState 更新客户端状态，回到空闲时清空电平表
+func (u *statusUI) setState(state string) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.state = state
+	if state == client.StateIdle {
+		u.level, u.peak = 0, 0
+	}
+	u.dirty = true
+}
+
+// setLevel 更新录音电平，作为录音电平回调使用
+func (u *statusUI) setLevel(rms float64, peak float64) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.level, u.peak = rms, peak
+	u.dirty = true
+}
+
+// setUserPartial 显示尚未结束的识别结果
+func (u *statusUI) setUserPartial(text string) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.commitReplyLocked()
+	u.userPartial = text
+	u.dirty = true
+}
+
+// addUserText 将最终识别结果加入对话记录
+func (u *statusUI) addUserText(text string) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.commitReplyLocked()
+	u.userPartial = ""
+	u.appendLocked(transcriptEntry{user: true, text: text})
+}
+
+// setReplyPartial 显示正在生成的AI回复
+func (u *statusUI) setReplyPartial(text string) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.replyPartial = text
+	u.dirty = true
+}
+
+// addReply 将完整的AI回复加入对话记录
+func (u *statusUI) addReply(text string) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.replyPartial = ""
+	u.appendLocked(transcriptEntry{text: text})
+}
+
+// commitReplyLocked 回复被打断、没有收到完整回复时，把已显示的部分保留在对话记录中
+func (u *statusUI) commitReplyLocked() {
+	if u.replyPartial == "" {
+		return
+	}
+	u.appendLocked(transcriptEntry{text: u.replyPartial})
+	u.replyPartial = ""
+}
+
+func (u *statusUI) appendLocked(entry transcriptEntry) {
+	u.transcript = append(u.transcript, entry)
+	if len(u.transcript) > uiTranscriptHistory {
+		u.transcript = append([]transcriptEntry(nil), u.transcript[len(u.transcript)-uiTranscriptHistory:]...)
+	}
+	u.dirty = true
+}
+
+// readKeys 读取按键：f/F2 开始录音，s/F3 停止录音，q/Esc/Ctrl+C 退出。
+// 界面接管终端后Ctrl+C不再产生SIGINT，按退出命令处理
+func (u *statusUI) readKeys(keyPressCh chan<- string, commandCh chan<- string) {
+	// 记录录音按键状态，防止重复触发
+	recordKeyPressed := false
+
+	for {
+		ev := u.screen.PollEvent()
+		if ev == nil {
+			// 界面已关闭
+			return
+		}
+		switch ev := ev.(type) {
+		case *tcell.EventResize:
+			u.screen.Sync()
+			u.mu.Lock()
+			u.dirty = true
+			u.mu.Unlock()
+		case *tcell.EventKey:
+			key, char := ev.Key(), ev.Rune()
+			if key != tcell.KeyRune {
+				char = 0
+			}
+			switch {
+			case key == tcell.KeyCtrlC || key == tcell.KeyEscape || char == 'q' || char == 'Q':
+				commandCh <- "quit"
+			case key == tcell.KeyF2 || char == 'f' || char == 'F':
+				if !recordKeyPressed {
+					recordKeyPressed = true
+					keyPressCh <- "F2_PRESSED"
+				}
+			case key == tcell.KeyF3 || char == 's' || char == 'S':
+				if recordKeyPressed {
+					recordKeyPressed = false
+					keyPressCh <- "F2_RELEASED"
+				}
+			}
+		}
+	}
+}
+
+// renderLoop 有内容变化时按 uiRefreshInterval 刷新界面
+func (u *statusUI) renderLoop() {
+	ticker := time.NewTicker(uiRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-u.done:
+			return
+		case <-ticker.C:
+			u.mu.Lock()
+			if u.dirty && !u.closed {
+				u.drawLocked()
+				u.dirty = false
+			}
+			u.mu.Unlock()
+		}
+	}
+}
+
+var (
+	styleBar     = tcell.StyleDefault.Reverse(true)
+	styleDim     = tcell.StyleDefault.Dim(true)
+	styleUser    = tcell.StyleDefault.Foreground(tcell.ColorTeal)
+	styleReply   = tcell.StyleDefault.Foreground(tcell.ColorGreen)
+	styleHeading = tcell.StyleDefault.Bold(true)
+)
+
+// stateLabels 状态栏中客户端状态的显示名称
+var stateLabels = map[string]string{
+	client.StateIdle:       "空闲",
+	client.StateConnecting: "连接中",
+	client.StateListening:  "录音中",
+	client.StateSpeaking:   "播放中",
+}
+
+// drawLocked 绘制整个界面
+func (u *statusUI) drawLocked() {
+	s := u.screen
+	s.Clear()
+	w, h := s.Size()
+
+	// 状态栏
+	conn := "○ 未连接"
+	if u.connected {
+		conn = "● 已连接"
+	}
+	state := stateLabels[u.state]
+	if state == "" {
+		state = u.state
+	}
+	fillRow(s, 0, w, styleBar)
+	drawText(s, 0, 0, w, styleBar, fmt.Sprintf(" 小智 | %s | %s | 监听模式: %s", conn, state, listenMode))
+
+	// 录音电平
+	u.drawLevel(1, w)
+
+	// 日志区和对话区按终端高度分配，底部一行为按键提示
+	logRows := h / 3
+	if logRows > uiMaxLogRows {
+		logRows = uiMaxLogRows
+	}
+	logTop := h - 1 - logRows
+	drawHeading(s, 2, w, "对话")
+	u.drawTranscript(3, logTop-1, w)
+	drawHeading(s, logTop-1, w, "日志")
+	logs := u.logs
+	if len(logs) > logRows {
+		logs = logs[len(logs)-logRows:]
+	}
+	for i, line := range logs {
+		drawText(s, 0, logTop+i, w, styleDim, line)
+	}
+
+	drawText(s, 0, h-1, w, styleDim, " f 开始录音   s 停止录音   q 退出")
+	s.Show()
+}
+
+// drawLevel 绘制录音电平表，长度按分贝刻度计算，峰值用竖线标出
+func (u *statusUI) drawLevel(y, w int) {
+	const label = " 电平 "
+	x := drawText(u.screen, 0, y, w, tcell.StyleDefault, label)
+	width := w - x - 10
+	if width <= 0 {
+		return
+	}
+	filled := int(levelFraction(u.level) * float64(width))
+	peakAt := int(levelFraction(u.peak)*float64(width)) - 1
+	for i := 0; i < width; i++ {
+		style := tcell.StyleDefault.Foreground(tcell.ColorGreen)
+		switch {
+		case i >= width*9/10:
+			style = tcell.StyleDefault.Foreground(tcell.ColorRed)
+		case i >= width*7/10:
+			style = tcell.StyleDefault.Foreground(tcell.ColorYellow)
+		}
+		r := '·'
+		if i < filled {
+			r = '█'
+		} else if i == peakAt {
+			r = '|'
+		} else {
+			style = styleDim
+		}
+		u.screen.SetContent(x+i, y, r, nil, style)
+	}
+	db := "-inf dB"
+	if u.level > 0 {
+		db = fmt.Sprintf("%.0f dB", 20*math.Log10(u.level))
+	}
+	drawText(u.screen, x+width+1, y, w, tcell.StyleDefault, db)
+}
+
+// drawTranscript 在[top, bottom)行内绘制对话记录，内容过多时只显示最后的部分
+func (u *statusUI) drawTranscript(top, bottom, w int) {
+	if bottom <= top || w <= 0 {
+		return
+	}
+	type row struct {
+		text  string
+		style tcell.Style
+	}
+	var rows []row
+	add := func(prefix, text string, style tcell.Style) {
+		for _, line := range wrapText(prefix+text, w) {
+			rows = append(rows, row{line, style})
+		}
+	}
+	for _, entry := range u.transcript {
+		if entry.user {
+			add("你: ", entry.text, styleUser)
+		} else {
+			add("小智: ", entry.text, styleReply)
+		}
+	}
+	if u.userPartial != "" {
+		add("你: ", u.userPartial+" …", styleUser.Dim(true))
+	}
+	if u.replyPartial != "" {
+		add("小智: ", u.replyPartial+" …", styleReply.Dim(true))
+	}
+
+	if len(rows) > bottom-top {
+		rows = rows[len(rows)-(bottom-top):]
+	}
+	for i, r := range rows {
+		drawText(u.screen, 0, top+i, w, r.style, r.text)
+	}
+}
+
+// levelFraction 将0..1的电平按分贝换算为电平表的填充比例
+func levelFraction(level float64) float64 {
+	if level <= 0 {
+		return 0
+	}
+	frac := (20*math.Log10(level) - levelFloorDB) / -levelFloorDB
+	return math.Max(0, math.Min(1, frac))
+}
+
+// drawText 从(x, y)开始绘制一行文本，超出maxX的部分截断，返回绘制结束的列
+func drawText(s tcell.Screen, x, y, maxX int, style tcell.Style, text string) int {
+	for _, r := range text {
+		rw := runewidth.RuneWidth(r)
+		if rw == 0 {
+			continue
+		}
+		if x+rw > maxX {
+			break
+		}
+		s.SetContent(x, y, r, nil, style)
+		x += rw
+	}
+	return x
+}
+
+// drawHeading 绘制分隔行，例如"── 对话 ─────"
+func drawHeading(s tcell.Screen, y, w int, title string) {
+	x := drawText(s, 0, y, w, styleHeading, "── "+title+" ")
+	for ; x < w; x++ {
+		s.SetContent(x, y, '─', nil, styleHeading)
+	}
+}
+
+// fillRow 用空格填满一行，用于绘制背景
+func fillRow(s tcell.Screen, y, w int, style tcell.Style) {
+	for x := 0; x < w; x++ {
+		s.SetContent(x, y, ' ', nil, style)
+	}
+}
+
+// wrapText 按显示宽度将文本折成多行，中文等宽字符占两列
+func wrapText(text string, width int) []string {
+	var lines []string
+	for _, para := range strings.Split(text, "\n") {
+		var b strings.Builder
+		col := 0
+		for _, r := range para {
+			rw := runewidth.RuneWidth(r)
+			if col+rw > width && col > 0 {
+				lines = append(lines, b.String())
+				b.Reset()
+				col = 0
+			}
+			b.WriteRune(r)
+			col += rw
+		}
+		lines = append(lines, b.String())
+	}
+	return lines
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/justa-cai/xiaozhi-go/internal/client"
+	"github.com/justa-cai/xiaozhi-go/internal/protocol"
+)
+
+// useTestUI 以模拟终端创建状态界面并设为全局界面，测试结束时恢复
+func useTestUI(t *testing.T) *statusUI {
+	t.Helper()
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	u := &statusUI{screen: screen, state: client.StateIdle, dirty: true, done: make(chan struct{})}
+	ui = u
+	t.Cleanup(func() {
+		ui = nil
+		screen.Fini()
+	})
+	return u
+}
+
+func TestStatusUIFollowsServerMessages(t *testing.T) {
+	u := useTestUI(t)
+
+	mock := protocol.NewMockProtocol()
+	mock.OnSendJSON = func(data []byte) {
+		if protocol.MessageType(data) == "hello" {
+			mock.InjectJSON(`{"type":"hello","version":1,"transport":"websocket"}`)
+		}
+	}
+	c := client.New(mock)
+	setupCallbacks(c)
+
+	if err := c.OpenAudioChannel("ws://test"); err != nil {
+		t.Fatalf("打开音频通道失败: %v", err)
+	}
+	u.mu.Lock()
+	connected := u.connected
+	u.mu.Unlock()
+	if !connected {
+		t.Fatal("音频通道打开后状态界面应显示已连接")
+	}
+
+	mock.InjectJSON(`{"type":"stt","text":"讲个笑话"}`)
+	mock.InjectJSON(`{"type":"tts","state":"start"}`)
+	u.mu.Lock()
+	state := u.state
+	u.mu.Unlock()
+	if state != client.StateSpeaking {
+		t.Errorf("TTS开始后状态界面显示 %s，期望 %s", state, client.StateSpeaking)
+	}
+
+	mock.InjectJSON(`{"type":"tts","state":"sentence_start","text":"从前有座山。"}`)
+	u.mu.Lock()
+	partial := u.replyPartial
+	u.mu.Unlock()
+	if partial != "从前有座山。" {
+		t.Errorf("正在生成的回复显示为 %q", partial)
+	}
+
+	mock.InjectJSON(`{"type":"tts","state":"stop"}`)
+	u.mu.Lock()
+	transcript := append([]transcriptEntry(nil), u.transcript...)
+	state = u.state
+	u.mu.Unlock()
+
+	want := []transcriptEntry{{user: true, text: "讲个笑话"}, {text: "从前有座山。"}}
+	if len(transcript) != len(want) {
+		t.Fatalf("对话记录为 %+v，期望 %+v", transcript, want)
+	}
+	for i := range want {
+		if transcript[i] != want[i] {
+			t.Errorf("对话记录第%d条为 %+v，期望 %+v", i, transcript[i], want[i])
+		}
+	}
+	if state != client.StateIdle {
+		t.Errorf("回复结束后状态界面显示 %s，期望 %s", state, client.StateIdle)
+	}
+
+	mock.InjectDisconnect(nil)
+	u.mu.Lock()
+	connected = u.connected
+	u.mu.Unlock()
+	if connected {
+		t.Error("连接断开后状态界面应显示未连接")
+	}
+}
@@ -0,0 +1,196 @@
+package audio
+
+import (
+	"math"
+	"sync"
+)
+
+// 回声消除的默认参数
+const (
+	DefaultEchoFilterLength = 128  // 自适应滤波器覆盖的回声路径长度（毫秒）
+	DefaultEchoStepSize     = 0.3  // NLMS步长，越大收敛越快但越不稳定
+	DefaultEchoMaxDelay     = 500  // 参考信号最多缓存的时长（毫秒），超出部分丢弃最旧的
+	echoRegularization      = 1e-3 // 归一化时加在参考信号能量上的常数，避免静音时步长发散
+	echoDoubleTalkRatio     = 1.0  // 近端幅度超过参考信号峰值的该倍数时视为双讲（Geigel检测），暂停自适应
+)
+
+// EchoCancellerOptions 回声消除器选项，零值字段使用默认值
+type EchoCancellerOptions struct {
+	SampleRate   int     // 采样率，为0时使用 DefaultSampleRate
+	ChannelCount int     // 通道数，为0时使用 DefaultChannelCount
+	FilterLength int     // 滤波器长度（毫秒），为0时使用 DefaultEchoFilterLength
+	StepSize     float64 // NLMS步长（0..1），为0时使用 DefaultEchoStepSize
+	MaxDelay     int     // 参考信号最多缓存的时长（毫秒），为0时使用 DefaultEchoMaxDelay
+}
+
+// EchoCanceller 基于NLMS自适应滤波的回声消除器
+//
+// 远端（播放）PCM通过 PushReference 送入作为参考信号，近端（麦克风）PCM经 Process 减去
+// 估计出的回声。参考信号与麦克风采样按到达顺序一一对齐，播放与采集之间的延迟需落在滤波器长度内。
+// 多通道时按各通道平均后的单声道估计回声，并从每个通道中减去
+type EchoCanceller struct {
+	mu        sync.Mutex
+	channels  int
+	taps      int
+	step      float64
+	maxQueued int
+	weights   []float64
+	history   []float64 // 长度为2*taps的参考信号历史，history[pos:pos+taps]为最新在前的窗口
+	pos       int
+	power     float64   // 窗口内参考信号能量
+	queue     []float64 // 已播放但尚未与麦克风采样对齐的参考信号
+}
+
+// NewEchoCanceller 创建回声消除器
+func NewEchoCanceller(options EchoCancellerOptions) *EchoCanceller {
+	if options.SampleRate <= 0 {
+		options.SampleRate = DefaultSampleRate
+	}
+	if options.ChannelCount <= 0 {
+		options.ChannelCount = DefaultChannelCount
+	}
+	if options.FilterLength <= 0 {
+		options.FilterLength = DefaultEchoFilterLength
+	}
+	if options.StepSize <= 0 || options.StepSize > 1 {
+		options.StepSize = DefaultEchoStepSize
+	}
+	if options.MaxDelay <= 0 {
+		options.MaxDelay = DefaultEchoMaxDelay
+	}
+
+	taps := options.SampleRate * options.FilterLength / 1000
+	if taps < 1 {
+		taps = 1
+	}
+	return &EchoCanceller{
+		channels:  options.ChannelCount,
+		taps:      taps,
+		step:      options.StepSize,
+		maxQueued: options.SampleRate * options.MaxDelay / 1000,
+		weights:   make([]float64, taps),
+		history:   make([]float64, 2*taps),
+		pos:       taps,
+	}
+}
+
+// PushReference 送入已播放的远端PCM（交织采样）作为参考信号
+func (e *EchoCanceller) PushReference(pcm []int16) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i := 0; i+e.channels <= len(pcm); i += e.channels {
+		var sum float64
+		for ch := 0; ch < e.channels; ch++ {
+			sum += float64(pcm[i+ch])
+		}
+		e.queue = append(e.queue, sum/float64(e.channels)/32768)
+	}
+	if len(e.queue) > e.maxQueued {
+		e.queue = e.queue[len(e.queue)-e.maxQueued:]
+	}
+}
+
+// Process 对近端PCM（交织采样）做回声消除，返回新的PCM切片，不修改输入
+func (e *EchoCanceller) Process(pcm []int16) []int16 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	out := make([]int16, len(pcm))
+	copy(out, pcm)
+
+	// 参考信号峰值，用于简单的双讲检测
+	var farPeak float64
+	for _, v := range e.history[e.pos : e.pos+e.taps] {
+		if a := math.Abs(v); a > farPeak {
+			farPeak = a
+		}
+	}
+
+	for i := 0; i+e.channels <= len(out); i += e.channels {
+		far := 0.0
+		if len(e.queue) > 0 {
+			far = e.queue[0]
+			e.queue = e.queue[1:]
+		}
+		if a := math.Abs(far); a > farPeak {
+			farPeak = a
+		}
+		e.pushHistory(far)
+
+		window := e.history[e.pos : e.pos+e.taps]
+		var echo float64
+		for k, w := range e.weights {
+			echo += w * window[k]
+		}
+
+		var near float64
+		for ch := 0; ch < e.channels; ch++ {
+			near += float64(out[i+ch])
+		}
+		near = near / float64(e.channels) / 32768
+		residual := near - echo
+
+		for ch := 0; ch < e.channels; ch++ {
+			out[i+ch] = clampInt16((float64(out[i+ch])/32768 - echo) * 32768)
+		}
+
+		// 近端比参考信号还响时多半是用户在说话，此时更新权重会让滤波器发散
+		if farPeak == 0 || math.Abs(near) > echoDoubleTalkRatio*farPeak {
+			continue
+		}
+		scale := e.step * residual / (e.power + echoRegularization)
+		for k := range e.weights {
+			e.weights[k] += scale * window[k]
+		}
+	}
+
+	if len(e.queue) == 0 {
+		e.queue = nil
+	}
+	return out
+}
+
+// pushHistory 将一个参考采样放入窗口最前端，并更新窗口能量
+func (e *EchoCanceller) pushHistory(sample float64) {
+	oldest := e.history[e.pos+e.taps-1]
+	e.power += sample*sample - oldest*oldest
+	if e.power < 0 {
+		e.power = 0
+	}
+
+	if e.pos == 0 {
+		// 窗口移到缓冲区头部后，把最新的taps-1个采样搬到尾部继续
+		copy(e.history[e.taps+1:], e.history[:e.taps-1])
+		e.pos = e.taps + 1
+	}
+	e.pos--
+	e.history[e.pos] = sample
+}
+
+// Reset 清空滤波器系数和参考信号，用于播放设备或音频参数变化后重新收敛
+func (e *EchoCanceller) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i := range e.weights {
+		e.weights[i] = 0
+	}
+	for i := range e.history {
+		e.history[i] = 0
+	}
+	e.pos = e.taps
+	e.power = 0
+	e.queue = nil
+}
+
+// clampInt16 将浮点采样四舍五入并限制在int16范围内
+func clampInt16(v float64) int16 {
+	if v > math.MaxInt16 {
+		return math.MaxInt16
+	}
+	if v < math.MinInt16 {
+		return math.MinInt16
+	}
+	return int16(math.Round(v))
+}
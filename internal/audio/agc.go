@@ -0,0 +1,123 @@
+package audio
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// 录音自动增益的默认参数
+const (
+	DefaultAGCTarget     = 0.1  // 目标RMS电平（相对满幅，约-20dBFS）
+	DefaultAGCMaxGain    = 10.0 // 最大增益（约+20dB），避免把静音和底噪放大成噪声
+	DefaultAGCMinGain    = 0.1  // 最小增益（约-20dB）
+	DefaultAGCAttack     = 50 * time.Millisecond
+	DefaultAGCRelease    = 1 * time.Second
+	DefaultAGCSilenceRMS = 0.005 // 低于该电平视为静音或底噪，保持当前增益不再提高
+)
+
+// AGCOptions 录音自动增益选项，零值字段使用默认值
+type AGCOptions struct {
+	SampleRate   int           // 采样率，为0时使用 DefaultSampleRate
+	ChannelCount int           // 通道数，为0时使用 DefaultChannelCount
+	TargetRMS    float64       // 目标RMS电平（0..1），为0时使用 DefaultAGCTarget
+	MaxGain      float64       // 增益上限，为0时使用 DefaultAGCMaxGain
+	Attack       time.Duration // 电平过高时增益下降的时间常数，为0时使用 DefaultAGCAttack
+	Release      time.Duration // 电平过低时增益上升的时间常数，为0时使用 DefaultAGCRelease
+	SilenceRMS   float64       // 静音门限（0..1），为0时使用 DefaultAGCSilenceRMS
+}
+
+// AGC 录音端自动增益控制
+//
+// 按帧测量RMS，将增益以指数平滑的方式调整到使输出接近目标电平：下降快（Attack）以免削波，
+// 上升慢（Release）以免说话间隙把底噪抬起来。低于静音门限的帧不提高增益，增益也不会超过 MaxGain；
+// 增益后超出满幅的部分经软拐点 Limiter 压缩
+type AGC struct {
+	mu      sync.Mutex
+	options AGCOptions
+	gain    float64
+	limiter Limiter
+}
+
+// NewAGC 创建录音自动增益控制器
+func NewAGC(options AGCOptions) *AGC {
+	if options.SampleRate <= 0 {
+		options.SampleRate = DefaultSampleRate
+	}
+	if options.ChannelCount <= 0 {
+		options.ChannelCount = DefaultChannelCount
+	}
+	if options.TargetRMS <= 0 || options.TargetRMS > 1 {
+		options.TargetRMS = DefaultAGCTarget
+	}
+	if options.MaxGain <= 0 {
+		options.MaxGain = DefaultAGCMaxGain
+	}
+	if options.Attack <= 0 {
+		options.Attack = DefaultAGCAttack
+	}
+	if options.Release <= 0 {
+		options.Release = DefaultAGCRelease
+	}
+	if options.SilenceRMS <= 0 {
+		options.SilenceRMS = DefaultAGCSilenceRMS
+	}
+	return &AGC{
+		options: options,
+		gain:    1,
+		limiter: Limiter{Mode: LimiterSoftKnee},
+	}
+}
+
+// Options 返回补全默认值后的选项
+func (a *AGC) Options() AGCOptions {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.options
+}
+
+// Gain 返回当前增益
+func (a *AGC) Gain() float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.gain
+}
+
+// Reset 将增益恢复为1
+func (a *AGC) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.gain = 1
+}
+
+// Process 根据本帧电平更新增益，返回增益后的新PCM切片，不修改输入
+func (a *AGC) Process(pcm []int16) []int16 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	out := make([]int16, len(pcm))
+	if len(pcm) == 0 {
+		return out
+	}
+
+	rms, _ := computeLevel(pcm)
+	desired := a.gain
+	if rms >= a.options.SilenceRMS {
+		desired = math.Max(math.Min(a.options.TargetRMS/rms, a.options.MaxGain), DefaultAGCMinGain)
+	} else if desired > a.options.MaxGain {
+		desired = a.options.MaxGain
+	}
+
+	// 按本帧时长换算平滑系数，使收敛速度与帧长无关
+	tau := a.options.Release
+	if desired < a.gain {
+		tau = a.options.Attack
+	}
+	frameDuration := float64(len(pcm)) / float64(a.options.SampleRate*a.options.ChannelCount)
+	a.gain += (desired - a.gain) * (1 - math.Exp(-frameDuration/tau.Seconds()))
+
+	for i, v := range pcm {
+		out[i] = a.limiter.Sample(float64(v) * a.gain)
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package audio
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// runAGC 把连续的正弦波按frameMs一帧送入AGC共duration，返回最后一帧输出的RMS电平
+func runAGC(agc *AGC, amplitude float64, frameMs int, duration time.Duration) float64 {
+	frame := DefaultSampleRate * frameMs / 1000
+	frames := int(duration / (time.Duration(frameMs) * time.Millisecond))
+	tone := sine(440, DefaultSampleRate, frame*frames, amplitude)
+	var rms float64
+	for i := 0; i < frames; i++ {
+		rms, _ = computeLevel(agc.Process(tone[i*frame : (i+1)*frame]))
+	}
+	return rms
+}
+
+func TestAGCConvergesOnSine(t *testing.T) {
+	tests := []struct {
+		name      string
+		amplitude float64
+		duration  time.Duration
+	}{
+		// 放大按 DefaultAGCRelease（1秒）的时间常数收敛，5秒后误差小于1%
+		{"安静的正弦波被放大", 0.02 * DefaultMaxValue, 5 * time.Second},
+		// 压低按 DefaultAGCAttack（50ms）收敛，远快于放大
+		{"响亮的正弦波被压低", 0.9 * DefaultMaxValue, 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agc := NewAGC(AGCOptions{})
+			rms := runAGC(agc, tt.amplitude, 20, tt.duration)
+			if math.Abs(rms-DefaultAGCTarget) > 0.005 {
+				t.Errorf("%v后输出电平为 %.4f（增益%.2f），期望接近 %.2f", tt.duration, rms, agc.Gain(), DefaultAGCTarget)
+			}
+		})
+	}
+}
+
+func TestAGCConvergenceIndependentOfFrameLength(t *testing.T) {
+	gains := make([]float64, 0, 3)
+	for _, frameMs := range []int{20, 40, 60} {
+		agc := NewAGC(AGCOptions{})
+		runAGC(agc, 0.03*DefaultMaxValue, frameMs, 1200*time.Millisecond)
+		gains = append(gains, agc.Gain())
+	}
+	for i, gain := range gains[1:] {
+		if math.Abs(gain-gains[0]) > 0.01*gains[0] {
+			t.Errorf("帧长不同时相同时长后的增益不同: %v（第%d项）", gains, i+1)
+		}
+	}
+}
+
+func TestAGCSilenceAndMaxGain(t *testing.T) {
+	agc := NewAGC(AGCOptions{})
+	runAGC(agc, 0.001*DefaultMaxValue, 20, 2*time.Second)
+	if gain := agc.Gain(); gain != 1 {
+		t.Errorf("静音期间增益变为 %.2f，期望保持1", gain)
+	}
+
+	// 需要约16.7倍增益才能达到目标，但不超过上限
+	runAGC(agc, 0.0085*DefaultMaxValue, 20, 10*time.Second)
+	if gain := agc.Gain(); gain > DefaultAGCMaxGain || gain < DefaultAGCMaxGain*0.99 {
+		t.Errorf("很安静的输入增益为 %.2f，期望接近上限 %v", gain, DefaultAGCMaxGain)
+	}
+
+	agc.Reset()
+	if gain := agc.Gain(); gain != 1 {
+		t.Errorf("Reset后增益为 %.2f，期望1", gain)
+	}
+}
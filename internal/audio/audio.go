@@ -1,10 +1,13 @@
 package audio
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
 	"time"
-
-	"github.com/sirupsen/logrus"
 )
 
 const (
@@ -17,26 +20,100 @@ const (
 	DefaultMaxValue        = 1<<15 - 1
 )
 
+// RecordingStage 开始录音失败时所处的阶段
+type RecordingStage int
+
+const (
+	// RecordingStageDeviceOpen 打开采集设备失败，设备未被占用
+	RecordingStageDeviceOpen RecordingStage = iota
+	// RecordingStageWiring 设备已打开但数据接收未就绪，设备已被释放
+	RecordingStageWiring
+)
+
+// String 返回阶段名称
+func (s RecordingStage) String() string {
+	switch s {
+	case RecordingStageDeviceOpen:
+		return "打开采集设备"
+	case RecordingStageWiring:
+		return "连接录音数据"
+	default:
+		return fmt.Sprintf("RecordingStage(%d)", int(s))
+	}
+}
+
+// RecordingError 开始录音失败时返回的错误，可通过 errors.As 判断失败阶段
+type RecordingError struct {
+	Stage RecordingStage
+	Err   error
+}
+
+func (e *RecordingError) Error() string {
+	return fmt.Sprintf("开始录音失败（%s）: %v", e.Stage, e.Err)
+}
+
+func (e *RecordingError) Unwrap() error {
+	return e.Err
+}
+
 // AudioManagerNew 使用新实现的音频管理器
 type AudioManagerNew struct {
 	recorder          Recorder        // 新的录音器，改为接口
 	player            *AudioPlayerNew // 新的播放器
-	codec             *OpusCodec      // 编解码器
+	codec             Codec           // 编解码器
 	initialized       bool            // 初始化标志
 	sampleRate        int             // 采样率
 	channelCount      int             // 通道数
 	frameDuration     int             // 帧持续时间（毫秒）
 	audioDataCallback func([]byte)    // 保存音频数据回调函数
+	pcmDataCallback   func([]int16, int)
+	levelCallback     func(rms float64, peak float64)
+	wavWriter         *WAVWriter     // 录音落盘（可选）
+	opusWriter        *OggOpusWriter // 编码后的Opus数据落盘（可选），与wavWriter共用wavMutex
+	wavMutex          sync.Mutex
+	captureResampler  *Resampler // 采集设备采样率与编码采样率不同时的重采样器（可选）
+	inputSampleRate   int        // 采集设备采样率
+	codecOptions      OpusCodecOptions
+	onQueueDrained    func() // 播放队列播空回调，重建播放器时沿用
+	processMutex      sync.Mutex
+	echoCanceller     *EchoCanceller   // 回声消除（可选），以播放输出为参考信号处理录音
+	noiseSuppressor   *NoiseSuppressor // 降噪（可选），在回声消除之后处理录音
+	agc               *AGC             // 自动增益（可选），在降噪之后处理录音，避免放大噪声
+	wakeWord          *asyncSink       // 唤醒词检测（可选），在独立goroutine中处理预处理后的录音，由processMutex保护
+	silenceGate       *silenceGate     // 静音抑制（可选），不发送低于阈值的静音帧，由processMutex保护
+	suppressedFrames  atomic.Uint64    // 静音抑制丢弃的帧数
+	capture           captureCounters  // 录音方向的统计
+	retiredPlayback   PlaybackMetrics  // 已被替换的播放器的累计统计，由processMutex保护
+	replaying         atomic.Bool      // 是否正在回放音频文件，期间不能开始录音
+	recorderOptions   RecorderOptions  // 录音器选项，切换输入设备时沿用
+	customRecorder    bool             // 是否使用调用方提供的录音器，此时不支持切换输入设备
+	playerOptions     NewPlayerOptions // 播放器选项，重建播放器和切换输出设备时沿用
+	switchMutex       sync.Mutex       // 串行化设备切换
+	playbackWriter    *WAVWriter       // 播放音频落盘（可选），由playbackMutex保护
+	playbackMutex     sync.Mutex
+	onDeviceSwitched  func(device DeviceInfo)
 }
 
 // AudioManagerOptions 音频管理器选项
 type AudioManagerOptions struct {
-	SampleRate        int    // 采样率
-	ChannelCount      int    // 通道数
-	FrameDuration     int    // 帧持续时间（毫秒）
-	InputDeviceName   string // 输入设备名称（可选）
-	OutputDeviceName  string // 输出设备名称（可选）
-	UseDefaultDevices bool   // 是否使用默认设备
+	SampleRate        int           // 采样率
+	ChannelCount      int           // 通道数
+	FrameDuration     int           // 帧持续时间（毫秒）
+	InputDeviceName   string        // 输入设备名称（可选），可通过 GetAudioDevices 查询，目前仅Linux支持，不存在时创建失败
+	OutputDeviceName  string        // 输出设备名称（可选），可通过 GetAudioDevices 查询，目前仅Linux支持，不存在时创建失败
+	UseDefaultDevices bool          // 是否使用默认设备
+	EnableFEC         bool          // 丢帧后是否从下一个数据包的Opus带内前向纠错信息恢复，需要服务器编码时启用FEC
+	InputSampleRate   int           // 采集设备采样率，为0时与SampleRate相同
+	OutputSampleRate  int           // 输出设备采样率，为0时与SampleRate相同
+	MaxFrameDuration  int           // 解码缓冲区可容纳的最长帧（毫秒），为0时为120ms
+	NormalizeOutput   bool          // 是否启用播放响度归一化
+	NormalizeTarget   float64       // 归一化目标RMS电平（0..1），为0时使用 DefaultNormalizeTarget
+	MaxQueueFrames    int           // 播放队列最大帧数，为0表示不限制
+	QueuePolicy       QueuePolicy   // 播放队列已满时的处理策略
+	JitterBuffer      time.Duration // 播放抖动缓冲的目标深度，为0时关闭
+	JitterBufferMax   time.Duration // 播放抖动缓冲的深度上限，为0时使用 DefaultJitterBufferMax
+	Recorder          Recorder      // 自定义录音器（可选），例如测试用的 MockRecorder；为nil时使用当前平台的录音器
+	HeadlessOutput    bool          // 不打开输出设备，由调用方通过 Player().Read 拉取播放音频，例如测试或在无声卡的环境中运行
 }
 
 // InitializeAudio 初始化音频系统（Oto无需初始化，直接返回nil）
@@ -49,16 +126,6 @@ func TerminateAudio() error {
 	return nil
 }
 
-// GetAudioDevices 获取音频设备列表（Oto不支持，返回空）
-func GetAudioDevices() ([]interface{}, error) {
-	return nil, nil
-}
-
-// PrintDeviceInfo 打印设备信息（Oto不支持，打印提示）
-func PrintDeviceInfo() {
-	logrus.Info("Oto不支持枚举音频设备，仅支持默认输出")
-}
-
 // NewAudioManagerWithOptions 使用指定选项创建新的音频管理器
 func NewAudioManagerWithOptions(options AudioManagerOptions) (*AudioManagerNew, error) {
 	// 初始化音频系统
@@ -77,16 +144,56 @@ func NewAudioManagerWithOptions(options AudioManagerOptions) (*AudioManagerNew,
 	if options.FrameDuration <= 0 {
 		options.FrameDuration = DefaultFrameDuration
 	}
+	if err := ValidateFrameDuration(options.SampleRate, options.FrameDuration); err != nil {
+		TerminateAudio()
+		return nil, err
+	}
 
-	// 创建编解码器
-	codec, err := NewOpusCodec(options.SampleRate, options.ChannelCount)
+	// 创建编解码器，录音器每帧采样数、播放器帧大小和解码缓冲区都由同一个帧时长推导
+	codecOptions := OpusCodecOptions{
+		EnableFEC:     options.EnableFEC,
+		FrameDuration: options.FrameDuration,
+	}
+	codec, err := newCodec(options.SampleRate, options.ChannelCount, codecOptions)
 	if err != nil {
 		TerminateAudio()
 		return nil, fmt.Errorf("创建Opus编解码器失败: %v", err)
 	}
 
-	// 创建录音器（直接用NewRecorder，不再用老的Options/WithOptions）
-	recorder := NewRecorder()
+	// 采集设备采样率与编码采样率不同时，在录音器和编码器之间插入重采样器
+	if options.InputSampleRate <= 0 {
+		options.InputSampleRate = options.SampleRate
+	}
+	var captureResampler *Resampler
+	if options.InputSampleRate != options.SampleRate {
+		captureResampler, err = NewResampler(options.InputSampleRate, options.SampleRate, options.ChannelCount)
+		if err != nil {
+			codec.Close()
+			TerminateAudio()
+			return nil, err
+		}
+		logger.Infof("录音将从设备采样率%dHz重采样到%dHz", options.InputSampleRate, options.SampleRate)
+	}
+
+	// 创建录音器，指定了设备名称时先确认设备存在，避免打开时才失败且原因不明
+	recorder := options.Recorder
+	if recorder == nil && options.InputDeviceName != "" {
+		if _, err := findDevice(options.InputDeviceName, true); err != nil {
+			codec.Close()
+			TerminateAudio()
+			return nil, err
+		}
+	}
+	customRecorder := recorder != nil
+	recorderOptions := RecorderOptions{
+		SampleRate:    options.InputSampleRate,
+		ChannelCount:  options.ChannelCount,
+		FrameDuration: options.FrameDuration,
+		DeviceName:    options.InputDeviceName,
+	}
+	if recorder == nil {
+		recorder = NewRecorderWithOptions(recorderOptions)
+	}
 
 	// 创建播放器
 	playerOptions := NewPlayerOptions{
@@ -95,6 +202,15 @@ func NewAudioManagerWithOptions(options AudioManagerOptions) (*AudioManagerNew,
 		FramesPerBuffer:  (options.SampleRate * options.FrameDuration) / 1000,
 		UseDefaultDevice: options.UseDefaultDevices,
 		DeviceName:       options.OutputDeviceName,
+		DeviceSampleRate: options.OutputSampleRate,
+		MaxFrameDuration: options.MaxFrameDuration,
+		Normalize:        options.NormalizeOutput,
+		NormalizeTarget:  options.NormalizeTarget,
+		MaxQueueFrames:   options.MaxQueueFrames,
+		QueuePolicy:      options.QueuePolicy,
+		JitterBuffer:     options.JitterBuffer,
+		JitterBufferMax:  options.JitterBufferMax,
+		Headless:         options.HeadlessOutput,
 	}
 
 	player, err := NewAudioPlayerWithOptions(playerOptions, codec)
@@ -106,13 +222,19 @@ func NewAudioManagerWithOptions(options AudioManagerOptions) (*AudioManagerNew,
 	}
 
 	return &AudioManagerNew{
-		recorder:      recorder,
-		player:        player,
-		codec:         codec,
-		initialized:   true,
-		sampleRate:    options.SampleRate,
-		channelCount:  options.ChannelCount,
-		frameDuration: options.FrameDuration,
+		recorder:         recorder,
+		player:           player,
+		codec:            codec,
+		initialized:      true,
+		sampleRate:       options.SampleRate,
+		channelCount:     options.ChannelCount,
+		frameDuration:    options.FrameDuration,
+		captureResampler: captureResampler,
+		inputSampleRate:  options.InputSampleRate,
+		codecOptions:     codecOptions,
+		recorderOptions:  recorderOptions,
+		customRecorder:   customRecorder,
+		playerOptions:    playerOptions,
 	}, nil
 }
 
@@ -133,7 +255,7 @@ func (m *AudioManagerNew) Close() error {
 	// 添加一个恢复机制，防止任何异常导致无法正常清理资源
 	defer func() {
 		if r := recover(); r != nil {
-			logrus.Errorf("关闭音频管理器时发生异常: %v", r)
+			logger.Errorf("关闭音频管理器时发生异常: %v", r)
 		}
 	}()
 
@@ -144,16 +266,21 @@ func (m *AudioManagerNew) Close() error {
 	// 关闭录音器
 	if m.recorder != nil {
 		if err := m.recorder.Close(); err != nil {
-			logrus.Warnf("关闭录音器失败: %v", err)
+			logger.Warnf("关闭录音器失败: %v", err)
 		}
 	}
+	m.finalizeRecordingFiles()
+	m.SetWakeWordDetector(nil, nil)
 
 	// 关闭播放器
 	if m.player != nil {
 		if err := m.player.Close(); err != nil {
-			logrus.Warnf("关闭播放器失败: %v", err)
+			logger.Warnf("关闭播放器失败: %v", err)
 		}
 	}
+	if err := m.StopPlaybackToFile(); err != nil {
+		logger.Warnf("关闭播放音频文件失败: %v", err)
+	}
 
 	// 关闭编解码器
 	if m.codec != nil {
@@ -166,11 +293,11 @@ func (m *AudioManagerNew) Close() error {
 	// 终止PortAudio
 	err := TerminateAudio()
 	if err != nil {
-		logrus.Warnf("终止音频系统失败: %v", err)
+		logger.Warnf("终止音频系统失败: %v", err)
 	}
 
 	m.initialized = false
-	logrus.Debug("音频管理器已关闭")
+	logger.Debugf("音频管理器已关闭")
 	return nil
 }
 
@@ -178,29 +305,453 @@ func (m *AudioManagerNew) Close() error {
 func (m *AudioManagerNew) SetAudioDataCallback(callback func([]byte)) {
 	// 保存回调
 	m.audioDataCallback = callback
-	// 设置PCM回调，编码后回调opus数据
-	m.recorder.SetPCMDataCallback(func(pcm []int16, _ int) {
-		if m.audioDataCallback != nil && m.codec != nil {
-			if opus, err := m.codec.Encode(pcm); err == nil {
-				m.audioDataCallback(opus)
+	m.recorder.SetPCMDataCallback(m.handlePCM)
+}
+
+// SetPCMDataCallback 设置PCM音频数据回调函数
+func (m *AudioManagerNew) SetPCMDataCallback(callback func([]int16, int)) {
+	m.pcmDataCallback = callback
+	m.recorder.SetPCMDataCallback(m.handlePCM)
+}
+
+// SetLevelCallback 设置输入电平回调，每采集一帧回调一次，rms和peak归一化到0..1
+// 回调在录音线程中执行，应尽快返回以免影响采集
+func (m *AudioManagerNew) SetLevelCallback(callback func(rms float64, peak float64)) {
+	m.levelCallback = callback
+	m.recorder.SetLevelCallback(callback)
+}
+
+// handlePCM 分发录音器采集到的PCM帧：写入WAV文件、回调PCM、编码后回调opus数据
+func (m *AudioManagerNew) handlePCM(pcm []int16, size int) {
+	m.capture.captured.Add(1)
+	if m.captureResampler != nil {
+		pcm = m.captureResampler.Process(pcm[:size])
+		size = len(pcm)
+	}
+
+	pcm, size = m.preprocessCapture(pcm, size)
+
+	m.processMutex.Lock()
+	wakeWord := m.wakeWord
+	gate := m.silenceGate
+	m.processMutex.Unlock()
+	if wakeWord != nil {
+		wakeWord.push(pcm[:size])
+	}
+
+	m.wavMutex.Lock()
+	if m.wavWriter != nil {
+		if err := m.wavWriter.Write(pcm[:size]); err != nil {
+			logger.Warnf("写入WAV文件失败: %v", err)
+		}
+	}
+	opusWriter := m.opusWriter
+	m.wavMutex.Unlock()
+
+	if m.pcmDataCallback != nil {
+		m.pcmDataCallback(pcm, size)
+	}
+
+	if (m.audioDataCallback != nil || opusWriter != nil) && m.codec != nil {
+		start := time.Now()
+		opus, err := m.codec.Encode(pcm[:size])
+		m.capture.encodeLatency.observe(time.Since(start))
+		if err != nil {
+			m.capture.encodeErrors.Add(1)
+		} else {
+			m.capture.encoded.Add(1)
+			// 编码器始终处理每一帧以保持状态连续，静音抑制只决定是否发出
+			packets := [][]byte{opus}
+			if gate != nil {
+				rms, _ := computeLevel(pcm[:size])
+				var suppressed int
+				packets, suppressed = gate.process(opus, rms)
+				m.suppressedFrames.Add(uint64(suppressed))
+			}
+			for _, packet := range packets {
+				if opusWriter != nil {
+					if err := opusWriter.Write(packet); err != nil {
+						logger.Warnf("写入Opus文件失败: %v", err)
+					}
+				}
+				if m.audioDataCallback != nil {
+					m.capture.sent.Add(1)
+					m.audioDataCallback(packet)
+				}
 			}
 		}
+	}
+}
+
+// preprocessCapture 在编码前对录音PCM依次做回声消除、降噪等预处理，均未启用时原样返回
+func (m *AudioManagerNew) preprocessCapture(pcm []int16, size int) ([]int16, int) {
+	m.processMutex.Lock()
+	echoCanceller := m.echoCanceller
+	noiseSuppressor := m.noiseSuppressor
+	agc := m.agc
+	m.processMutex.Unlock()
+
+	if echoCanceller != nil {
+		pcm = echoCanceller.Process(pcm[:size])
+		size = len(pcm)
+	}
+	if noiseSuppressor != nil {
+		pcm = noiseSuppressor.Process(pcm[:size])
+		size = len(pcm)
+	}
+	if agc != nil {
+		pcm = agc.Process(pcm[:size])
+		size = len(pcm)
+	}
+	return pcm, size
+}
+
+// SetSilenceThreshold 开启静音抑制：编码后RMS电平（0..1）低于threshold的帧不发送给音频数据回调和Opus录音文件，
+// 减少按住说话前后静音占用的上行带宽和服务器识别时间；threshold小于等于0时关闭（默认关闭）。
+// 语音开始前的 DefaultSilenceLeadIn 和结束后的 DefaultSilenceHangover 照常发送，避免截断字头字尾
+func (m *AudioManagerNew) SetSilenceThreshold(threshold float64) {
+	var gate *silenceGate
+	if threshold > 0 {
+		gate = newSilenceGate(threshold, m.frameDuration)
+	}
+
+	m.processMutex.Lock()
+	m.silenceGate = gate
+	m.processMutex.Unlock()
+}
+
+// SuppressedFrames 返回静音抑制累计丢弃的帧数
+func (m *AudioManagerNew) SuppressedFrames() uint64 {
+	return m.suppressedFrames.Load()
+}
+
+// resetSilenceGate 丢弃静音抑制暂存的前导帧，避免上一轮结尾的静音在下一轮开头发出
+func (m *AudioManagerNew) resetSilenceGate() {
+	m.processMutex.Lock()
+	gate := m.silenceGate
+	m.processMutex.Unlock()
+
+	if gate != nil {
+		m.suppressedFrames.Add(uint64(gate.reset()))
+	}
+}
+
+// SetWakeWordDetector 设置唤醒词检测器，detector为nil时关闭
+// 录音期间每帧经回声消除、降噪和自动增益处理后的PCM在独立goroutine中依次送入detector，不影响录音和编码；
+// 检测到唤醒词时在该goroutine中以唤醒词调用callback，例如接到 Client.HandleWakeWord。
+// 空闲时也需要检测唤醒词的，应保持录音进行；callback中不能再调用本方法
+func (m *AudioManagerNew) SetWakeWordDetector(detector WakeWordDetector, callback func(keyword string)) {
+	var next *asyncSink
+	if detector != nil {
+		next = newAsyncSink("唤醒词检测", &wakeWordSink{detector: detector, callback: callback})
+	}
+
+	m.processMutex.Lock()
+	previous := m.wakeWord
+	m.wakeWord = next
+	m.processMutex.Unlock()
+
+	if previous != nil {
+		previous.close()
+	}
+	if next != nil {
+		m.recorder.SetPCMDataCallback(m.handlePCM)
+	}
+}
+
+// EnableAGC 开启录音自动增益，将录音电平调整到targetRMS（0..1，例如 DefaultAGCTarget），
+// targetRMS小于等于0时关闭。增益不超过 DefaultAGCMaxGain，静音时不提高增益
+func (m *AudioManagerNew) EnableAGC(targetRMS float64) {
+	m.EnableAGCWithOptions(AGCOptions{TargetRMS: targetRMS})
+}
+
+// EnableAGCWithOptions 按指定的目标电平、增益上限和攻击/释放时间开启录音自动增益，
+// TargetRMS小于等于0时关闭；SampleRate和ChannelCount使用录音参数
+func (m *AudioManagerNew) EnableAGCWithOptions(options AGCOptions) {
+	m.processMutex.Lock()
+	defer m.processMutex.Unlock()
+
+	if options.TargetRMS <= 0 {
+		m.agc = nil
+		return
+	}
+	options.SampleRate = m.sampleRate
+	options.ChannelCount = m.channelCount
+	m.agc = NewAGC(options)
+}
+
+// AGCGain 返回录音自动增益当前施加的增益，未开启时返回1
+func (m *AudioManagerNew) AGCGain() float64 {
+	m.processMutex.Lock()
+	agc := m.agc
+	m.processMutex.Unlock()
+
+	if agc == nil {
+		return 1
+	}
+	return agc.Gain()
+}
+
+// EnableNoiseSuppression 设置录音降噪强度（NoiseSuppressionLow..NoiseSuppressionHigh），
+// NoiseSuppressionOff 或负数关闭降噪。开启后录音会增加约半个分析窗（8ms左右）的延迟
+func (m *AudioManagerNew) EnableNoiseSuppression(level int) {
+	m.processMutex.Lock()
+	defer m.processMutex.Unlock()
+
+	if level <= NoiseSuppressionOff {
+		m.noiseSuppressor = nil
+		return
+	}
+	m.noiseSuppressor = NewNoiseSuppressor(m.sampleRate, m.channelCount, level)
+}
+
+// EnableAEC 开启或关闭回声消除。开启后播放器的输出作为参考信号，从录音中减去扬声器被麦克风采回的声音，
+// 用于实时（全双工）模式下外放时的打断；重建播放器后仍然有效
+func (m *AudioManagerNew) EnableAEC(enabled bool) {
+	m.processMutex.Lock()
+	defer m.processMutex.Unlock()
+
+	if !enabled {
+		m.echoCanceller = nil
+		m.player.SetOutputTap(nil)
+		return
+	}
+	m.echoCanceller = NewEchoCanceller(EchoCancellerOptions{
+		SampleRate:   m.sampleRate,
+		ChannelCount: m.channelCount,
 	})
+	m.wireEchoReferenceLocked()
 }
 
-// SetPCMDataCallback 设置PCM音频数据回调函数
-func (m *AudioManagerNew) SetPCMDataCallback(callback func([]int16, int)) {
-	m.recorder.SetPCMDataCallback(callback)
+// wireEchoReferenceLocked 将播放器输出接到回声消除器的参考输入，
+// 输出设备的采样率和通道数与录音参数不同时先转换到录音的通道数和采样率；调用方需持有processMutex
+func (m *AudioManagerNew) wireEchoReferenceLocked() {
+	if m.echoCanceller == nil || m.player == nil {
+		return
+	}
+
+	echoCanceller := m.echoCanceller
+	fromRate, fromChannels := m.player.deviceFormat()
+	toRate, toChannels := m.sampleRate, m.channelCount
+
+	var resampler *Resampler
+	if fromRate != toRate {
+		r, err := NewResampler(fromRate, toRate, toChannels)
+		if err != nil {
+			logger.Warnf("创建回声参考信号重采样器失败，回声消除不可用: %v", err)
+			m.player.SetOutputTap(nil)
+			return
+		}
+		resampler = r
+	}
+
+	m.player.SetOutputTap(func(pcm []int16) {
+		pcm = convertChannels(pcm, fromChannels, toChannels)
+		if resampler != nil {
+			pcm = resampler.Process(pcm)
+		}
+		echoCanceller.PushReference(pcm)
+	})
 }
 
 // StartRecording 开始录音
 func (m *AudioManagerNew) StartRecording() error {
-	return m.recorder.StartRecording(m.codec)
+	return m.StartRecordingContext(context.Background())
+}
+
+// StartRecordingContext 开始录音，失败时返回 *RecordingError
+// 设备打开后若数据接收未就绪或ctx已取消，会先释放设备再返回，不会留下无人读取的采集
+func (m *AudioManagerNew) StartRecordingContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return &RecordingError{Stage: RecordingStageDeviceOpen, Err: err}
+	}
+	if m.replaying.Load() {
+		return &RecordingError{Stage: RecordingStageDeviceOpen, Err: errors.New("正在回放音频文件")}
+	}
+
+	if m.captureResampler != nil {
+		m.captureResampler.Reset()
+	}
+	m.resetSilenceGate()
+	if err := m.recorder.StartRecording(m.codec); err != nil {
+		return &RecordingError{Stage: RecordingStageDeviceOpen, Err: err}
+	}
+
+	if err := m.checkRecordingWired(ctx); err != nil {
+		if stopErr := m.recorder.StopRecording(); stopErr != nil {
+			logger.Warnf("释放录音设备失败: %v", stopErr)
+		}
+		m.finalizeRecordingFiles()
+		return &RecordingError{Stage: RecordingStageWiring, Err: err}
+	}
+	return nil
+}
+
+// checkRecordingWired 检查录音数据是否有接收方，以及ctx是否已取消
+func (m *AudioManagerNew) checkRecordingWired(ctx context.Context) error {
+	m.wavMutex.Lock()
+	hasWAV := m.wavWriter != nil || m.opusWriter != nil
+	m.wavMutex.Unlock()
+
+	m.processMutex.Lock()
+	hasWakeWord := m.wakeWord != nil
+	m.processMutex.Unlock()
+
+	if m.audioDataCallback == nil && m.pcmDataCallback == nil && m.levelCallback == nil && !hasWAV && !hasWakeWord {
+		return errors.New("未设置录音数据回调")
+	}
+	return ctx.Err()
+}
+
+// StartRecordingToFile 开始录音，并将采集到的PCM同时保存为WAV文件
+func (m *AudioManagerNew) StartRecordingToFile(path string) error {
+	writer, err := NewWAVWriter(path, m.sampleRate, m.channelCount)
+	if err != nil {
+		return err
+	}
+
+	m.wavMutex.Lock()
+	previous := m.wavWriter
+	m.wavWriter = writer
+	m.wavMutex.Unlock()
+	if previous != nil {
+		previous.Close()
+	}
+
+	m.recorder.SetPCMDataCallback(m.handlePCM)
+	if err := m.StartRecording(); err != nil {
+		m.finalizeRecordingFiles()
+		return err
+	}
+	logger.Infof("录音将保存到: %s", path)
+	return nil
+}
+
+// StartRecordingToOpusFile 开始录音，并把编码后发送给服务器的Opus数据包保存为Ogg-Opus文件（.opus），
+// 用于检查实际上传的音频；同时设置的音频数据回调照常收到相同的数据包。StopRecording 时写入结束页
+func (m *AudioManagerNew) StartRecordingToOpusFile(path string) error {
+	if !encodesOpus {
+		return errors.New("当前构建未启用CGO或使用了noaudio标签，编码器输出的不是Opus数据，无法保存为Opus文件")
+	}
+	writer, err := NewOggOpusWriter(path, m.sampleRate, m.channelCount)
+	if err != nil {
+		return err
+	}
+
+	m.wavMutex.Lock()
+	previous := m.opusWriter
+	m.opusWriter = writer
+	m.wavMutex.Unlock()
+	if previous != nil {
+		previous.Close()
+	}
+
+	m.recorder.SetPCMDataCallback(m.handlePCM)
+	if err := m.StartRecording(); err != nil {
+		m.finalizeRecordingFiles()
+		return err
+	}
+	logger.Infof("编码后的录音将保存到: %s", path)
+	return nil
 }
 
 // StopRecording 停止录音
 func (m *AudioManagerNew) StopRecording() error {
-	return m.recorder.StopRecording()
+	err := m.recorder.StopRecording()
+	m.resetSilenceGate()
+	m.finalizeRecordingFiles()
+	return err
+}
+
+// finalizeRecordingFiles 关闭录音文件：WAV回填文件头，Opus写入结束页
+func (m *AudioManagerNew) finalizeRecordingFiles() {
+	m.wavMutex.Lock()
+	writer := m.wavWriter
+	m.wavWriter = nil
+	opusWriter := m.opusWriter
+	m.opusWriter = nil
+	m.wavMutex.Unlock()
+
+	if writer != nil {
+		if err := writer.Close(); err != nil {
+			logger.Warnf("关闭WAV文件失败: %v", err)
+		}
+	}
+	if opusWriter != nil {
+		if err := opusWriter.Close(); err != nil {
+			logger.Warnf("关闭Opus文件失败: %v", err)
+		}
+	}
+}
+
+// StartPlaybackToFile 将播放的音频（解码并经音量等处理后、送往输出设备前的PCM）同时保存为WAV文件，
+// 用于排查服务器下发的TTS音频；写入在独立goroutine中进行，不影响播放节奏
+// 文件采样率和通道数为当前编码参数，输出设备的采样率或通道数与之不同时自动转换；调用 StopPlaybackToFile 结束
+func (m *AudioManagerNew) StartPlaybackToFile(path string) error {
+	writer, err := NewWAVWriter(path, m.sampleRate, m.channelCount)
+	if err != nil {
+		return err
+	}
+
+	m.playbackMutex.Lock()
+	previous := m.playbackWriter
+	m.playbackWriter = writer
+	m.attachPlaybackSinkLocked(m.player)
+	m.playbackMutex.Unlock()
+
+	if previous != nil {
+		if err := previous.Close(); err != nil {
+			logger.Warnf("关闭WAV文件失败: %v", err)
+		}
+	}
+	logger.Infof("播放的音频将保存到: %s", path)
+	return nil
+}
+
+// StopPlaybackToFile 停止保存播放的音频，写完已排队的帧后回填WAV文件头；未开始时直接返回
+func (m *AudioManagerNew) StopPlaybackToFile() error {
+	m.playbackMutex.Lock()
+	writer := m.playbackWriter
+	m.playbackWriter = nil
+	if writer != nil && m.player != nil {
+		m.player.SetSink(nil)
+	}
+	m.playbackMutex.Unlock()
+
+	if writer == nil {
+		return nil
+	}
+	return writer.Close()
+}
+
+// attachPlaybackSinkLocked 把播放音频文件接到播放器上，旁路收到的是输出设备采样率和通道数的PCM，
+// 与文件参数不同时先转换通道数并重采样；调用方需持有playbackMutex
+func (m *AudioManagerNew) attachPlaybackSinkLocked(player *AudioPlayerNew) {
+	if player == nil {
+		return
+	}
+	writer := m.playbackWriter
+	if writer == nil {
+		player.SetSink(nil)
+		return
+	}
+
+	sampleRate, channelCount := player.deviceFormat()
+	if sampleRate == writer.sampleRate && channelCount == writer.channels {
+		player.SetSink(writer)
+		return
+	}
+	sink := &convertedSink{sink: writer, fromChannels: channelCount, toChannels: writer.channels}
+	if sampleRate != writer.sampleRate {
+		resampler, err := NewResampler(sampleRate, writer.sampleRate, writer.channels)
+		if err != nil {
+			logger.Warnf("创建播放音频文件的重采样器失败，停止保存播放的音频: %v", err)
+			player.SetSink(nil)
+			return
+		}
+		sink.resampler = resampler
+	}
+	player.SetSink(sink)
 }
 
 // StartPlaying 开始播放
@@ -213,11 +764,83 @@ func (m *AudioManagerNew) StopPlaying() error {
 	return m.player.Stop()
 }
 
+// SetOnQueueDrained 设置播放结束（播放队列播空）回调，重建播放器后仍然有效
+func (m *AudioManagerNew) SetOnQueueDrained(callback func()) {
+	m.onQueueDrained = callback
+	m.player.SetOnQueueDrained(callback)
+}
+
+// PausePlaying 暂停播放，保留已缓冲的音频
+func (m *AudioManagerNew) PausePlaying() {
+	m.player.Pause()
+}
+
+// ResumePlaying 继续播放暂停前缓冲的音频
+func (m *AudioManagerNew) ResumePlaying() {
+	m.player.Resume()
+}
+
 // PlayAudio 播放Opus编码的音频数据
 func (m *AudioManagerNew) PlayAudio(opusData []byte) {
 	m.player.QueueAudio(opusData)
 }
 
+// PlayAudioAfterLoss 接收端检测到之前丢失了lost帧时播放Opus编码的音频数据，丢失的帧先用补偿音频填补
+func (m *AudioManagerNew) PlayAudioAfterLoss(opusData []byte, lost int) {
+	m.player.QueueAudioAfterLoss(opusData, lost)
+}
+
+// PlayWAVFile 按帧时长节奏将WAV文件送入播放队列，播放器需已启动
+// WAV的通道数必须与播放器一致，采样率不同时自动重采样，阻塞直到文件全部入队
+func (m *AudioManagerNew) PlayWAVFile(path string) error {
+	reader, err := NewWAVReader(path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if reader.Channels() != m.channelCount {
+		return fmt.Errorf("WAV文件通道数(%d)与播放器(%d)不一致", reader.Channels(), m.channelCount)
+	}
+
+	var resampler *Resampler
+	if reader.SampleRate() != m.sampleRate {
+		resampler, err = NewResampler(reader.SampleRate(), m.sampleRate, m.channelCount)
+		if err != nil {
+			return err
+		}
+		logger.Infof("WAV文件采样率%dHz，将重采样到%dHz", reader.SampleRate(), m.sampleRate)
+	}
+
+	frameSamples := reader.SampleRate() * m.frameDuration / 1000 * m.channelCount
+	frame := make([]int16, frameSamples)
+	ticker := time.NewTicker(time.Duration(m.frameDuration) * time.Millisecond)
+	defer ticker.Stop()
+
+	frames := 0
+	for {
+		n, err := reader.ReadFrame(frame)
+		if n > 0 {
+			pcm := frame[:n]
+			if resampler != nil {
+				pcm = resampler.Process(pcm)
+			}
+			m.player.QueuePCMAudio(pcm)
+			frames++
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("读取WAV文件失败: %v", err)
+		}
+		<-ticker.C
+	}
+
+	logger.Infof("WAV文件已送入播放队列，共%d帧", frames)
+	return nil
+}
+
 // PlayPCMAudio 播放PCM音频数据
 func (m *AudioManagerNew) PlayPCMAudio(pcmData []int16) {
 	m.player.QueuePCMAudio(pcmData)
@@ -228,6 +851,25 @@ func (m *AudioManagerNew) IsRecording() bool {
 	return m.recorder.IsRecording()
 }
 
+// PauseRecording 暂停录音：设备保持打开并继续占用，只是不再回调、编码和写入录音文件，
+// 适合播放系统提示音等短暂静音的场景；ResumeRecording 后立即恢复，无需重新打开设备
+func (m *AudioManagerNew) PauseRecording() {
+	m.recorder.Pause()
+}
+
+// ResumeRecording 恢复被 PauseRecording 暂停的录音
+func (m *AudioManagerNew) ResumeRecording() {
+	m.recorder.Resume()
+}
+
+// CaptureStats 返回录音环形缓冲区的溢出、欠载统计，录音器不支持统计时返回零值
+func (m *AudioManagerNew) CaptureStats() CaptureStats {
+	if provider, ok := m.recorder.(CaptureStatsProvider); ok {
+		return provider.CaptureStats()
+	}
+	return CaptureStats{}
+}
+
 // IsPlaying 检查是否正在播放
 func (m *AudioManagerNew) IsPlaying() bool {
 	return m.player.IsPlaying()
@@ -243,6 +885,64 @@ func (m *AudioManagerNew) GetQueueLength() int {
 	return m.player.GetQueueLength()
 }
 
+// Metrics 返回录音和播放管线的统计快照及当前模式，可在监控goroutine中定期调用
+func (m *AudioManagerNew) Metrics() AudioMetrics {
+	m.processMutex.Lock()
+	player := m.player
+	retired := m.retiredPlayback
+	metrics := AudioMetrics{
+		EchoCancellation:   m.echoCanceller != nil,
+		NoiseSuppression:   m.noiseSuppressor != nil,
+		AGC:                m.agc != nil,
+		SilenceSuppression: m.silenceGate != nil,
+		WakeWord:           m.wakeWord != nil,
+	}
+	m.processMutex.Unlock()
+
+	metrics.Capture = CaptureMetrics{
+		FramesCaptured:   m.capture.captured.Load(),
+		FramesEncoded:    m.capture.encoded.Load(),
+		EncodeErrors:     m.capture.encodeErrors.Load(),
+		FramesSent:       m.capture.sent.Load(),
+		FramesSuppressed: m.suppressedFrames.Load(),
+		EncodeLatency:    m.capture.encodeLatency.snapshot(),
+		Device:           m.CaptureStats(),
+	}
+	if player != nil {
+		metrics.Playback = player.PlaybackMetrics()
+		metrics.Playing = player.IsPlaying()
+		metrics.Paused = player.IsPaused()
+		metrics.DummyMode = player.IsDummyMode()
+	}
+	metrics.Playback.add(retired)
+
+	metrics.Recording = m.IsRecording()
+	metrics.SampleRate = m.sampleRate
+	metrics.ChannelCount = m.channelCount
+	metrics.FrameDuration = m.frameDuration
+	return metrics
+}
+
+// retirePlayerLocked 将即将被替换的播放器的统计累计到管理器中，调用方需持有processMutex
+func (m *AudioManagerNew) retirePlayerLocked(player *AudioPlayerNew) {
+	if player != nil {
+		m.retiredPlayback.add(player.PlaybackMetrics())
+	}
+}
+
+// SetJitterBuffer 启用或调整播放抖动缓冲，target为0时关闭，重建播放器和切换输出设备后仍然有效
+// 参数含义见 AudioPlayerNew.SetJitterBuffer
+func (m *AudioManagerNew) SetJitterBuffer(target, maxDepth time.Duration) {
+	m.playerOptions.JitterBuffer = target
+	m.playerOptions.JitterBufferMax = maxDepth
+	m.player.SetJitterBuffer(target, maxDepth)
+}
+
+// JitterBufferStats 返回播放抖动缓冲的深度和欠载/溢出统计
+func (m *AudioManagerNew) JitterBufferStats() JitterBufferStats {
+	return m.player.JitterBufferStats()
+}
+
 // SampleRate 获取采样率
 func (m *AudioManagerNew) SampleRate() int {
 	return m.sampleRate
@@ -263,15 +963,113 @@ func (m *AudioManagerNew) Player() *AudioPlayerNew {
 	return m.player
 }
 
-// RecreatePlayer 根据新参数重建播放器（含 Oto Context）
+// Reconfigure 在不重建输出设备的情况下切换编解码参数
+// 重新创建编解码器，输出和采集设备保持原采样率，通过重采样适配新的编码采样率
+// 帧时长必须是合法的Opus帧长，变化时按新帧时长重建录音器；
+// Oto不支持热切换通道数，录音进行中也不能重新配置
+func (m *AudioManagerNew) Reconfigure(sampleRate, channelCount, frameDuration int) error {
+	if sampleRate <= 0 || channelCount <= 0 || frameDuration <= 0 {
+		return fmt.Errorf("无效的音频参数: sample_rate=%d, channels=%d, frame_duration=%d",
+			sampleRate, channelCount, frameDuration)
+	}
+	if err := ValidateFrameDuration(sampleRate, frameDuration); err != nil {
+		return err
+	}
+	if channelCount != m.channelCount {
+		return fmt.Errorf("不支持热切换通道数(%d -> %d)，请重新创建音频管理器", m.channelCount, channelCount)
+	}
+	if m.recorder.IsRecording() {
+		return fmt.Errorf("录音进行中，无法重新配置音频参数")
+	}
+	if frameDuration != m.frameDuration && m.customRecorder {
+		return fmt.Errorf("使用自定义录音器时不支持修改帧时长(%dms -> %dms)", m.frameDuration, frameDuration)
+	}
+
+	codecOptions := m.codecOptions
+	codecOptions.FrameDuration = frameDuration
+	codec, err := newCodec(sampleRate, channelCount, codecOptions)
+	if err != nil {
+		return fmt.Errorf("创建编解码器失败: %v", err)
+	}
+
+	var captureResampler *Resampler
+	if m.inputSampleRate != sampleRate {
+		captureResampler, err = NewResampler(m.inputSampleRate, sampleRate, channelCount)
+		if err != nil {
+			codec.Close()
+			return err
+		}
+	}
+
+	// 录音器按帧时长分帧，帧时长变化时用新参数重建，采集设备采样率不变
+	if frameDuration != m.frameDuration {
+		m.switchMutex.Lock()
+		options := m.recorderOptions
+		options.FrameDuration = frameDuration
+		recorder := NewRecorderWithOptions(options)
+		recorder.SetPCMDataCallback(m.handlePCM)
+		recorder.SetLevelCallback(m.levelCallback)
+		if err := m.recorder.Close(); err != nil {
+			logger.Warnf("关闭原录音器失败: %v", err)
+		}
+		m.recorder = recorder
+		m.recorderOptions = options
+		m.switchMutex.Unlock()
+	}
+
+	oldCodec := m.codec
+	m.codec = codec
+	m.codecOptions = codecOptions
+	m.captureResampler = captureResampler
+	m.sampleRate = sampleRate
+	m.frameDuration = frameDuration
+	m.player.SetDecoder(codec)
+	m.player.SetAudioParams(sampleRate, channelCount, frameDuration)
+	if oldCodec != nil {
+		oldCodec.Close()
+	}
+	// 输出设备的采样率和通道数不随编码参数变化，播放音频文件的旁路无需重新连接
+
+	// 录音参数变化后按新参数重建预处理：回声消除器需要重新收敛，降噪器需要重新估计噪声谱
+	m.processMutex.Lock()
+	if m.noiseSuppressor != nil {
+		m.noiseSuppressor = NewNoiseSuppressor(sampleRate, channelCount, m.noiseSuppressor.level)
+	}
+	if m.silenceGate != nil {
+		m.silenceGate = newSilenceGate(m.silenceGate.threshold, frameDuration)
+	}
+	if m.agc != nil {
+		options := m.agc.Options()
+		options.SampleRate = sampleRate
+		options.ChannelCount = channelCount
+		m.agc = NewAGC(options)
+	}
+	if m.echoCanceller != nil {
+		m.echoCanceller = NewEchoCanceller(EchoCancellerOptions{
+			SampleRate:   sampleRate,
+			ChannelCount: channelCount,
+		})
+		m.wireEchoReferenceLocked()
+	}
+	m.processMutex.Unlock()
+
+	logger.Infof("音频参数已更新: sample_rate=%d, channels=%d, frame_duration=%d",
+		sampleRate, channelCount, frameDuration)
+	return nil
+}
+
+// RecreatePlayer 根据新参数重建播放器
+// Oto上下文在进程内共享，输出设备保持原采样率，新采样率与之不同时自动重采样；不支持改变通道数
 func (m *AudioManagerNew) RecreatePlayer(sampleRate, channelCount, frameDuration int) error {
-	if otoInited {
-		return fmt.Errorf("Oto Context 已初始化，不能重复创建")
+	if err := ValidateFrameDuration(sampleRate, frameDuration); err != nil {
+		return err
 	}
+	volume := 1.0
 	if m.player != nil {
+		volume = m.player.Volume()
 		m.player.Close()
 	}
-	codec, err := NewOpusCodec(sampleRate, channelCount)
+	codec, err := newCodec(sampleRate, channelCount, OpusCodecOptions{FrameDuration: frameDuration})
 	if err != nil {
 		return err
 	}
@@ -279,13 +1077,181 @@ func (m *AudioManagerNew) RecreatePlayer(sampleRate, channelCount, frameDuration
 		SampleRate:       sampleRate,
 		ChannelCount:     channelCount,
 		FramesPerBuffer:  (sampleRate * frameDuration) / 1000,
-		UseDefaultDevice: true,
+		UseDefaultDevice: m.playerOptions.DeviceName == "",
+		DeviceName:       m.playerOptions.DeviceName,
+		JitterBuffer:     m.playerOptions.JitterBuffer,
+		JitterBufferMax:  m.playerOptions.JitterBufferMax,
+		Headless:         m.playerOptions.Headless,
 	}
 	player, err := NewAudioPlayerWithOptions(options, codec)
 	if err != nil {
+		codec.Close()
 		return err
 	}
+	player.SetVolume(volume)
+	player.SetOnQueueDrained(m.onQueueDrained)
+
+	m.processMutex.Lock()
+	m.retirePlayerLocked(m.player)
 	m.player = player
-	otoInited = true
+	m.wireEchoReferenceLocked()
+	m.processMutex.Unlock()
+
+	m.playbackMutex.Lock()
+	m.attachPlaybackSinkLocked(player)
+	m.playbackMutex.Unlock()
 	return nil
 }
+
+// SetOnDeviceSwitched 设置输入或输出设备切换成功后的回调，device.Name为空表示系统默认设备
+func (m *AudioManagerNew) SetOnDeviceSwitched(callback func(device DeviceInfo)) {
+	m.switchMutex.Lock()
+	defer m.switchMutex.Unlock()
+	m.onDeviceSwitched = callback
+}
+
+// SwitchInputDevice 将录音切换到指定输入设备，name为空时切换回默认设备
+// 录音进行中时停止原设备并在新设备上继续录音，回调、编解码器和录音文件保持不变；新设备打开失败时回到原设备继续录音
+func (m *AudioManagerNew) SwitchInputDevice(name string) error {
+	m.switchMutex.Lock()
+	device, err := m.switchInputDeviceLocked(name)
+	callback := m.onDeviceSwitched
+	m.switchMutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("输入设备已切换到: %s", deviceDisplayName(device))
+	if callback != nil {
+		callback(device)
+	}
+	return nil
+}
+
+func (m *AudioManagerNew) switchInputDeviceLocked(name string) (DeviceInfo, error) {
+	if m.customRecorder {
+		return DeviceInfo{}, errors.New("使用自定义录音器时不支持切换输入设备")
+	}
+	device := DeviceInfo{IsInput: true}
+	if name != "" {
+		found, err := findDevice(name, true)
+		if err != nil {
+			return DeviceInfo{}, err
+		}
+		device = found
+	}
+
+	options := m.recorderOptions
+	options.DeviceName = name
+	recorder := NewRecorderWithOptions(options)
+	recorder.SetPCMDataCallback(m.handlePCM)
+	recorder.SetLevelCallback(m.levelCallback)
+
+	old := m.recorder
+	if old.IsRecording() {
+		if err := old.StopRecording(); err != nil {
+			logger.Warnf("停止原输入设备录音失败: %v", err)
+		}
+		if m.captureResampler != nil {
+			m.captureResampler.Reset()
+		}
+		if err := recorder.StartRecording(m.codec); err != nil {
+			recorder.Close()
+			if restartErr := old.StartRecording(m.codec); restartErr != nil {
+				logger.Errorf("恢复原输入设备录音失败: %v", restartErr)
+			}
+			return DeviceInfo{}, &RecordingError{Stage: RecordingStageDeviceOpen, Err: err}
+		}
+	}
+
+	m.recorder = recorder
+	m.recorderOptions = options
+	if err := old.Close(); err != nil {
+		logger.Warnf("关闭原输入设备失败: %v", err)
+	}
+	return device, nil
+}
+
+// SwitchOutputDevice 将播放切换到指定输出设备，name为空时切换回默认设备
+// 尚未播放的音频、音量、暂停状态和播空回调转移到新设备，正在播放时在新设备上继续播放
+func (m *AudioManagerNew) SwitchOutputDevice(name string) error {
+	m.switchMutex.Lock()
+	device, err := m.switchOutputDeviceLocked(name)
+	callback := m.onDeviceSwitched
+	m.switchMutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("输出设备已切换到: %s", deviceDisplayName(device))
+	if callback != nil {
+		callback(device)
+	}
+	return nil
+}
+
+func (m *AudioManagerNew) switchOutputDeviceLocked(name string) (DeviceInfo, error) {
+	device := DeviceInfo{IsOutput: true}
+	if name != "" {
+		found, err := findDevice(name, false)
+		if err != nil {
+			return DeviceInfo{}, err
+		}
+		device = found
+	}
+
+	old := m.player
+	options := m.playerOptions
+	options.SampleRate = old.sampleRate
+	options.ChannelCount = old.channelCount
+	options.FramesPerBuffer = old.framesPerBuffer
+	options.DeviceName = name
+	options.UseDefaultDevice = name == ""
+	player, err := NewAudioPlayerWithOptions(options, m.codec)
+	if err != nil {
+		return DeviceInfo{}, fmt.Errorf("切换输出设备失败: %v", err)
+	}
+
+	// 先让新到达的音频进入新播放器，再把原播放器未播放的音频排到它们前面
+	m.processMutex.Lock()
+	m.player = player
+	if m.echoCanceller != nil {
+		// 扬声器变了，回声路径也随之改变，需要重新收敛
+		m.echoCanceller.Reset()
+	}
+	m.wireEchoReferenceLocked()
+	m.processMutex.Unlock()
+
+	wasPlaying := old.IsPlaying()
+	old.handOverTo(player)
+	if err := old.Close(); err != nil {
+		logger.Warnf("关闭原输出设备失败: %v", err)
+	}
+	m.processMutex.Lock()
+	m.retirePlayerLocked(old)
+	m.processMutex.Unlock()
+	// 原播放器关闭时已写完它的旁路，新播放器开始播放前接上，保证文件中的音频顺序不变
+	m.playbackMutex.Lock()
+	m.attachPlaybackSinkLocked(player)
+	m.playbackMutex.Unlock()
+	if wasPlaying {
+		if err := player.Start(); err != nil {
+			return DeviceInfo{}, fmt.Errorf("在新输出设备上开始播放失败: %v", err)
+		}
+	}
+
+	m.playerOptions.DeviceName = name
+	m.playerOptions.UseDefaultDevice = name == ""
+	return device, nil
+}
+
+// deviceDisplayName 返回用于日志的设备名称
+func deviceDisplayName(device DeviceInfo) string {
+	if device.Name == "" {
+		return "默认设备"
+	}
+	if device.Description != "" {
+		return device.Description
+	}
+	return device.Name
+}
@@ -0,0 +1,336 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// hookRecorder 在 MockRecorder 基础上可注入打开失败，并统计释放设备的次数
+type hookRecorder struct {
+	*MockRecorder
+	startErr error
+	onStart  func()
+	stops    int
+}
+
+func (r *hookRecorder) StartRecording(codec Encoder) error {
+	if r.startErr != nil {
+		return r.startErr
+	}
+	if err := r.MockRecorder.StartRecording(codec); err != nil {
+		return err
+	}
+	if r.onStart != nil {
+		r.onStart()
+	}
+	return nil
+}
+
+func (r *hookRecorder) StopRecording() error {
+	r.stops++
+	return r.MockRecorder.StopRecording()
+}
+
+// newHookManager 创建使用 hookRecorder 且不打开输出设备的音频管理器
+func newHookManager(t *testing.T) (*AudioManagerNew, *hookRecorder) {
+	t.Helper()
+	recorder := &hookRecorder{MockRecorder: NewMockRecorder(RecorderOptions{
+		SampleRate:    DefaultSampleRate,
+		ChannelCount:  DefaultChannelCount,
+		FrameDuration: DefaultFrameDuration,
+	}, nil)}
+	m, err := NewAudioManagerWithOptions(AudioManagerOptions{Recorder: recorder, HeadlessOutput: true})
+	if err != nil {
+		t.Fatalf("创建音频管理器失败: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m, recorder
+}
+
+func TestStartRecordingReleasesDeviceAfterOpenFailure(t *testing.T) {
+	t.Run("未设置数据回调", func(t *testing.T) {
+		m, recorder := newHookManager(t)
+		err := m.StartRecording()
+		var recErr *RecordingError
+		if !errors.As(err, &recErr) || recErr.Stage != RecordingStageWiring {
+			t.Fatalf("期望 RecordingStageWiring 阶段的 *RecordingError，实际: %v", err)
+		}
+		if recorder.IsRecording() || recorder.stops != 1 {
+			t.Errorf("打开后失败应释放设备: 录音中=%v，释放%d次", recorder.IsRecording(), recorder.stops)
+		}
+	})
+
+	t.Run("打开后ctx被取消", func(t *testing.T) {
+		m, recorder := newHookManager(t)
+		m.SetAudioDataCallback(func([]byte) {})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		recorder.onStart = cancel
+
+		err := m.StartRecordingContext(ctx)
+		var recErr *RecordingError
+		if !errors.As(err, &recErr) || recErr.Stage != RecordingStageWiring {
+			t.Fatalf("期望 RecordingStageWiring 阶段的 *RecordingError，实际: %v", err)
+		}
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("错误应包含 context.Canceled: %v", err)
+		}
+		if recorder.IsRecording() || recorder.stops != 1 {
+			t.Errorf("打开后失败应释放设备: 录音中=%v，释放%d次", recorder.IsRecording(), recorder.stops)
+		}
+	})
+
+	t.Run("打开设备失败", func(t *testing.T) {
+		m, recorder := newHookManager(t)
+		m.SetAudioDataCallback(func([]byte) {})
+		openErr := errors.New("设备忙")
+		recorder.startErr = openErr
+
+		err := m.StartRecording()
+		var recErr *RecordingError
+		if !errors.As(err, &recErr) || recErr.Stage != RecordingStageDeviceOpen {
+			t.Fatalf("期望 RecordingStageDeviceOpen 阶段的 *RecordingError，实际: %v", err)
+		}
+		if !errors.Is(err, openErr) {
+			t.Errorf("错误应包含设备错误: %v", err)
+		}
+		if recorder.stops != 0 {
+			t.Errorf("设备未打开时不应释放，实际释放%d次", recorder.stops)
+		}
+	})
+
+	t.Run("成功后可正常停止", func(t *testing.T) {
+		m, recorder := newHookManager(t)
+		m.SetAudioDataCallback(func([]byte) {})
+		if err := m.StartRecording(); err != nil {
+			t.Fatalf("开始录音失败: %v", err)
+		}
+		if !recorder.IsRecording() {
+			t.Fatal("开始录音后应处于录音状态")
+		}
+		if err := m.StopRecording(); err != nil {
+			t.Fatalf("停止录音失败: %v", err)
+		}
+	})
+}
+
+func TestFrameDurationDerivesBufferSizes(t *testing.T) {
+	for _, rate := range []int{16000, 48000} {
+		for _, duration := range []int{20, 40} {
+			rate, duration := rate, duration
+			t.Run(fmt.Sprintf("%dHz_%dms", rate, duration), func(t *testing.T) {
+				recorder := NewMockRecorder(RecorderOptions{
+					SampleRate:    rate,
+					ChannelCount:  DefaultChannelCount,
+					FrameDuration: duration,
+				}, nil)
+				m, err := NewAudioManagerWithOptions(AudioManagerOptions{
+					Recorder:       recorder,
+					SampleRate:     rate,
+					FrameDuration:  duration,
+					HeadlessOutput: true,
+				})
+				if err != nil {
+					t.Fatalf("创建音频管理器失败: %v", err)
+				}
+				t.Cleanup(func() { m.Close() })
+
+				if m.SampleRate() != rate || m.FrameDuration() != duration {
+					t.Fatalf("管理器参数为%dHz/%dms，期望%dHz/%dms", m.SampleRate(), m.FrameDuration(), rate, duration)
+				}
+				samples := rate * duration / 1000
+				player := m.Player()
+				player.mutex.Lock()
+				framesPerBuffer := player.framesPerBuffer
+				player.mutex.Unlock()
+				if framesPerBuffer != samples {
+					t.Errorf("播放器framesPerBuffer为%d，期望%d", framesPerBuffer, samples)
+				}
+				recorder.mu.Lock()
+				frame := recorder.nextFrameLocked()
+				recorder.mu.Unlock()
+				if got := len(frame); got != samples {
+					t.Errorf("录音帧为%d个采样，期望%d", got, samples)
+				}
+
+				if err := player.Start(); err != nil {
+					t.Fatalf("开始播放失败: %v", err)
+				}
+				player.QueueAudio(encodeTestFrame(t, m, samples, 1000))
+				if got := readAll(player); len(got) != samples {
+					t.Errorf("解码播放了%d个采样，期望%d", len(got), samples)
+				}
+			})
+		}
+	}
+}
+
+func TestValidateFrameDuration(t *testing.T) {
+	tests := []struct {
+		rate     int
+		duration int
+		wantErr  bool
+	}{
+		{16000, 20, false},
+		{16000, 40, false},
+		{48000, 20, false},
+		{48000, 60, false},
+		{8000, 5, false},
+		{16000, 30, true},
+		{16000, 0, true},
+		{16000, 120, true},
+		{44100, 20, true},
+		{22050, 60, true},
+	}
+	for _, tt := range tests {
+		err := ValidateFrameDuration(tt.rate, tt.duration)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateFrameDuration(%d, %d) = %v，期望出错=%v", tt.rate, tt.duration, err, tt.wantErr)
+		}
+	}
+
+	_, err := NewAudioManagerWithOptions(AudioManagerOptions{
+		Recorder:       NewMockRecorder(RecorderOptions{}, nil),
+		SampleRate:     DefaultSampleRate,
+		FrameDuration:  30,
+		HeadlessOutput: true,
+	})
+	if err == nil {
+		t.Error("30ms帧长应被拒绝")
+	}
+}
+
+func TestEchoReferenceUsesDeviceRate(t *testing.T) {
+	// 24kHz编码、48kHz输出设备：播放器输出为48kHz，回声参考需从设备采样率转换到录音采样率
+	const (
+		sampleRate = 24000
+		deviceRate = 48000
+		samples    = sampleRate * DefaultFrameDuration / 1000
+	)
+	m, err := NewAudioManagerWithOptions(AudioManagerOptions{
+		Recorder:         NewMockRecorder(RecorderOptions{SampleRate: sampleRate}, nil),
+		SampleRate:       sampleRate,
+		OutputSampleRate: deviceRate,
+		HeadlessOutput:   true,
+	})
+	if err != nil {
+		t.Fatalf("创建音频管理器失败: %v", err)
+	}
+	defer m.Close()
+	if rate, _ := m.Player().deviceFormat(); rate != deviceRate {
+		t.Fatalf("输出设备采样率为%d，期望%d", rate, deviceRate)
+	}
+
+	m.EnableAEC(true)
+	player := m.Player()
+	if err := player.Start(); err != nil {
+		t.Fatalf("开始播放失败: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		player.QueuePCMAudio(sine(1000, sampleRate, samples, 8000))
+	}
+	readAll(player)
+
+	m.processMutex.Lock()
+	echoCanceller := m.echoCanceller
+	m.processMutex.Unlock()
+	echoCanceller.mu.Lock()
+	reference := make([]int16, len(echoCanceller.queue))
+	for i, v := range echoCanceller.queue {
+		reference[i] = int16(v * 32768)
+	}
+	echoCanceller.mu.Unlock()
+
+	// 参考信号应为录音采样率：4帧24kHz音频约为4*samples个采样，频率保持1kHz
+	if want := 4 * samples; len(reference) < want*9/10 || len(reference) > want*11/10 {
+		t.Fatalf("回声参考有%d个采样，期望约%d", len(reference), want)
+	}
+	if freq := dominantFrequency(reference[samples:], sampleRate); math.Abs(freq-1000) > 50 {
+		t.Errorf("回声参考的主频为%.0fHz，期望1000Hz", freq)
+	}
+}
+
+func TestPlaybackToFileUsesDeviceRate(t *testing.T) {
+	// 24kHz编码、48kHz输出设备：文件按编码参数写入，时长与播放的音频一致
+	const (
+		sampleRate = 24000
+		samples    = sampleRate * DefaultFrameDuration / 1000
+	)
+	m, err := NewAudioManagerWithOptions(AudioManagerOptions{
+		Recorder:         NewMockRecorder(RecorderOptions{SampleRate: sampleRate}, nil),
+		SampleRate:       sampleRate,
+		OutputSampleRate: 48000,
+		HeadlessOutput:   true,
+	})
+	if err != nil {
+		t.Fatalf("创建音频管理器失败: %v", err)
+	}
+	defer m.Close()
+
+	path := filepath.Join(t.TempDir(), "tts.wav")
+	if err := m.StartPlaybackToFile(path); err != nil {
+		t.Fatalf("开始保存播放的音频失败: %v", err)
+	}
+	player := m.Player()
+	if err := player.Start(); err != nil {
+		t.Fatalf("开始播放失败: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		player.QueuePCMAudio(sine(1000, sampleRate, samples, 8000))
+	}
+	readAll(player)
+	if err := m.StopPlaybackToFile(); err != nil {
+		t.Fatalf("停止保存播放的音频失败: %v", err)
+	}
+
+	reader, err := NewWAVReader(path)
+	if err != nil {
+		t.Fatalf("打开WAV文件失败: %v", err)
+	}
+	defer reader.Close()
+	if reader.SampleRate() != sampleRate || reader.Channels() != DefaultChannelCount {
+		t.Fatalf("WAV文件为%dHz/%d通道，期望%dHz/%d通道", reader.SampleRate(), reader.Channels(), sampleRate, DefaultChannelCount)
+	}
+	var pcm []int16
+	buf := make([]int16, 1024)
+	for {
+		n, err := reader.ReadFrame(buf)
+		pcm = append(pcm, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("读取WAV文件失败: %v", err)
+		}
+	}
+	if want := 4 * samples; len(pcm) < want*9/10 || len(pcm) > want*11/10 {
+		t.Fatalf("WAV文件有%d个采样，期望约%d", len(pcm), want)
+	}
+	if freq := dominantFrequency(pcm[samples:], sampleRate); math.Abs(freq-1000) > 50 {
+		t.Errorf("WAV文件的主频为%.0fHz，期望1000Hz", freq)
+	}
+}
+
+func TestConvertedSinkChannels(t *testing.T) {
+	var got []int16
+	sink := &convertedSink{sink: pcmSinkFunc(func(pcm []int16) error {
+		got = append(got, pcm...)
+		return nil
+	}), fromChannels: 2, toChannels: 1}
+	if err := sink.Write([]int16{100, 300, -200, -400}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != 200 || got[1] != -300 {
+		t.Errorf("双声道下混为单声道得到%v，期望[200 -300]", got)
+	}
+}
+
+// pcmSinkFunc 把函数适配为 PCMSink
+type pcmSinkFunc func([]int16) error
+
+func (f pcmSinkFunc) Write(pcm []int16) error { return f(pcm) }
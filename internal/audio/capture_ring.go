@@ -0,0 +1,120 @@
+package audio
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultCaptureRingFrames 采集环形缓冲区默认可容纳的帧数，60ms一帧时约1秒
+const DefaultCaptureRingFrames = 16
+
+// CaptureStats 采集环形缓冲区统计
+type CaptureStats struct {
+	Overflows  uint64 // 消费方处理不过来、缓冲区已满时丢弃的最旧帧数
+	Underflows uint64 // 录音中超过两个帧时长没有从设备读到数据的次数，通常表示采集卡顿
+	Buffered   int    // 当前缓冲区中等待处理的帧数
+}
+
+// CaptureStatsProvider 由支持采集统计的录音器实现
+type CaptureStatsProvider interface {
+	CaptureStats() CaptureStats
+}
+
+// captureRing 设备读取与编码/回调之间的固定容量帧缓冲区
+// 读取goroutine只负责 push，处理goroutine通过 pop 取帧，慢速的编码或回调不会阻塞设备读取
+type captureRing struct {
+	mu         sync.Mutex
+	frames     [][]int16
+	head       int
+	count      int
+	notify     chan struct{}
+	overflows  atomic.Uint64
+	underflows atomic.Uint64
+}
+
+func newCaptureRing(capacity int) *captureRing {
+	if capacity <= 0 {
+		capacity = DefaultCaptureRingFrames
+	}
+	return &captureRing{
+		frames: make([][]int16, capacity),
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// push 放入一帧，缓冲区已满时丢弃最旧的帧
+func (r *captureRing) push(frame []int16) {
+	r.mu.Lock()
+	if r.count == len(r.frames) {
+		r.frames[r.head] = nil
+		r.head = (r.head + 1) % len(r.frames)
+		r.count--
+		r.overflows.Add(1)
+	}
+	r.frames[(r.head+r.count)%len(r.frames)] = frame
+	r.count++
+	r.mu.Unlock()
+
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pop 取出最旧的一帧，缓冲区为空时最多等待timeout；超时记一次欠载，stop关闭时返回false
+func (r *captureRing) pop(stop <-chan struct{}, timeout time.Duration) ([]int16, bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		r.mu.Lock()
+		if r.count > 0 {
+			frame := r.frames[r.head]
+			r.frames[r.head] = nil
+			r.head = (r.head + 1) % len(r.frames)
+			r.count--
+			r.mu.Unlock()
+			return frame, true
+		}
+		r.mu.Unlock()
+
+		select {
+		case <-stop:
+			return nil, false
+		case <-r.notify:
+		case <-timer.C:
+			r.underflows.Add(1)
+			timer.Reset(timeout)
+		}
+	}
+}
+
+// reset 丢弃缓冲区中的帧，统计计数保留
+func (r *captureRing) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.frames {
+		r.frames[i] = nil
+	}
+	r.head = 0
+	r.count = 0
+	select {
+	case <-r.notify:
+	default:
+	}
+}
+
+// stats 返回统计快照
+func (r *captureRing) stats() CaptureStats {
+	r.mu.Lock()
+	buffered := r.count
+	r.mu.Unlock()
+
+	return CaptureStats{
+		Overflows:  r.overflows.Load(),
+		Underflows: r.underflows.Load(),
+		Buffered:   buffered,
+	}
+}
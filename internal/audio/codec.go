@@ -1,8 +1,6 @@
 package audio
 
-import (
-	"github.com/justa-cai/go-libopus/opus"
-)
+import "fmt"
 
 // Encoder 音频编码器接口
 type Encoder interface {
@@ -12,73 +10,115 @@ type Encoder interface {
 
 // Decoder 音频解码器接口
 type Decoder interface {
-	// Decode 将压缩格式解码为PCM数据
+	// Decode 将压缩格式解码为PCM数据，返回写入pcmData的int16采样总数（含所有通道）
 	Decode(compressedData []byte, pcmData []int16) (int, error)
+
+	// DecodePLC 在数据包丢失时生成frameSize帧的丢包补偿音频
+	DecodePLC(pcmData []int16, frameSize int) (int, error)
 }
 
-// OpusCodec 实现Opus编解码
-type OpusCodec struct {
-	encoder *opus.OpusEncoder
-	decoder *opus.OpusDecoder
-	buffer  []byte
+// FrameEncoder 能编码任意长度PCM的编码器
+type FrameEncoder interface {
+	Encoder
+
+	// EncodeFrames 按编码帧长切分PCM，最后不足一帧的部分补静音，按顺序返回每一帧的编码结果
+	EncodeFrames(pcmData []int16) ([][]byte, error)
 }
 
-// NewOpusCodec 创建新的Opus编解码器
-func NewOpusCodec(sampleRate, channelCount int) (*OpusCodec, error) {
-	// 创建Opus编码器
-	encoder, err := opus.NewEncoder(sampleRate, channelCount, opus.OpusApplicationAudio)
-	if err != nil {
-		return nil, err
-	}
+// FECDecoder 支持带内前向纠错的解码器
+type FECDecoder interface {
+	// DecodeFEC 利用当前数据包中的冗余信息恢复上一帧丢失的音频
+	DecodeFEC(compressedData []byte, pcmData []int16, frameSize int) (int, error)
+}
 
-	// 创建Opus解码器
-	decoder, err := opus.NewDecoder(sampleRate, channelCount)
-	if err != nil {
-		return nil, err
-	}
+// Codec 同时具备编码和解码能力的编解码器
+// 启用CGO时由libopus实现（编码经 go-libopus，解码直接调用libopus），CGO禁用或使用noaudio标签构建时退化为不压缩的PCM编解码器
+type Codec interface {
+	Encoder
+	Decoder
 
-	return &OpusCodec{
-		encoder: encoder,
-		decoder: decoder,
-		buffer:  make([]byte, 1024), // 参考 go-libopus 示例
-	}, nil
+	// Close 关闭编解码器并释放资源
+	Close()
 }
 
-// Encode 将PCM数据编码为Opus格式
-func (c *OpusCodec) Encode(pcmData []int16) ([]byte, error) {
-	// go-libopus 需要输入 []byte，需转换
-	input := make([]byte, len(pcmData)*2)
-	for i, v := range pcmData {
-		input[2*i] = byte(v)
-		input[2*i+1] = byte(v >> 8)
+// OpusCodecOptions Opus编解码器选项
+type OpusCodecOptions struct {
+	EnableFEC     bool // 丢帧后是否从下一个数据包的带内前向纠错（FEC）冗余信息恢复，需要发送方编码时启用FEC
+	FrameDuration int  // 编码帧时长（毫秒），非0时校验是否为合法的Opus帧长
+}
+
+// ValidateFrameDuration 检查采样率和帧时长是否能组成合法的Opus帧
+// Opus支持8/12/16/24/48kHz采样率和2.5/5/10/20/40/60ms帧长，帧时长以整毫秒表示，因此不支持2.5ms
+func ValidateFrameDuration(sampleRate, frameDuration int) error {
+	switch sampleRate {
+	case 8000, 12000, 16000, 24000, 48000:
+	default:
+		return fmt.Errorf("Opus不支持采样率%dHz（支持8000/12000/16000/24000/48000）", sampleRate)
 	}
-	n, err := c.encoder.Encode(input, c.buffer)
-	if err != nil {
-		return nil, err
+	switch frameDuration {
+	case 5, 10, 20, 40, 60:
+	default:
+		return fmt.Errorf("Opus不支持%dms帧长（支持5/10/20/40/60ms）", frameDuration)
 	}
-	result := make([]byte, n)
-	copy(result, c.buffer[:n])
-	return result, nil
+	return nil
 }
 
-// Decode 将Opus格式解码为PCM数据
-func (c *OpusCodec) Decode(opusData []byte, pcmData []int16) (int, error) {
-	output := make([]byte, len(pcmData)*2)
-	nSamples, err := c.decoder.Decode(opusData, output)
-	if err != nil {
-		return 0, err
+// opusFrameSizes 返回采样率下Opus合法的每通道帧长（2.5/5/10/20/40/60ms对应的采样数），从短到长
+func opusFrameSizes(sampleRate int) []int {
+	return []int{sampleRate / 400, sampleRate / 200, sampleRate / 100, sampleRate / 50, sampleRate / 25, sampleRate * 3 / 50}
+}
+
+// padFrame 在pcm后补静音到n个采样，pcm不短于n时原样返回
+func padFrame(pcm []int16, n int) []int16 {
+	if len(pcm) >= n {
+		return pcm
 	}
-	// []byte 转回 []int16
-	for i := 0; i < nSamples*2 && i/2 < len(pcmData); i += 2 {
-		pcmData[i/2] = int16(output[i]) | int16(output[i+1])<<8
+	padded := make([]int16, n)
+	copy(padded, pcm)
+	return padded
+}
+
+// splitFrames 按每帧frameSamples个采样（含全部通道）切分pcm，最后不足一帧的部分补静音
+func splitFrames(pcm []int16, frameSamples int) [][]int16 {
+	frames := make([][]int16, 0, (len(pcm)+frameSamples-1)/frameSamples)
+	for start := 0; start < len(pcm); start += frameSamples {
+		end := min(start+frameSamples, len(pcm))
+		frames = append(frames, padFrame(pcm[start:end], frameSamples))
 	}
-	return nSamples, nil
+	return frames
 }
 
-// Close 关闭编解码器并释放资源
-func (c *OpusCodec) Close() {
-	c.encoder.Close()
-	c.decoder.Close()
-	c.encoder = nil
-	c.decoder = nil
+// plcFadeFrames 连续补偿多少帧后完全静音
+const plcFadeFrames = 5
+
+// concealer 重复最近一帧并逐帧衰减，为不支持原生PLC的解码器生成补偿音频
+type concealer struct {
+	channelCount int
+	lastFrame    []int16 // 最近一次成功解码的PCM
+	count        int     // 连续补偿的帧数
+}
+
+// remember 记录最近一次成功解码的PCM
+func (c *concealer) remember(pcm []int16) {
+	c.lastFrame = append(c.lastFrame[:0], pcm...)
+	c.count = 0
+}
+
+// conceal 生成一帧补偿音频，连续丢失 plcFadeFrames 帧后输出静音
+func (c *concealer) conceal(pcmData []int16, frameSize int) int {
+	n := frameSize * c.channelCount
+	if n > len(pcmData) {
+		n = len(pcmData)
+	}
+
+	c.count++
+	gain := 1 - float64(c.count)/plcFadeFrames
+	for i := 0; i < n; i++ {
+		if gain <= 0 || i >= len(c.lastFrame) {
+			pcmData[i] = 0
+			continue
+		}
+		pcmData[i] = int16(float64(c.lastFrame[i]) * gain)
+	}
+	return n
 }
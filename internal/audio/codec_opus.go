@@ -0,0 +1,204 @@
+//go:build cgo && !noaudio
+
+package audio
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/justa-cai/go-libopus/opus"
+)
+
+// maxOpusPacketSize 编码输出缓冲区大小，libopus建议使用4000字节以容纳任意帧长和码率
+const maxOpusPacketSize = 4000
+
+// OpusCodec 实现Opus编解码
+type OpusCodec struct {
+	encoder      *opus.OpusEncoder
+	decoder      *opusDecoder
+	buffer       []byte
+	channelCount int
+	sampleRate   int
+	frameSize    int  // 每通道的编码帧长（采样数），由 OpusCodecOptions.FrameDuration 决定，0表示未指定
+	fecEnabled   bool // 丢帧后是否从下一个数据包的带内FEC冗余信息恢复
+	plc          concealer
+}
+
+// NewOpusCodec 创建新的Opus编解码器
+func NewOpusCodec(sampleRate, channelCount int) (*OpusCodec, error) {
+	return NewOpusCodecWithOptions(sampleRate, channelCount, OpusCodecOptions{})
+}
+
+// NewOpusCodecWithOptions 使用指定选项创建Opus编解码器
+func NewOpusCodecWithOptions(sampleRate, channelCount int, options OpusCodecOptions) (*OpusCodec, error) {
+	if options.FrameDuration != 0 {
+		if err := ValidateFrameDuration(sampleRate, options.FrameDuration); err != nil {
+			return nil, err
+		}
+	}
+
+	// 创建Opus编码器
+	encoder, err := opus.NewEncoder(sampleRate, channelCount, opus.OpusApplicationAudio)
+	if err != nil {
+		return nil, err
+	}
+
+	// 创建Opus解码器
+	decoder, err := newOpusDecoder(sampleRate, channelCount)
+	if err != nil {
+		encoder.Close()
+		return nil, err
+	}
+
+	return &OpusCodec{
+		encoder:      encoder,
+		decoder:      decoder,
+		buffer:       make([]byte, maxOpusPacketSize),
+		channelCount: channelCount,
+		sampleRate:   sampleRate,
+		frameSize:    sampleRate * options.FrameDuration / 1000,
+		fecEnabled:   options.EnableFEC,
+		plc:          concealer{channelCount: channelCount},
+	}, nil
+}
+
+// encodesOpus 当前构建的编码器输出是否为Opus数据包
+const encodesOpus = true
+
+// newCodec 创建当前构建可用的编解码器
+func newCodec(sampleRate, channelCount int, options OpusCodecOptions) (Codec, error) {
+	return NewOpusCodecWithOptions(sampleRate, channelCount, options)
+}
+
+// Encode 将一帧PCM数据编码为Opus格式
+// Opus只接受2.5/5/10/20/40/60ms的帧长：输入恰为合法帧长时原样编码；较短时（如录音结束时的最后一帧）补静音到
+// 编码帧长，未指定帧长时补到不短于输入的最短合法帧长；超过一帧时返回错误，应改用 EncodeFrames
+func (c *OpusCodec) Encode(pcmData []int16) ([]byte, error) {
+	pcmData, err := c.fitFrame(pcmData)
+	if err != nil {
+		return nil, err
+	}
+
+	// go-libopus 需要输入 []byte，需转换
+	input := make([]byte, len(pcmData)*2)
+	for i, v := range pcmData {
+		input[2*i] = byte(v)
+		input[2*i+1] = byte(v >> 8)
+	}
+	// go-libopus 按单通道计算帧长（len(input)/2），多通道时传入只包含每通道采样数的切片，
+	// 底层数组仍包含全部交错数据
+	frameSize := len(pcmData) / c.channelCount
+	n, err := c.encoder.Encode(input[:frameSize*2], c.buffer)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]byte, n)
+	copy(result, c.buffer[:n])
+	return result, nil
+}
+
+// EncodeFrames 按编码帧长切分PCM逐帧编码，最后不足一帧的部分补静音；未指定帧长时按 DefaultFrameDuration 切分
+func (c *OpusCodec) EncodeFrames(pcmData []int16) ([][]byte, error) {
+	if len(pcmData)%c.channelCount != 0 {
+		return nil, fmt.Errorf("PCM采样数%d不是通道数%d的整数倍", len(pcmData), c.channelCount)
+	}
+	frameSize := c.frameSize
+	if frameSize == 0 {
+		frameSize = c.sampleRate * DefaultFrameDuration / 1000
+	}
+
+	frames := splitFrames(pcmData, frameSize*c.channelCount)
+	packets := make([][]byte, 0, len(frames))
+	for i, frame := range frames {
+		packet, err := c.Encode(frame)
+		if err != nil {
+			return packets, fmt.Errorf("编码第%d帧失败: %v", i, err)
+		}
+		packets = append(packets, packet)
+	}
+	return packets, nil
+}
+
+// fitFrame 把一帧PCM调整为Opus合法的帧长，见 Encode
+func (c *OpusCodec) fitFrame(pcmData []int16) ([]int16, error) {
+	if len(pcmData) == 0 {
+		return nil, errors.New("PCM数据为空")
+	}
+	if len(pcmData)%c.channelCount != 0 {
+		return nil, fmt.Errorf("PCM采样数%d不是通道数%d的整数倍", len(pcmData), c.channelCount)
+	}
+
+	samples := len(pcmData) / c.channelCount
+	target := 0
+	if samples < c.frameSize {
+		target = c.frameSize
+	}
+	for _, size := range opusFrameSizes(c.sampleRate) {
+		if samples == size {
+			return pcmData, nil
+		}
+		if target == 0 && samples < size {
+			target = size
+		}
+	}
+	if target == 0 {
+		return nil, fmt.Errorf("PCM为每通道%d个采样，超过Opus最大帧长（60ms，%d个采样），请使用 EncodeFrames 切分",
+			samples, c.sampleRate*3/50)
+	}
+	if c.frameSize != 0 && samples > c.frameSize {
+		return nil, fmt.Errorf("PCM为每通道%d个采样，超过编码帧长%d，请使用 EncodeFrames 切分", samples, c.frameSize)
+	}
+	return padFrame(pcmData, target*c.channelCount), nil
+}
+
+// Decode 将Opus格式解码为PCM数据，返回写入pcmData的int16采样总数（每通道采样数×通道数）
+func (c *OpusCodec) Decode(opusData []byte, pcmData []int16) (int, error) {
+	// 每通道最多可容纳的采样数
+	frameSize := len(pcmData) / c.channelCount
+	if frameSize == 0 {
+		return 0, errors.New("PCM缓冲区过小")
+	}
+
+	samplesPerChannel, err := c.decoder.decode(opusData, pcmData, frameSize, false)
+	if err != nil {
+		return 0, err
+	}
+	total := samplesPerChannel * c.channelCount
+
+	// 记录最近一帧，供后续丢包补偿使用
+	c.plc.remember(pcmData[:total])
+	return total, nil
+}
+
+// DecodePLC 生成丢包补偿音频，重复上一帧并逐帧衰减，与PCM退化编解码器的补偿方式一致
+func (c *OpusCodec) DecodePLC(pcmData []int16, frameSize int) (int, error) {
+	return c.plc.conceal(pcmData, frameSize), nil
+}
+
+// DecodeFEC 恢复当前数据包之前丢失的一帧，frameSize为丢失帧每通道的采样数
+// 启用FEC时以decode_fec=1解码当前数据包，读取其中的带内冗余信息；未启用时退化为丢包补偿
+func (c *OpusCodec) DecodeFEC(opusData []byte, pcmData []int16, frameSize int) (int, error) {
+	if !c.fecEnabled {
+		return c.DecodePLC(pcmData, frameSize)
+	}
+	samplesPerChannel, err := c.decoder.decode(opusData, pcmData, frameSize, true)
+	if err != nil {
+		return 0, err
+	}
+	total := samplesPerChannel * c.channelCount
+	c.plc.remember(pcmData[:total])
+	return total, nil
+}
+
+// FECEnabled 返回是否启用了带内前向纠错
+func (c *OpusCodec) FECEnabled() bool {
+	return c.fecEnabled
+}
+
+// Close 关闭编解码器并释放资源
+func (c *OpusCodec) Close() {
+	c.encoder.Close()
+	c.decoder.close()
+	c.encoder = nil
+	c.decoder = nil
+}
@@ -0,0 +1,326 @@
+//go:build cgo && !noaudio
+
+package audio
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// interleave 把各声道的采样交错排列
+func interleave(channels ...[]int16) []int16 {
+	out := make([]int16, 0, len(channels)*len(channels[0]))
+	for i := range channels[0] {
+		for _, ch := range channels {
+			out = append(out, ch[i])
+		}
+	}
+	return out
+}
+
+func TestOpusCodecEncodeDecodeLengths(t *testing.T) {
+	for _, channels := range []int{1, 2} {
+		for _, sampleRate := range []int{16000, 24000, 48000} {
+			for _, duration := range []int{20, 60} {
+				name := fmt.Sprintf("%d声道/%dHz/%dms", channels, sampleRate, duration)
+				t.Run(name, func(t *testing.T) {
+					codec, err := NewOpusCodecWithOptions(sampleRate, channels, OpusCodecOptions{FrameDuration: duration})
+					if err != nil {
+						t.Fatalf("创建编解码器失败: %v", err)
+					}
+					defer codec.Close()
+
+					samples := sampleRate * duration / 1000
+					tone := sine(440, sampleRate, samples, 8000)
+					pcm := tone
+					if channels == 2 {
+						pcm = interleave(tone, tone)
+					}
+					packet, err := codec.Encode(pcm)
+					if err != nil {
+						t.Fatalf("编码失败: %v", err)
+					}
+
+					// 按最长帧分配的缓冲区和恰好一帧的缓冲区都应得到每通道采样数×通道数
+					for _, size := range []int{sampleRate * DefaultMaxFrameDuration / 1000 * channels, samples * channels} {
+						n, err := codec.Decode(packet, make([]int16, size))
+						if err != nil {
+							t.Fatalf("缓冲区为%d个采样时解码失败: %v", size, err)
+						}
+						if n != samples*channels {
+							t.Errorf("缓冲区为%d个采样时解码出%d个采样，期望%d", size, n, samples*channels)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestOpusCodecDecodeRejectsTinyBuffer(t *testing.T) {
+	codec, err := NewOpusCodec(16000, 2)
+	if err != nil {
+		t.Fatalf("创建编解码器失败: %v", err)
+	}
+	defer codec.Close()
+
+	packet, err := codec.Encode(make([]int16, 320*2))
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	if _, err := codec.Decode(packet, make([]int16, 1)); err == nil {
+		t.Error("缓冲区容纳不下一个多声道采样时应返回错误")
+	}
+}
+
+// channelRMS 返回交错PCM中第ch个声道的RMS
+func channelRMS(pcm []int16, channels, ch int) float64 {
+	var sum float64
+	frames := len(pcm) / channels
+	for i := 0; i < frames; i++ {
+		v := float64(pcm[i*channels+ch])
+		sum += v * v
+	}
+	return math.Sqrt(sum / float64(frames))
+}
+
+// deinterleave 取出交错PCM中第ch个声道的采样
+func deinterleave(pcm []int16, channels, ch int) []int16 {
+	out := make([]int16, len(pcm)/channels)
+	for i := range out {
+		out[i] = pcm[i*channels+ch]
+	}
+	return out
+}
+
+func TestOpusCodecStereoDecodeInterleaving(t *testing.T) {
+	const (
+		sampleRate = 48000
+		frameSize  = sampleRate * 20 / 1000
+		frames     = 10
+	)
+	codec, err := NewOpusCodecWithOptions(sampleRate, 2, OpusCodecOptions{FrameDuration: 20})
+	if err != nil {
+		t.Fatalf("创建编解码器失败: %v", err)
+	}
+	defer codec.Close()
+
+	tests := []struct {
+		name        string
+		left, right []int16
+		check       func(t *testing.T, decoded []int16)
+	}{
+		{
+			name:  "左声道有声右声道静音",
+			left:  sine(440, sampleRate, frameSize*frames, 12000),
+			right: make([]int16, frameSize*frames),
+			check: func(t *testing.T, decoded []int16) {
+				left, right := channelRMS(decoded, 2, 0), channelRMS(decoded, 2, 1)
+				if left < 4000 || right > left/10 {
+					t.Errorf("左声道RMS %.0f，右声道RMS %.0f，声道交错错位", left, right)
+				}
+			},
+		},
+		{
+			name:  "左右声道频率不同",
+			left:  sine(440, sampleRate, frameSize*frames, 8000),
+			right: sine(1000, sampleRate, frameSize*frames, 8000),
+			check: func(t *testing.T, decoded []int16) {
+				for ch, want := range []float64{440, 1000} {
+					if got := dominantFrequency(deinterleave(decoded, 2, ch), sampleRate); math.Abs(got-want) > 20 {
+						t.Errorf("第%d声道主频为%vHz，期望%vHz", ch, got, want)
+					}
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pcm := interleave(tt.left, tt.right)
+			decoded := make([]int16, frameSize*2)
+			for i := 0; i < frames; i++ {
+				packet, err := codec.Encode(pcm[i*frameSize*2 : (i+1)*frameSize*2])
+				if err != nil {
+					t.Fatalf("编码第%d帧失败: %v", i, err)
+				}
+				n, err := codec.Decode(packet, decoded)
+				if err != nil {
+					t.Fatalf("解码第%d帧失败: %v", i, err)
+				}
+				if n != frameSize*2 {
+					t.Fatalf("第%d帧解码出%d个采样，期望%d", i, n, frameSize*2)
+				}
+			}
+			// 只检查最后一帧，跳过编解码器起始的过渡
+			tt.check(t, decoded)
+		})
+	}
+}
+
+func TestOpusCodecEncodeFitsFrame(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration int // 编码帧长，0表示未指定
+		channels int
+		samples  int // 每通道采样数
+		want     int // 补齐后的每通道采样数，0表示应返回错误
+	}{
+		{"恰好一帧", 20, 1, 320, 320},
+		{"不足一帧补到编码帧长", 20, 1, 100, 320},
+		{"一个采样", 20, 1, 1, 320},
+		{"双声道不足一帧", 20, 2, 200, 320},
+		{"超过编码帧长的合法帧长原样编码", 20, 1, 640, 640},
+		{"超过编码帧长且不合法", 20, 1, 321, 0},
+		{"空输入", 20, 1, 0, 0},
+		{"未指定帧长时恰为合法帧长", 0, 1, 640, 640},
+		{"未指定帧长时补到最短合法帧长", 0, 1, 100, 160},
+		{"未指定帧长时补到40ms", 0, 1, 400, 640},
+		{"超过60ms", 0, 1, 961, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codec, err := NewOpusCodecWithOptions(16000, tt.channels, OpusCodecOptions{FrameDuration: tt.duration})
+			if err != nil {
+				t.Fatalf("创建编解码器失败: %v", err)
+			}
+			defer codec.Close()
+
+			packet, err := codec.Encode(make([]int16, tt.samples*tt.channels))
+			if tt.want == 0 {
+				if err == nil {
+					t.Fatal("期望返回错误")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("编码失败: %v", err)
+			}
+			n, err := codec.Decode(packet, make([]int16, 960*tt.channels))
+			if err != nil {
+				t.Fatalf("解码失败: %v", err)
+			}
+			if n != tt.want*tt.channels {
+				t.Errorf("解码出%d个采样，期望%d", n, tt.want*tt.channels)
+			}
+		})
+	}
+
+	codec, err := NewOpusCodec(16000, 2)
+	if err != nil {
+		t.Fatalf("创建编解码器失败: %v", err)
+	}
+	defer codec.Close()
+	if _, err := codec.Encode(make([]int16, 321)); err == nil {
+		t.Error("双声道的奇数个采样应返回错误")
+	}
+}
+
+func TestOpusCodecEncodeFrames(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration int
+		samples  int
+		packets  int
+		perFrame int
+	}{
+		{"恰好一帧", 20, 320, 1, 320},
+		{"不足一帧", 20, 100, 1, 320},
+		{"多帧加尾部", 20, 1000, 4, 320},
+		{"未指定帧长按60ms切分", 0, 2000, 3, 960},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codec, err := NewOpusCodecWithOptions(16000, 1, OpusCodecOptions{FrameDuration: tt.duration})
+			if err != nil {
+				t.Fatalf("创建编解码器失败: %v", err)
+			}
+			defer codec.Close()
+
+			packets, err := codec.EncodeFrames(sine(440, 16000, tt.samples, 8000))
+			if err != nil {
+				t.Fatalf("分帧编码失败: %v", err)
+			}
+			if len(packets) != tt.packets {
+				t.Fatalf("编码为%d个数据包，期望%d个", len(packets), tt.packets)
+			}
+			for i, packet := range packets {
+				n, err := codec.Decode(packet, make([]int16, 960))
+				if err != nil {
+					t.Fatalf("第%d个数据包解码失败: %v", i, err)
+				}
+				if n != tt.perFrame {
+					t.Errorf("第%d个数据包解码出%d个采样，期望%d", i, n, tt.perFrame)
+				}
+			}
+		})
+	}
+
+	codec, err := NewOpusCodec(16000, 2)
+	if err != nil {
+		t.Fatalf("创建编解码器失败: %v", err)
+	}
+	defer codec.Close()
+	if _, err := codec.EncodeFrames(make([]int16, 641)); err == nil {
+		t.Error("双声道的奇数个采样应返回错误")
+	}
+}
+
+func TestOpusCodecDecodeFEC(t *testing.T) {
+	const frameSize = 16000 * 20 / 1000
+	for _, enabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("FEC=%v", enabled), func(t *testing.T) {
+			codec, err := NewOpusCodecWithOptions(16000, 1, OpusCodecOptions{FrameDuration: 20, EnableFEC: enabled})
+			if err != nil {
+				t.Fatalf("创建编解码器失败: %v", err)
+			}
+			defer codec.Close()
+			if codec.FECEnabled() != enabled {
+				t.Fatalf("FECEnabled() = %v，期望%v", codec.FECEnabled(), enabled)
+			}
+
+			tone := sine(440, 16000, frameSize*3, 8000)
+			var packets [][]byte
+			for i := 0; i < 3; i++ {
+				packet, err := codec.Encode(tone[i*frameSize : (i+1)*frameSize])
+				if err != nil {
+					t.Fatalf("编码失败: %v", err)
+				}
+				packets = append(packets, packet)
+			}
+
+			pcm := make([]int16, 16000*DefaultMaxFrameDuration/1000)
+			if _, err := codec.Decode(packets[0], pcm); err != nil {
+				t.Fatalf("解码失败: %v", err)
+			}
+			// 第二个数据包丢失，由第三个数据包恢复后再正常解码第三个数据包
+			n, err := codec.DecodeFEC(packets[2], pcm, frameSize)
+			if err != nil {
+				t.Fatalf("恢复丢失帧失败: %v", err)
+			}
+			if n != frameSize {
+				t.Errorf("恢复出%d个采样，期望%d", n, frameSize)
+			}
+			if n, err := codec.Decode(packets[2], pcm); err != nil || n != frameSize {
+				t.Errorf("恢复后解码当前数据包得到%d个采样，err=%v", n, err)
+			}
+		})
+	}
+
+	codec, err := NewOpusCodecWithOptions(16000, 1, OpusCodecOptions{EnableFEC: true})
+	if err != nil {
+		t.Fatalf("创建编解码器失败: %v", err)
+	}
+	defer codec.Close()
+	packet, err := codec.Encode(make([]int16, frameSize))
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	if _, err := codec.DecodeFEC(packet, make([]int16, frameSize-1), frameSize); err == nil {
+		t.Error("缓冲区容纳不下丢失帧时应返回错误")
+	}
+	if _, err := codec.DecodeFEC(nil, make([]int16, frameSize), frameSize); err == nil {
+		t.Error("空数据包应返回错误")
+	}
+}
@@ -0,0 +1,87 @@
+//go:build !cgo || noaudio
+
+package audio
+
+import (
+	"errors"
+	"fmt"
+)
+
+// PCMCodec 在CGO禁用或使用noaudio标签构建、无法使用libopus时的退化编解码器
+// 编码结果是小端序的原始16位PCM，不做任何压缩；无法解码服务器下发的Opus数据
+type PCMCodec struct {
+	channelCount int
+	frameSize    int // EncodeFrames 切分时每通道的采样数
+	plc          concealer
+}
+
+// NewPCMCodec 创建PCM编解码器
+func NewPCMCodec(sampleRate, channelCount int) (*PCMCodec, error) {
+	if sampleRate <= 0 || channelCount <= 0 {
+		return nil, fmt.Errorf("无效的编解码参数: sample_rate=%d, channels=%d", sampleRate, channelCount)
+	}
+	return &PCMCodec{
+		channelCount: channelCount,
+		frameSize:    sampleRate * DefaultFrameDuration / 1000,
+		plc:          concealer{channelCount: channelCount},
+	}, nil
+}
+
+// encodesOpus 当前构建的编码器输出是否为Opus数据包
+const encodesOpus = false
+
+// newCodec 创建当前构建可用的编解码器
+func newCodec(sampleRate, channelCount int, options OpusCodecOptions) (Codec, error) {
+	codec, err := NewPCMCodec(sampleRate, channelCount)
+	if err != nil {
+		return nil, err
+	}
+	if options.FrameDuration > 0 {
+		codec.frameSize = sampleRate * options.FrameDuration / 1000
+	}
+	return codec, nil
+}
+
+// Encode 将PCM数据按小端序打包
+func (c *PCMCodec) Encode(pcmData []int16) ([]byte, error) {
+	result := make([]byte, len(pcmData)*2)
+	for i, v := range pcmData {
+		result[2*i] = byte(v)
+		result[2*i+1] = byte(v >> 8)
+	}
+	return result, nil
+}
+
+// EncodeFrames 按帧时长切分PCM逐帧打包，最后不足一帧的部分补静音
+func (c *PCMCodec) EncodeFrames(pcmData []int16) ([][]byte, error) {
+	if len(pcmData)%c.channelCount != 0 {
+		return nil, fmt.Errorf("PCM采样数%d不是通道数%d的整数倍", len(pcmData), c.channelCount)
+	}
+	frames := splitFrames(pcmData, c.frameSize*c.channelCount)
+	packets := make([][]byte, len(frames))
+	for i, frame := range frames {
+		packets[i], _ = c.Encode(frame)
+	}
+	return packets, nil
+}
+
+// Decode 将小端序字节还原为PCM数据
+func (c *PCMCodec) Decode(data []byte, pcmData []int16) (int, error) {
+	if len(data)%2 != 0 {
+		return 0, errors.New("PCM数据长度必须为偶数")
+	}
+	n := min(len(data)/2, len(pcmData))
+	for i := 0; i < n; i++ {
+		pcmData[i] = int16(data[2*i]) | int16(data[2*i+1])<<8
+	}
+	c.plc.remember(pcmData[:n])
+	return n, nil
+}
+
+// DecodePLC 重复上一帧并逐帧衰减
+func (c *PCMCodec) DecodePLC(pcmData []int16, frameSize int) (int, error) {
+	return c.plc.conceal(pcmData, frameSize), nil
+}
+
+// Close PCM编解码器没有需要释放的资源
+func (c *PCMCodec) Close() {}
@@ -0,0 +1,98 @@
+//go:build !cgo || noaudio
+
+package audio
+
+import "testing"
+
+func TestFallbackCodecRoundTrip(t *testing.T) {
+	codec, err := newCodec(16000, 1, OpusCodecOptions{FrameDuration: 20})
+	if err != nil {
+		t.Fatalf("创建退化编解码器失败: %v", err)
+	}
+	defer codec.Close()
+	if _, ok := codec.(*PCMCodec); !ok {
+		t.Fatalf("非cgo构建应使用 *PCMCodec，实际为 %T", codec)
+	}
+	if encodesOpus {
+		t.Error("非cgo构建的编码结果不是Opus")
+	}
+
+	pcm := []int16{0, 1, -1, 32767, -32768, 1234}
+	data, err := codec.Encode(pcm)
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	if len(data) != len(pcm)*2 {
+		t.Fatalf("编码结果%d字节，期望%d字节", len(data), len(pcm)*2)
+	}
+	decoded := make([]int16, 16)
+	n, err := codec.Decode(data, decoded)
+	if err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	for i, v := range pcm {
+		if decoded[i] != v {
+			t.Fatalf("第%d个采样解码为%d，期望%d", i, decoded[i], v)
+		}
+	}
+	if n != len(pcm) {
+		t.Errorf("解码出%d个采样，期望%d", n, len(pcm))
+	}
+
+	if _, err := codec.Decode([]byte{1, 2, 3}, decoded); err == nil {
+		t.Error("奇数长度的数据应解码失败")
+	}
+}
+
+func TestFallbackCodecFramesAndConcealment(t *testing.T) {
+	c, err := newCodec(16000, 1, OpusCodecOptions{FrameDuration: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	codec := c.(*PCMCodec)
+
+	// 20ms@16kHz每帧320个采样，不足一帧的尾部补静音
+	packets, err := codec.EncodeFrames(make([]int16, 500))
+	if err != nil {
+		t.Fatalf("分帧编码失败: %v", err)
+	}
+	if len(packets) != 2 || len(packets[0]) != 640 || len(packets[1]) != 640 {
+		t.Fatalf("分帧结果不符合预期: %d帧", len(packets))
+	}
+
+	frame := make([]int16, 320)
+	for i := range frame {
+		frame[i] = 1000
+	}
+	data, _ := codec.Encode(frame)
+	out := make([]int16, 320)
+	if _, err := codec.Decode(data, out); err != nil {
+		t.Fatal(err)
+	}
+
+	// 丢包补偿重复上一帧并逐帧衰减，最终静音
+	previous := int16(1000)
+	for i := 0; i < plcFadeFrames; i++ {
+		n, err := codec.DecodePLC(out, 320)
+		if err != nil || n != 320 {
+			t.Fatalf("第%d帧补偿失败: n=%d, err=%v", i+1, n, err)
+		}
+		if out[0] >= previous {
+			t.Fatalf("第%d帧补偿幅度%d没有衰减（上一帧%d）", i+1, out[0], previous)
+		}
+		previous = out[0]
+	}
+	if previous != 0 {
+		t.Errorf("连续补偿%d帧后幅度为%d，期望静音", plcFadeFrames, previous)
+	}
+}
+
+func TestFallbackBuildCreatesAudioManager(t *testing.T) {
+	m := newTestManager(t)
+	if _, ok := m.codec.(*PCMCodec); !ok {
+		t.Errorf("音频管理器应使用退化编解码器，实际为 %T", m.codec)
+	}
+	if m.Player() == nil {
+		t.Error("音频管理器没有播放器")
+	}
+}
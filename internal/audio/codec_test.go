@@ -0,0 +1,84 @@
+package audio
+
+import "testing"
+
+func TestSplitFrames(t *testing.T) {
+	tests := []struct {
+		name       string
+		samples    int
+		frame      int
+		wantFrames int
+	}{
+		{"空输入", 0, 320, 0},
+		{"恰好一帧", 320, 320, 1},
+		{"不足一帧", 100, 320, 1},
+		{"恰好多帧", 960, 320, 3},
+		{"多帧加尾部", 1000, 320, 4},
+		{"多一个采样", 321, 320, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pcm := make([]int16, tt.samples)
+			for i := range pcm {
+				pcm[i] = int16(i + 1)
+			}
+			frames := splitFrames(pcm, tt.frame)
+			if len(frames) != tt.wantFrames {
+				t.Fatalf("切分为%d帧，期望%d帧", len(frames), tt.wantFrames)
+			}
+			// 每帧都是完整帧长，依次拼接后前samples个采样与输入一致，其余为静音
+			for i, frame := range frames {
+				if len(frame) != tt.frame {
+					t.Fatalf("第%d帧有%d个采样，期望%d", i, len(frame), tt.frame)
+				}
+				for j, v := range frame {
+					index := i*tt.frame + j
+					want := int16(0)
+					if index < tt.samples {
+						want = int16(index + 1)
+					}
+					if v != want {
+						t.Fatalf("第%d帧第%d个采样为%d，期望%d", i, j, v, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPadFrame(t *testing.T) {
+	pcm := []int16{1, 2, 3}
+	if got := padFrame(pcm, 3); &got[0] != &pcm[0] {
+		t.Error("长度足够时应原样返回")
+	}
+	if got := padFrame(pcm, 2); len(got) != 3 {
+		t.Errorf("过长的输入不应截断，得到%d个采样", len(got))
+	}
+	got := padFrame(pcm, 5)
+	if len(got) != 5 || got[0] != 1 || got[2] != 3 || got[3] != 0 || got[4] != 0 {
+		t.Errorf("补静音结果为%v", got)
+	}
+	if pcm[0] != 1 || len(pcm) != 3 {
+		t.Error("补静音修改了输入")
+	}
+}
+
+func TestOpusFrameSizes(t *testing.T) {
+	// 2.5/5/10/20/40/60ms
+	want := map[int][]int{
+		16000: {40, 80, 160, 320, 640, 960},
+		48000: {120, 240, 480, 960, 1920, 2880},
+	}
+	for rate, sizes := range want {
+		got := opusFrameSizes(rate)
+		if len(got) != len(sizes) {
+			t.Fatalf("%dHz帧长为%v，期望%v", rate, got, sizes)
+		}
+		for i := range got {
+			if got[i] != sizes[i] {
+				t.Errorf("%dHz帧长为%v，期望%v", rate, got, sizes)
+				break
+			}
+		}
+	}
+}
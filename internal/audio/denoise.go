@@ -0,0 +1,230 @@
+package audio
+
+import (
+	"math"
+	"math/cmplx"
+	"sync"
+)
+
+// 降噪强度
+const (
+	NoiseSuppressionOff      = 0 // 不降噪，原样输出
+	NoiseSuppressionLow      = 1 // 轻度降噪，保留较多环境声，语音失真最小
+	NoiseSuppressionModerate = 2 // 中等降噪
+	NoiseSuppressionHigh     = 3 // 强降噪，适合嘈杂环境，可能带来少量“音乐噪声”
+)
+
+// 降噪的内部参数
+const (
+	denoiseWindowDuration    = 16    // 分析窗最短时长（毫秒），实际取不小于该时长的2的幂个采样
+	denoiseCalibrationFrames = 10    // 开始时用于估计噪声谱的帧数，期间假定没有说话
+	denoiseSpeechRatio       = 4.0   // 频点能量超过噪声估计的该倍数时视为语音，不参与噪声平滑
+	denoiseNoiseSmoothing    = 0.05  // 噪声帧更新噪声估计的平滑系数
+	denoiseNoiseRise         = 1.002 // 语音帧上噪声估计每帧的增长倍数，约1dB/秒，用于跟上变大的环境噪声
+)
+
+// NoiseSuppressor 基于谱减法的降噪器
+//
+// PCM按50%重叠分帧，经FFT后从每个频点减去估计的噪声功率，再逆变换重叠相加。
+// 噪声谱在开始时校准，之后只在不像语音的频点上平滑更新。输出比输入延迟半个分析窗，
+// 每次 Process 返回与输入等长的PCM；多通道时各通道独立处理
+type NoiseSuppressor struct {
+	mu           sync.Mutex
+	channels     int
+	level        int
+	size         int // 分析窗长度（采样数，2的幂）
+	hop          int
+	overSubtract float64 // 噪声功率的过减系数
+	floor        float64 // 最小增益，避免频点被完全抹掉
+	window       []float64
+	spectrum     []complex128
+	states       []*denoiseChannel
+}
+
+// denoiseChannel 单个通道的分帧和噪声估计状态
+type denoiseChannel struct {
+	frame   []float64 // 最近size个输入采样
+	pending []float64 // 尚未凑满一个hop的输入采样
+	overlap []float64 // 重叠相加缓冲区
+	output  []float64 // 已处理完待输出的采样
+	noise   []float64 // 各频点的噪声功率估计
+	frames  int       // 已处理的帧数
+}
+
+// NewNoiseSuppressor 创建降噪器，level取 NoiseSuppressionLow..NoiseSuppressionHigh，超出范围时取最近的有效值
+func NewNoiseSuppressor(sampleRate, channelCount, level int) *NoiseSuppressor {
+	if sampleRate <= 0 {
+		sampleRate = DefaultSampleRate
+	}
+	if channelCount <= 0 {
+		channelCount = DefaultChannelCount
+	}
+	if level < NoiseSuppressionLow {
+		level = NoiseSuppressionLow
+	}
+	if level > NoiseSuppressionHigh {
+		level = NoiseSuppressionHigh
+	}
+
+	size := 1
+	for size < sampleRate*denoiseWindowDuration/1000 {
+		size <<= 1
+	}
+	if size < 4 {
+		size = 4
+	}
+
+	// 周期Hann窗开平方作分析窗和合成窗，50%重叠时两者乘积相加恰好为1
+	window := make([]float64, size)
+	for i := range window {
+		window[i] = math.Sqrt(0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(size)))
+	}
+
+	ns := &NoiseSuppressor{
+		channels:     channelCount,
+		level:        level,
+		size:         size,
+		hop:          size / 2,
+		overSubtract: float64(level),
+		floor:        []float64{0.3, 0.15, 0.08}[level-1],
+		window:       window,
+		spectrum:     make([]complex128, size),
+	}
+	ns.states = make([]*denoiseChannel, channelCount)
+	for ch := range ns.states {
+		ns.states[ch] = ns.newChannel()
+	}
+	return ns
+}
+
+// newChannel 创建单个通道的状态，输出缓冲区预先填充一个hop的静音以保证每次都能输出等长的PCM
+func (ns *NoiseSuppressor) newChannel() *denoiseChannel {
+	return &denoiseChannel{
+		frame:   make([]float64, ns.size),
+		overlap: make([]float64, ns.size),
+		output:  make([]float64, ns.hop),
+		noise:   make([]float64, ns.size/2+1),
+	}
+}
+
+// Process 对PCM（交织采样）降噪，返回新的PCM切片，不修改输入
+func (ns *NoiseSuppressor) Process(pcm []int16) []int16 {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	frames := len(pcm) / ns.channels
+	out := make([]int16, len(pcm))
+	for ch, state := range ns.states {
+		for i := 0; i < frames; i++ {
+			state.pending = append(state.pending, float64(pcm[i*ns.channels+ch]))
+			if len(state.pending) == ns.hop {
+				ns.processFrame(state)
+				state.pending = state.pending[:0]
+			}
+		}
+		for i := 0; i < frames; i++ {
+			out[i*ns.channels+ch] = clampInt16(state.output[i])
+		}
+		state.output = append(state.output[:0], state.output[frames:]...)
+	}
+	return out
+}
+
+// processFrame 将一个hop的新采样并入分析窗，完成一帧谱减和重叠相加
+func (ns *NoiseSuppressor) processFrame(state *denoiseChannel) {
+	copy(state.frame, state.frame[ns.hop:])
+	copy(state.frame[ns.size-ns.hop:], state.pending)
+
+	for i, v := range state.frame {
+		ns.spectrum[i] = complex(v*ns.window[i], 0)
+	}
+	fft(ns.spectrum, false)
+
+	calibrating := state.frames < denoiseCalibrationFrames
+	state.frames++
+	bins := ns.size / 2
+	for k := 0; k <= bins; k++ {
+		power := real(ns.spectrum[k])*real(ns.spectrum[k]) + imag(ns.spectrum[k])*imag(ns.spectrum[k])
+
+		noise := state.noise[k]
+		switch {
+		case calibrating:
+			noise += (power - noise) / float64(state.frames)
+		case power < denoiseSpeechRatio*noise:
+			noise += denoiseNoiseSmoothing * (power - noise)
+		default:
+			noise *= denoiseNoiseRise
+		}
+		state.noise[k] = noise
+
+		gain := ns.floor
+		if power > 0 {
+			gain = math.Max(math.Sqrt(math.Max(1-ns.overSubtract*noise/power, 0)), ns.floor)
+		}
+		ns.spectrum[k] *= complex(gain, 0)
+		// 实信号的频谱共轭对称，负频率部分使用相同增益
+		if k > 0 && k < bins {
+			ns.spectrum[ns.size-k] *= complex(gain, 0)
+		}
+	}
+	fft(ns.spectrum, true)
+
+	for i := range state.overlap {
+		state.overlap[i] += real(ns.spectrum[i]) * ns.window[i]
+	}
+	state.output = append(state.output, state.overlap[:ns.hop]...)
+	copy(state.overlap, state.overlap[ns.hop:])
+	for i := ns.size - ns.hop; i < ns.size; i++ {
+		state.overlap[i] = 0
+	}
+}
+
+// Reset 清空噪声估计和缓冲区，下次处理时重新校准噪声谱
+func (ns *NoiseSuppressor) Reset() {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	for ch := range ns.states {
+		ns.states[ch] = ns.newChannel()
+	}
+}
+
+// fft 原地计算长度为2的幂的复数FFT，inverse为true时计算逆变换（含1/N缩放）
+func fft(x []complex128, inverse bool) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j |= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	sign := -1.0
+	if inverse {
+		sign = 1.0
+	}
+	for length := 2; length <= n; length <<= 1 {
+		step := cmplx.Rect(1, sign*2*math.Pi/float64(length))
+		for start := 0; start < n; start += length {
+			w := complex(1, 0)
+			for k := 0; k < length/2; k++ {
+				u := x[start+k]
+				v := x[start+k+length/2] * w
+				x[start+k] = u + v
+				x[start+k+length/2] = u - v
+				w *= step
+			}
+		}
+	}
+
+	if inverse {
+		scale := complex(1/float64(n), 0)
+		for i := range x {
+			x[i] *= scale
+		}
+	}
+}
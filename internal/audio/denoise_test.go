@@ -0,0 +1,102 @@
+package audio
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// whiteNoise 生成n个采样、标准差为sigma的高斯白噪声，seed固定以便结果可复现
+func whiteNoise(n int, sigma float64, seed int64) []int16 {
+	r := rand.New(rand.NewSource(seed))
+	pcm := make([]int16, n)
+	for i := range pcm {
+		pcm[i] = clampInt16(r.NormFloat64() * sigma)
+	}
+	return pcm
+}
+
+// bandEnergy 以50Hz步长对[low, high]内的频点做DFT，返回能量之和
+func bandEnergy(pcm []int16, sampleRate int, low, high float64) float64 {
+	var total float64
+	for freq := low; freq <= high; freq += 50 {
+		var re, im float64
+		for i, v := range pcm {
+			phase := 2 * math.Pi * freq * float64(i) / float64(sampleRate)
+			re += float64(v) * math.Cos(phase)
+			im -= float64(v) * math.Sin(phase)
+		}
+		total += re*re + im*im
+	}
+	return total
+}
+
+// runSuppressor 按60ms一帧送入降噪器，返回拼接后的输出
+func runSuppressor(ns *NoiseSuppressor, pcm []int16, sampleRate int) []int16 {
+	frame := sampleRate * 60 / 1000
+	out := make([]int16, 0, len(pcm))
+	for start := 0; start < len(pcm); start += frame {
+		out = append(out, ns.Process(pcm[start:min(start+frame, len(pcm))])...)
+	}
+	return out
+}
+
+func TestNoiseSuppressorReducesNoiseBand(t *testing.T) {
+	const sampleRate = 16000
+	noise := whiteNoise(2*sampleRate, 1000, 1)
+	tail := len(noise) - sampleRate/2
+
+	inputEnergy := bandEnergy(noise[tail:], sampleRate, 2000, 6000)
+	// 各强度下噪声频段能量相对输入的上限：约-1.5dB、-4.5dB、-8dB
+	maxRatio := map[int]float64{
+		NoiseSuppressionLow:      0.7,
+		NoiseSuppressionModerate: 0.35,
+		NoiseSuppressionHigh:     0.15,
+	}
+	previous := inputEnergy
+	for level := NoiseSuppressionLow; level <= NoiseSuppressionHigh; level++ {
+		out := runSuppressor(NewNoiseSuppressor(sampleRate, 1, level), noise, sampleRate)
+		energy := bandEnergy(out[tail:], sampleRate, 2000, 6000)
+		if ratio := energy / inputEnergy; ratio > maxRatio[level] {
+			t.Errorf("强度%d时噪声频段能量为输入的%.2f，期望不超过%.2f", level, ratio, maxRatio[level])
+		}
+		if energy > previous {
+			t.Errorf("强度%d时噪声频段能量 %.3g 高于较低强度的 %.3g", level, energy, previous)
+		}
+		previous = energy
+	}
+}
+
+func TestNoiseSuppressorKeepsTone(t *testing.T) {
+	const sampleRate = 16000
+	noise := whiteNoise(2*sampleRate, 500, 2)
+	// 先只有噪声用于校准，1秒后叠加1kHz的正弦波
+	input := append([]int16(nil), noise...)
+	tone := sine(1000, sampleRate, sampleRate, 8000)
+	for i, v := range tone {
+		input[sampleRate+i] = clampInt16(float64(input[sampleRate+i]) + float64(v))
+	}
+
+	out := runSuppressor(NewNoiseSuppressor(sampleRate, 1, NoiseSuppressionModerate), input, sampleRate)
+	segment := func(pcm []int16) []int16 { return pcm[len(pcm)-sampleRate/2:] }
+
+	toneIn := bandEnergy(segment(input), sampleRate, 1000, 1000)
+	toneOut := bandEnergy(segment(out), sampleRate, 1000, 1000)
+	if toneOut < toneIn/2 {
+		t.Errorf("1kHz能量降低到输入的%.2f，语音频点不应被明显削弱", toneOut/toneIn)
+	}
+	noiseIn := bandEnergy(segment(input), sampleRate, 3000, 6000)
+	noiseOut := bandEnergy(segment(out), sampleRate, 3000, 6000)
+	if noiseOut > noiseIn/4 {
+		t.Errorf("说话期间噪声频段能量只降低到输入的%.2f", noiseOut/noiseIn)
+	}
+}
+
+func TestNoiseSuppressorOutputLength(t *testing.T) {
+	ns := NewNoiseSuppressor(16000, 2, NoiseSuppressionLow)
+	for _, n := range []int{0, 2, 320, 1918} {
+		if out := ns.Process(make([]int16, n)); len(out) != n {
+			t.Errorf("输入%d个采样，输出%d个", n, len(out))
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrDeviceEnumerationUnsupported 当前平台或构建不支持枚举音频设备
+var ErrDeviceEnumerationUnsupported = errors.New("当前平台不支持枚举音频设备")
+
+// DeviceInfo 音频设备信息
+type DeviceInfo struct {
+	Name        string // 设备名称，可用作 AudioManagerOptions.InputDeviceName / OutputDeviceName
+	Description string // 便于阅读的设备描述
+	IsInput     bool   // 是否为输入（录音）设备
+	IsOutput    bool   // 是否为输出（播放）设备
+	Channels    int    // 设备默认通道数
+}
+
+// GetAudioDevices 获取音频设备列表，当前平台不支持时返回 ErrDeviceEnumerationUnsupported
+func GetAudioDevices() ([]DeviceInfo, error) {
+	return listDevices()
+}
+
+// PrintDeviceInfo 打印音频设备列表
+func PrintDeviceInfo() {
+	devices, err := GetAudioDevices()
+	if err != nil {
+		logger.Infof("无法获取音频设备列表: %v", err)
+		return
+	}
+	if len(devices) == 0 {
+		logger.Infof("未找到音频设备")
+		return
+	}
+
+	logger.Infof("音频设备列表:")
+	for i, device := range devices {
+		logger.Infof("[%d] %s设备: %s (%s)，通道数: %d", i, deviceKind(device.IsInput), device.Name, device.Description, device.Channels)
+	}
+}
+
+// findDevice 在设备列表中按名称精确查找输入或输出设备，找不到时返回的错误中列出可用设备
+func findDevice(name string, input bool) (DeviceInfo, error) {
+	kind := deviceKind(input)
+
+	devices, err := GetAudioDevices()
+	if err != nil {
+		return DeviceInfo{}, fmt.Errorf("无法校验%s设备 %s: %v", kind, name, err)
+	}
+
+	var names []string
+	for _, device := range devices {
+		if device.IsInput != input {
+			continue
+		}
+		if device.Name == name {
+			return device, nil
+		}
+		names = append(names, device.Name)
+	}
+	if len(names) == 0 {
+		return DeviceInfo{}, fmt.Errorf("未找到%s设备 %s，当前没有可用的%s设备", kind, name, kind)
+	}
+	return DeviceInfo{}, fmt.Errorf("未找到%s设备 %s，可用的%s设备: %s", kind, name, kind, strings.Join(names, ", "))
+}
+
+// deviceKind 返回设备方向的中文名称，用于日志和错误信息
+func deviceKind(input bool) string {
+	if input {
+		return "输入"
+	}
+	return "输出"
+}
@@ -0,0 +1,160 @@
+//go:build linux && !portaudio && !noaudio
+
+package audio
+
+/*
+#cgo pkg-config: libpulse
+#include <pulse/pulseaudio.h>
+#include <stdlib.h>
+#include <string.h>
+
+typedef struct {
+    char* name;
+    char* description;
+    int input;
+    int channels;
+} xz_device;
+
+typedef struct {
+    xz_device* devices;
+    int count;
+    int capacity;
+    int pending;
+    int failed;
+} xz_device_list;
+
+static void xz_add_device(xz_device_list* list, const char* name, const char* description, int input, int channels) {
+    if (list->count == list->capacity) {
+        int capacity = list->capacity ? list->capacity * 2 : 8;
+        xz_device* devices = realloc(list->devices, capacity * sizeof(xz_device));
+        if (!devices) {
+            list->failed = 1;
+            return;
+        }
+        list->devices = devices;
+        list->capacity = capacity;
+    }
+    xz_device* device = &list->devices[list->count++];
+    device->name = strdup(name ? name : "");
+    device->description = strdup(description ? description : "");
+    device->input = input;
+    device->channels = channels;
+}
+
+static void xz_source_cb(pa_context* c, const pa_source_info* info, int eol, void* userdata) {
+    xz_device_list* list = userdata;
+    if (eol != 0) {
+        if (eol < 0) list->failed = 1;
+        list->pending--;
+        return;
+    }
+    // 跳过播放设备的监视源，它们不是麦克风
+    if (info->monitor_of_sink != PA_INVALID_INDEX) return;
+    xz_add_device(list, info->name, info->description, 1, info->sample_spec.channels);
+}
+
+static void xz_sink_cb(pa_context* c, const pa_sink_info* info, int eol, void* userdata) {
+    xz_device_list* list = userdata;
+    if (eol != 0) {
+        if (eol < 0) list->failed = 1;
+        list->pending--;
+        return;
+    }
+    xz_add_device(list, info->name, info->description, 0, info->sample_spec.channels);
+}
+
+// xz_list_devices 连接PulseAudio服务器并查询所有输入、输出设备，成功返回0，失败返回PulseAudio错误码
+static int xz_list_devices(xz_device_list* list) {
+    pa_mainloop* mainloop = pa_mainloop_new();
+    if (!mainloop) return -1;
+    pa_context* ctx = pa_context_new(pa_mainloop_get_api(mainloop), "xiaozhi-go");
+    if (!ctx) {
+        pa_mainloop_free(mainloop);
+        return -1;
+    }
+
+    int result = -1;
+    int started = 0;
+    if (pa_context_connect(ctx, NULL, PA_CONTEXT_NOAUTOSPAWN, NULL) < 0) {
+        result = pa_context_errno(ctx);
+        goto done;
+    }
+    for (;;) {
+        if (pa_mainloop_iterate(mainloop, 1, NULL) < 0) break;
+        pa_context_state_t state = pa_context_get_state(ctx);
+        if (state == PA_CONTEXT_FAILED || state == PA_CONTEXT_TERMINATED) {
+            result = pa_context_errno(ctx);
+            if (result == 0) result = -1;
+            break;
+        }
+        if (state == PA_CONTEXT_READY && !started) {
+            started = 1;
+            list->pending = 2;
+            pa_operation* op = pa_context_get_source_info_list(ctx, xz_source_cb, list);
+            if (op) pa_operation_unref(op); else { list->pending--; list->failed = 1; }
+            op = pa_context_get_sink_info_list(ctx, xz_sink_cb, list);
+            if (op) pa_operation_unref(op); else { list->pending--; list->failed = 1; }
+        }
+        if (started && list->pending == 0) {
+            result = list->failed ? pa_context_errno(ctx) : 0;
+            if (list->failed && result == 0) result = -1;
+            break;
+        }
+    }
+    pa_context_disconnect(ctx);
+done:
+    pa_context_unref(ctx);
+    pa_mainloop_free(mainloop);
+    return result;
+}
+
+static void xz_free_devices(xz_device_list* list) {
+    for (int i = 0; i < list->count; i++) {
+        free(list->devices[i].name);
+        free(list->devices[i].description);
+    }
+    free(list->devices);
+}
+
+static xz_device* xz_device_at(xz_device_list* list, int i) {
+    return &list->devices[i];
+}
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// pulseMutex 串行化设备查询，每次查询创建独立的mainloop
+var pulseMutex sync.Mutex
+
+// listDevices 通过PulseAudio的introspection接口枚举输入和输出设备
+func listDevices() ([]DeviceInfo, error) {
+	pulseMutex.Lock()
+	defer pulseMutex.Unlock()
+
+	var list C.xz_device_list
+	defer C.xz_free_devices(&list)
+
+	if code := C.xz_list_devices(&list); code != 0 {
+		if code < 0 {
+			return nil, errors.New("枚举PulseAudio设备失败")
+		}
+		return nil, fmt.Errorf("枚举PulseAudio设备失败: %s", C.GoString(C.pa_strerror(code)))
+	}
+
+	devices := make([]DeviceInfo, 0, int(list.count))
+	for i := 0; i < int(list.count); i++ {
+		device := C.xz_device_at(&list, C.int(i))
+		devices = append(devices, DeviceInfo{
+			Name:        C.GoString(device.name),
+			Description: C.GoString(device.description),
+			IsInput:     device.input != 0,
+			IsOutput:    device.input == 0,
+			Channels:    int(device.channels),
+		})
+	}
+	return devices, nil
+}
@@ -0,0 +1,8 @@
+//go:build (!linux || !cgo || noaudio) && !(portaudio && cgo && !noaudio)
+
+package audio
+
+// listDevices 当前平台没有实现设备枚举
+func listDevices() ([]DeviceInfo, error) {
+	return nil, ErrDeviceEnumerationUnsupported
+}
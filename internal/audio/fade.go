@@ -0,0 +1,69 @@
+package audio
+
+import "time"
+
+// DefaultFadeDuration 播放开始/停止、暂停/恢复时默认的淡入淡出时长，足以消除爆音且不易察觉
+const DefaultFadeDuration = 10 * time.Millisecond
+
+// fadeEnvelope 播放淡入淡出包络，仅由播放循环访问
+type fadeEnvelope struct {
+	frames   int     // 从0到1完整变化所需的帧数（每通道采样数），为0时不淡入淡出
+	channels int     // 交错采样的通道数
+	gain     float64 // 当前包络增益（0..1）
+}
+
+// newFadeEnvelope 按设备采样率创建包络，duration小于0时不淡入淡出，初始增益为0
+func newFadeEnvelope(duration time.Duration, sampleRate, channelCount int) fadeEnvelope {
+	frames := 0
+	if duration > 0 {
+		frames = int(int64(sampleRate) * int64(duration) / int64(time.Second))
+	}
+	if channelCount <= 0 {
+		channelCount = 1
+	}
+	return fadeEnvelope{frames: frames, channels: channelCount}
+}
+
+// apply 将包络增益逐帧线性推向target，并乘到交错采样上
+func (e *fadeEnvelope) apply(samples []float64, target float64) {
+	if e.frames <= 0 {
+		e.gain = target
+		if target == 1 {
+			return
+		}
+		for i := range samples {
+			samples[i] *= target
+		}
+		return
+	}
+
+	step := 1 / float64(e.frames)
+	for i := 0; i+e.channels <= len(samples); i += e.channels {
+		if e.gain < target {
+			e.gain += step
+			if e.gain > target {
+				e.gain = target
+			}
+		} else if e.gain > target {
+			e.gain -= step
+			if e.gain < target {
+				e.gain = target
+			}
+		}
+		if e.gain == 1 {
+			continue
+		}
+		for c := 0; c < e.channels; c++ {
+			samples[i+c] *= e.gain
+		}
+	}
+}
+
+// tailLength 淡出到静音所需的采样数（含所有通道）
+func (e *fadeEnvelope) tailLength() int {
+	frames := e.frames
+	if frames <= 0 {
+		frames = 1
+	}
+	return frames * e.channels
+}
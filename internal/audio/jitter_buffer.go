@@ -0,0 +1,143 @@
+package audio
+
+import (
+	"math"
+	"time"
+)
+
+// 抖动缓冲的默认参数
+const (
+	DefaultJitterBufferMax = time.Second // 缓冲深度上限，超过时丢弃最早的帧以限制播放延迟
+	// jitterTargetFactor 目标深度取到达抖动估计值的倍数，约可覆盖绝大多数到达延迟
+	jitterTargetFactor = 4
+)
+
+// JitterBufferStats 抖动缓冲统计
+type JitterBufferStats struct {
+	Enabled   bool          // 是否启用
+	Depth     time.Duration // 当前排队等待播放的音频时长
+	Target    time.Duration // 当前目标深度，随到达抖动在设定值和上限之间调整
+	Jitter    time.Duration // 帧到达间隔抖动的平滑估计值
+	Buffering bool          // 是否正在积累缓冲、尚未开始播放
+	Underruns uint64        // 播放中途缓冲播空、随后同一段音频又有帧到达的次数
+	Overruns  uint64        // 缓冲深度超过上限、丢弃旧帧的次数
+}
+
+// jitterBuffer 播放队列前的自适应抖动缓冲，由播放器的queueMutex保护
+// 开始播放前和播放中途播空后先积累到目标深度（或最早的帧已等待目标时长）再开始输出，
+// 目标深度按RFC 3550的方式估计帧到达间隔抖动，取其 jitterTargetFactor 倍，不低于设定值、不超过上限
+type jitterBuffer struct {
+	baseTarget time.Duration
+	max        time.Duration
+	target     time.Duration
+	jitter     float64 // 到达抖动估计值（纳秒）
+
+	lastArrival    time.Time
+	lastFrame      time.Duration // 上一帧的时长
+	buffering      bool
+	bufferingSince time.Time // 积累期间第一帧到达的时间
+	starvedAt      time.Time // 播放中途播空的时间，用于区分欠载和一段音频正常播完
+
+	underruns uint64
+	overruns  uint64
+}
+
+func newJitterBuffer(target, maxDepth time.Duration) *jitterBuffer {
+	if maxDepth <= 0 {
+		maxDepth = DefaultJitterBufferMax
+	}
+	if maxDepth < target {
+		maxDepth = target
+	}
+	return &jitterBuffer{
+		baseTarget: target,
+		max:        maxDepth,
+		target:     target,
+		buffering:  true,
+	}
+}
+
+// arrive 记录一帧到达，frame为该帧的时长
+func (j *jitterBuffer) arrive(now time.Time, frame time.Duration) {
+	newStream := j.lastArrival.IsZero()
+	if !j.starvedAt.IsZero() {
+		if now.Sub(j.starvedAt) < DefaultDrainDebounce {
+			j.underruns++
+		} else {
+			// 播空后很久才到达，是新的一段音频，间隔不计入抖动
+			newStream = true
+		}
+		j.starvedAt = time.Time{}
+	}
+
+	if !newStream {
+		d := math.Abs(float64(now.Sub(j.lastArrival) - j.lastFrame))
+		j.jitter += (d - j.jitter) / 16
+		j.target = j.baseTarget
+		if adaptive := time.Duration(j.jitter * jitterTargetFactor); adaptive > j.target {
+			j.target = adaptive
+		}
+		if j.target > j.max {
+			j.target = j.max
+		}
+	}
+	j.lastArrival = now
+	j.lastFrame = frame
+
+	if j.buffering && j.bufferingSince.IsZero() {
+		j.bufferingSince = now
+	}
+}
+
+// ready 返回是否可以输出，depth为当前排队的音频时长；积累到目标深度或最早的帧已等待目标时长后开始输出，
+// 后者保证一段音频的结尾不足目标深度时也能播出
+func (j *jitterBuffer) ready(now time.Time, depth time.Duration) bool {
+	if !j.buffering {
+		return true
+	}
+	if depth < j.target && now.Sub(j.bufferingSince) < j.target {
+		return false
+	}
+	j.buffering = false
+	j.bufferingSince = time.Time{}
+	return true
+}
+
+// starve 队列为空时调用，播放中途播空后重新开始积累
+func (j *jitterBuffer) starve(now time.Time) {
+	if j.buffering {
+		return
+	}
+	j.buffering = true
+	j.starvedAt = now
+}
+
+// overrun 返回深度超过上限时需要丢弃到的深度（当前目标深度），未超过时返回-1
+func (j *jitterBuffer) overrun(depth time.Duration) time.Duration {
+	if depth <= j.max {
+		return -1
+	}
+	j.overruns++
+	return j.target
+}
+
+// reset 清除积累状态，用于停止播放后重新开始；统计计数和抖动估计保留
+func (j *jitterBuffer) reset() {
+	j.buffering = true
+	j.bufferingSince = time.Time{}
+	j.starvedAt = time.Time{}
+	j.lastArrival = time.Time{}
+}
+
+// stats 返回统计，depth为当前排队的音频时长
+func (j *jitterBuffer) stats(depth time.Duration) JitterBufferStats {
+	return JitterBufferStats{
+		Enabled:   true,
+		Depth:     depth,
+		Target:    j.target,
+		Jitter:    time.Duration(j.jitter),
+		Buffering: j.buffering,
+		Underruns: j.underruns,
+		Overruns:  j.overruns,
+	}
+}
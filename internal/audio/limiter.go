@@ -0,0 +1,57 @@
+package audio
+
+import "math"
+
+// LimiterMode 输出限幅方式
+type LimiterMode int
+
+const (
+	LimiterHardClip LimiterMode = iota // 超出int16范围的采样直接截断
+	LimiterSoftKnee                    // 超过拐点后用tanh平滑压缩，避免削波失真
+)
+
+// DefaultLimiterKnee 软拐点限幅的默认拐点（相对满幅的比例）
+const DefaultLimiterKnee = 0.8
+
+// Limiter 输出级限幅器，将混音、增益后可能越界的浮点采样安全地转换为int16
+type Limiter struct {
+	Mode LimiterMode
+	Knee float64 // 软拐点位置（0..1），为0时使用 DefaultLimiterKnee
+}
+
+// Sample 对一个以int16满幅为尺度的浮点采样限幅并转换为int16，不会发生回绕
+func (l Limiter) Sample(x float64) int16 {
+	if math.IsNaN(x) {
+		return 0
+	}
+
+	if l.Mode == LimiterSoftKnee {
+		knee := l.Knee
+		if knee <= 0 || knee >= 1 {
+			knee = DefaultLimiterKnee
+		}
+		y := x / DefaultMaxValue
+		if a := math.Abs(y); a > knee {
+			a = knee + (1-knee)*math.Tanh((a-knee)/(1-knee))
+			y = math.Copysign(a, y)
+		}
+		x = y * DefaultMaxValue
+	}
+
+	if x > DefaultMaxValue {
+		return DefaultMaxValue
+	}
+	if x < -DefaultMaxValue-1 {
+		return -DefaultMaxValue - 1
+	}
+	return int16(math.Round(x))
+}
+
+// Process 对一组浮点采样限幅，结果写入out，返回写入的采样数
+func (l Limiter) Process(in []float64, out []int16) int {
+	n := min(len(in), len(out))
+	for i := 0; i < n; i++ {
+		out[i] = l.Sample(in[i])
+	}
+	return n
+}
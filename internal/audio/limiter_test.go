@@ -0,0 +1,81 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLimiterClampsOverRange(t *testing.T) {
+	tests := []struct {
+		name string
+		mode LimiterMode
+		in   float64
+		want int16
+	}{
+		{"硬削波/范围内", LimiterHardClip, 1234.4, 1234},
+		{"硬削波/正满幅", LimiterHardClip, 32767, 32767},
+		{"硬削波/负满幅", LimiterHardClip, -32768, -32768},
+		{"硬削波/略超正满幅", LimiterHardClip, 32767.6, 32767},
+		{"硬削波/略超负满幅", LimiterHardClip, -32768.6, -32768},
+		{"硬削波/四倍正满幅", LimiterHardClip, 4 * 32767, 32767},
+		{"硬削波/四倍负满幅", LimiterHardClip, -4 * 32768, -32768},
+		{"硬削波/正无穷", LimiterHardClip, math.Inf(1), 32767},
+		{"硬削波/负无穷", LimiterHardClip, math.Inf(-1), -32768},
+		{"硬削波/NaN", LimiterHardClip, math.NaN(), 0},
+		{"软拐点/拐点以下不变", LimiterSoftKnee, 1000, 1000},
+		{"软拐点/四倍正满幅", LimiterSoftKnee, 4 * 32767, 32767},
+		{"软拐点/四倍负满幅", LimiterSoftKnee, -4 * 32768, -32767},
+		{"软拐点/正无穷", LimiterSoftKnee, math.Inf(1), 32767},
+		{"软拐点/负无穷", LimiterSoftKnee, math.Inf(-1), -32767},
+		{"软拐点/NaN", LimiterSoftKnee, math.NaN(), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Limiter{Mode: tt.mode}).Sample(tt.in); got != tt.want {
+				t.Errorf("Sample(%v) = %d，期望 %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimiterSoftKneeIsMonotonic(t *testing.T) {
+	l := Limiter{Mode: LimiterSoftKnee, Knee: 0.5}
+	prev := l.Sample(-8 * 32768)
+	for x := -8 * 32768.0; x <= 8*32768; x += 97 {
+		got := l.Sample(x)
+		if got < prev {
+			t.Fatalf("Sample(%v) = %d 小于前一个采样的 %d，压缩曲线应单调", x, got, prev)
+		}
+		if (x > 0 && got < 0) || (x < 0 && got > 0) {
+			t.Fatalf("Sample(%v) = %d 符号翻转", x, got)
+		}
+		prev = got
+	}
+	if knee := 0.5 * 32767; l.Sample(knee) != int16(math.Round(knee)) {
+		t.Errorf("拐点处的采样不应被压缩: %d", l.Sample(knee))
+	}
+}
+
+func TestLimiterProcessWritesShorterLength(t *testing.T) {
+	in := []float64{1e6, -1e6, 100}
+	out := make([]int16, 2)
+	if n := (Limiter{}).Process(in, out); n != 2 {
+		t.Fatalf("Process 返回 %d，期望 2", n)
+	}
+	if out[0] != 32767 || out[1] != -32768 {
+		t.Errorf("Process 输出 %v", out)
+	}
+}
+
+func TestPlayerVolumeBoostDoesNotWrap(t *testing.T) {
+	player := NewAudioPlayer2(24000, 1, 60, nil)
+	player.SetVolume(MaxVolume)
+	pcm := []int16{32767, -32768, 20000, -20000, 0}
+	out := player.renderPCM(pcm, 1)
+	for i, v := range pcm {
+		got := out[i]
+		if (v > 0 && got <= 0) || (v < 0 && got >= 0) {
+			t.Errorf("增益%v时采样 %d 输出 %d，发生回绕", MaxVolume, v, got)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package audio
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// LatencyStats 耗时统计
+type LatencyStats struct {
+	Count uint64        // 统计次数
+	Avg   time.Duration // 平均耗时
+	Max   time.Duration // 最大耗时
+}
+
+// merge 合并另一段统计，平均值按次数加权
+func (s LatencyStats) merge(other LatencyStats) LatencyStats {
+	count := s.Count + other.Count
+	if count == 0 {
+		return LatencyStats{}
+	}
+	merged := LatencyStats{
+		Count: count,
+		Avg:   time.Duration((float64(s.Avg)*float64(s.Count) + float64(other.Avg)*float64(other.Count)) / float64(count)),
+		Max:   s.Max,
+	}
+	if other.Max > merged.Max {
+		merged.Max = other.Max
+	}
+	return merged
+}
+
+// latencyRecorder 并发安全地累计耗时的次数、总和与最大值
+type latencyRecorder struct {
+	count atomic.Uint64
+	total atomic.Int64
+	max   atomic.Int64
+}
+
+func (r *latencyRecorder) observe(d time.Duration) {
+	r.count.Add(1)
+	r.total.Add(int64(d))
+	for {
+		current := r.max.Load()
+		if int64(d) <= current || r.max.CompareAndSwap(current, int64(d)) {
+			return
+		}
+	}
+}
+
+func (r *latencyRecorder) snapshot() LatencyStats {
+	count := r.count.Load()
+	if count == 0 {
+		return LatencyStats{}
+	}
+	return LatencyStats{
+		Count: count,
+		Avg:   time.Duration(r.total.Load() / int64(count)),
+		Max:   time.Duration(r.max.Load()),
+	}
+}
+
+// CaptureMetrics 录音方向的统计
+type CaptureMetrics struct {
+	FramesCaptured   uint64       // 录音器送来的帧数
+	FramesEncoded    uint64       // 编码成功的帧数
+	EncodeErrors     uint64       // 编码失败的帧数
+	FramesSent       uint64       // 交给音频数据回调（上传）的数据包数
+	FramesSuppressed uint64       // 静音抑制丢弃的帧数
+	EncodeLatency    LatencyStats // 单帧编码耗时
+	Device           CaptureStats // 录音缓冲区的溢出、欠载统计，录音器不支持时为零值
+}
+
+// PlaybackMetrics 播放方向的统计
+type PlaybackMetrics struct {
+	FramesReceived  uint64            // 送入播放器的Opus数据包数
+	FramesDecoded   uint64            // 解码成功的数据包数
+	DecodeErrors    uint64            // 解码失败的数据包数
+	FramesConcealed uint64            // 丢包补偿生成的帧数
+	FramesPlayed    uint64            // 从播放队列取出播放的帧数
+	FramesDropped   uint64            // 因播放队列已满丢弃的帧数
+	QueueLength     int               // 当前播放队列长度
+	DecodeLatency   LatencyStats      // 单个数据包的解码耗时
+	JitterBuffer    JitterBufferStats // 抖动缓冲统计
+}
+
+// add 累加另一个播放器的计数，用于重建播放器后保留之前的统计；队列长度和抖动缓冲只反映当前播放器
+func (m *PlaybackMetrics) add(other PlaybackMetrics) {
+	m.FramesReceived += other.FramesReceived
+	m.FramesDecoded += other.FramesDecoded
+	m.DecodeErrors += other.DecodeErrors
+	m.FramesConcealed += other.FramesConcealed
+	m.FramesPlayed += other.FramesPlayed
+	m.FramesDropped += other.FramesDropped
+	m.DecodeLatency = m.DecodeLatency.merge(other.DecodeLatency)
+}
+
+// AudioMetrics 音频管线的统计快照，计数为创建音频管理器以来的累计值，重建播放器或切换设备后继续累计
+type AudioMetrics struct {
+	Capture  CaptureMetrics
+	Playback PlaybackMetrics
+
+	// 当前模式
+	Recording          bool // 是否正在录音
+	Playing            bool // 播放器是否已启动
+	Paused             bool // 播放是否已暂停
+	DummyMode          bool // 播放器是否以哑模式运行（没有可用的输出设备）
+	SampleRate         int  // 编解码采样率
+	ChannelCount       int  // 通道数
+	FrameDuration      int  // 帧时长（毫秒）
+	EchoCancellation   bool // 是否启用回声消除
+	NoiseSuppression   bool // 是否启用降噪
+	AGC                bool // 是否启用自动增益
+	SilenceSuppression bool // 是否启用静音抑制
+	WakeWord           bool // 是否启用唤醒词检测
+}
+
+// captureCounters 录音方向的累计计数
+type captureCounters struct {
+	captured      atomic.Uint64
+	encoded       atomic.Uint64
+	encodeErrors  atomic.Uint64
+	sent          atomic.Uint64
+	encodeLatency latencyRecorder
+}
+
+// playbackCounters 播放器的累计计数
+type playbackCounters struct {
+	received      atomic.Uint64
+	decoded       atomic.Uint64
+	decodeErrors  atomic.Uint64
+	played        atomic.Uint64
+	decodeLatency latencyRecorder
+}
@@ -0,0 +1,71 @@
+package audio
+
+// 输出响度归一化的默认参数
+const (
+	DefaultNormalizeTarget  = 0.1   // 目标RMS电平（相对满幅，约-20dBFS）
+	DefaultNormalizeMaxGain = 8.0   // 最大增益，避免把安静段落过度放大
+	normalizeSilenceRMS     = 0.003 // 低于该电平视为静音，不调整增益
+	normalizeAttack         = 0.5   // 需要降低增益时每帧逼近目标的比例
+	normalizeRelease        = 0.1   // 需要提高增益时每帧逼近目标的比例
+)
+
+// Normalizer 播放端自动增益：按帧测量RMS，将增益平滑地调整到使输出接近目标电平
+// 增益下降快、上升慢，以免响亮的开头被放大后削波；超出满幅的部分交给 Limiter 处理
+type Normalizer struct {
+	target  float64
+	maxGain float64
+	gain    float64
+}
+
+// NewNormalizer 创建归一化器，target为目标RMS电平（0..1），为0时使用 DefaultNormalizeTarget
+func NewNormalizer(target float64) *Normalizer {
+	if target <= 0 || target > 1 {
+		target = DefaultNormalizeTarget
+	}
+	return &Normalizer{
+		target:  target,
+		maxGain: DefaultNormalizeMaxGain,
+		gain:    1,
+	}
+}
+
+// Target 返回目标RMS电平
+func (n *Normalizer) Target() float64 {
+	return n.target
+}
+
+// Gain 返回当前增益
+func (n *Normalizer) Gain() float64 {
+	return n.gain
+}
+
+// Reset 将增益恢复为1，用于新的一段TTS开始时
+func (n *Normalizer) Reset() {
+	n.gain = 1
+}
+
+// Process 根据本帧电平更新增益，并将增益后的浮点采样写入out，返回写入的采样数
+func (n *Normalizer) Process(pcm []int16, out []float64) int {
+	count := min(len(pcm), len(out))
+	if count == 0 {
+		return 0
+	}
+
+	rms, _ := computeLevel(pcm[:count])
+	if rms >= normalizeSilenceRMS {
+		desired := n.target / rms
+		if desired > n.maxGain {
+			desired = n.maxGain
+		}
+		rate := normalizeRelease
+		if desired < n.gain {
+			rate = normalizeAttack
+		}
+		n.gain += (desired - n.gain) * rate
+	}
+
+	for i := 0; i < count; i++ {
+		out[i] = float64(pcm[i]) * n.gain
+	}
+	return count
+}
@@ -0,0 +1,84 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+// floatRMS 返回浮点采样相对int16满幅的RMS电平
+func floatRMS(samples []float64) float64 {
+	var sum float64
+	for _, v := range samples {
+		sum += v * v
+	}
+	return math.Sqrt(sum/float64(len(samples))) / DefaultMaxValue
+}
+
+// runNormalizer 把同一帧送入归一化器frames次，返回最后一帧输出的RMS电平
+func runNormalizer(n *Normalizer, frame []int16, frames int) float64 {
+	out := make([]float64, len(frame))
+	for i := 0; i < frames; i++ {
+		n.Process(frame, out)
+	}
+	return floatRMS(out)
+}
+
+func TestNormalizerConvergesToTarget(t *testing.T) {
+	const frameSize = 24000 * 60 / 1000
+	tests := []struct {
+		name      string
+		amplitude float64
+		frames    int
+	}{
+		{"安静的正弦波被放大", 0.03 * DefaultMaxValue, 60},
+		{"响亮的正弦波被压低", 0.9 * DefaultMaxValue, 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := NewNormalizer(0)
+			rms := runNormalizer(n, sine(440, 24000, frameSize, tt.amplitude), tt.frames)
+			if math.Abs(rms-DefaultNormalizeTarget) > 0.005 {
+				t.Errorf("%d帧后输出电平为 %.4f，期望接近 %.2f", tt.frames, rms, DefaultNormalizeTarget)
+			}
+		})
+	}
+}
+
+func TestNormalizerAttackFasterThanRelease(t *testing.T) {
+	const frameSize = 24000 * 60 / 1000
+	// 两段正弦波所需增益约为0.5和1.5，与初始增益1的距离相同，压低应比放大更快收敛
+	framesToConverge := func(amplitude float64) int {
+		n := NewNormalizer(0)
+		frame := sine(440, 24000, frameSize, amplitude)
+		for i := 1; i <= 200; i++ {
+			if math.Abs(runNormalizer(n, frame, 1)-DefaultNormalizeTarget) < 0.005 {
+				return i
+			}
+		}
+		return 200
+	}
+	attack := framesToConverge(0.28 * DefaultMaxValue)
+	release := framesToConverge(0.094 * DefaultMaxValue)
+	if attack >= release {
+		t.Errorf("压低用了%d帧，放大用了%d帧，期望压低更快", attack, release)
+	}
+}
+
+func TestNormalizerLimitsGainAndIgnoresSilence(t *testing.T) {
+	const frameSize = 24000 * 60 / 1000
+	n := NewNormalizer(0)
+	runNormalizer(n, sine(440, 24000, frameSize, 0.002*DefaultMaxValue), 10)
+	if gain := n.Gain(); gain != 1 {
+		t.Errorf("静音帧后增益为 %v，期望保持1", gain)
+	}
+
+	runNormalizer(n, sine(440, 24000, frameSize, 0.005*DefaultMaxValue), 200)
+	if gain := n.Gain(); gain > DefaultNormalizeMaxGain || gain < DefaultNormalizeMaxGain*0.99 {
+		t.Errorf("很安静的输入增益为 %v，期望接近上限 %v", gain, DefaultNormalizeMaxGain)
+	}
+
+	n.Reset()
+	if gain := n.Gain(); gain != 1 {
+		t.Errorf("Reset后增益为 %v，期望1", gain)
+	}
+}
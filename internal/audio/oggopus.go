@@ -0,0 +1,371 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Ogg-Opus封装参数（RFC 7845）
+const (
+	oggOpusPreSkip = 312 // 解码器开头需要丢弃的48kHz采样数，与libopus默认编码延迟一致
+	oggOpusVendor  = "xiaozhi-go"
+	oggMaxSegments = 255
+)
+
+// oggCRCTable Ogg页校验使用的CRC-32表（多项式0x04c11db7，不反转）
+var oggCRCTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		crc := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// OggOpusWriter 将Opus数据包写入Ogg-Opus文件（.opus/.ogg），可直接用ffplay、VLC等播放
+// 每个数据包单独占一页，granule position根据数据包的TOC计算，不依赖帧时长配置；
+// 最近一个数据包缓存到下一次写入或Close时再落盘，以便给最后一页打上流结束标志
+type OggOpusWriter struct {
+	file     *os.File
+	mu       sync.Mutex
+	serial   uint32
+	sequence uint32
+	granule  uint64 // 已写入音频的48kHz采样数（含pre-skip）
+	pending  []byte // 尚未写入的最近一个数据包
+	closed   bool
+}
+
+// NewOggOpusWriter 创建Ogg-Opus文件并写入OpusHead和OpusTags头页
+// sampleRate为编码前PCM的采样率，仅作为元数据写入文件头，播放时总以48kHz解码
+func NewOggOpusWriter(path string, sampleRate, channels int) (*OggOpusWriter, error) {
+	if sampleRate <= 0 || channels <= 0 || channels > 2 {
+		return nil, fmt.Errorf("无效的Ogg-Opus参数: sample_rate=%d, channels=%d", sampleRate, channels)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("创建Opus文件失败: %v", err)
+	}
+
+	w := &OggOpusWriter{
+		file:    f,
+		serial:  uint32(os.Getpid())<<16 ^ uint32(sampleRate),
+		granule: oggOpusPreSkip,
+	}
+	if err := w.writeHeaders(sampleRate, channels); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("写入Opus文件头失败: %v", err)
+	}
+	return w, nil
+}
+
+// writeHeaders 写入OpusHead（BOS页）和OpusTags页，两者的granule position均为0
+func (w *OggOpusWriter) writeHeaders(sampleRate, channels int) error {
+	head := make([]byte, 19)
+	copy(head[0:8], "OpusHead")
+	head[8] = 1 // 版本
+	head[9] = byte(channels)
+	binary.LittleEndian.PutUint16(head[10:12], oggOpusPreSkip)
+	binary.LittleEndian.PutUint32(head[12:16], uint32(sampleRate))
+	binary.LittleEndian.PutUint16(head[16:18], 0) // 输出增益
+	head[18] = 0                                  // 声道映射族0：单声道或立体声
+	if err := w.writePage(head, 0, 0x02); err != nil {
+		return err
+	}
+
+	tags := make([]byte, 0, 8+4+len(oggOpusVendor)+4)
+	tags = append(tags, "OpusTags"...)
+	tags = binary.LittleEndian.AppendUint32(tags, uint32(len(oggOpusVendor)))
+	tags = append(tags, oggOpusVendor...)
+	tags = binary.LittleEndian.AppendUint32(tags, 0) // 用户注释数
+	return w.writePage(tags, 0, 0)
+}
+
+// writePage 将一个完整的数据包写成一页，headerType为0x02表示流开始、0x04表示流结束
+// packet为nil时写入不含数据包的空页，仅用于没有任何音频时结束流
+func (w *OggOpusWriter) writePage(packet []byte, granule uint64, headerType byte) error {
+	segments := len(packet)/255 + 1
+	if packet == nil {
+		segments = 0
+	}
+	if segments > oggMaxSegments {
+		return fmt.Errorf("数据包过大: %d字节", len(packet))
+	}
+
+	page := make([]byte, 27+segments, 27+segments+len(packet))
+	copy(page[0:4], "OggS")
+	page[4] = 0 // 版本
+	page[5] = headerType
+	binary.LittleEndian.PutUint64(page[6:14], granule)
+	binary.LittleEndian.PutUint32(page[14:18], w.serial)
+	binary.LittleEndian.PutUint32(page[18:22], w.sequence)
+	page[26] = byte(segments)
+	// 分段表：满255字节的段之后以一个小于255的段（可为0）结束数据包
+	for i := 0; i < segments; i++ {
+		page[27+i] = 255
+	}
+	if segments > 0 {
+		page[27+segments-1] = byte(len(packet) % 255)
+	}
+	page = append(page, packet...)
+
+	var crc uint32
+	for _, b := range page {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	binary.LittleEndian.PutUint32(page[22:26], crc)
+
+	if _, err := w.file.Write(page); err != nil {
+		return err
+	}
+	w.sequence++
+	return nil
+}
+
+// Write 追加一个Opus数据包
+func (w *OggOpusWriter) Write(packet []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return errors.New("Opus文件已关闭")
+	}
+	samples, err := opusPacketSamples(packet)
+	if err != nil {
+		return err
+	}
+	if w.pending != nil {
+		if err := w.writePage(w.pending, w.granule, 0); err != nil {
+			return err
+		}
+	}
+	w.pending = append(w.pending[:0:0], packet...)
+	w.granule += uint64(samples)
+	return nil
+}
+
+// Close 写入带流结束标志的最后一页并关闭文件
+func (w *OggOpusWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if err := w.writePage(w.pending, w.granule, 0x04); err != nil {
+		w.file.Close()
+		return fmt.Errorf("写入Opus文件结束页失败: %v", err)
+	}
+	return w.file.Close()
+}
+
+// opusPacketSamples 根据数据包的TOC字节计算其包含的48kHz采样数（RFC 6716 3.1节）
+func opusPacketSamples(packet []byte) (int, error) {
+	if len(packet) == 0 {
+		return 0, errors.New("空的Opus数据包")
+	}
+
+	toc := packet[0]
+	config := int(toc >> 3)
+	var frameSamples int // 单帧的48kHz采样数
+	switch {
+	case config < 12: // SILK：10/20/40/60ms
+		frameSamples = []int{480, 960, 1920, 2880}[config%4]
+	case config < 16: // Hybrid：10/20ms
+		frameSamples = []int{480, 960}[config%2]
+	default: // CELT：2.5/5/10/20ms
+		frameSamples = []int{120, 240, 480, 960}[config%4]
+	}
+
+	frames := 1
+	switch toc & 0x03 {
+	case 1, 2:
+		frames = 2
+	case 3:
+		if len(packet) < 2 {
+			return 0, errors.New("Opus数据包缺少帧数字节")
+		}
+		frames = int(packet[1] & 0x3f)
+	}
+	if frames == 0 || frames*frameSamples > 5760 {
+		return 0, fmt.Errorf("无效的Opus数据包: 帧数%d", frames)
+	}
+	return frames * frameSamples, nil
+}
+
+// OggOpusReader 从Ogg-Opus文件中按顺序读取Opus数据包
+// 支持 OggOpusWriter 写出的文件和常见编码器生成的单声道/立体声单流文件，多路复用的文件只读取第一个逻辑流
+type OggOpusReader struct {
+	file       *os.File
+	reader     *bufio.Reader
+	serial     uint32 // 读取的逻辑流，取第一个BOS页的序列号
+	hasSerial  bool
+	channels   int
+	sampleRate int      // OpusHead中记录的编码前采样率
+	preSkip    int      // 解码后开头需要丢弃的48kHz采样数
+	packets    [][]byte // 已解析、尚未返回的数据包
+	partial    []byte   // 跨页的数据包中已读取的部分
+	eos        bool
+}
+
+// NewOggOpusReader 打开Ogg-Opus文件并解析OpusHead和OpusTags头
+func NewOggOpusReader(path string) (*OggOpusReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开Opus文件失败: %v", err)
+	}
+
+	r := &OggOpusReader{file: f, reader: bufio.NewReader(f)}
+	if err := r.readHeaders(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// readHeaders 读取并校验前两个数据包：OpusHead和OpusTags
+func (r *OggOpusReader) readHeaders() error {
+	head, err := r.nextPacket()
+	if err != nil {
+		return fmt.Errorf("读取OpusHead失败: %v", err)
+	}
+	if len(head) < 19 || string(head[0:8]) != "OpusHead" {
+		return errors.New("不是有效的Ogg-Opus文件")
+	}
+	if head[18] != 0 {
+		return fmt.Errorf("不支持的Opus声道映射族: %d", head[18])
+	}
+	r.channels = int(head[9])
+	r.preSkip = int(binary.LittleEndian.Uint16(head[10:12]))
+	r.sampleRate = int(binary.LittleEndian.Uint32(head[12:16]))
+	if r.channels < 1 || r.channels > 2 {
+		return fmt.Errorf("不支持的Opus通道数: %d", r.channels)
+	}
+
+	tags, err := r.nextPacket()
+	if err != nil {
+		return fmt.Errorf("读取OpusTags失败: %v", err)
+	}
+	if len(tags) < 8 || string(tags[0:8]) != "OpusTags" {
+		return errors.New("Ogg-Opus文件缺少OpusTags")
+	}
+	return nil
+}
+
+// Channels 返回文件通道数
+func (r *OggOpusReader) Channels() int {
+	return r.channels
+}
+
+// SampleRate 返回OpusHead中记录的编码前采样率，为0表示未记录；Opus数据本身可按任意支持的采样率解码
+func (r *OggOpusReader) SampleRate() int {
+	return r.sampleRate
+}
+
+// PreSkip 返回解码后开头需要丢弃的48kHz采样数
+func (r *OggOpusReader) PreSkip() int {
+	return r.preSkip
+}
+
+// ReadPacket 返回下一个音频数据包，文件读完时返回 io.EOF
+func (r *OggOpusReader) ReadPacket() ([]byte, error) {
+	return r.nextPacket()
+}
+
+// nextPacket 返回下一个完整的数据包，必要时继续读取后续页
+func (r *OggOpusReader) nextPacket() ([]byte, error) {
+	for len(r.packets) == 0 {
+		if r.eos {
+			return nil, io.EOF
+		}
+		if err := r.readPage(); err != nil {
+			return nil, err
+		}
+	}
+	packet := r.packets[0]
+	r.packets = r.packets[1:]
+	return packet, nil
+}
+
+// readPage 读取一页并校验CRC，把其中完整的数据包加入packets，未结束的数据包留在partial中
+func (r *OggOpusReader) readPage() error {
+	header := make([]byte, 27)
+	if _, err := io.ReadFull(r.reader, header); err != nil {
+		if err == io.EOF {
+			r.eos = true
+			return io.EOF
+		}
+		return fmt.Errorf("读取Ogg页头失败: %v", err)
+	}
+	if string(header[0:4]) != "OggS" || header[4] != 0 {
+		return errors.New("无效的Ogg页")
+	}
+	segmentTable := make([]byte, header[26])
+	if _, err := io.ReadFull(r.reader, segmentTable); err != nil {
+		return fmt.Errorf("读取Ogg分段表失败: %v", err)
+	}
+	size := 0
+	for _, s := range segmentTable {
+		size += int(s)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r.reader, body); err != nil {
+		return fmt.Errorf("读取Ogg页数据失败: %v", err)
+	}
+
+	// CRC按校验和字段为0计算
+	expected := binary.LittleEndian.Uint32(header[22:26])
+	binary.LittleEndian.PutUint32(header[22:26], 0)
+	var crc uint32
+	for _, part := range [][]byte{header, segmentTable, body} {
+		for _, b := range part {
+			crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+		}
+	}
+	if crc != expected {
+		return errors.New("Ogg页校验失败，文件可能已损坏")
+	}
+
+	serial := binary.LittleEndian.Uint32(header[14:18])
+	if header[5]&0x02 != 0 && !r.hasSerial {
+		r.serial = serial
+		r.hasSerial = true
+	}
+	if serial != r.serial {
+		// 其他逻辑流的页
+		return nil
+	}
+
+	offset := 0
+	for _, s := range segmentTable {
+		r.partial = append(r.partial, body[offset:offset+int(s)]...)
+		offset += int(s)
+		if s < 255 {
+			r.packets = append(r.packets, r.partial)
+			r.partial = nil
+		}
+	}
+	if header[5]&0x04 != 0 {
+		r.eos = true
+	}
+	return nil
+}
+
+// Close 关闭文件
+func (r *OggOpusReader) Close() error {
+	return r.file.Close()
+}
@@ -0,0 +1,164 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// oggPage 解析出的一页Ogg数据
+type oggPage struct {
+	headerType byte
+	granule    uint64
+	sequence   uint32
+	body       []byte
+}
+
+// parseOggPages 逐页解析Ogg数据并校验CRC
+func parseOggPages(t *testing.T, data []byte) []oggPage {
+	t.Helper()
+	var pages []oggPage
+	for len(data) > 0 {
+		if len(data) < 27 || string(data[0:4]) != "OggS" || data[4] != 0 {
+			t.Fatalf("第%d页页头无效: % x", len(pages), data[:min(len(data), 27)])
+		}
+		segments := int(data[26])
+		size := 27 + segments
+		for _, s := range data[27 : 27+segments] {
+			size += int(s)
+		}
+		page := append([]byte(nil), data[:size]...)
+
+		crc := binary.LittleEndian.Uint32(page[22:26])
+		binary.LittleEndian.PutUint32(page[22:26], 0)
+		var want uint32
+		for _, b := range page {
+			want = want<<8 ^ oggCRCTable[byte(want>>24)^b]
+		}
+		if crc != want {
+			t.Errorf("第%d页CRC为%08x，期望%08x", len(pages), crc, want)
+		}
+
+		pages = append(pages, oggPage{
+			headerType: page[5],
+			granule:    binary.LittleEndian.Uint64(page[6:14]),
+			sequence:   binary.LittleEndian.Uint32(page[18:22]),
+			body:       page[27+segments:],
+		})
+		data = data[size:]
+	}
+	return pages
+}
+
+func TestOggOpusWriterHeaderBytes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.opus")
+	w, err := NewOggOpusWriter(path, 16000, 1)
+	if err != nil {
+		t.Fatalf("创建Opus文件失败: %v", err)
+	}
+	// TOC 0xf8：CELT全频带20ms单帧，每个数据包960个48kHz采样
+	packets := [][]byte{{0xf8, 1, 2, 3}, {0xf8, 4, 5}}
+	for _, p := range packets {
+		if err := w.Write(p); err != nil {
+			t.Fatalf("写入失败: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("关闭失败: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages := parseOggPages(t, data)
+	if len(pages) != 4 {
+		t.Fatalf("共%d页，期望4页（OpusHead、OpusTags和两个数据包）", len(pages))
+	}
+
+	wantHead := []byte{'O', 'p', 'u', 's', 'H', 'e', 'a', 'd',
+		1,          // 版本
+		1,          // 通道数
+		0x38, 0x01, // pre-skip 312
+		0x80, 0x3e, 0x00, 0x00, // 16000Hz
+		0x00, 0x00, // 输出增益
+		0, // 声道映射族
+	}
+	if !bytes.Equal(pages[0].body, wantHead) {
+		t.Errorf("OpusHead为 % x，期望 % x", pages[0].body, wantHead)
+	}
+	wantTags := append([]byte("OpusTags\x0a\x00\x00\x00xiaozhi-go"), 0, 0, 0, 0)
+	if !bytes.Equal(pages[1].body, wantTags) {
+		t.Errorf("OpusTags为 % x，期望 % x", pages[1].body, wantTags)
+	}
+
+	want := []struct {
+		headerType byte
+		granule    uint64
+		body       []byte
+	}{
+		{0x02, 0, wantHead},
+		{0x00, 0, wantTags},
+		{0x00, 312 + 960, packets[0]},
+		{0x04, 312 + 1920, packets[1]},
+	}
+	for i, w := range want {
+		page := pages[i]
+		if page.headerType != w.headerType || page.granule != w.granule || page.sequence != uint32(i) {
+			t.Errorf("第%d页 类型=%#x granule=%d 序号=%d，期望 类型=%#x granule=%d 序号=%d",
+				i, page.headerType, page.granule, page.sequence, w.headerType, w.granule, i)
+		}
+		if !bytes.Equal(page.body, w.body) {
+			t.Errorf("第%d页数据为 % x，期望 % x", i, page.body, w.body)
+		}
+	}
+
+	r, err := NewOggOpusReader(path)
+	if err != nil {
+		t.Fatalf("打开Opus文件失败: %v", err)
+	}
+	defer r.Close()
+	if r.Channels() != 1 || r.SampleRate() != 16000 || r.PreSkip() != 312 {
+		t.Errorf("读取到 %d声道 %dHz pre-skip=%d", r.Channels(), r.SampleRate(), r.PreSkip())
+	}
+	for i, p := range packets {
+		got, err := r.ReadPacket()
+		if err != nil || !bytes.Equal(got, p) {
+			t.Fatalf("第%d个数据包为 % x（%v），期望 % x", i, got, err, p)
+		}
+	}
+	if _, err := r.ReadPacket(); err != io.EOF {
+		t.Errorf("读完后期望 io.EOF，实际: %v", err)
+	}
+}
+
+func TestOpusPacketSamples(t *testing.T) {
+	tests := []struct {
+		name    string
+		packet  []byte
+		want    int
+		wantErr bool
+	}{
+		{"SILK 10ms", []byte{0 << 3}, 480, false},
+		{"SILK 60ms", []byte{3 << 3}, 2880, false},
+		{"Hybrid 20ms", []byte{13 << 3}, 960, false},
+		{"CELT 2.5ms", []byte{16 << 3}, 120, false},
+		{"CELT 20ms 两帧", []byte{31<<3 | 1}, 1920, false},
+		{"CELT 20ms 三帧", []byte{31<<3 | 3, 3}, 2880, false},
+		{"超过120ms", []byte{31<<3 | 3, 7}, 0, true},
+		{"帧数为0", []byte{31<<3 | 3, 0}, 0, true},
+		{"缺少帧数字节", []byte{31<<3 | 3}, 0, true},
+		{"空数据包", nil, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := opusPacketSamples(tt.packet)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("opusPacketSamples(% x) = %d, %v，期望 %d", tt.packet, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+//go:build cgo && !noaudio
+
+package audio
+
+/*
+#cgo pkg-config: opus
+#include <opus.h>
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// opusDecoder libopus解码器的封装
+// go-libopus 的解码器固定以decode_fec=0调用 opus_decode，无法读取带内FEC的冗余信息，因此解码直接调用libopus
+type opusDecoder struct {
+	decoder  *C.OpusDecoder
+	channels int
+}
+
+// newOpusDecoder 创建Opus解码器
+func newOpusDecoder(sampleRate, channels int) (*opusDecoder, error) {
+	var code C.int
+	decoder := C.opus_decoder_create(C.opus_int32(sampleRate), C.int(channels), &code)
+	if code != C.OPUS_OK {
+		return nil, opusError(code)
+	}
+	return &opusDecoder{decoder: decoder, channels: channels}, nil
+}
+
+// decode 将数据包解码为交错PCM写入pcm，返回每通道的采样数
+// frameSize为每通道最多写入的采样数；fec为true时从数据包的带内FEC冗余信息恢复它之前丢失的一帧，
+// 此时frameSize必须等于丢失帧的时长，数据包不含冗余信息时libopus按丢包补偿生成该帧
+func (d *opusDecoder) decode(data []byte, pcm []int16, frameSize int, fec bool) (int, error) {
+	if d.decoder == nil {
+		return 0, errors.New("解码器已关闭")
+	}
+	if len(data) == 0 {
+		return 0, errors.New("Opus数据包为空")
+	}
+	if frameSize <= 0 || len(pcm) < frameSize*d.channels {
+		return 0, errors.New("PCM缓冲区过小")
+	}
+
+	decodeFEC := C.int(0)
+	if fec {
+		decodeFEC = 1
+	}
+	n := C.opus_decode(
+		d.decoder,
+		(*C.uchar)(unsafe.Pointer(&data[0])),
+		C.opus_int32(len(data)),
+		(*C.opus_int16)(unsafe.Pointer(&pcm[0])),
+		C.int(frameSize),
+		decodeFEC,
+	)
+	if n < 0 {
+		return 0, opusError(n)
+	}
+	return int(n), nil
+}
+
+// close 释放解码器
+func (d *opusDecoder) close() {
+	if d.decoder != nil {
+		C.opus_decoder_destroy(d.decoder)
+		d.decoder = nil
+	}
+}
+
+// opusError 将libopus错误码转换为错误
+func opusError(code C.int) error {
+	return fmt.Errorf("libopus错误: %s", C.GoString(C.opus_strerror(code)))
+}
@@ -0,0 +1,10 @@
+//go:build (!linux || !cgo || noaudio) && !(portaudio && cgo && !noaudio)
+
+package audio
+
+import "fmt"
+
+// newDeviceOutputContext Oto只能使用系统默认输出设备，当前平台无法按名称选择
+func newDeviceOutputContext(deviceName string, sampleRate, channelCount int) (outputContext, error) {
+	return nil, fmt.Errorf("无法使用输出设备 %s: %v，请不指定设备名称以使用默认输出设备", deviceName, ErrDeviceEnumerationUnsupported)
+}
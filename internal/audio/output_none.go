@@ -0,0 +1,10 @@
+//go:build (!cgo && !windows) || noaudio
+
+package audio
+
+import "errors"
+
+// newOutputContext CGO禁用或使用noaudio标签构建时无法访问音频设备，返回错误让调用方以哑模式运行
+func newOutputContext(sampleRate, channelCount, bufferSizeInBytes int) (outputContext, error) {
+	return nil, errors.New("当前构建未启用CGO或使用了noaudio标签，不支持音频输出")
+}
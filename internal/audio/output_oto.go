@@ -0,0 +1,28 @@
+//go:build (cgo || windows) && !(portaudio && cgo) && !noaudio
+
+package audio
+
+import (
+	"io"
+
+	"github.com/hajimehoshi/oto"
+)
+
+// otoOutput 基于Oto的音频输出
+type otoOutput struct {
+	context *oto.Context
+}
+
+// newOutputContext 创建Oto上下文，每个进程只能创建一次
+func newOutputContext(sampleRate, channelCount, bufferSizeInBytes int) (outputContext, error) {
+	ctx, err := oto.NewContext(sampleRate, channelCount, 2, bufferSizeInBytes)
+	if err != nil {
+		return nil, err
+	}
+	return &otoOutput{context: ctx}, nil
+}
+
+// NewPlayer 创建Oto播放器
+func (o *otoOutput) NewPlayer() io.WriteCloser {
+	return o.context.NewPlayer()
+}
@@ -0,0 +1,99 @@
+//go:build portaudio && cgo && !noaudio
+
+package audio
+
+/*
+#include <portaudio.h>
+*/
+import "C"
+import (
+	"errors"
+	"io"
+	"sync"
+	"unsafe"
+)
+
+// paOutput 基于PortAudio阻塞写入的音频输出，每个播放器各自打开一个输出流
+type paOutput struct {
+	device          C.PaDeviceIndex
+	sampleRate      int
+	channelCount    int
+	framesPerBuffer int
+}
+
+// newOutputContext 使用默认输出设备创建PortAudio输出
+func newOutputContext(sampleRate, channelCount, bufferSizeInBytes int) (outputContext, error) {
+	return newPaOutput("", sampleRate, channelCount, bufferSizeInBytes/(2*channelCount))
+}
+
+// newDeviceOutputContext 使用指定名称的输出设备创建PortAudio输出，设备名需来自 GetAudioDevices
+func newDeviceOutputContext(deviceName string, sampleRate, channelCount int) (outputContext, error) {
+	return newPaOutput(deviceName, sampleRate, channelCount, sampleRate*DefaultFrameDuration/1000)
+}
+
+func newPaOutput(deviceName string, sampleRate, channelCount, framesPerBuffer int) (outputContext, error) {
+	index, err := paDeviceIndex(deviceName, false)
+	if err != nil {
+		return nil, err
+	}
+	return &paOutput{
+		device:          index,
+		sampleRate:      sampleRate,
+		channelCount:    channelCount,
+		framesPerBuffer: framesPerBuffer,
+	}, nil
+}
+
+// NewPlayer 打开一个PortAudio输出流，打开失败时返回的输出在写入时报告错误
+func (o *paOutput) NewPlayer() io.WriteCloser {
+	stream, err := openPaStream(o.device, false, o.sampleRate, o.channelCount, o.framesPerBuffer)
+	if err != nil {
+		logger.Errorf("%v", err)
+		return &paStream{err: err}
+	}
+	return &paStream{stream: stream, frameBytes: 2 * o.channelCount}
+}
+
+// paStream 一个PortAudio输出流，Write阻塞直到数据被设备接收，与Oto播放器的行为一致
+type paStream struct {
+	mu         sync.Mutex
+	stream     unsafe.Pointer
+	frameBytes int
+	err        error
+}
+
+// Write 写入S16LE交织PCM字节，不足一个采样帧的尾部字节被丢弃
+func (s *paStream) Write(data []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return 0, s.err
+	}
+	if s.stream == nil {
+		return 0, errors.New("播放流已关闭")
+	}
+	frames := len(data) / s.frameBytes
+	if frames == 0 {
+		return len(data), nil
+	}
+	// 输出欠载只表示出现过短暂的静音，数据已经写入
+	code := C.Pa_WriteStream(s.stream, unsafe.Pointer(&data[0]), C.ulong(frames))
+	if code != C.paNoError && code != C.paOutputUnderflowed {
+		return 0, paError("写入PortAudio输出设备失败", code)
+	}
+	return len(data), nil
+}
+
+// Close 等待已写入的音频播放完毕后关闭输出流
+func (s *paStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stream == nil {
+		return nil
+	}
+	closePaStream(s.stream)
+	s.stream = nil
+	return nil
+}
@@ -0,0 +1,104 @@
+//go:build linux && cgo && !portaudio && !noaudio
+
+package audio
+
+/*
+#cgo pkg-config: libpulse-simple
+#include <pulse/simple.h>
+#include <pulse/error.h>
+#include <stdlib.h>
+
+static pa_simple* open_pulse_playback(const char* device, unsigned int sampleRate, int channels, int* error) {
+    pa_sample_spec ss;
+    ss.format = PA_SAMPLE_S16LE;
+    ss.rate = sampleRate;
+    ss.channels = channels;
+    return pa_simple_new(NULL, "xiaozhi-go", PA_STREAM_PLAYBACK, device, "playback", &ss, NULL, NULL, error);
+}
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"unsafe"
+)
+
+// pulseOutput 直接写入指定PulseAudio输出设备的音频输出，用于Oto无法选择设备的场景
+// 与Oto上下文不同，每个播放器各自打开一个播放流，不需要进程内共享
+type pulseOutput struct {
+	device       string
+	sampleRate   int
+	channelCount int
+}
+
+// newDeviceOutputContext 创建写入指定输出设备的上下文，设备名需来自 GetAudioDevices
+func newDeviceOutputContext(deviceName string, sampleRate, channelCount int) (outputContext, error) {
+	if _, err := findDevice(deviceName, false); err != nil {
+		return nil, err
+	}
+	return &pulseOutput{
+		device:       deviceName,
+		sampleRate:   sampleRate,
+		channelCount: channelCount,
+	}, nil
+}
+
+// NewPlayer 打开一个PulseAudio播放流，打开失败时返回的输出在写入时报告错误
+func (o *pulseOutput) NewPlayer() io.WriteCloser {
+	device := C.CString(o.device)
+	defer C.free(unsafe.Pointer(device))
+
+	var errorCode C.int
+	handle := C.open_pulse_playback(device, C.uint(o.sampleRate), C.int(o.channelCount), &errorCode)
+	if handle == nil {
+		err := fmt.Errorf("打开PulseAudio输出设备 %s 失败: %s", o.device, C.GoString(C.pa_strerror(errorCode)))
+		logger.Errorf("%v", err)
+		return &pulseStream{err: err}
+	}
+	return &pulseStream{handle: handle}
+}
+
+// pulseStream 一个PulseAudio播放流，Write阻塞直到数据被服务器接收，与Oto播放器的行为一致
+type pulseStream struct {
+	mu     sync.Mutex
+	handle *C.pa_simple
+	err    error
+}
+
+// Write 写入S16LE交织PCM字节
+func (s *pulseStream) Write(data []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return 0, s.err
+	}
+	if s.handle == nil {
+		return 0, errors.New("播放流已关闭")
+	}
+	if len(data) == 0 {
+		return 0, nil
+	}
+	var errorCode C.int
+	if C.pa_simple_write(s.handle, unsafe.Pointer(&data[0]), C.size_t(len(data)), &errorCode) < 0 {
+		return 0, fmt.Errorf("写入PulseAudio输出设备失败: %s", C.GoString(C.pa_strerror(errorCode)))
+	}
+	return len(data), nil
+}
+
+// Close 等待已写入的音频播放完毕后关闭播放流
+func (s *pulseStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.handle == nil {
+		return nil
+	}
+	var errorCode C.int
+	C.pa_simple_drain(s.handle, &errorCode)
+	C.pa_simple_free(s.handle)
+	s.handle = nil
+	return nil
+}
@@ -2,50 +2,144 @@ package audio
 
 import (
 	"fmt"
+	"io"
+	"math"
 	"sync"
+	"sync/atomic"
 	"time"
-
-	"github.com/hajimehoshi/oto"
-	"github.com/sirupsen/logrus"
 )
 
+// outputContext 音频输出上下文，默认由Oto实现
+type outputContext interface {
+	// NewPlayer 创建一个写入PCM字节流的输出
+	NewPlayer() io.WriteCloser
+}
+
 // AudioPlayerNew 音频播放器，使用Oto播放
 type AudioPlayerNew struct {
-	context         *oto.Context  // Oto上下文
-	player          *oto.Player   // Oto播放器
-	buffer          []int16       // PCM缓冲区
-	mutex           sync.Mutex    // 状态互斥锁
-	queue           [][]int16     // PCM数据队列
-	queueMutex      sync.Mutex    // 队列互斥锁
-	isPlaying       bool          // 是否正在播放
-	stopChan        chan struct{} // 停止信号通道
-	stopChanMutex   sync.Mutex    // 通道关闭互斥锁
-	stopChanClosed  bool          // 通道是否已关闭
-	sampleRate      int           // 采样率
-	channelCount    int           // 通道数
-	framesPerBuffer int           // 每次回调的帧数
-	dummyMode       bool          // 哑模式标志
-	decoder         Decoder       // 解码器（可选）
+	context         outputContext  // Oto上下文
+	player          io.WriteCloser // Oto播放器
+	buffer          []int16        // PCM缓冲区
+	mutex           sync.Mutex     // 状态互斥锁
+	queue           [][]int16      // PCM数据队列
+	queueMutex      sync.Mutex     // 队列互斥锁
+	isPlaying       bool           // 是否正在播放
+	paused          atomic.Bool    // 是否暂停消费队列
+	stopChan        chan struct{}  // 停止信号通道
+	stopChanMutex   sync.Mutex     // 通道关闭互斥锁
+	stopChanClosed  bool           // 通道是否已关闭
+	sampleRate      int            // 采样率
+	channelCount    int            // 通道数
+	framesPerBuffer int            // 每次回调的帧数
+	maxFrameDur     int            // 解码缓冲区可容纳的最长帧（毫秒）
+	dummyMode       bool           // 哑模式标志
+	headless        bool           // 无输出设备模式，由调用方通过Read拉取音频
+	pending         []int16        // 无输出设备模式下上次Read未取完的音频，由readMutex保护
+	readMutex       sync.Mutex     // 保护Read的状态，不可在持有其他锁时获取
+	decoder         Decoder        // 解码器（可选）
+	resampler       *Resampler     // 解码采样率与设备采样率不同时的重采样器（可选）
+	deviceRate      int            // 输出设备采样率
+	deviceChannels  int            // 输出设备通道数，与解码通道数不同时做上混/下混
+	limiter         Limiter        // 输出级限幅器
+	normalizer      *Normalizer    // 输出响度归一化（可选，为nil表示关闭）
+	volume          float64        // 输出音量增益，默认1.0
+	fade            fadeEnvelope   // 淡入淡出包络，仅由播放循环访问
+	render          renderScratch  // 输出级的复用缓冲区，仅由播放循环访问
+	fadeDuration    time.Duration  // 淡入淡出时长，小于0表示关闭
+	loopDone        chan struct{}  // 播放循环退出时关闭
+	onQueueDrained  func()         // 队列播空回调
+	outputTap       func([]int16)  // 已处理的输出PCM回调（可选），例如作为回声消除的参考信号
+	sink            *asyncSink     // 播放音频旁路（可选），在独立goroutine中写入
+	drainPending    atomic.Bool    // 播放过音频且尚未触发播空回调
+	emptySince      time.Time      // 队列开始为空的时间，仅由消费队列的一方访问
+	concealedFrames atomic.Uint64  // 丢包补偿生成的帧数
+	maxQueueFrames  int            // 播放队列最大帧数，为0表示不限制
+	queuePolicy     QueuePolicy    // 队列已满时的处理策略
+	droppedFrames   atomic.Uint64  // 因队列已满丢弃的帧数
+	lastDropLog     time.Time      // 上次输出丢帧警告的时间，由queueMutex保护
+	jitter          *jitterBuffer  // 抖动缓冲（可选，为nil表示关闭），由queueMutex保护
+	jitterDropped   int            // 上次输出抖动缓冲溢出警告以来丢弃的帧数，由queueMutex保护
+	lastJitterLog   time.Time      // 上次输出抖动缓冲溢出警告的时间，由queueMutex保护
+	counters        playbackCounters
 }
 
+// QueuePolicy 播放队列已满时的处理策略
+type QueuePolicy int
+
+const (
+	QueueDropOldest QueuePolicy = iota // 丢弃最早的帧，保持播放延迟不增长
+	QueueDropNewest                    // 丢弃新到达的帧
+	QueueBlock                         // 阻塞调用方直到队列有空位；播放器未运行时丢弃新帧
+)
+
+const (
+	// DefaultDrainDebounce 队列持续为空多久才视为播放结束，避免帧间短暂空档误触发
+	DefaultDrainDebounce = 300 * time.Millisecond
+	// queueBlockPollInterval QueueBlock 策略下检查队列空位的间隔
+	queueBlockPollInterval = 5 * time.Millisecond
+	// queueDropLogInterval 丢帧警告（含抖动缓冲溢出丢帧）的最小输出间隔
+	queueDropLogInterval = 5 * time.Second
+)
+
 // NewPlayerOptions 创建播放器的选项
 type NewPlayerOptions struct {
 	SampleRate       int
 	ChannelCount     int
 	FramesPerBuffer  int
 	UseDefaultDevice bool
-	DeviceName       string // 如果不为空，则尝试使用指定名称的设备
+	DeviceName       string      // 输出设备名称，为空时使用默认设备；目前仅Linux（PulseAudio）支持，名称不存在时创建失败
+	DeviceSampleRate int         // 输出设备采样率，为0或与SampleRate相同时不重采样
+	MaxFrameDuration int         // 单个数据包可能解码出的最长帧（毫秒），为0时使用 DefaultMaxFrameDuration
+	Normalize        bool        // 是否启用输出响度归一化
+	NormalizeTarget  float64     // 归一化目标RMS电平（0..1），为0时使用 DefaultNormalizeTarget
+	MaxQueueFrames   int         // 播放队列最大帧数，为0表示不限制
+	QueuePolicy      QueuePolicy // 队列已满时的处理策略
+	// FadeDuration 开始/停止、暂停/恢复时的淡入淡出时长，为0时使用 DefaultFadeDuration，小于0时关闭
+	FadeDuration time.Duration
+	// JitterBuffer 抖动缓冲的目标深度，开始播放前先缓冲这么长的音频，并按到达抖动自适应加深，为0时关闭
+	JitterBuffer time.Duration
+	// JitterBufferMax 抖动缓冲的深度上限，超过时丢弃最早的帧，为0时使用 DefaultJitterBufferMax
+	JitterBufferMax time.Duration
+	// Headless 不创建Oto输出，由调用方在自己的音频回调中通过 Read 拉取音频，
+	// 此时DeviceSampleRate为调用方输出的采样率，Start/Stop不操作任何输出设备，播放节奏由调用方决定
+	Headless bool
 }
 
-const maxOpusFrameSize = 5760 // 120ms at 48kHz, 单通道
+// DefaultMaxFrameDuration Opus单个数据包最长可解码出120ms音频
+const DefaultMaxFrameDuration = 120
+
+// sharedOutput 进程内共享的输出上下文
+// Oto的Context在同一进程中不能重复创建，因此第一次创建后由所有播放器共用，
+// 之后的播放器如采样率不同则重采样到该上下文的采样率
+var sharedOutput struct {
+	mu           sync.Mutex
+	context      outputContext
+	sampleRate   int
+	channelCount int
+}
 
-var otoInited = false
+// acquireOutputContext 返回共享的输出上下文及其采样率和通道数，不存在时按参数创建
+func acquireOutputContext(sampleRate, channelCount, bufferSizeInBytes int) (outputContext, int, int, error) {
+	sharedOutput.mu.Lock()
+	defer sharedOutput.mu.Unlock()
+
+	if sharedOutput.context != nil {
+		return sharedOutput.context, sharedOutput.sampleRate, sharedOutput.channelCount, nil
+	}
+
+	ctx, err := newOutputContext(sampleRate, channelCount, bufferSizeInBytes)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	sharedOutput.context = ctx
+	sharedOutput.sampleRate = sampleRate
+	sharedOutput.channelCount = channelCount
+	return ctx, sampleRate, channelCount, nil
+}
 
 // NewAudioPlayerWithOptions 使用指定选项创建新的音频播放器
+// 输出上下文在进程内共享，可在关闭播放器后重新创建；重建时输出设备保持首次打开的采样率
 func NewAudioPlayerWithOptions(options NewPlayerOptions, decoder Decoder) (*AudioPlayerNew, error) {
-	if otoInited {
-		return nil, fmt.Errorf("Oto Context 已初始化，不能重复创建")
-	}
 	// 使用默认值处理未指定的选项
 	if options.SampleRate <= 0 {
 		options.SampleRate = DefaultSampleRate
@@ -55,15 +149,52 @@ func NewAudioPlayerWithOptions(options NewPlayerOptions, decoder Decoder) (*Audi
 	}
 	if options.FramesPerBuffer <= 0 {
 		// 根据默认帧持续时间计算帧大小
-		options.FramesPerBuffer = (DefaultSampleRate * DefaultFrameDuration) / 1000
+		options.FramesPerBuffer = (options.SampleRate * DefaultFrameDuration) / 1000
 	}
 
-	// 创建Oto上下文
-	ctx, err := oto.NewContext(options.SampleRate, options.ChannelCount, 2, options.FramesPerBuffer*options.ChannelCount*2)
-	if err != nil {
-		return nil, fmt.Errorf("初始化Oto失败: %v", err)
+	if options.DeviceSampleRate <= 0 {
+		options.DeviceSampleRate = options.SampleRate
+	}
+	if options.MaxFrameDuration <= 0 {
+		options.MaxFrameDuration = DefaultMaxFrameDuration
+	}
+	if options.FadeDuration == 0 {
+		options.FadeDuration = DefaultFadeDuration
+	}
+
+	// 获取Oto上下文，已存在时沿用其采样率；指定了设备名称时直接打开该设备
+	var ctx outputContext
+	deviceChannels := options.ChannelCount
+	if !options.Headless && options.DeviceName != "" {
+		c, err := newDeviceOutputContext(options.DeviceName, options.DeviceSampleRate, options.ChannelCount)
+		if err != nil {
+			return nil, err
+		}
+		ctx = c
+		logger.Infof("使用输出设备: %s", options.DeviceName)
+	} else if !options.Headless {
+		deviceFrames := options.FramesPerBuffer * options.DeviceSampleRate / options.SampleRate
+		c, deviceRate, channels, err := acquireOutputContext(options.DeviceSampleRate, options.ChannelCount, deviceFrames*options.ChannelCount*2)
+		if err != nil {
+			return nil, fmt.Errorf("初始化Oto失败: %v", err)
+		}
+		ctx = c
+		options.DeviceSampleRate = deviceRate
+		deviceChannels = channels
+		if deviceChannels != options.ChannelCount {
+			logger.Infof("输出设备已按%d通道打开，播放时将从%d通道转换", deviceChannels, options.ChannelCount)
+		}
+	}
+
+	var resampler *Resampler
+	if options.DeviceSampleRate != options.SampleRate {
+		r, err := NewResampler(options.SampleRate, options.DeviceSampleRate, options.ChannelCount)
+		if err != nil {
+			return nil, err
+		}
+		resampler = r
+		logger.Infof("播放将从%dHz重采样到设备采样率%dHz", options.SampleRate, options.DeviceSampleRate)
 	}
-	otoInited = true
 
 	player := &AudioPlayerNew{
 		context:         ctx,
@@ -75,8 +206,23 @@ func NewAudioPlayerWithOptions(options NewPlayerOptions, decoder Decoder) (*Audi
 		sampleRate:      options.SampleRate,
 		channelCount:    options.ChannelCount,
 		framesPerBuffer: options.FramesPerBuffer,
+		maxFrameDur:     options.MaxFrameDuration,
+		volume:          1,
 		dummyMode:       false,
 		decoder:         decoder,
+		resampler:       resampler,
+		deviceRate:      options.DeviceSampleRate,
+		deviceChannels:  deviceChannels,
+		maxQueueFrames:  options.MaxQueueFrames,
+		queuePolicy:     options.QueuePolicy,
+		fadeDuration:    options.FadeDuration,
+		headless:        options.Headless,
+	}
+	if options.Normalize {
+		player.normalizer = NewNormalizer(options.NormalizeTarget)
+	}
+	if options.JitterBuffer > 0 {
+		player.jitter = newJitterBuffer(options.JitterBuffer, options.JitterBufferMax)
 	}
 	return player, nil
 }
@@ -95,7 +241,7 @@ func NewAudioPlayer2(sampleRate, channelCount, frameDuration int, decoder Decode
 
 	player, err := NewAudioPlayerWithOptions(options, decoder)
 	if err != nil {
-		logrus.Errorf("创建音频播放器失败: %v, 将以哑模式运行", err)
+		logger.Errorf("创建音频播放器失败: %v, 将以哑模式运行", err)
 		// 返回一个哑模式实例，避免nil检查
 		return &AudioPlayerNew{
 			buffer:          make([]int16, framesPerBuffer*channelCount),
@@ -104,6 +250,8 @@ func NewAudioPlayer2(sampleRate, channelCount, frameDuration int, decoder Decode
 			sampleRate:      sampleRate,
 			channelCount:    channelCount,
 			framesPerBuffer: framesPerBuffer,
+			maxFrameDur:     DefaultMaxFrameDuration,
+			volume:          1,
 			dummyMode:       true,
 			decoder:         decoder,
 		}
@@ -135,40 +283,351 @@ func (p *AudioPlayerNew) Start() error {
 	}
 
 	p.isPlaying = true
-	go p.otoPlayLoop()
+	p.fade = newFadeEnvelope(p.fadeDuration, p.deviceRate, p.deviceChannels)
+	if p.headless {
+		// 由调用方通过Read拉取音频
+		return nil
+	}
+	p.loopDone = make(chan struct{})
+	go p.otoPlayLoop(p.stopChan, p.loopDone)
 	return nil
 }
 
 // otoPlayLoop 用于持续播放队列中的PCM数据
-func (p *AudioPlayerNew) otoPlayLoop() {
+// 每段连续音频以淡入开始；暂停和停止时先写入一小段淡出音频再停止写入设备
+func (p *AudioPlayerNew) otoPlayLoop(stopChan <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
 	p.player = p.context.NewPlayer()
 	defer p.player.Close()
 	for {
 		select {
-		case <-p.stopChan:
+		case <-stopChan:
+			p.writeFadeOut()
 			return
 		default:
-			p.queueMutex.Lock()
-			if len(p.queue) == 0 {
-				p.queueMutex.Unlock()
+			if p.paused.Load() {
+				p.writeFadeOut()
 				time.Sleep(10 * time.Millisecond)
 				continue
 			}
-			pcmData := p.queue[0]
-			p.queue = p.queue[1:]
+			p.queueMutex.Lock()
+			pcmData, empty := p.popFrameLocked()
 			p.queueMutex.Unlock()
+			if pcmData == nil {
+				// 队列播空或抖动缓冲积累期间重新以淡入开始
+				p.fade.gain = 0
+				if empty {
+					p.checkDrained()
+				}
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+
+			p.markFramePlayed()
+			_, _ = p.player.Write(p.renderOutput(pcmData, 1))
+		}
+	}
+}
+
+// writeFadeOut 从队首音频生成一小段淡出到静音的音频并写入设备，队首帧保留在队列中
+// 恢复播放时从该帧开头淡入，保证暂停前后的音频不丢失
+func (p *AudioPlayerNew) writeFadeOut() {
+	if p.fade.gain == 0 {
+		return
+	}
+
+	p.queueMutex.Lock()
+	var tail []int16
+	if len(p.queue) > 0 {
+		head := p.queue[0]
+		tail = make([]int16, min(len(head), p.fade.tailLength()))
+		copy(tail, head)
+	}
+	p.queueMutex.Unlock()
+
+	if len(tail) > 0 {
+		_, _ = p.player.Write(p.renderOutput(tail, 0))
+	}
+	p.fade.gain = 0
+}
+
+// renderScratch 输出级每帧复用的缓冲区，避免连续播放时每帧分配
+// 输出设备的Write是同步的，写入返回后缓冲区即可复用
+type renderScratch struct {
+	samples []float64
+	pcm     []int16
+	bytes   []byte
+}
+
+// buffers 返回容纳n个采样的浮点、PCM缓冲区，容量不足时扩容
+func (r *renderScratch) buffers(n int) ([]float64, []int16) {
+	if cap(r.samples) < n {
+		r.samples = make([]float64, n)
+		r.pcm = make([]int16, n)
+	}
+	return r.samples[:n], r.pcm[:n]
+}
+
+// renderOutput 输出级：处理采样后转换为小端序字节流，返回的切片在下一次渲染前有效
+func (p *AudioPlayerNew) renderOutput(pcmData []int16, fadeTarget float64) []byte {
+	rendered := p.renderPCM(pcmData, fadeTarget)
+	if cap(p.render.bytes) < len(rendered)*2 {
+		p.render.bytes = make([]byte, len(rendered)*2)
+	}
+	buf := p.render.bytes[:len(rendered)*2]
+	for i, s := range rendered {
+		buf[2*i] = byte(s)
+		buf[2*i+1] = byte(s >> 8)
+	}
+	return buf
+}
+
+// renderPCM 在浮点域依次进行响度归一化、淡入淡出（趋向fadeTarget）和音量调整，经限幅后转换为int16
+// 返回的切片复用播放器的缓冲区，在下一次渲染前有效
+func (p *AudioPlayerNew) renderPCM(pcmData []int16, fadeTarget float64) []int16 {
+	p.mutex.Lock()
+	limiter := p.limiter
+	normalizer := p.normalizer
+	volume := p.volume
+	outputTap := p.outputTap
+	sink := p.sink
+	p.mutex.Unlock()
+
+	// 增益大于1时可能越界，使用软拐点压缩而不是直接削波
+	if volume > 1 {
+		limiter.Mode = LimiterSoftKnee
+	}
+
+	samples, out := p.render.buffers(len(pcmData))
+	if normalizer != nil {
+		normalizer.Process(pcmData, samples)
+	} else {
+		for i, v := range pcmData {
+			samples[i] = float64(v)
+		}
+	}
 
-			// 转换为字节流
-			buf := make([]byte, len(pcmData)*2)
-			for i, v := range pcmData {
-				buf[2*i] = byte(v)
-				buf[2*i+1] = byte(v >> 8)
+	p.fade.apply(samples, fadeTarget)
+
+	for i, v := range samples {
+		out[i] = limiter.Sample(v * volume)
+	}
+
+	if outputTap != nil {
+		outputTap(out)
+	}
+	if sink != nil {
+		sink.push(out)
+	}
+	return out
+}
+
+// Read 在无输出设备模式（NewPlayerOptions.Headless）下从播放队列拉取已处理的PCM，写入pcmOut
+// 返回写入的采样数（含所有通道），不足部分由调用方填充静音；未启动、已暂停或队列为空时返回0
+// 非无输出设备模式下播放循环会消费队列，不应调用Read
+func (p *AudioPlayerNew) Read(pcmOut []int16) int {
+	if !p.IsPlaying() || p.paused.Load() {
+		return 0
+	}
+
+	p.readMutex.Lock()
+	defer p.readMutex.Unlock()
+
+	n := 0
+	for n < len(pcmOut) {
+		if len(p.pending) == 0 {
+			p.queueMutex.Lock()
+			frame, empty := p.popFrameLocked()
+			p.queueMutex.Unlock()
+			if frame == nil {
+				// 队列播空或抖动缓冲积累期间重新以淡入开始
+				p.fade.gain = 0
+				if empty {
+					p.checkDrained()
+				}
+				break
 			}
-			_, _ = p.player.Write(buf)
+
+			p.markFramePlayed()
+
+			p.pending = p.renderPCM(frame, 1)
 		}
+		copied := copy(pcmOut[n:], p.pending)
+		p.pending = p.pending[copied:]
+		n += copied
+	}
+	return n
+}
+
+// SetOnQueueDrained 设置播放队列播空回调：播放过音频后队列持续为空超过 DefaultDrainDebounce，
+// 或播放中途调用Stop时触发一次。回调在独立的goroutine中执行
+func (p *AudioPlayerNew) SetOnQueueDrained(callback func()) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.onQueueDrained = callback
+}
+
+// markFramePlayed 记录消费了一帧音频
+func (p *AudioPlayerNew) markFramePlayed() {
+	p.counters.played.Add(1)
+	p.drainPending.Store(true)
+	p.emptySince = time.Time{}
+}
+
+// checkDrained 队列为空时调用，为空持续时间超过去抖时长后触发播空回调
+func (p *AudioPlayerNew) checkDrained() {
+	if !p.drainPending.Load() {
+		return
+	}
+	now := time.Now()
+	if p.emptySince.IsZero() {
+		p.emptySince = now
+		return
+	}
+	if now.Sub(p.emptySince) >= DefaultDrainDebounce {
+		p.emptySince = time.Time{}
+		p.fireDrained()
+	}
+}
+
+// fireDrained 若有待通知的播放则触发一次播空回调
+func (p *AudioPlayerNew) fireDrained() {
+	if !p.drainPending.CompareAndSwap(true, false) {
+		return
+	}
+
+	p.mutex.Lock()
+	callback := p.onQueueDrained
+	p.mutex.Unlock()
+
+	if callback != nil {
+		go callback()
+	}
+}
+
+// MaxVolume 音量增益上限
+const MaxVolume = 4.0
+
+// SetVolume 设置输出音量增益，1.0为原始音量，0为静音
+// 大于1.0时放大，超出满幅的部分经软拐点限幅，增益超过 MaxVolume 时取 MaxVolume
+func (p *AudioPlayerNew) SetVolume(gain float64) {
+	if math.IsNaN(gain) || gain < 0 {
+		gain = 0
+	}
+	if gain > MaxVolume {
+		gain = MaxVolume
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.volume = gain
+}
+
+// Volume 返回当前输出音量增益
+func (p *AudioPlayerNew) Volume() float64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.volume
+}
+
+// handOverTo 将未播放的队列、音量、暂停状态和播空回调转移给参数相同的新播放器，用于切换输出设备
+// 转移后本播放器不再触发播空回调，由调用方关闭；新播放器已有的帧排在转移过去的帧之后
+func (p *AudioPlayerNew) handOverTo(next *AudioPlayerNew) {
+	p.mutex.Lock()
+	onQueueDrained := p.onQueueDrained
+	p.onQueueDrained = nil
+	volume := p.volume
+	p.mutex.Unlock()
+
+	p.queueMutex.Lock()
+	queue := p.queue
+	p.queue = nil
+	p.queueMutex.Unlock()
+
+	next.SetVolume(volume)
+	next.SetOnQueueDrained(onQueueDrained)
+	next.paused.Store(p.paused.Load())
+	if p.drainPending.Swap(false) {
+		next.drainPending.Store(true)
+	}
+
+	next.queueMutex.Lock()
+	next.queue = append(queue, next.queue...)
+	next.queueMutex.Unlock()
+}
+
+// SetOutputTap 设置输出PCM回调，每帧音频经音量、归一化和淡入淡出处理后、送往输出设备前回调一次
+// PCM已重采样并转换为输出设备的采样率和通道数（见 deviceFormat），回调不应修改或持有该切片
+func (p *AudioPlayerNew) SetOutputTap(tap func([]int16)) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.outputTap = tap
+}
+
+// deviceFormat 返回输出设备的采样率和通道数，即 SetOutputTap 和 SetSink 收到的PCM格式
+// 两者在创建播放器时确定，之后 SetAudioParams 只改变解码参数
+func (p *AudioPlayerNew) deviceFormat() (int, int) {
+	return p.deviceRate, p.deviceChannels
+}
+
+// SetSink 设置播放音频旁路，每帧送往输出设备前按播放顺序写入sink，传nil关闭
+// 写入的PCM与 SetOutputTap 收到的相同，在独立goroutine中异步写入，不影响播放节奏；
+// 替换或关闭时等待已排队的帧写完，但不关闭sink本身
+func (p *AudioPlayerNew) SetSink(sink PCMSink) {
+	var next *asyncSink
+	if sink != nil {
+		next = newAsyncSink("播放音频旁路", sink)
+	}
+
+	p.mutex.Lock()
+	previous := p.sink
+	p.sink = next
+	p.mutex.Unlock()
+
+	if previous != nil {
+		previous.close()
 	}
 }
 
+// SetNormalizer 启用或关闭输出响度归一化，target为目标RMS电平（0..1），为0时使用默认值
+// 归一化后超出满幅的采样由限幅器处理，建议同时使用 LimiterSoftKnee
+func (p *AudioPlayerNew) SetNormalizer(enabled bool, target float64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if enabled {
+		p.normalizer = NewNormalizer(target)
+	} else {
+		p.normalizer = nil
+	}
+}
+
+// SetLimiter 设置输出级限幅方式，knee为软拐点位置（0..1），为0时使用默认值
+func (p *AudioPlayerNew) SetLimiter(mode LimiterMode, knee float64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.limiter = Limiter{Mode: mode, Knee: knee}
+}
+
+// Pause 暂停播放：停止消费队列，但保留输出设备和已缓冲的音频，可通过 Resume 继续
+// 设备内部缓冲中已写入的少量音频仍会播完
+func (p *AudioPlayerNew) Pause() {
+	if !p.paused.Swap(true) {
+		logger.Debugf("音频播放已暂停")
+	}
+}
+
+// Resume 从暂停处继续播放队列中的音频
+func (p *AudioPlayerNew) Resume() {
+	if p.paused.Swap(false) {
+		logger.Debugf("音频播放已恢复")
+	}
+}
+
+// IsPaused 返回播放是否处于暂停状态
+func (p *AudioPlayerNew) IsPaused() bool {
+	return p.paused.Load()
+}
+
 // Stop 停止播放
 func (p *AudioPlayerNew) Stop() error {
 	p.mutex.Lock()
@@ -179,6 +638,7 @@ func (p *AudioPlayerNew) Stop() error {
 		return nil
 	}
 	p.isPlaying = false
+	loopDone := p.loopDone
 	p.mutex.Unlock()
 
 	// 发送停止信号，防止重复关闭
@@ -189,13 +649,33 @@ func (p *AudioPlayerNew) Stop() error {
 	}
 	p.stopChanMutex.Unlock()
 
+	// 等待播放循环写完淡出音频后再清空队列
+	if loopDone != nil && !p.dummyMode {
+		select {
+		case <-loopDone:
+		case <-time.After(500 * time.Millisecond):
+			logger.Warnf("等待播放循环退出超时")
+		}
+	}
+	p.paused.Store(false)
+
 	// 清空队列
 	p.queueMutex.Lock()
 	p.queue = nil
+	if p.jitter != nil {
+		p.jitter.reset()
+	}
 	p.queueMutex.Unlock()
 
-	// 如果是哑模式，直接返回
-	if p.dummyMode {
+	p.readMutex.Lock()
+	p.pending = nil
+	p.readMutex.Unlock()
+
+	// 播放中途停止也视为播放结束，drainPending保证回调只触发一次
+	p.fireDrained()
+
+	// 如果是哑模式或无输出设备模式，直接返回
+	if p.dummyMode || p.headless {
 		return nil
 	}
 
@@ -230,33 +710,322 @@ func (p *AudioPlayerNew) stopStreamSafely() error {
 	case err := <-done:
 		return err
 	case <-time.After(2 * time.Second):
-		logrus.Warn("停止音频流操作超时")
+		logger.Warnf("停止音频流操作超时")
 		return fmt.Errorf("停止音频流操作超时")
 	}
 }
 
 // QueueAudio 将音频数据添加到播放队列
 func (p *AudioPlayerNew) QueueAudio(encodedData []byte) {
-	if p.decoder == nil || len(encodedData) == 0 {
+	decoder, framesPerBuffer, bufferSize := p.decodeState()
+	if decoder == nil || len(encodedData) == 0 {
+		return
+	}
+	p.counters.received.Add(1)
+
+	// 解码数据，临时缓冲区复用，只有进入队列的帧按实际长度分配
+	scratch := getDecodeScratch(bufferSize)
+	defer decodeScratchPool.Put(scratch)
+	pcmBuffer := *scratch
+	start := time.Now()
+	n, err := decoder.Decode(encodedData, pcmBuffer)
+	p.counters.decodeLatency.observe(time.Since(start))
+	if err != nil {
+		p.counters.decodeErrors.Add(1)
+		logger.Errorf("解码音频数据失败: %v，使用丢包补偿", err)
+		p.concealFrame(decoder, pcmBuffer, framesPerBuffer)
 		return
 	}
+	p.counters.decoded.Add(1)
+
+	p.enqueueDecoded(pcmBuffer[:n])
+}
+
+// maxLossConcealFrames 一次丢包最多补偿的帧数，长时间中断后不再补偿更多帧，以免积压的补偿音频拖慢之后的播放
+const maxLossConcealFrames = 5
+
+// QueueAudioAfterLoss 在接收端检测到丢失lost个数据包后，将新到达的数据包加入播放队列
+// 丢失的帧先用补偿音频填补，最多补偿 maxLossConcealFrames 帧；若解码器启用了FEC，最后一帧由当前数据包的冗余信息恢复
+func (p *AudioPlayerNew) QueueAudioAfterLoss(encodedData []byte, lost int) {
+	decoder, framesPerBuffer, bufferSize := p.decodeState()
+	if decoder == nil {
+		return
+	}
+	lost = min(lost, maxLossConcealFrames)
+
+	scratch := getDecodeScratch(bufferSize)
+	pcmBuffer := *scratch
+	for i := 0; i < lost; i++ {
+		fec, ok := decoder.(FECDecoder)
+		if i == lost-1 && ok && len(encodedData) > 0 {
+			n, err := fec.DecodeFEC(encodedData, pcmBuffer, framesPerBuffer)
+			if err == nil {
+				p.concealedFrames.Add(1)
+				p.enqueueDecoded(pcmBuffer[:n])
+				continue
+			}
+			logger.Warnf("FEC恢复丢失帧失败: %v", err)
+		}
+		p.concealFrame(decoder, pcmBuffer, framesPerBuffer)
+	}
+	decodeScratchPool.Put(scratch)
+
+	p.QueueAudio(encodedData)
+}
+
+// decodeScratchPool 解码临时缓冲区池，避免连续播放时每帧分配最长帧大小的缓冲区
+var decodeScratchPool sync.Pool
+
+// decodeState 返回当前的解码器、每帧采样数和解码缓冲区大小
+// 解码在锁外进行，SetDecoder/SetAudioParams 只影响之后到达的数据包
+func (p *AudioPlayerNew) decodeState() (Decoder, int, int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.decoder, p.framesPerBuffer, p.decodeBufferSizeLocked()
+}
 
-	// 解码数据
-	pcmBuffer := make([]int16, maxOpusFrameSize*p.channelCount) // 足够大的缓冲区
-	n, err := p.decoder.Decode(encodedData, pcmBuffer)
+// getDecodeScratch 从池中取出至少size个采样的解码缓冲区，用完需放回池中
+func getDecodeScratch(size int) *[]int16 {
+	if buf, ok := decodeScratchPool.Get().(*[]int16); ok && cap(*buf) >= size {
+		*buf = (*buf)[:size]
+		return buf
+	}
+	buf := make([]int16, size)
+	return &buf
+}
+
+// decodeBufferSizeLocked 根据解码采样率和最长帧时长计算解码缓冲区的采样数，调用方需持有mutex
+func (p *AudioPlayerNew) decodeBufferSizeLocked() int {
+	maxFrameDur := p.maxFrameDur
+	if maxFrameDur <= 0 {
+		maxFrameDur = DefaultMaxFrameDuration
+	}
+	return p.sampleRate * maxFrameDur / 1000 * p.channelCount
+}
+
+// concealFrame 用decoder生成一帧丢包补偿音频并加入队列
+func (p *AudioPlayerNew) concealFrame(decoder Decoder, pcmBuffer []int16, framesPerBuffer int) {
+	n, err := decoder.DecodePLC(pcmBuffer, framesPerBuffer)
 	if err != nil {
-		logrus.Errorf("解码音频数据失败: %v", err)
+		logger.Errorf("生成丢包补偿音频失败: %v", err)
 		return
 	}
+	p.concealedFrames.Add(1)
+	p.enqueueDecoded(pcmBuffer[:n])
+}
+
+// enqueueDecoded 复制有效的PCM数据并加入队列
+func (p *AudioPlayerNew) enqueueDecoded(pcm []int16) {
+	p.queueMutex.Lock()
+	defer p.queueMutex.Unlock()
+	p.pushLocked(p.toDeviceRate(pcm))
+}
+
+// pushLocked 按队列长度限制和策略将一帧加入队列，调用方需持有queueMutex
+func (p *AudioPlayerNew) pushLocked(frame []int16) {
+	if p.maxQueueFrames > 0 && len(p.queue) >= p.maxQueueFrames {
+		switch p.queuePolicy {
+		case QueueDropNewest:
+			p.noteDropLocked()
+			return
+		case QueueBlock:
+			if !p.waitForSpaceLocked() {
+				p.noteDropLocked()
+				return
+			}
+		default:
+			p.queue = p.queue[1:]
+			p.noteDropLocked()
+		}
+	}
+	p.queue = append(p.queue, frame)
+
+	if p.jitter != nil {
+		p.jitter.arrive(time.Now(), p.pcmDuration(frame))
+		if target := p.jitter.overrun(p.queuedDurationLocked()); target >= 0 {
+			dropped := 0
+			for len(p.queue) > 1 && p.queuedDurationLocked() > target {
+				p.queue = p.queue[1:]
+				dropped++
+			}
+			p.noteJitterDropLocked(dropped)
+		}
+	}
+}
+
+// popFrameLocked 取出队首帧，调用方需持有queueMutex
+// 队列为空时返回nil和true；启用抖动缓冲且仍在积累时返回nil和false，此时不应视为播放结束
+func (p *AudioPlayerNew) popFrameLocked() ([]int16, bool) {
+	if len(p.queue) == 0 {
+		if p.jitter != nil {
+			p.jitter.starve(time.Now())
+		}
+		return nil, true
+	}
+	if p.jitter != nil && !p.jitter.ready(time.Now(), p.queuedDurationLocked()) {
+		return nil, false
+	}
+	frame := p.queue[0]
+	p.queue = p.queue[1:]
+	return frame, false
+}
+
+// pcmDuration 返回一帧已转换为设备参数的PCM的时长
+func (p *AudioPlayerNew) pcmDuration(frame []int16) time.Duration {
+	rate, channels := p.deviceRate, p.deviceChannels
+	if rate <= 0 {
+		rate = p.sampleRate
+	}
+	if channels <= 0 {
+		channels = p.channelCount
+	}
+	if rate <= 0 || channels <= 0 {
+		return 0
+	}
+	return time.Duration(len(frame)/channels) * time.Second / time.Duration(rate)
+}
 
-	// 只保留有效的PCM数据
-	pcmData := make([]int16, n)
-	copy(pcmData, pcmBuffer[:n])
+// queuedDurationLocked 返回队列中音频的总时长，调用方需持有queueMutex
+func (p *AudioPlayerNew) queuedDurationLocked() time.Duration {
+	var total time.Duration
+	for _, frame := range p.queue {
+		total += p.pcmDuration(frame)
+	}
+	return total
+}
 
-	// 添加到队列
+// SetJitterBuffer 启用或调整抖动缓冲，target为目标深度，为0时关闭；maxDepth为深度上限，为0时使用 DefaultJitterBufferMax
+// 调整时重新开始积累，统计计数清零
+func (p *AudioPlayerNew) SetJitterBuffer(target, maxDepth time.Duration) {
 	p.queueMutex.Lock()
 	defer p.queueMutex.Unlock()
-	p.queue = append(p.queue, pcmData)
+	if target <= 0 {
+		p.jitter = nil
+		return
+	}
+	p.jitter = newJitterBuffer(target, maxDepth)
+}
+
+// JitterBufferStats 返回抖动缓冲的当前深度和欠载/溢出计数，未启用时只有Depth有效
+func (p *AudioPlayerNew) JitterBufferStats() JitterBufferStats {
+	p.queueMutex.Lock()
+	defer p.queueMutex.Unlock()
+	depth := p.queuedDurationLocked()
+	if p.jitter == nil {
+		return JitterBufferStats{Depth: depth}
+	}
+	return p.jitter.stats(depth)
+}
+
+// waitForSpaceLocked 等待队列出现空位，播放器停止时返回false
+// 等待期间释放queueMutex，返回时重新持有
+func (p *AudioPlayerNew) waitForSpaceLocked() bool {
+	for len(p.queue) >= p.maxQueueFrames {
+		p.queueMutex.Unlock()
+		playing := p.IsPlaying()
+		if playing {
+			time.Sleep(queueBlockPollInterval)
+		}
+		p.queueMutex.Lock()
+		if !playing {
+			return false
+		}
+	}
+	return true
+}
+
+// noteDropLocked 记录一次丢帧，并限频输出警告，调用方需持有queueMutex
+func (p *AudioPlayerNew) noteDropLocked() {
+	dropped := p.droppedFrames.Add(1)
+	now := time.Now()
+	if now.Sub(p.lastDropLog) >= queueDropLogInterval {
+		p.lastDropLog = now
+		logger.Warnf("播放队列已满（%d帧），累计丢弃%d帧", p.maxQueueFrames, dropped)
+	}
+}
+
+// noteJitterDropLocked 记录抖动缓冲溢出时丢弃的帧数并限频输出警告，调用方需持有queueMutex
+// 溢出次数已由抖动缓冲统计，只有确实丢了帧才需要警告
+func (p *AudioPlayerNew) noteJitterDropLocked(dropped int) {
+	if dropped == 0 {
+		return
+	}
+	p.jitterDropped += dropped
+	now := time.Now()
+	if now.Sub(p.lastJitterLog) >= queueDropLogInterval {
+		p.lastJitterLog = now
+		logger.Warnf("抖动缓冲超过上限，丢弃最早的%d帧", p.jitterDropped)
+		p.jitterDropped = 0
+	}
+}
+
+// QueueFramesDropped 返回因播放队列已满而丢弃的累计帧数
+func (p *AudioPlayerNew) QueueFramesDropped() uint64 {
+	return p.droppedFrames.Load()
+}
+
+// toDeviceRate 复制PCM数据，必要时重采样到设备采样率并转换为设备通道数，调用方需持有queueMutex
+func (p *AudioPlayerNew) toDeviceRate(pcm []int16) []int16 {
+	var pcmData []int16
+	if p.resampler != nil {
+		pcmData = p.resampler.Process(pcm)
+	} else {
+		pcmData = make([]int16, len(pcm))
+		copy(pcmData, pcm)
+	}
+	if p.deviceChannels > 0 && p.deviceChannels != p.channelCount {
+		pcmData = convertChannels(pcmData, p.channelCount, p.deviceChannels)
+	}
+	return pcmData
+}
+
+// convertChannels 转换交错PCM的通道数：下混到单声道时取各通道平均，其余情况按通道循环复制
+func convertChannels(pcm []int16, from, to int) []int16 {
+	if from <= 0 || to <= 0 || from == to {
+		return pcm
+	}
+
+	frames := len(pcm) / from
+	out := make([]int16, frames*to)
+	for f := 0; f < frames; f++ {
+		in := pcm[f*from : (f+1)*from]
+		if to == 1 {
+			var sum int
+			for _, v := range in {
+				sum += int(v)
+			}
+			out[f] = int16(sum / from)
+			continue
+		}
+		for c := 0; c < to; c++ {
+			out[f*to+c] = in[c%from]
+		}
+	}
+	return out
+}
+
+// PlaybackMetrics 返回本播放器的收包、解码、播放和丢帧统计
+func (p *AudioPlayerNew) PlaybackMetrics() PlaybackMetrics {
+	p.queueMutex.Lock()
+	queueLength := len(p.queue)
+	p.queueMutex.Unlock()
+
+	return PlaybackMetrics{
+		FramesReceived:  p.counters.received.Load(),
+		FramesDecoded:   p.counters.decoded.Load(),
+		DecodeErrors:    p.counters.decodeErrors.Load(),
+		FramesConcealed: p.concealedFrames.Load(),
+		FramesPlayed:    p.counters.played.Load(),
+		FramesDropped:   p.droppedFrames.Load(),
+		QueueLength:     queueLength,
+		DecodeLatency:   p.counters.decodeLatency.snapshot(),
+		JitterBuffer:    p.JitterBufferStats(),
+	}
+}
+
+// ConcealedFrames 返回丢包补偿生成的累计帧数
+func (p *AudioPlayerNew) ConcealedFrames() uint64 {
+	return p.concealedFrames.Load()
 }
 
 // QueuePCMAudio 将PCM音频数据直接添加到播放队列
@@ -266,13 +1035,9 @@ func (p *AudioPlayerNew) QueuePCMAudio(pcmData []int16) {
 	}
 
 	// 复制数据以避免竞争条件
-	dataCopy := make([]int16, len(pcmData))
-	copy(dataCopy, pcmData)
-
-	// 添加到队列
 	p.queueMutex.Lock()
 	defer p.queueMutex.Unlock()
-	p.queue = append(p.queue, dataCopy)
+	p.pushLocked(p.toDeviceRate(pcmData))
 }
 
 // processQueue 处理音频队列
@@ -283,7 +1048,7 @@ func (p *AudioPlayerNew) processQueue() {
 	// 添加恢复机制
 	defer func() {
 		if rec := recover(); rec != nil {
-			logrus.Errorf("音频处理协程崩溃: %v", rec)
+			logger.Errorf("音频处理协程崩溃: %v", rec)
 		}
 	}()
 
@@ -297,11 +1062,18 @@ func (p *AudioPlayerNew) processQueue() {
 			case <-stopChan:
 				return
 			case <-timeout.C:
-				p.queueMutex.Lock()
-				if len(p.queue) > 0 {
-					p.queue = p.queue[1:] // 移除一帧数据
+				if p.paused.Load() {
+					continue
 				}
+				p.queueMutex.Lock()
+				frame, empty := p.popFrameLocked() // 移除一帧数据
 				p.queueMutex.Unlock()
+
+				if frame != nil {
+					p.markFramePlayed()
+				} else if empty {
+					p.checkDrained()
+				}
 			}
 		}
 	}
@@ -340,7 +1112,7 @@ func (p *AudioPlayerNew) Close() error {
 	// 添加恢复机制
 	defer func() {
 		if rec := recover(); rec != nil {
-			logrus.Errorf("关闭播放器时发生异常: %v", rec)
+			logger.Errorf("关闭播放器时发生异常: %v", rec)
 		}
 	}()
 
@@ -358,6 +1130,12 @@ func (p *AudioPlayerNew) Close() error {
 
 	p.decoder = nil
 
+	// 写完旁路中已排队的帧
+	if p.sink != nil {
+		p.sink.close()
+		p.sink = nil
+	}
+
 	return nil
 }
 
@@ -369,15 +1147,41 @@ func min(a, b int) int {
 	return b
 }
 
-// SetDecoder 设置新的解码器
+// SetDecoder 设置新的解码器，可与 QueueAudio 并发调用
 func (p *AudioPlayerNew) SetDecoder(decoder Decoder) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
 	p.decoder = decoder
 }
 
-// SetAudioParams 同步更新播放器参数
+// SetAudioParams 同步更新播放器参数，可与 QueueAudio 并发调用
+// 输出设备保持原采样率打开，解码采样率与设备不同时自动重建重采样器
 func (p *AudioPlayerNew) SetAudioParams(sampleRate, channelCount, frameDuration int) {
+	var resampler *Resampler
+	if p.deviceRate > 0 && sampleRate != p.deviceRate {
+		r, err := NewResampler(sampleRate, p.deviceRate, channelCount)
+		if err != nil {
+			logger.Errorf("创建播放重采样器失败: %v", err)
+			return
+		}
+		resampler = r
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
 	p.sampleRate = sampleRate
-	p.channelCount = channelCount
 	p.framesPerBuffer = (sampleRate * frameDuration) / 1000
-	p.buffer = make([]int16, p.framesPerBuffer*p.channelCount)
+	p.buffer = make([]int16, p.framesPerBuffer*channelCount)
+	// 解码缓冲区至少要能容纳一个协商帧长的数据包
+	if frameDuration > p.maxFrameDur {
+		p.maxFrameDur = frameDuration
+	}
+
+	// 入队时的重采样和通道转换由queueMutex保护
+	p.queueMutex.Lock()
+	p.channelCount = channelCount
+	if p.deviceRate > 0 {
+		p.resampler = resampler
+	}
+	p.queueMutex.Unlock()
 }
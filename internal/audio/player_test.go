@@ -0,0 +1,429 @@
+package audio
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestManager 创建使用 MockRecorder 且不打开输出设备的音频管理器，测试结束时关闭
+func newTestManager(t *testing.T) *AudioManagerNew {
+	t.Helper()
+	recorder := NewMockRecorder(RecorderOptions{
+		SampleRate:    DefaultSampleRate,
+		ChannelCount:  DefaultChannelCount,
+		FrameDuration: DefaultFrameDuration,
+	}, nil)
+	m, err := NewAudioManagerWithOptions(AudioManagerOptions{Recorder: recorder, HeadlessOutput: true})
+	if err != nil {
+		t.Fatalf("创建音频管理器失败: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+// encodeTestFrame 用管理器当前的编码器编码一帧恒定幅度的音频
+func encodeTestFrame(t *testing.T, m *AudioManagerNew, samples int, value int16) []byte {
+	t.Helper()
+	pcm := make([]int16, samples)
+	for i := range pcm {
+		pcm[i] = value
+	}
+	data, err := m.codec.Encode(pcm)
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	return data
+}
+
+// readAll 从无输出设备的播放器拉取当前可播放的全部采样
+func readAll(p *AudioPlayerNew) []int16 {
+	var out []int16
+	buf := make([]int16, 256)
+	for {
+		n := p.Read(buf)
+		if n == 0 {
+			return out
+		}
+		out = append(out, buf[:n]...)
+	}
+}
+
+func TestRecreatePlayerAfterClose(t *testing.T) {
+	m := newTestManager(t)
+	old := m.Player()
+	if err := m.StartPlaying(); err != nil {
+		t.Fatalf("开始播放失败: %v", err)
+	}
+
+	if err := m.RecreatePlayer(24000, DefaultChannelCount, 60); err != nil {
+		t.Fatalf("重建播放器失败: %v", err)
+	}
+	player := m.Player()
+	if player == old {
+		t.Fatal("重建后仍是原播放器")
+	}
+	if old.IsPlaying() {
+		t.Error("重建后原播放器仍在播放")
+	}
+
+	if err := player.Start(); err != nil {
+		t.Fatalf("新播放器开始播放失败: %v", err)
+	}
+	player.QueueAudio(encodeTestFrame(t, m, 24000*60/1000, 1000))
+	if got := readAll(player); len(got) != 24000*60/1000 {
+		t.Errorf("新播放器播放了%d个采样，期望%d", len(got), 24000*60/1000)
+	}
+
+	// 关闭后重新创建管理器，输出上下文可以再次获取
+	if err := m.Close(); err != nil {
+		t.Fatalf("关闭音频管理器失败: %v", err)
+	}
+	again := newTestManager(t)
+	if again.Player() == nil || again.Player().IsDummyMode() {
+		t.Error("关闭后重新创建的音频管理器没有可用的播放器")
+	}
+}
+
+func TestPauseResumeKeepsQueuedFrames(t *testing.T) {
+	m := newTestManager(t)
+	player := m.Player()
+	if err := player.Start(); err != nil {
+		t.Fatalf("开始播放失败: %v", err)
+	}
+	frameSamples := DefaultSampleRate * DefaultFrameDuration / 1000
+	frame := encodeTestFrame(t, m, frameSamples, 1000)
+	for i := 0; i < 3; i++ {
+		player.QueueAudio(frame)
+	}
+
+	// 先播放第一帧的一部分，暂停时这部分之后的采样也不能丢
+	played := player.Read(make([]int16, frameSamples/2))
+	player.Pause()
+	if !player.IsPaused() {
+		t.Fatal("Pause 后应处于暂停状态")
+	}
+	if n := player.Read(make([]int16, frameSamples)); n != 0 {
+		t.Errorf("暂停期间读出了%d个采样", n)
+	}
+	player.QueueAudio(frame)
+	if n := player.GetQueueLength(); n != 3 {
+		t.Errorf("暂停期间队列中有%d帧，期望3帧", n)
+	}
+
+	player.Resume()
+	if player.IsPaused() {
+		t.Fatal("Resume 后不应处于暂停状态")
+	}
+	played += len(readAll(player))
+	if want := 4 * frameSamples; played != want {
+		t.Errorf("暂停恢复后共播放%d个采样，期望%d", played, want)
+	}
+}
+
+func TestConvertChannels(t *testing.T) {
+	tests := []struct {
+		name     string
+		pcm      []int16
+		from, to int
+		want     []int16
+	}{
+		{"单声道上混为双声道", []int16{1, 2, 3}, 1, 2, []int16{1, 1, 2, 2, 3, 3}},
+		{"双声道下混为单声道取平均", []int16{100, 300, -100, -300, 32767, 32767}, 2, 1, []int16{200, -200, 32767}},
+		{"通道数相同原样返回", []int16{1, 2, 3, 4}, 2, 2, []int16{1, 2, 3, 4}},
+		{"不完整的帧被丢弃", []int16{1, 2, 3}, 2, 1, []int16{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := convertChannels(tt.pcm, tt.from, tt.to)
+			if len(got) != len(tt.want) {
+				t.Fatalf("转换结果为 %v，期望 %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("转换结果为 %v，期望 %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSetAudioParamsWhileQueueing(t *testing.T) {
+	m := newTestManager(t)
+	player := m.Player()
+	if err := player.Start(); err != nil {
+		t.Fatalf("开始播放失败: %v", err)
+	}
+	frame := encodeTestFrame(t, m, DefaultSampleRate*DefaultFrameDuration/1000, 1000)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			player.QueueAudio(frame)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			codec, err := newCodec(DefaultSampleRate, DefaultChannelCount, OpusCodecOptions{FrameDuration: 40})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			player.SetDecoder(codec)
+			player.SetAudioParams(DefaultSampleRate, DefaultChannelCount, 40+20*(i%2))
+		}
+	}()
+	wg.Wait()
+
+	if got := player.PlaybackMetrics().FramesDecoded; got != 200 {
+		t.Errorf("解码了%d帧，期望200", got)
+	}
+	player.mutex.Lock()
+	framesPerBuffer := player.framesPerBuffer
+	player.mutex.Unlock()
+	if want := DefaultSampleRate * 60 / 1000; framesPerBuffer != want {
+		t.Errorf("每帧采样数为%d，期望%d", framesPerBuffer, want)
+	}
+}
+
+// benchmarkFrame60ms 24kHz单声道60ms的一帧编码数据
+func benchmarkFrame60ms(b *testing.B) (*AudioPlayerNew, []byte) {
+	b.Helper()
+	const sampleRate, frameDuration = 24000, 60
+	codec, err := newCodec(sampleRate, 1, OpusCodecOptions{FrameDuration: frameDuration})
+	if err != nil {
+		b.Fatal(err)
+	}
+	player, err := NewAudioPlayerWithOptions(NewPlayerOptions{
+		SampleRate:      sampleRate,
+		ChannelCount:    1,
+		FramesPerBuffer: sampleRate * frameDuration / 1000,
+		Headless:        true,
+	}, codec)
+	if err != nil {
+		b.Fatal(err)
+	}
+	frame, err := codec.Encode(make([]int16, sampleRate*frameDuration/1000))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return player, frame
+}
+
+// clearQueue 清空播放队列，避免基准测试中队列无限增长
+func clearQueue(p *AudioPlayerNew) {
+	p.queueMutex.Lock()
+	p.queue = p.queue[:0]
+	p.queueMutex.Unlock()
+}
+
+// BenchmarkQueueAudio60ms 对比解码缓冲区取自 decodeScratchPool 与每帧按最长帧重新分配的开销
+func BenchmarkQueueAudio60ms(b *testing.B) {
+	b.Run("pool", func(b *testing.B) {
+		player, frame := benchmarkFrame60ms(b)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			player.QueueAudio(frame)
+			clearQueue(player)
+		}
+	})
+
+	b.Run("alloc", func(b *testing.B) {
+		player, frame := benchmarkFrame60ms(b)
+		decoder, _, size := player.decodeState()
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			// 引入缓冲池之前的做法：每帧分配最长帧大小的解码缓冲区
+			pcmBuffer := make([]int16, size)
+			n, err := decoder.Decode(frame, pcmBuffer)
+			if err != nil {
+				b.Fatal(err)
+			}
+			player.enqueueDecoded(pcmBuffer[:n])
+			clearQueue(player)
+		}
+	})
+}
+
+// BenchmarkRenderOutput60ms 输出级每帧的开销，缓冲区复用后稳定状态下不应分配
+func BenchmarkRenderOutput60ms(b *testing.B) {
+	player, _ := benchmarkFrame60ms(b)
+	pcm := make([]int16, 24000*60/1000)
+	for i := range pcm {
+		pcm[i] = int16(i % 2000)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		player.renderOutput(pcm, 1)
+	}
+}
+
+func TestRenderOutputDoesNotAllocate(t *testing.T) {
+	player := NewAudioPlayer2(24000, 1, 60, nil)
+	pcm := make([]int16, 24000*60/1000)
+	player.renderOutput(pcm, 1)
+	allocs := testing.AllocsPerRun(100, func() {
+		player.renderOutput(pcm, 1)
+	})
+	if allocs != 0 {
+		t.Errorf("输出级每帧分配%v次，期望0", allocs)
+	}
+}
+
+// fillDecoder 把整个解码缓冲区填满，并记录收到的缓冲区大小
+type fillDecoder struct {
+	sizes []int
+}
+
+func (d *fillDecoder) Decode(data []byte, pcm []int16) (int, error) {
+	d.sizes = append(d.sizes, len(pcm))
+	for i := range pcm {
+		pcm[i] = 1000
+	}
+	return len(pcm), nil
+}
+
+func (d *fillDecoder) DecodePLC(pcm []int16, frameSize int) (int, error) {
+	return 0, nil
+}
+
+func TestDecodeBufferSizedFromSampleRate(t *testing.T) {
+	tests := []struct {
+		name             string
+		sampleRate       int
+		channels         int
+		maxFrameDuration int
+		want             int
+	}{
+		{"16kHz单声道默认120ms", 16000, 1, 0, 1920},
+		{"16kHz双声道默认120ms", 16000, 2, 0, 3840},
+		{"16kHz单声道最长60ms", 16000, 1, 60, 960},
+		{"24kHz单声道默认120ms", 24000, 1, 0, 2880},
+		{"48kHz单声道默认120ms", 48000, 1, 0, 5760},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := &fillDecoder{}
+			player, err := NewAudioPlayerWithOptions(NewPlayerOptions{
+				SampleRate:       tt.sampleRate,
+				ChannelCount:     tt.channels,
+				FramesPerBuffer:  tt.sampleRate * 60 / 1000,
+				MaxFrameDuration: tt.maxFrameDuration,
+				Headless:         true,
+			}, decoder)
+			if err != nil {
+				t.Fatalf("创建播放器失败: %v", err)
+			}
+			defer player.Close()
+			if err := player.Start(); err != nil {
+				t.Fatalf("开始播放失败: %v", err)
+			}
+
+			player.QueueAudio([]byte{0})
+			if len(decoder.sizes) != 1 || decoder.sizes[0] != tt.want {
+				t.Fatalf("解码缓冲区大小为 %v，期望 %d", decoder.sizes, tt.want)
+			}
+			// 最长帧解码出的采样全部进入播放队列，没有被截断
+			if got := len(readAll(player)); got != tt.want {
+				t.Errorf("可播放%d个采样，期望 %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetAudioParamsGrowsDecodeBuffer(t *testing.T) {
+	decoder := &fillDecoder{}
+	player, err := NewAudioPlayerWithOptions(NewPlayerOptions{
+		SampleRate:       16000,
+		ChannelCount:     1,
+		FramesPerBuffer:  960,
+		MaxFrameDuration: 60,
+		Headless:         true,
+	}, decoder)
+	if err != nil {
+		t.Fatalf("创建播放器失败: %v", err)
+	}
+	defer player.Close()
+
+	// 协商的帧长超过最长帧时长时，解码缓冲区随之扩大
+	player.SetAudioParams(16000, 1, 120)
+	player.QueueAudio([]byte{0})
+	if len(decoder.sizes) != 1 || decoder.sizes[0] != 1920 {
+		t.Errorf("解码缓冲区大小为 %v，期望 1920", decoder.sizes)
+	}
+}
+
+// warnRecorder 记录警告日志，其余级别丢弃
+type warnRecorder struct {
+	mu    sync.Mutex
+	warns []string
+}
+
+func (r *warnRecorder) Debugf(format string, args ...interface{}) {}
+func (r *warnRecorder) Infof(format string, args ...interface{})  {}
+func (r *warnRecorder) Errorf(format string, args ...interface{}) {}
+
+func (r *warnRecorder) Warnf(format string, args ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.warns = append(r.warns, fmt.Sprintf(format, args...))
+}
+
+// jitterWarns 返回与抖动缓冲溢出有关的警告
+func (r *warnRecorder) jitterWarns() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var warns []string
+	for _, w := range r.warns {
+		if strings.Contains(w, "抖动缓冲") {
+			warns = append(warns, w)
+		}
+	}
+	return warns
+}
+
+func TestJitterOverrunWarningOnlyWhenDropping(t *testing.T) {
+	recorder := &warnRecorder{}
+	SetLogger(recorder)
+	t.Cleanup(func() { SetLogger(nil) })
+
+	player, err := NewAudioPlayerWithOptions(NewPlayerOptions{
+		SampleRate:      16000,
+		ChannelCount:    1,
+		FramesPerBuffer: 320,
+		Headless:        true,
+	}, &fillDecoder{})
+	if err != nil {
+		t.Fatalf("创建播放器失败: %v", err)
+	}
+	defer player.Close()
+	player.SetJitterBuffer(20*time.Millisecond, 40*time.Millisecond)
+
+	// 单个超过上限的长帧：溢出但无帧可丢，只计数不警告
+	player.enqueueDecoded(make([]int16, 16000*60/1000))
+	if got := player.JitterBufferStats().Overruns; got != 1 {
+		t.Fatalf("溢出次数为 %d，期望 1", got)
+	}
+	if warns := recorder.jitterWarns(); len(warns) != 0 {
+		t.Fatalf("未丢帧时输出了警告: %v", warns)
+	}
+
+	// 持续溢出丢帧时警告限频，短时间内只输出一次
+	for i := 0; i < 20; i++ {
+		player.enqueueDecoded(make([]int16, 16000*20/1000))
+	}
+	if got := player.JitterBufferStats().Overruns; got < 10 {
+		t.Fatalf("溢出次数为 %d，期望持续溢出", got)
+	}
+	if warns := recorder.jitterWarns(); len(warns) != 1 {
+		t.Errorf("持续溢出时输出了%d条警告，期望1条: %v", len(warns), warns)
+	}
+}
@@ -0,0 +1,153 @@
+//go:build portaudio && cgo && !noaudio
+
+package audio
+
+/*
+#cgo pkg-config: portaudio-2.0
+#include <portaudio.h>
+*/
+import "C"
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+// PortAudio后端（-tags portaudio）在Linux、Windows和macOS上统一提供录音、播放和设备枚举，
+// 替代默认的PulseAudio/WinMM录音器和Oto播放
+
+// paInit 进程内只初始化一次PortAudio，之后一直保持初始化状态
+var paInit struct {
+	once sync.Once
+	err  error
+}
+
+// initPortAudio 初始化PortAudio，重复调用返回首次初始化的结果
+func initPortAudio() error {
+	paInit.once.Do(func() {
+		if code := C.Pa_Initialize(); code != C.paNoError {
+			paInit.err = paError("初始化PortAudio失败", code)
+		}
+	})
+	return paInit.err
+}
+
+// paError 将PortAudio错误码转换为error
+func paError(message string, code C.PaError) error {
+	return fmt.Errorf("%s: %s", message, C.GoString(C.Pa_GetErrorText(code)))
+}
+
+// listDevices 通过Pa_GetDeviceInfo枚举设备，同时支持输入和输出的设备分别列出一次
+func listDevices() ([]DeviceInfo, error) {
+	if err := initPortAudio(); err != nil {
+		return nil, err
+	}
+
+	count := int(C.Pa_GetDeviceCount())
+	if count < 0 {
+		return nil, paError("枚举PortAudio设备失败", C.PaError(count))
+	}
+
+	var devices []DeviceInfo
+	for i := 0; i < count; i++ {
+		info := C.Pa_GetDeviceInfo(C.PaDeviceIndex(i))
+		if info == nil {
+			continue
+		}
+		name := C.GoString(info.name)
+		description := name
+		if hostAPI := C.Pa_GetHostApiInfo(info.hostApi); hostAPI != nil {
+			description = fmt.Sprintf("%s (%s)", name, C.GoString(hostAPI.name))
+		}
+		if info.maxInputChannels > 0 {
+			devices = append(devices, DeviceInfo{
+				Name:        name,
+				Description: description,
+				IsInput:     true,
+				Channels:    int(info.maxInputChannels),
+			})
+		}
+		if info.maxOutputChannels > 0 {
+			devices = append(devices, DeviceInfo{
+				Name:        name,
+				Description: description,
+				IsOutput:    true,
+				Channels:    int(info.maxOutputChannels),
+			})
+		}
+	}
+	return devices, nil
+}
+
+// paDeviceIndex 按名称查找设备序号，name为空时返回默认输入或输出设备
+// 不同主机API下可能有同名设备，取第一个匹配的
+func paDeviceIndex(name string, input bool) (C.PaDeviceIndex, error) {
+	if err := initPortAudio(); err != nil {
+		return C.paNoDevice, err
+	}
+
+	if name == "" {
+		index := C.Pa_GetDefaultOutputDevice()
+		if input {
+			index = C.Pa_GetDefaultInputDevice()
+		}
+		if index == C.paNoDevice {
+			return C.paNoDevice, fmt.Errorf("没有默认的PortAudio%s设备", deviceKind(input))
+		}
+		return index, nil
+	}
+
+	if _, err := findDevice(name, input); err != nil {
+		return C.paNoDevice, err
+	}
+	count := C.Pa_GetDeviceCount()
+	for i := C.PaDeviceIndex(0); i < count; i++ {
+		info := C.Pa_GetDeviceInfo(i)
+		if info == nil || C.GoString(info.name) != name {
+			continue
+		}
+		if (input && info.maxInputChannels > 0) || (!input && info.maxOutputChannels > 0) {
+			return i, nil
+		}
+	}
+	return C.paNoDevice, fmt.Errorf("未找到%s设备 %s", deviceKind(input), name)
+}
+
+// openPaStream 以阻塞读写方式打开一个16位PCM的输入或输出流
+func openPaStream(index C.PaDeviceIndex, input bool, sampleRate, channelCount, framesPerBuffer int) (unsafe.Pointer, error) {
+	info := C.Pa_GetDeviceInfo(index)
+	if info == nil {
+		return nil, fmt.Errorf("无效的PortAudio设备序号: %d", int(index))
+	}
+
+	params := C.PaStreamParameters{
+		device:       index,
+		channelCount: C.int(channelCount),
+		sampleFormat: C.paInt16,
+	}
+	var inputParams, outputParams *C.PaStreamParameters
+	if input {
+		params.suggestedLatency = info.defaultLowInputLatency
+		inputParams = &params
+	} else {
+		params.suggestedLatency = info.defaultHighOutputLatency
+		outputParams = &params
+	}
+
+	var stream unsafe.Pointer
+	code := C.Pa_OpenStream(&stream, inputParams, outputParams, C.double(sampleRate), C.ulong(framesPerBuffer), C.paClipOff, nil, nil)
+	if code != C.paNoError {
+		return nil, paError(fmt.Sprintf("打开PortAudio%s设备 %s 失败", deviceKind(input), C.GoString(info.name)), code)
+	}
+	if code := C.Pa_StartStream(stream); code != C.paNoError {
+		C.Pa_CloseStream(stream)
+		return nil, paError("启动PortAudio音频流失败", code)
+	}
+	return stream, nil
+}
+
+// closePaStream 停止并关闭音频流，输出流会先播放完已写入的数据
+func closePaStream(stream unsafe.Pointer) {
+	C.Pa_StopStream(stream)
+	C.Pa_CloseStream(stream)
+}
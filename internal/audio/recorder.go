@@ -1,5 +1,7 @@
 package audio
 
+import "math"
+
 // 这里已移除portaudio相关内容，如需录音请用oto库实现。
 
 type Recorder interface {
@@ -8,10 +10,65 @@ type Recorder interface {
 	Close() error
 	SetAudioDataCallback(cb func([]byte))
 	SetPCMDataCallback(cb func([]int16, int))
+	// SetLevelCallback 设置输入电平回调，每采集一帧回调一次，rms和peak归一化到0..1
+	SetLevelCallback(cb func(rms float64, peak float64))
 	IsRecording() bool
+	// Pause 暂停回调录音数据。设备保持打开（仍然占用采集设备），期间采集到的数据被丢弃，
+	// 比停止后重新打开设备快；未在录音时无效果，重新开始录音后自动取消暂停
+	Pause()
+	// Resume 恢复回调录音数据
+	Resume()
+}
+
+// RecorderOptions 录音器选项，描述采集设备实际打开的格式
+type RecorderOptions struct {
+	SampleRate    int    // 采样率
+	ChannelCount  int    // 通道数
+	FrameDuration int    // 每次回调的帧持续时间（毫秒）
+	DeviceName    string // 录音设备名称，为空时使用默认设备；目前仅Linux（PulseAudio）支持，可通过 GetAudioDevices 查询
+	RingFrames    int    // 设备读取与编码之间的缓冲帧数，为0时使用 DefaultCaptureRingFrames
 }
 
-// NewRecorder 返回当前平台的录音器实例
+// NewRecorder 返回当前平台的录音器实例（使用默认选项）
 func NewRecorder() Recorder {
-	return newRecorder()
+	return NewRecorderWithOptions(RecorderOptions{})
+}
+
+// NewRecorderWithOptions 使用指定选项返回当前平台的录音器实例
+func NewRecorderWithOptions(options RecorderOptions) Recorder {
+	if options.SampleRate <= 0 {
+		options.SampleRate = DefaultSampleRate
+	}
+	if options.ChannelCount <= 0 {
+		options.ChannelCount = DefaultChannelCount
+	}
+	if options.FrameDuration <= 0 {
+		options.FrameDuration = DefaultFrameDuration
+	}
+	return newRecorder(options)
+}
+
+// computeLevel 计算一帧PCM的均方根和峰值，结果归一化到0..1
+func computeLevel(pcm []int16) (rms float64, peak float64) {
+	if len(pcm) == 0 {
+		return 0, 0
+	}
+
+	var sum float64
+	var maxAbs int32
+	for _, v := range pcm {
+		s := int32(v)
+		if s < 0 {
+			s = -s
+		}
+		if s > maxAbs {
+			maxAbs = s
+		}
+		f := float64(v)
+		sum += f * f
+	}
+
+	rms = math.Sqrt(sum/float64(len(pcm))) / 32768
+	peak = float64(maxAbs) / 32768
+	return rms, peak
 }
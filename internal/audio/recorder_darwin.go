@@ -1,4 +1,4 @@
-//go:build darwin
+//go:build darwin && !portaudio && !noaudio
 
 package audio
 
@@ -21,11 +21,12 @@ type darwinRecorder struct {
 	isRecording bool
 	onAudioData func([]byte)
 	onPCMData   func([]int16, int)
+	onLevel     func(rms float64, peak float64)
 	stopCh      chan struct{}
 	mu          sync.Mutex
 }
 
-func newRecorder() Recorder {
+func newRecorder(options RecorderOptions) Recorder {
 	return &darwinRecorder{}
 }
 
@@ -64,6 +65,16 @@ func (r *darwinRecorder) SetAudioDataCallback(cb func([]byte)) {
 func (r *darwinRecorder) SetPCMDataCallback(cb func([]int16, int)) {
 	r.onPCMData = cb
 }
+func (r *darwinRecorder) SetLevelCallback(cb func(rms float64, peak float64)) {
+	r.onLevel = cb
+}
+
+// Pause 录音未实现，无需处理
+func (r *darwinRecorder) Pause() {}
+
+// Resume 录音未实现，无需处理
+func (r *darwinRecorder) Resume() {}
+
 func (r *darwinRecorder) IsRecording() bool {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -1,4 +1,4 @@
-//go:build linux
+//go:build linux && !portaudio && !noaudio
 
 package audio
 
@@ -10,12 +10,12 @@ package audio
 
 typedef struct pa_simple pa_simple;
 
-static pa_simple* open_pulse_capture(unsigned int sampleRate, int channels, int* error) {
+static pa_simple* open_pulse_capture(const char* device, unsigned int sampleRate, int channels, int* error) {
     pa_sample_spec ss;
     ss.format = PA_SAMPLE_S16LE;
     ss.rate = sampleRate;
     ss.channels = channels;
-    return pa_simple_new(NULL, "xiaozhi-go", PA_STREAM_RECORD, NULL, "record", &ss, NULL, NULL, error);
+    return pa_simple_new(NULL, "xiaozhi-go", PA_STREAM_RECORD, device, "record", &ss, NULL, NULL, error);
 }
 static int read_pulse(pa_simple* s, void* buf, int bytes, int* error) {
     return pa_simple_read(s, buf, bytes, error);
@@ -27,7 +27,10 @@ static void close_pulse(pa_simple* s) {
 import "C"
 import (
 	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 	"unsafe"
 )
 
@@ -35,14 +38,18 @@ type linuxRecorder struct {
 	isRecording bool
 	onAudioData func([]byte)
 	onPCMData   func([]int16, int)
+	onLevel     func(rms float64, peak float64)
 	stopCh      chan struct{}
 	mu          sync.Mutex
 	handle      *C.pa_simple
 	wg          sync.WaitGroup
+	options     RecorderOptions
+	ring        *captureRing
+	paused      atomic.Bool
 }
 
-func newRecorder() Recorder {
-	return &linuxRecorder{}
+func newRecorder(options RecorderOptions) Recorder {
+	return &linuxRecorder{options: options, ring: newCaptureRing(options.RingFrames)}
 }
 
 func (r *linuxRecorder) StartRecording(codec Encoder) error {
@@ -52,48 +59,77 @@ func (r *linuxRecorder) StartRecording(codec Encoder) error {
 		return errors.New("录音已在进行中")
 	}
 	var errorCode C.int
-	sampleRate := C.uint(16000)
-	channels := C.int(1)
-	framesPerBuffer := 960 // 60ms at 16kHz
+	sampleRate := C.uint(r.options.SampleRate)
+	channels := C.int(r.options.ChannelCount)
+	framesPerBuffer := r.options.SampleRate * r.options.FrameDuration / 1000
 	bytesPerFrame := int(channels) * 2
 	bufSize := framesPerBuffer * bytesPerFrame
 
-	h := C.open_pulse_capture(sampleRate, channels, &errorCode)
+	// 设备名为空时传NULL，使用PulseAudio默认输入设备
+	var device *C.char
+	if r.options.DeviceName != "" {
+		device = C.CString(r.options.DeviceName)
+		defer C.free(unsafe.Pointer(device))
+	}
+	h := C.open_pulse_capture(device, sampleRate, channels, &errorCode)
 	if h == nil {
+		if r.options.DeviceName != "" {
+			return fmt.Errorf("打开PulseAudio录音设备 %s 失败", r.options.DeviceName)
+		}
 		return errors.New("打开PulseAudio录音设备失败")
 	}
 	r.handle = h
 	r.isRecording = true
 	r.stopCh = make(chan struct{})
-	r.wg.Add(1)
+	r.ring.reset()
+	r.paused.Store(false)
+	r.wg.Add(2)
 
-	go func() {
+	// 读取goroutine只把帧放入环形缓冲区，编码和回调在处理goroutine中进行，
+	// 处理变慢时丢弃最旧的帧而不是阻塞设备读取
+	samples := framesPerBuffer * int(channels)
+	go func(stopCh <-chan struct{}) {
 		defer r.wg.Done()
-		buf := make([]int16, framesPerBuffer*int(channels))
-		byteBuf := (*[1 << 20]byte)(unsafe.Pointer(&buf[0]))[:bufSize]
 		for {
 			select {
-			case <-r.stopCh:
+			case <-stopCh:
 				return
 			default:
 			}
-			if C.read_pulse(r.handle, unsafe.Pointer(&buf[0]), C.int(bufSize), &errorCode) != 0 {
+			buf := make([]int16, samples)
+			if C.read_pulse(h, unsafe.Pointer(&buf[0]), C.int(bufSize), &errorCode) != 0 {
 				continue // 采集失败，跳过
 			}
-			// 回调PCM数据
+			// 暂停时照常读取以免设备缓冲区积压旧数据，恢复后直接从最新的音频开始
+			if r.paused.Load() {
+				continue
+			}
+			r.ring.push(buf)
+		}
+	}(r.stopCh)
+
+	frameTimeout := 2 * time.Duration(r.options.FrameDuration) * time.Millisecond
+	go func(stopCh <-chan struct{}) {
+		defer r.wg.Done()
+		for {
+			buf, ok := r.ring.pop(stopCh, frameTimeout)
+			if !ok {
+				return
+			}
+			// 回调输入电平
+			if r.onLevel != nil {
+				r.onLevel(computeLevel(buf))
+			}
+			// 回调PCM数据，buf由本goroutine独占，无需再复制
 			if r.onPCMData != nil {
-				pcmCopy := make([]int16, framesPerBuffer*int(channels))
-				copy(pcmCopy, buf[:framesPerBuffer*int(channels)])
-				r.onPCMData(pcmCopy, framesPerBuffer*int(channels))
+				r.onPCMData(buf, len(buf))
 			}
 			// 回调原始字节数据
 			if r.onAudioData != nil {
-				dataCopy := make([]byte, bufSize)
-				copy(dataCopy, byteBuf[:bufSize])
-				r.onAudioData(dataCopy)
+				r.onAudioData(pcmToBytes(buf))
 			}
 		}
-	}()
+	}(r.stopCh)
 	return nil
 }
 
@@ -129,8 +165,25 @@ func (r *linuxRecorder) SetPCMDataCallback(cb func([]int16, int)) {
 	r.onPCMData = cb
 }
 
+func (r *linuxRecorder) SetLevelCallback(cb func(rms float64, peak float64)) {
+	r.onLevel = cb
+}
+
+func (r *linuxRecorder) Pause() {
+	r.paused.Store(true)
+}
+
+func (r *linuxRecorder) Resume() {
+	r.paused.Store(false)
+}
+
 func (r *linuxRecorder) IsRecording() bool {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	return r.isRecording
 }
+
+// CaptureStats 实现 CaptureStatsProvider 接口
+func (r *linuxRecorder) CaptureStats() CaptureStats {
+	return r.ring.stats()
+}
@@ -0,0 +1,175 @@
+package audio
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// MockRecorder 用于测试的录音器，不访问采集设备，按帧时长定时回调预置的PCM帧
+// 预置帧依次循环发送；未提供帧时发送静音帧
+type MockRecorder struct {
+	mu          sync.Mutex
+	options     RecorderOptions
+	frames      [][]int16
+	next        int
+	isRecording bool
+	stopCh      chan struct{}
+	wg          sync.WaitGroup
+	onAudioData func([]byte)
+	onPCMData   func([]int16, int)
+	onLevel     func(rms float64, peak float64)
+	paused      bool
+}
+
+// NewMockRecorder 创建测试录音器，frames为依次发送的PCM帧（交织采样）
+func NewMockRecorder(options RecorderOptions, frames [][]int16) *MockRecorder {
+	if options.SampleRate <= 0 {
+		options.SampleRate = DefaultSampleRate
+	}
+	if options.ChannelCount <= 0 {
+		options.ChannelCount = DefaultChannelCount
+	}
+	if options.FrameDuration <= 0 {
+		options.FrameDuration = DefaultFrameDuration
+	}
+	return &MockRecorder{options: options, frames: frames}
+}
+
+// StartRecording 实现Recorder接口，开始按帧时长发送PCM帧
+func (r *MockRecorder) StartRecording(codec Encoder) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.isRecording {
+		return errors.New("录音已在进行中")
+	}
+	r.isRecording = true
+	r.paused = false
+	r.stopCh = make(chan struct{})
+	r.wg.Add(1)
+	go r.run(r.stopCh)
+	return nil
+}
+
+// run 定时发送下一帧直到停止
+func (r *MockRecorder) run(stopCh chan struct{}) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(time.Duration(r.options.FrameDuration) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			r.Emit()
+		}
+	}
+}
+
+// Emit 立即发送下一帧，测试可用它代替定时器逐帧驱动；暂停时该帧被丢弃
+func (r *MockRecorder) Emit() {
+	r.mu.Lock()
+	frame := r.nextFrameLocked()
+	if r.paused {
+		r.mu.Unlock()
+		return
+	}
+	onLevel := r.onLevel
+	onPCMData := r.onPCMData
+	onAudioData := r.onAudioData
+	r.mu.Unlock()
+
+	if onLevel != nil {
+		onLevel(computeLevel(frame))
+	}
+	if onPCMData != nil {
+		onPCMData(append([]int16(nil), frame...), len(frame))
+	}
+	if onAudioData != nil {
+		onAudioData(pcmToBytes(frame))
+	}
+}
+
+// nextFrameLocked 返回下一帧，调用方需持有mu
+func (r *MockRecorder) nextFrameLocked() []int16 {
+	if len(r.frames) == 0 {
+		samples := r.options.SampleRate * r.options.FrameDuration / 1000 * r.options.ChannelCount
+		return make([]int16, samples)
+	}
+	frame := r.frames[r.next%len(r.frames)]
+	r.next++
+	return frame
+}
+
+// pcmToBytes 将PCM样本按小端序转换为字节
+func pcmToBytes(pcm []int16) []byte {
+	data := make([]byte, len(pcm)*2)
+	for i, v := range pcm {
+		data[2*i] = byte(v)
+		data[2*i+1] = byte(v >> 8)
+	}
+	return data
+}
+
+// StopRecording 实现Recorder接口
+func (r *MockRecorder) StopRecording() error {
+	r.mu.Lock()
+	if !r.isRecording {
+		r.mu.Unlock()
+		return nil
+	}
+	close(r.stopCh)
+	r.isRecording = false
+	r.mu.Unlock()
+
+	r.wg.Wait()
+	return nil
+}
+
+// Close 实现Recorder接口
+func (r *MockRecorder) Close() error {
+	return r.StopRecording()
+}
+
+// SetAudioDataCallback 实现Recorder接口
+func (r *MockRecorder) SetAudioDataCallback(cb func([]byte)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onAudioData = cb
+}
+
+// SetPCMDataCallback 实现Recorder接口
+func (r *MockRecorder) SetPCMDataCallback(cb func([]int16, int)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onPCMData = cb
+}
+
+// SetLevelCallback 实现Recorder接口
+func (r *MockRecorder) SetLevelCallback(cb func(rms float64, peak float64)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onLevel = cb
+}
+
+// Pause 实现Recorder接口
+func (r *MockRecorder) Pause() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.paused = true
+}
+
+// Resume 实现Recorder接口
+func (r *MockRecorder) Resume() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.paused = false
+}
+
+// IsRecording 实现Recorder接口
+func (r *MockRecorder) IsRecording() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.isRecording
+}
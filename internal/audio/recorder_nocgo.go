@@ -0,0 +1,48 @@
+//go:build !cgo || noaudio
+
+package audio
+
+import "errors"
+
+// nullRecorder CGO禁用或使用noaudio标签构建时的录音器，无法访问采集设备
+type nullRecorder struct {
+	onAudioData func([]byte)
+	onPCMData   func([]int16, int)
+	onLevel     func(rms float64, peak float64)
+}
+
+func newRecorder(options RecorderOptions) Recorder {
+	return &nullRecorder{}
+}
+
+func (r *nullRecorder) StartRecording(codec Encoder) error {
+	return errors.New("当前构建未启用CGO或使用了noaudio标签，不支持录音")
+}
+
+func (r *nullRecorder) StopRecording() error {
+	return nil
+}
+
+func (r *nullRecorder) Close() error {
+	return nil
+}
+
+func (r *nullRecorder) SetAudioDataCallback(cb func([]byte)) {
+	r.onAudioData = cb
+}
+
+func (r *nullRecorder) SetPCMDataCallback(cb func([]int16, int)) {
+	r.onPCMData = cb
+}
+
+func (r *nullRecorder) SetLevelCallback(cb func(rms float64, peak float64)) {
+	r.onLevel = cb
+}
+
+func (r *nullRecorder) Pause() {}
+
+func (r *nullRecorder) Resume() {}
+
+func (r *nullRecorder) IsRecording() bool {
+	return false
+}
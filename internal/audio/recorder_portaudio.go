@@ -0,0 +1,171 @@
+//go:build portaudio && cgo && !noaudio
+
+package audio
+
+/*
+#include <portaudio.h>
+*/
+import "C"
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// portaudioRecorder 基于PortAudio阻塞读取的录音器，各平台行为一致，支持按名称选择设备
+type portaudioRecorder struct {
+	isRecording bool
+	onAudioData func([]byte)
+	onPCMData   func([]int16, int)
+	onLevel     func(rms float64, peak float64)
+	stopCh      chan struct{}
+	mu          sync.Mutex
+	stream      unsafe.Pointer
+	wg          sync.WaitGroup
+	options     RecorderOptions
+	ring        *captureRing
+	paused      atomic.Bool
+}
+
+func newRecorder(options RecorderOptions) Recorder {
+	return &portaudioRecorder{options: options, ring: newCaptureRing(options.RingFrames)}
+}
+
+func (r *portaudioRecorder) StartRecording(codec Encoder) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.isRecording {
+		return errors.New("录音已在进行中")
+	}
+
+	index, err := paDeviceIndex(r.options.DeviceName, true)
+	if err != nil {
+		return err
+	}
+	channels := r.options.ChannelCount
+	framesPerBuffer := r.options.SampleRate * r.options.FrameDuration / 1000
+	stream, err := openPaStream(index, true, r.options.SampleRate, channels, framesPerBuffer)
+	if err != nil {
+		return err
+	}
+
+	r.stream = stream
+	r.isRecording = true
+	r.stopCh = make(chan struct{})
+	r.ring.reset()
+	r.paused.Store(false)
+	r.wg.Add(2)
+
+	// 读取goroutine只把帧放入环形缓冲区，编码和回调在处理goroutine中进行
+	go func(stopCh <-chan struct{}) {
+		defer r.wg.Done()
+		for {
+			select {
+			case <-stopCh:
+				return
+			default:
+			}
+			buf := make([]int16, framesPerBuffer*channels)
+			// 输入溢出只表示丢了少量采样，读到的数据仍然有效
+			code := C.Pa_ReadStream(stream, unsafe.Pointer(&buf[0]), C.ulong(framesPerBuffer))
+			if code != C.paNoError && code != C.paInputOverflowed {
+				logger.Warnf("%v", paError("读取PortAudio录音数据失败", code))
+				continue
+			}
+			// 暂停时照常读取以免设备缓冲区积压旧数据，恢复后直接从最新的音频开始
+			if r.paused.Load() {
+				continue
+			}
+			r.ring.push(buf)
+		}
+	}(r.stopCh)
+
+	frameTimeout := 2 * time.Duration(r.options.FrameDuration) * time.Millisecond
+	go func(stopCh <-chan struct{}) {
+		defer r.wg.Done()
+		for {
+			buf, ok := r.ring.pop(stopCh, frameTimeout)
+			if !ok {
+				return
+			}
+
+			r.mu.Lock()
+			onLevel, onPCMData, onAudioData := r.onLevel, r.onPCMData, r.onAudioData
+			r.mu.Unlock()
+
+			if onLevel != nil {
+				onLevel(computeLevel(buf))
+			}
+			if onPCMData != nil {
+				onPCMData(buf, len(buf))
+			}
+			if onAudioData != nil {
+				onAudioData(pcmToBytes(buf))
+			}
+		}
+	}(r.stopCh)
+	return nil
+}
+
+func (r *portaudioRecorder) StopRecording() error {
+	r.mu.Lock()
+	if !r.isRecording {
+		r.mu.Unlock()
+		return nil
+	}
+	close(r.stopCh)
+	r.isRecording = false
+	stream := r.stream
+	r.stream = nil
+	r.mu.Unlock()
+
+	// 等待录音goroutine退出后再关闭音频流
+	r.wg.Wait()
+	if stream != nil {
+		closePaStream(stream)
+	}
+	return nil
+}
+
+func (r *portaudioRecorder) Close() error {
+	return r.StopRecording()
+}
+
+func (r *portaudioRecorder) SetAudioDataCallback(cb func([]byte)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onAudioData = cb
+}
+
+func (r *portaudioRecorder) SetPCMDataCallback(cb func([]int16, int)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onPCMData = cb
+}
+
+func (r *portaudioRecorder) SetLevelCallback(cb func(rms float64, peak float64)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onLevel = cb
+}
+
+func (r *portaudioRecorder) Pause() {
+	r.paused.Store(true)
+}
+
+func (r *portaudioRecorder) Resume() {
+	r.paused.Store(false)
+}
+
+func (r *portaudioRecorder) IsRecording() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.isRecording
+}
+
+// CaptureStats 实现 CaptureStatsProvider 接口
+func (r *portaudioRecorder) CaptureStats() CaptureStats {
+	return r.ring.stats()
+}
@@ -1,4 +1,4 @@
-//go:build windows
+//go:build windows && !portaudio && !noaudio
 
 package audio
 
@@ -78,6 +78,7 @@ import "C"
 import (
 	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 )
@@ -86,12 +87,15 @@ type winRecorder struct {
 	isRecording bool
 	onAudioData func([]byte)
 	onPCMData   func([]int16, int)
+	onLevel     func(rms float64, peak float64)
 	stopCh      chan struct{}
 	mu          sync.Mutex
+	options     RecorderOptions
+	paused      atomic.Bool
 }
 
-func newRecorder() Recorder {
-	return &winRecorder{}
+func newRecorder(options RecorderOptions) Recorder {
+	return &winRecorder{options: options}
 }
 
 func (r *winRecorder) StartRecording(codec Encoder) error {
@@ -100,15 +104,16 @@ func (r *winRecorder) StartRecording(codec Encoder) error {
 	if r.isRecording {
 		return errors.New("录音已在进行中")
 	}
-	sampleRate := 16000
-	channels := 1
-	framesPerBuffer := 960 // 60ms at 16kHz
+	sampleRate := r.options.SampleRate
+	channels := r.options.ChannelCount
+	framesPerBuffer := sampleRate * r.options.FrameDuration / 1000
 
 	if C.start_recording(C.int(sampleRate), C.int(channels), C.int(framesPerBuffer)) != 0 {
 		return errors.New("打开Windows录音设备失败")
 	}
 	r.isRecording = true
 	r.stopCh = make(chan struct{})
+	r.paused.Store(false)
 
 	go func() {
 		for {
@@ -117,10 +122,17 @@ func (r *winRecorder) StartRecording(codec Encoder) error {
 				return
 			default:
 			}
+			// 暂停时仍然取走并重新提交缓冲区，只是不回调数据
 			n := C.read_pcm(C.int(framesPerBuffer))
-			if int(n) > 0 {
+			if int(n) > 0 && r.paused.Load() {
+				time.Sleep(10 * time.Millisecond)
+			} else if int(n) > 0 {
 				// 取出缓冲区数据
 				buf := (*[1 << 20]C.short)(unsafe.Pointer(C.buffer))[:int(n)]
+				// 回调输入电平
+				if r.onLevel != nil {
+					r.onLevel(computeLevel((*[1 << 20]int16)(unsafe.Pointer(C.buffer))[:int(n)]))
+				}
 				// 回调PCM数据
 				if r.onPCMData != nil {
 					pcm := make([]int16, int(n))
@@ -171,6 +183,18 @@ func (r *winRecorder) SetPCMDataCallback(cb func([]int16, int)) {
 	r.onPCMData = cb
 }
 
+func (r *winRecorder) SetLevelCallback(cb func(rms float64, peak float64)) {
+	r.onLevel = cb
+}
+
+func (r *winRecorder) Pause() {
+	r.paused.Store(true)
+}
+
+func (r *winRecorder) Resume() {
+	r.paused.Store(false)
+}
+
 func (r *winRecorder) IsRecording() bool {
 	r.mu.Lock()
 	defer r.mu.Unlock()
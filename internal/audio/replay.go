@@ -0,0 +1,196 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// replaySource 按顺序读取回放文件中交错排列的PCM采样
+type replaySource interface {
+	ReadFrame(pcm []int16) (int, error)
+	SampleRate() int
+	Channels() int
+	Close() error
+}
+
+// opusReplaySource 解码Ogg-Opus文件中的数据包，按 replaySource 的方式提供PCM
+type opusReplaySource struct {
+	reader     *OggOpusReader
+	decoder    Codec // 回放专用的解码器，不与播放器共用，避免打乱播放的解码状态
+	sampleRate int
+	decoded    []int16 // 解码缓冲区
+	pending    []int16 // 已解码、尚未读取的采样
+	skip       int     // 开头仍需丢弃的采样数（pre-skip）
+}
+
+func newOpusReplaySource(path string, sampleRate int) (*opusReplaySource, error) {
+	reader, err := NewOggOpusReader(path)
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := newCodec(sampleRate, reader.Channels(), OpusCodecOptions{})
+	if err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("创建回放解码器失败: %v", err)
+	}
+	return &opusReplaySource{
+		reader:     reader,
+		decoder:    decoder,
+		sampleRate: sampleRate,
+		decoded:    make([]int16, sampleRate*DefaultMaxFrameDuration/1000*reader.Channels()),
+		skip:       reader.PreSkip() * sampleRate / 48000 * reader.Channels(),
+	}, nil
+}
+
+func (s *opusReplaySource) ReadFrame(pcm []int16) (int, error) {
+	for len(s.pending) == 0 {
+		packet, err := s.reader.ReadPacket()
+		if err != nil {
+			return 0, err
+		}
+		if len(packet) == 0 {
+			continue
+		}
+		n, err := s.decoder.Decode(packet, s.decoded)
+		if err != nil {
+			return 0, fmt.Errorf("解码Opus数据包失败: %v", err)
+		}
+		s.pending = s.decoded[:n]
+		if s.skip > 0 {
+			dropped := min(s.skip, len(s.pending))
+			s.pending = s.pending[dropped:]
+			s.skip -= dropped
+		}
+	}
+	n := copy(pcm, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+func (s *opusReplaySource) SampleRate() int {
+	return s.sampleRate
+}
+
+func (s *opusReplaySource) Channels() int {
+	return s.reader.Channels()
+}
+
+func (s *opusReplaySource) Close() error {
+	s.decoder.Close()
+	return s.reader.Close()
+}
+
+// openReplaySource 按扩展名打开WAV或Ogg-Opus文件，Opus按编码采样率解码
+func (m *AudioManagerNew) openReplaySource(path string) (replaySource, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".wav":
+		reader, err := NewWAVReader(path)
+		if err != nil {
+			return nil, err
+		}
+		return reader, nil
+	case ".opus", ".ogg":
+		source, err := newOpusReplaySource(path, m.sampleRate)
+		if err != nil {
+			return nil, err
+		}
+		return source, nil
+	default:
+		return nil, fmt.Errorf("不支持的回放文件格式: %s（支持.wav/.opus/.ogg）", path)
+	}
+}
+
+// ReplayFile 以音频文件代替麦克风：读取WAV或Ogg-Opus文件，按帧时长的实时节奏送入录音处理流程，
+// 经过与麦克风录音相同的重采样、预处理、PCM回调、编码和静音抑制后从音频数据回调发出，用于复现对话和端到端测试。
+// 文件的通道数必须与编码参数一致，采样率不同时自动重采样，最后不足一帧的部分补静音。
+// 阻塞直到文件读完或ctx取消；录音进行中或已有文件在回放时返回错误
+func (m *AudioManagerNew) ReplayFile(ctx context.Context, path string) error {
+	if m.IsRecording() {
+		return errors.New("录音进行中，无法回放音频文件")
+	}
+	if !m.replaying.CompareAndSwap(false, true) {
+		return errors.New("已有音频文件正在回放")
+	}
+	defer m.replaying.Store(false)
+
+	if err := m.checkRecordingWired(ctx); err != nil {
+		return err
+	}
+
+	source, err := m.openReplaySource(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	if source.Channels() != m.channelCount {
+		return fmt.Errorf("回放文件通道数(%d)与编码参数(%d)不一致", source.Channels(), m.channelCount)
+	}
+	// 录音处理流程的输入是采集设备采样率的PCM
+	var resampler *Resampler
+	if source.SampleRate() != m.inputSampleRate {
+		resampler, err = NewResampler(source.SampleRate(), m.inputSampleRate, m.channelCount)
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.captureResampler != nil {
+		m.captureResampler.Reset()
+	}
+	m.resetSilenceGate()
+	defer m.resetSilenceGate()
+
+	frameSamples := m.inputSampleRate * m.frameDuration / 1000 * m.channelCount
+	readBuffer := make([]int16, source.SampleRate()*m.frameDuration/1000*m.channelCount)
+	ticker := time.NewTicker(time.Duration(m.frameDuration) * time.Millisecond)
+	defer ticker.Stop()
+
+	logger.Infof("开始回放音频文件: %s", path)
+	var pending []int16
+	frames := 0
+	eof := false
+	for {
+		for !eof && len(pending) < frameSamples {
+			n, err := source.ReadFrame(readBuffer)
+			if n > 0 {
+				pcm := readBuffer[:n]
+				if resampler != nil {
+					pcm = resampler.Process(pcm)
+				}
+				pending = append(pending, pcm...)
+			}
+			if err == io.EOF {
+				eof = true
+			} else if err != nil {
+				return fmt.Errorf("读取回放文件失败: %v", err)
+			}
+		}
+		if len(pending) == 0 {
+			break
+		}
+
+		frame := make([]int16, frameSamples)
+		pending = pending[copy(frame, pending):]
+		m.handlePCM(frame, frameSamples)
+		frames++
+		if eof && len(pending) == 0 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Infof("音频文件回放已取消，已送出%d帧", frames)
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	logger.Infof("音频文件回放完毕，共%d帧", frames)
+	return nil
+}
@@ -0,0 +1,220 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newReplayManager 创建16kHz、20ms帧长的无设备音频管理器，录音器按frames逐帧发送
+func newReplayManager(t *testing.T, frames [][]int16) (*AudioManagerNew, *MockRecorder) {
+	t.Helper()
+	recorder := NewMockRecorder(RecorderOptions{SampleRate: 16000, ChannelCount: 1, FrameDuration: 20}, frames)
+	m, err := NewAudioManagerWithOptions(AudioManagerOptions{
+		Recorder:       recorder,
+		SampleRate:     16000,
+		ChannelCount:   1,
+		FrameDuration:  20,
+		HeadlessOutput: true,
+	})
+	if err != nil {
+		t.Fatalf("创建音频管理器失败: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m, recorder
+}
+
+// writeTestWAV 把pcm写入临时WAV文件并返回路径
+func writeTestWAV(t *testing.T, pcm []int16, sampleRate, channels int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "replay.wav")
+	w, err := NewWAVWriter(path, sampleRate, channels)
+	if err != nil {
+		t.Fatalf("创建WAV文件失败: %v", err)
+	}
+	if err := w.Write(pcm); err != nil {
+		t.Fatalf("写入WAV文件失败: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("关闭WAV文件失败: %v", err)
+	}
+	return path
+}
+
+// collectPackets 记录音频数据回调收到的每个编码包
+func collectPackets(m *AudioManagerNew) func() [][]byte {
+	var mu sync.Mutex
+	var packets [][]byte
+	m.SetAudioDataCallback(func(data []byte) {
+		mu.Lock()
+		packets = append(packets, append([]byte(nil), data...))
+		mu.Unlock()
+	})
+	return func() [][]byte {
+		mu.Lock()
+		defer mu.Unlock()
+		return packets
+	}
+}
+
+func TestReplayFileMatchesLiveCapture(t *testing.T) {
+	// 4.5帧的正弦波，最后半帧补静音
+	const frameSamples = 16000 * 20 / 1000
+	pcm := sine(440, 16000, frameSamples*9/2, 8000)
+	path := writeTestWAV(t, pcm, 16000, 1)
+
+	frames := make([][]int16, 5)
+	for i := range frames {
+		frames[i] = make([]int16, frameSamples)
+		copy(frames[i], pcm[min(i*frameSamples, len(pcm)):])
+	}
+
+	live, recorder := newReplayManager(t, frames)
+	livePackets := collectPackets(live)
+	// 逐帧驱动录音器，与麦克风采集走同一条处理流程
+	for range frames {
+		recorder.Emit()
+	}
+
+	replay, _ := newReplayManager(t, nil)
+	replayPackets := collectPackets(replay)
+	var pcmFrames [][]int16
+	replay.SetPCMDataCallback(func(pcm []int16, size int) {
+		pcmFrames = append(pcmFrames, append([]int16(nil), pcm[:size]...))
+	})
+	start := time.Now()
+	if err := replay.ReplayFile(context.Background(), path); err != nil {
+		t.Fatalf("回放失败: %v", err)
+	}
+	// 帧之间按实时节奏等待，5帧至少间隔4个帧时长
+	if elapsed := time.Since(start); elapsed < 4*20*time.Millisecond {
+		t.Errorf("回放5帧只用了%v，没有按实时节奏发送", elapsed)
+	}
+
+	if len(pcmFrames) != len(frames) {
+		t.Fatalf("PCM回调收到%d帧，期望%d帧", len(pcmFrames), len(frames))
+	}
+	for i, frame := range pcmFrames {
+		if len(frame) != frameSamples {
+			t.Fatalf("第%d帧有%d个采样，期望%d", i, len(frame), frameSamples)
+		}
+		for j := range frame {
+			if frame[j] != frames[i][j] {
+				t.Fatalf("第%d帧第%d个采样为%d，期望%d", i, j, frame[j], frames[i][j])
+			}
+		}
+	}
+
+	got, want := replayPackets(), livePackets()
+	if len(got) != len(want) {
+		t.Fatalf("回放发出%d个编码包，麦克风录音发出%d个", len(got), len(want))
+	}
+	for i := range got {
+		if string(got[i]) != string(want[i]) {
+			t.Errorf("第%d个编码包与麦克风录音不一致", i)
+		}
+	}
+}
+
+func TestReplayFileResamples(t *testing.T) {
+	// 24kHz的200ms音频重采样到16kHz后为10帧
+	path := writeTestWAV(t, sine(440, 24000, 24000/5, 8000), 24000, 1)
+	m, _ := newReplayManager(t, nil)
+	var samples, frames int
+	m.SetPCMDataCallback(func(pcm []int16, size int) {
+		samples += size
+		frames++
+	})
+	if err := m.ReplayFile(context.Background(), path); err != nil {
+		t.Fatalf("回放失败: %v", err)
+	}
+	if frames < 10 || frames > 11 {
+		t.Errorf("回放了%d帧，期望约10帧", frames)
+	}
+	if samples != frames*320 {
+		t.Errorf("回放了%d个采样，不是整帧", samples)
+	}
+}
+
+func TestReplayFileErrors(t *testing.T) {
+	mono := writeTestWAV(t, make([]int16, 320), 16000, 1)
+	stereo := writeTestWAV(t, make([]int16, 640), 16000, 2)
+
+	t.Run("未设置回调", func(t *testing.T) {
+		m, _ := newReplayManager(t, nil)
+		if err := m.ReplayFile(context.Background(), mono); err == nil {
+			t.Error("未设置录音数据回调时应返回错误")
+		}
+	})
+	t.Run("不支持的格式", func(t *testing.T) {
+		m, _ := newReplayManager(t, nil)
+		collectPackets(m)
+		if err := m.ReplayFile(context.Background(), filepath.Join(t.TempDir(), "a.mp3")); err == nil {
+			t.Error(".mp3文件应被拒绝")
+		}
+	})
+	t.Run("文件不存在", func(t *testing.T) {
+		m, _ := newReplayManager(t, nil)
+		collectPackets(m)
+		if err := m.ReplayFile(context.Background(), filepath.Join(t.TempDir(), "missing.wav")); err == nil {
+			t.Error("文件不存在时应返回错误")
+		}
+	})
+	t.Run("通道数不一致", func(t *testing.T) {
+		m, _ := newReplayManager(t, nil)
+		collectPackets(m)
+		if err := m.ReplayFile(context.Background(), stereo); err == nil {
+			t.Error("双声道文件应被单声道管理器拒绝")
+		}
+	})
+	t.Run("录音进行中", func(t *testing.T) {
+		m, _ := newReplayManager(t, nil)
+		collectPackets(m)
+		if err := m.StartRecording(); err != nil {
+			t.Fatalf("开始录音失败: %v", err)
+		}
+		defer m.StopRecording()
+		if err := m.ReplayFile(context.Background(), mono); err == nil {
+			t.Error("录音进行中时应拒绝回放")
+		}
+	})
+}
+
+func TestReplayFileCancel(t *testing.T) {
+	// 2秒的文件，回放开始后很快取消
+	path := writeTestWAV(t, sine(440, 16000, 32000, 8000), 16000, 1)
+	m, _ := newReplayManager(t, nil)
+	started := make(chan struct{})
+	var once sync.Once
+	m.SetPCMDataCallback(func([]int16, int) { once.Do(func() { close(started) }) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- m.ReplayFile(ctx, path) }()
+	<-started
+
+	// 回放期间不能开始第二次回放或录音
+	if err := m.ReplayFile(context.Background(), path); err == nil {
+		t.Error("回放期间应拒绝第二次回放")
+	}
+	if err := m.StartRecording(); err == nil {
+		m.StopRecording()
+		t.Error("回放期间应拒绝开始录音")
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("取消后返回%v，期望context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("取消后回放没有结束")
+	}
+	if err := m.ReplayFile(context.Background(), writeTestWAV(t, make([]int16, 320), 16000, 1)); err != nil {
+		t.Errorf("取消后无法再次回放: %v", err)
+	}
+}
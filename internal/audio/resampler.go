@@ -0,0 +1,86 @@
+package audio
+
+import "fmt"
+
+// Resampler 基于线性插值的采样率转换器
+// 在多次调用之间保留插值位置和上一帧采样，连续处理分块音频时不会产生接缝
+type Resampler struct {
+	inRate   int
+	outRate  int
+	channels int
+	step     float64 // 每个输出采样在输入中前进的帧数
+	pos      float64 // 下一个输出采样在当前输入块中的位置，-1表示上一块的最后一帧
+	last     []int16 // 上一块的最后一帧
+}
+
+// NewResampler 创建从inRate转换到outRate的重采样器，采样为交错排列的channels声道
+func NewResampler(inRate, outRate, channels int) (*Resampler, error) {
+	if inRate <= 0 || outRate <= 0 || channels <= 0 {
+		return nil, fmt.Errorf("无效的重采样参数: in=%d, out=%d, channels=%d", inRate, outRate, channels)
+	}
+	return &Resampler{
+		inRate:   inRate,
+		outRate:  outRate,
+		channels: channels,
+		step:     float64(inRate) / float64(outRate),
+		last:     make([]int16, channels),
+	}, nil
+}
+
+// InputRate 返回输入采样率
+func (r *Resampler) InputRate() int {
+	return r.inRate
+}
+
+// OutputRate 返回输出采样率
+func (r *Resampler) OutputRate() int {
+	return r.outRate
+}
+
+// Process 转换一块交错排列的PCM数据，返回新分配的输出
+func (r *Resampler) Process(in []int16) []int16 {
+	if r.inRate == r.outRate {
+		out := make([]int16, len(in))
+		copy(out, in)
+		return out
+	}
+
+	frames := len(in) / r.channels
+	if frames == 0 {
+		return nil
+	}
+
+	sample := func(frame, ch int) float64 {
+		if frame < 0 {
+			return float64(r.last[ch])
+		}
+		return float64(in[frame*r.channels+ch])
+	}
+
+	out := make([]int16, 0, (int(float64(frames)/r.step)+1)*r.channels)
+	for r.pos < float64(frames-1) {
+		i := int(r.pos)
+		if r.pos < 0 {
+			i = -1
+		}
+		frac := r.pos - float64(i)
+		for ch := 0; ch < r.channels; ch++ {
+			a := sample(i, ch)
+			b := sample(i+1, ch)
+			out = append(out, int16(a+(b-a)*frac))
+		}
+		r.pos += r.step
+	}
+
+	r.pos -= float64(frames)
+	copy(r.last, in[(frames-1)*r.channels:frames*r.channels])
+	return out
+}
+
+// Reset 清除内部状态，用于开始一段不连续的新音频
+func (r *Resampler) Reset() {
+	r.pos = 0
+	for i := range r.last {
+		r.last[i] = 0
+	}
+}
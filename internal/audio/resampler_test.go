@@ -0,0 +1,100 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+// sine 生成n个采样、频率为freq的单声道正弦波
+func sine(freq float64, sampleRate, n int, amplitude float64) []int16 {
+	pcm := make([]int16, n)
+	for i := range pcm {
+		pcm[i] = int16(amplitude * math.Sin(2*math.Pi*freq*float64(i)/float64(sampleRate)))
+	}
+	return pcm
+}
+
+// dominantFrequency 以10Hz步长扫描0到奈奎斯特频率，返回能量最大的频率
+func dominantFrequency(pcm []int16, sampleRate int) float64 {
+	best, bestPower := 0.0, 0.0
+	for freq := 10.0; freq < float64(sampleRate)/2; freq += 10 {
+		var re, im float64
+		for i, v := range pcm {
+			phase := 2 * math.Pi * freq * float64(i) / float64(sampleRate)
+			re += float64(v) * math.Cos(phase)
+			im -= float64(v) * math.Sin(phase)
+		}
+		if power := re*re + im*im; power > bestPower {
+			best, bestPower = freq, power
+		}
+	}
+	return best
+}
+
+func TestResampler48kTo16kSine(t *testing.T) {
+	for _, freq := range []float64{440, 1000, 3000} {
+		r, err := NewResampler(48000, 16000, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		input := sine(freq, 48000, 48000/2, 10000)
+
+		// 按20ms分块处理，块之间不能出现接缝
+		var out []int16
+		for start := 0; start < len(input); start += 960 {
+			out = append(out, r.Process(input[start:start+960])...)
+		}
+
+		if want := len(input) / 3; math.Abs(float64(len(out)-want)) > 2 {
+			t.Errorf("%vHz: 输出%d个采样，期望约%d", freq, len(out), want)
+		}
+		if got := dominantFrequency(out, 16000); math.Abs(got-freq) > 10 {
+			t.Errorf("%vHz: 重采样后主频为%vHz", freq, got)
+		}
+
+		oneShot, _ := NewResampler(48000, 16000, 1)
+		whole := oneShot.Process(input)
+		if len(whole) != len(out) {
+			t.Fatalf("%vHz: 分块处理输出%d个采样，一次处理输出%d个", freq, len(out), len(whole))
+		}
+		for i := range whole {
+			if d := int(whole[i]) - int(out[i]); d > 1 || d < -1 {
+				t.Fatalf("%vHz: 第%d个采样分块处理为%d，一次处理为%d", freq, i, out[i], whole[i])
+			}
+		}
+	}
+}
+
+func TestResamplerStereoKeepsChannels(t *testing.T) {
+	r, err := NewResampler(48000, 16000, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	left := sine(500, 48000, 4800, 8000)
+	right := sine(2000, 48000, 4800, 8000)
+	input := make([]int16, 0, 2*len(left))
+	for i := range left {
+		input = append(input, left[i], right[i])
+	}
+
+	out := r.Process(input)
+	var outLeft, outRight []int16
+	for i := 0; i+1 < len(out); i += 2 {
+		outLeft = append(outLeft, out[i])
+		outRight = append(outRight, out[i+1])
+	}
+	if got := dominantFrequency(outLeft, 16000); math.Abs(got-500) > 10 {
+		t.Errorf("左声道主频为%vHz，期望500Hz", got)
+	}
+	if got := dominantFrequency(outRight, 16000); math.Abs(got-2000) > 10 {
+		t.Errorf("右声道主频为%vHz，期望2000Hz", got)
+	}
+}
+
+func TestNewResamplerRejectsInvalidParams(t *testing.T) {
+	for _, p := range [][3]int{{0, 16000, 1}, {48000, 0, 1}, {48000, 16000, 0}, {-1, 16000, 1}} {
+		if _, err := NewResampler(p[0], p[1], p[2]); err == nil {
+			t.Errorf("NewResampler(%d, %d, %d) 应返回错误", p[0], p[1], p[2])
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package audio
+
+import (
+	"sync"
+	"time"
+)
+
+// 静音抑制的默认参数
+const (
+	DefaultSilenceLeadIn   = 300 * time.Millisecond // 语音开始前补发的静音时长，避免截断字头
+	DefaultSilenceHangover = 500 * time.Millisecond // 语音结束后继续发送的时长，避免截断字尾
+)
+
+// silenceGate 按帧能量决定编码后的数据包是否发送
+// 静音帧先暂存在前导缓冲区中，检测到语音时连同前导帧一起发出；语音结束后的hangoverFrames帧照常发送，
+// 之后的静音帧在前导缓冲区溢出时丢弃并计为被抑制的帧
+type silenceGate struct {
+	mu             sync.Mutex
+	threshold      float64
+	leadInFrames   int
+	hangoverFrames int
+	pending        [][]byte // 前导缓冲区，最多leadInFrames帧
+	quietFrames    int      // 自上次语音以来的静音帧数
+	active         bool     // 是否处于语音或hangover中
+}
+
+// newSilenceGate 创建静音抑制器，threshold为RMS电平（0..1），帧数按frameDuration（毫秒）换算
+func newSilenceGate(threshold float64, frameDuration int) *silenceGate {
+	if frameDuration <= 0 {
+		frameDuration = DefaultFrameDuration
+	}
+	frame := time.Duration(frameDuration) * time.Millisecond
+	return &silenceGate{
+		threshold:      threshold,
+		leadInFrames:   int((DefaultSilenceLeadIn + frame - 1) / frame),
+		hangoverFrames: int((DefaultSilenceHangover + frame - 1) / frame),
+	}
+}
+
+// process 输入一帧的数据包和电平，返回应当按顺序发送的数据包及因此被丢弃的帧数
+func (g *silenceGate) process(packet []byte, rms float64) (send [][]byte, suppressed int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if rms >= g.threshold {
+		// 语音：先补发前导帧
+		send = append(g.pending, packet)
+		g.pending = nil
+		g.quietFrames = 0
+		g.active = true
+		return send, 0
+	}
+
+	g.quietFrames++
+	if g.active && g.quietFrames <= g.hangoverFrames {
+		return [][]byte{packet}, 0
+	}
+	g.active = false
+
+	if g.leadInFrames <= 0 {
+		return nil, 1
+	}
+	if len(g.pending) == g.leadInFrames {
+		g.pending = g.pending[1:]
+		suppressed = 1
+	}
+	g.pending = append(g.pending, packet)
+	return nil, suppressed
+}
+
+// reset 丢弃暂存的前导帧，用于开始新一轮录音；返回丢弃的帧数
+func (g *silenceGate) reset() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	dropped := len(g.pending)
+	g.pending = nil
+	g.quietFrames = 0
+	g.active = false
+	return dropped
+}
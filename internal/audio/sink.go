@@ -0,0 +1,121 @@
+package audio
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// asyncSinkQueueFrames 异步旁路的最大积压帧数，60ms一帧时约30秒
+const asyncSinkQueueFrames = 512
+
+// PCMSink 接收交错排列的int16 PCM帧，WAVWriter 实现了该接口
+type PCMSink interface {
+	Write(pcm []int16) error
+}
+
+// writerSink 把PCM以16位小端序写入任意 io.Writer
+type writerSink struct {
+	w io.Writer
+}
+
+// NewWriterSink 返回把PCM以16位小端序原始数据写入w的 PCMSink，例如原始PCM文件或管道
+func NewWriterSink(w io.Writer) PCMSink {
+	return &writerSink{w: w}
+}
+
+func (s *writerSink) Write(pcm []int16) error {
+	_, err := s.w.Write(pcmToBytes(pcm))
+	return err
+}
+
+// convertedSink 将PCM转换为下游的通道数并重采样到其采样率后写入，用于输出设备格式与文件不同时
+type convertedSink struct {
+	sink         PCMSink
+	fromChannels int
+	toChannels   int
+	resampler    *Resampler // 采样率相同时为nil
+}
+
+func (s *convertedSink) Write(pcm []int16) error {
+	pcm = convertChannels(pcm, s.fromChannels, s.toChannels)
+	if s.resampler != nil {
+		pcm = s.resampler.Process(pcm)
+	}
+	return s.sink.Write(pcm)
+}
+
+// asyncSink 在独立goroutine中按顺序把帧写入下游，避免文件IO或耗时处理拖慢播放循环和录音处理
+// 积压超过 asyncSinkQueueFrames 时丢弃新帧并计数，不阻塞调用方
+type asyncSink struct {
+	name        string // 用于日志的名称
+	sink        PCMSink
+	frames      chan []int16
+	done        chan struct{}
+	mu          sync.Mutex // 保护closed，保证关闭后不再向frames发送
+	closed      bool
+	dropped     atomic.Uint64
+	lastDropLog time.Time // 由mu保护
+}
+
+func newAsyncSink(name string, sink PCMSink) *asyncSink {
+	s := &asyncSink{
+		name:   name,
+		sink:   sink,
+		frames: make(chan []int16, asyncSinkQueueFrames),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *asyncSink) run() {
+	defer close(s.done)
+	failed := false
+	for frame := range s.frames {
+		if failed {
+			continue
+		}
+		if err := s.sink.Write(frame); err != nil {
+			// 写入失败后不再重试，避免每帧都输出错误
+			logger.Warnf("%s处理失败，后续音频将被丢弃: %v", s.name, err)
+			failed = true
+		}
+	}
+}
+
+// push 复制一帧放入队列，调用方可继续使用pcm
+func (s *asyncSink) push(pcm []int16) {
+	frame := make([]int16, len(pcm))
+	copy(frame, pcm)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.frames <- frame:
+	default:
+		dropped := s.dropped.Add(1)
+		if now := time.Now(); now.Sub(s.lastDropLog) >= queueDropLogInterval {
+			s.lastDropLog = now
+			logger.Warnf("%s处理过慢，已丢弃%d帧", s.name, dropped)
+		}
+	}
+}
+
+// close 停止接收新帧，等待已排队的帧全部写入下游；不关闭下游
+func (s *asyncSink) close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		<-s.done
+		return
+	}
+	s.closed = true
+	close(s.frames)
+	s.mu.Unlock()
+	<-s.done
+}
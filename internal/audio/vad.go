@@ -0,0 +1,157 @@
+package audio
+
+import (
+	"sync"
+	"time"
+)
+
+// VADEvent 语音活动检测事件
+type VADEvent int
+
+const (
+	SpeechStarted VADEvent = iota + 1 // 检测到开始说话
+	SpeechEnded                       // 说话结束（静音持续超过挂起时间）
+)
+
+// String 返回事件名称
+func (e VADEvent) String() string {
+	switch e {
+	case SpeechStarted:
+		return "speech_started"
+	case SpeechEnded:
+		return "speech_ended"
+	default:
+		return "unknown"
+	}
+}
+
+// VAD 默认参数
+const (
+	DefaultVADHangover          = 800 * time.Millisecond
+	DefaultVADCalibrationFrames = 5   // 60ms一帧，约300ms
+	DefaultVADNoiseMultiplier   = 3.0 // 阈值为噪声基底RMS的倍数
+	DefaultVADMinThreshold      = 0.01
+)
+
+// VADOptions 语音活动检测选项
+type VADOptions struct {
+	SampleRate        int           // 采样率
+	ChannelCount      int           // 通道数
+	Threshold         float64       // RMS能量阈值（0..1），为0时根据噪声基底自动校准
+	Hangover          time.Duration // 能量低于阈值持续多久判定为说话结束
+	CalibrationFrames int           // 校准噪声基底使用的帧数
+	NoiseMultiplier   float64       // 自动校准时阈值相对噪声基底的倍数
+}
+
+// VAD 基于能量的语音活动检测器
+type VAD struct {
+	mu          sync.Mutex
+	options     VADOptions
+	threshold   float64       // 当前使用的阈值，校准完成前为0
+	noiseSum    float64       // 校准期间RMS累加值
+	noiseFrames int           // 已用于校准的帧数
+	inSpeech    bool          // 是否处于说话状态
+	silence     time.Duration // 说话状态下连续静音的时长
+	onEvent     func(event VADEvent)
+}
+
+// NewVAD 创建语音活动检测器
+func NewVAD(options VADOptions) *VAD {
+	if options.SampleRate <= 0 {
+		options.SampleRate = DefaultSampleRate
+	}
+	if options.ChannelCount <= 0 {
+		options.ChannelCount = DefaultChannelCount
+	}
+	if options.Hangover <= 0 {
+		options.Hangover = DefaultVADHangover
+	}
+	if options.CalibrationFrames <= 0 {
+		options.CalibrationFrames = DefaultVADCalibrationFrames
+	}
+	if options.NoiseMultiplier <= 0 {
+		options.NoiseMultiplier = DefaultVADNoiseMultiplier
+	}
+
+	return &VAD{
+		options:   options,
+		threshold: options.Threshold,
+	}
+}
+
+// SetOnEvent 设置事件回调，回调在Process的调用线程中执行
+func (v *VAD) SetOnEvent(callback func(event VADEvent)) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.onEvent = callback
+}
+
+// Threshold 返回当前能量阈值，尚未完成校准时返回0
+func (v *VAD) Threshold() float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.threshold
+}
+
+// Calibrate 丢弃当前阈值，用接下来的若干帧重新测量噪声基底
+func (v *VAD) Calibrate() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.threshold = 0
+	v.noiseSum = 0
+	v.noiseFrames = 0
+}
+
+// Reset 清除说话状态，开始新一轮检测，保留已校准的阈值
+func (v *VAD) Reset() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.inSpeech = false
+	v.silence = 0
+}
+
+// Process 处理一帧交错排列的PCM数据，必要时触发事件
+func (v *VAD) Process(pcm []int16) {
+	if len(pcm) == 0 {
+		return
+	}
+	rms, _ := computeLevel(pcm)
+	frameDuration := time.Duration(len(pcm)/v.options.ChannelCount) * time.Second / time.Duration(v.options.SampleRate)
+
+	v.mu.Lock()
+	// 校准阶段：累计噪声基底
+	if v.threshold <= 0 {
+		v.noiseSum += rms
+		v.noiseFrames++
+		if v.noiseFrames >= v.options.CalibrationFrames {
+			v.threshold = v.noiseSum / float64(v.noiseFrames) * v.options.NoiseMultiplier
+			if v.threshold < DefaultVADMinThreshold {
+				v.threshold = DefaultVADMinThreshold
+			}
+		}
+		v.mu.Unlock()
+		return
+	}
+
+	var event VADEvent
+	if rms >= v.threshold {
+		v.silence = 0
+		if !v.inSpeech {
+			v.inSpeech = true
+			event = SpeechStarted
+		}
+	} else if v.inSpeech {
+		v.silence += frameDuration
+		if v.silence >= v.options.Hangover {
+			v.inSpeech = false
+			v.silence = 0
+			event = SpeechEnded
+		}
+	}
+	onEvent := v.onEvent
+	v.mu.Unlock()
+
+	if event != 0 && onEvent != nil {
+		onEvent(event)
+	}
+}
@@ -0,0 +1,110 @@
+package audio
+
+import "time"
+
+// DefaultWakeWord 默认唤醒词，与服务器端的唤醒词一致
+const DefaultWakeWord = "你好小智"
+
+// 能量检测唤醒的默认参数
+const (
+	DefaultWakeEnergyThreshold = 0.1                    // 触发唤醒的RMS电平（0..1）
+	DefaultWakeMinDuration     = 300 * time.Millisecond // 电平需要持续超过阈值的时长
+	DefaultWakeCooldown        = 2 * time.Second        // 触发后不再触发的时长
+)
+
+// WakeWordDetector 唤醒词检测器，可接入Porcupine、Snowboy等唤醒引擎
+// Process 按录音顺序接收交错排列的PCM帧（采样率和通道数与编码参数一致），检测到唤醒词时返回true和唤醒词。
+// 各帧在同一个goroutine中依次送入，实现无需考虑并发
+type WakeWordDetector interface {
+	Process(pcm []int16) (detected bool, keyword string)
+}
+
+// EnergyWakeWordOptions 能量检测唤醒选项
+type EnergyWakeWordOptions struct {
+	SampleRate   int           // 采样率，为0时使用 DefaultSampleRate
+	ChannelCount int           // 通道数，为0时使用 DefaultChannelCount
+	Threshold    float64       // 触发唤醒的RMS电平（0..1），为0时使用 DefaultWakeEnergyThreshold
+	MinDuration  time.Duration // 电平需要持续超过阈值的时长，为0时使用 DefaultWakeMinDuration
+	Cooldown     time.Duration // 触发后不再触发的时长，为0时使用 DefaultWakeCooldown
+	Keyword      string        // 触发时返回的唤醒词，为空时使用 DefaultWakeWord
+}
+
+// EnergyWakeWordDetector 以音量代替唤醒词的简易检测器：电平持续超过阈值即视为唤醒
+// 不识别语音内容，仅用于调试唤醒流程或作为接入真正唤醒引擎前的占位
+type EnergyWakeWordDetector struct {
+	options  EnergyWakeWordOptions
+	loud     time.Duration // 电平连续超过阈值的时长
+	cooldown time.Duration // 剩余冷却时长
+}
+
+// NewEnergyWakeWordDetector 创建能量检测唤醒器
+func NewEnergyWakeWordDetector(options EnergyWakeWordOptions) *EnergyWakeWordDetector {
+	if options.SampleRate <= 0 {
+		options.SampleRate = DefaultSampleRate
+	}
+	if options.ChannelCount <= 0 {
+		options.ChannelCount = DefaultChannelCount
+	}
+	if options.Threshold <= 0 {
+		options.Threshold = DefaultWakeEnergyThreshold
+	}
+	if options.MinDuration <= 0 {
+		options.MinDuration = DefaultWakeMinDuration
+	}
+	if options.Cooldown <= 0 {
+		options.Cooldown = DefaultWakeCooldown
+	}
+	if options.Keyword == "" {
+		options.Keyword = DefaultWakeWord
+	}
+	return &EnergyWakeWordDetector{options: options}
+}
+
+// Process 实现 WakeWordDetector 接口
+func (d *EnergyWakeWordDetector) Process(pcm []int16) (bool, string) {
+	if len(pcm) == 0 {
+		return false, ""
+	}
+	frameDuration := time.Duration(len(pcm)/d.options.ChannelCount) * time.Second / time.Duration(d.options.SampleRate)
+
+	if d.cooldown > 0 {
+		d.cooldown -= frameDuration
+		return false, ""
+	}
+
+	rms, _ := computeLevel(pcm)
+	if rms < d.options.Threshold {
+		d.loud = 0
+		return false, ""
+	}
+	d.loud += frameDuration
+	if d.loud < d.options.MinDuration {
+		return false, ""
+	}
+
+	d.loud = 0
+	d.cooldown = d.options.Cooldown
+	return true, d.options.Keyword
+}
+
+// Reset 清除累计的电平时长和冷却状态
+func (d *EnergyWakeWordDetector) Reset() {
+	d.loud = 0
+	d.cooldown = 0
+}
+
+// wakeWordSink 把录音帧送入唤醒词检测器，检测到时调用回调；配合 asyncSink 在录音处理之外运行
+type wakeWordSink struct {
+	detector WakeWordDetector
+	callback func(keyword string)
+}
+
+func (s *wakeWordSink) Write(pcm []int16) error {
+	if detected, keyword := s.detector.Process(pcm); detected {
+		logger.Infof("检测到唤醒词: %s", keyword)
+		if s.callback != nil {
+			s.callback(keyword)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// wavHeaderSize 标准PCM WAV文件头长度（RIFF + fmt + data块头）
+const wavHeaderSize = 44
+
+// WAVWriter 将16位PCM数据写入WAV文件
+type WAVWriter struct {
+	file         *os.File
+	mu           sync.Mutex
+	sampleRate   int
+	channels     int
+	dataSize     uint32 // 已写入的音频数据字节数
+	closed       bool
+	sampleBuffer []byte
+}
+
+// NewWAVWriter 创建WAV文件并写入占位文件头，块大小在Close时回填
+func NewWAVWriter(path string, sampleRate, channels int) (*WAVWriter, error) {
+	if sampleRate <= 0 || channels <= 0 {
+		return nil, fmt.Errorf("无效的WAV参数: sample_rate=%d, channels=%d", sampleRate, channels)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("创建WAV文件失败: %v", err)
+	}
+
+	w := &WAVWriter{
+		file:       f,
+		sampleRate: sampleRate,
+		channels:   channels,
+	}
+	if err := w.writeHeader(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("写入WAV文件头失败: %v", err)
+	}
+	return w, nil
+}
+
+// writeHeader 按当前数据长度写入44字节的文件头
+func (w *WAVWriter) writeHeader() error {
+	const bitsPerSample = 16
+	blockAlign := w.channels * bitsPerSample / 8
+
+	header := make([]byte, wavHeaderSize)
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], 36+w.dataSize)
+	copy(header[8:12], "WAVE")
+	copy(header[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:20], 16) // fmt块长度
+	binary.LittleEndian.PutUint16(header[20:22], 1)  // PCM格式
+	binary.LittleEndian.PutUint16(header[22:24], uint16(w.channels))
+	binary.LittleEndian.PutUint32(header[24:28], uint32(w.sampleRate))
+	binary.LittleEndian.PutUint32(header[28:32], uint32(w.sampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(header[32:34], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(header[34:36], bitsPerSample)
+	copy(header[36:40], "data")
+	binary.LittleEndian.PutUint32(header[40:44], w.dataSize)
+
+	_, err := w.file.WriteAt(header, 0)
+	return err
+}
+
+// Write 追加交错排列的int16采样
+func (w *WAVWriter) Write(pcm []int16) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return errors.New("WAV文件已关闭")
+	}
+	if len(pcm) == 0 {
+		return nil
+	}
+
+	if cap(w.sampleBuffer) < len(pcm)*2 {
+		w.sampleBuffer = make([]byte, len(pcm)*2)
+	}
+	buf := w.sampleBuffer[:len(pcm)*2]
+	for i, v := range pcm {
+		binary.LittleEndian.PutUint16(buf[2*i:], uint16(v))
+	}
+
+	n, err := w.file.WriteAt(buf, int64(wavHeaderSize)+int64(w.dataSize))
+	w.dataSize += uint32(n)
+	return err
+}
+
+// Close 回填RIFF和data块大小并关闭文件
+func (w *WAVWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if err := w.writeHeader(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("更新WAV文件头失败: %v", err)
+	}
+	return w.file.Close()
+}
+
+// WAVReader 从16位PCM WAV文件中按帧读取采样
+type WAVReader struct {
+	file          *os.File
+	sampleRate    int
+	channels      int
+	dataRemaining int64 // data块中尚未读取的字节数
+	byteBuffer    []byte
+}
+
+// NewWAVReader 打开WAV文件并解析文件头，仅支持16位PCM格式
+func NewWAVReader(path string) (*WAVReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开WAV文件失败: %v", err)
+	}
+
+	r := &WAVReader{file: f}
+	if err := r.readHeader(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// readHeader 解析RIFF头并定位到data块
+func (r *WAVReader) readHeader() error {
+	riff := make([]byte, 12)
+	if _, err := io.ReadFull(r.file, riff); err != nil {
+		return fmt.Errorf("读取WAV文件头失败: %v", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return errors.New("不是有效的WAV文件")
+	}
+
+	gotFormat := false
+	chunkHeader := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r.file, chunkHeader); err != nil {
+			return fmt.Errorf("未找到WAV data块: %v", err)
+		}
+		chunkID := string(chunkHeader[0:4])
+		chunkSize := int64(binary.LittleEndian.Uint32(chunkHeader[4:8]))
+
+		switch chunkID {
+		case "fmt ":
+			if chunkSize < 16 {
+				return errors.New("WAV fmt块长度无效")
+			}
+			fmtChunk := make([]byte, chunkSize+chunkSize%2)
+			if _, err := io.ReadFull(r.file, fmtChunk); err != nil {
+				return fmt.Errorf("读取WAV fmt块失败: %v", err)
+			}
+			audioFormat := binary.LittleEndian.Uint16(fmtChunk[0:2])
+			bitsPerSample := binary.LittleEndian.Uint16(fmtChunk[14:16])
+			if audioFormat != 1 || bitsPerSample != 16 {
+				return fmt.Errorf("仅支持16位PCM WAV文件 (format=%d, bits=%d)", audioFormat, bitsPerSample)
+			}
+			r.channels = int(binary.LittleEndian.Uint16(fmtChunk[2:4]))
+			r.sampleRate = int(binary.LittleEndian.Uint32(fmtChunk[4:8]))
+			gotFormat = true
+		case "data":
+			if !gotFormat {
+				return errors.New("WAV data块出现在fmt块之前")
+			}
+			r.dataRemaining = chunkSize
+			return nil
+		default:
+			// 跳过LIST等其他块，块长度按2字节对齐
+			if _, err := r.file.Seek(chunkSize+chunkSize%2, io.SeekCurrent); err != nil {
+				return fmt.Errorf("跳过WAV块失败: %v", err)
+			}
+		}
+	}
+}
+
+// SampleRate 返回文件采样率
+func (r *WAVReader) SampleRate() int {
+	return r.sampleRate
+}
+
+// Channels 返回文件通道数
+func (r *WAVReader) Channels() int {
+	return r.channels
+}
+
+// ReadFrame 读取最多len(pcm)个交错排列的采样，返回实际读取的采样数
+// 数据读完时返回 io.EOF
+func (r *WAVReader) ReadFrame(pcm []int16) (int, error) {
+	if r.dataRemaining <= 0 {
+		return 0, io.EOF
+	}
+
+	want := int64(len(pcm) * 2)
+	if want > r.dataRemaining {
+		want = r.dataRemaining
+	}
+	if int64(cap(r.byteBuffer)) < want {
+		r.byteBuffer = make([]byte, want)
+	}
+	buf := r.byteBuffer[:want]
+
+	n, err := io.ReadFull(r.file, buf)
+	r.dataRemaining -= int64(n)
+	samples := n / 2
+	for i := 0; i < samples; i++ {
+		pcm[i] = int16(binary.LittleEndian.Uint16(buf[2*i:]))
+	}
+	if err == io.ErrUnexpectedEOF {
+		// 文件被截断，返回已读到的部分
+		r.dataRemaining = 0
+		err = nil
+	}
+	return samples, err
+}
+
+// Close 关闭文件
+func (r *WAVReader) Close() error {
+	return r.file.Close()
+}
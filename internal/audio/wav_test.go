@@ -0,0 +1,74 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWAVWriterHeaderBytes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.wav")
+	w, err := NewWAVWriter(path, 16000, 2)
+	if err != nil {
+		t.Fatalf("创建WAV文件失败: %v", err)
+	}
+	pcm := []int16{1, -1, 32767, -32768, 256, 0}
+	if err := w.Write(pcm); err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("关闭失败: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want bytes.Buffer
+	want.WriteString("RIFF")
+	binary.Write(&want, binary.LittleEndian, uint32(36+12))
+	want.WriteString("WAVEfmt ")
+	for _, v := range []any{
+		uint32(16),        // fmt块长度
+		uint16(1),         // PCM
+		uint16(2),         // 通道数
+		uint32(16000),     // 采样率
+		uint32(16000 * 4), // 字节率
+		uint16(4),         // 块对齐
+		uint16(16),        // 位深
+	} {
+		binary.Write(&want, binary.LittleEndian, v)
+	}
+	want.WriteString("data")
+	binary.Write(&want, binary.LittleEndian, uint32(12))
+	want.Write([]byte{0x01, 0x00, 0xff, 0xff, 0xff, 0x7f, 0x00, 0x80, 0x00, 0x01, 0x00, 0x00})
+
+	if !bytes.Equal(data, want.Bytes()) {
+		t.Fatalf("WAV文件内容为\n% x\n期望\n% x", data, want.Bytes())
+	}
+
+	r, err := NewWAVReader(path)
+	if err != nil {
+		t.Fatalf("打开WAV文件失败: %v", err)
+	}
+	defer r.Close()
+	if r.SampleRate() != 16000 || r.Channels() != 2 {
+		t.Errorf("读取到 %dHz %d声道", r.SampleRate(), r.Channels())
+	}
+	got := make([]int16, 16)
+	n, err := r.ReadFrame(got)
+	if err != nil || n != len(pcm) {
+		t.Fatalf("读取到%d个采样: %v", n, err)
+	}
+	for i := range pcm {
+		if got[i] != pcm[i] {
+			t.Fatalf("第%d个采样为%d，期望%d", i, got[i], pcm[i])
+		}
+	}
+	if _, err := r.ReadFrame(got); err != io.EOF {
+		t.Errorf("读完后期望 io.EOF，实际: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/justa-cai/xiaozhi-go/internal/audio"
+	"github.com/justa-cai/xiaozhi-go/internal/protocol"
+)
+
+// newAudioParamsClient 创建音频通道已打开的客户端，服务器hello按supported声明是否支持会话中更新音频参数
+func newAudioParamsClient(t *testing.T, supported bool) (*Client, *protocol.MockProtocol) {
+	t.Helper()
+	serverHello := `{"type":"hello","version":1,"transport":"websocket"}`
+	if supported {
+		serverHello = `{"type":"hello","version":1,"transport":"websocket","features":{"audio_params_update":true}}`
+	}
+	mock := newMockServer(serverHello)
+	c := New(mock)
+	if err := c.OpenAudioChannel("ws://test"); err != nil {
+		t.Fatalf("打开音频通道失败: %v", err)
+	}
+	mock.Reset()
+	return c, mock
+}
+
+func TestUpdateAudioParamsReconfiguresLocalAudio(t *testing.T) {
+	c, mock := newAudioParamsClient(t, true)
+
+	recorder := audio.NewMockRecorder(audio.RecorderOptions{
+		SampleRate:    audio.DefaultSampleRate,
+		ChannelCount:  audio.DefaultChannelCount,
+		FrameDuration: audio.DefaultFrameDuration,
+	}, nil)
+	manager, err := audio.NewAudioManagerWithOptions(audio.AudioManagerOptions{Recorder: recorder, HeadlessOutput: true})
+	if err != nil {
+		t.Fatalf("创建音频管理器失败: %v", err)
+	}
+	defer manager.Close()
+
+	var changed []protocol.AudioParams
+	c.SetOnAudioParamsChanged(func(params protocol.AudioParams) error {
+		changed = append(changed, params)
+		return manager.Reconfigure(params.SampleRate, params.Channels, params.FrameDuration)
+	})
+
+	params := protocol.AudioParams{
+		Format:        "opus",
+		SampleRate:    24000,
+		Channels:      audio.DefaultChannelCount,
+		FrameDuration: audio.DefaultFrameDuration,
+	}
+	if err := c.UpdateAudioParams(params); err != nil {
+		t.Fatalf("更新音频参数失败: %v", err)
+	}
+
+	hellos := mock.SentJSONOfType("hello")
+	if len(hellos) != 1 {
+		t.Fatalf("发送了%d条hello，期望1条", len(hellos))
+	}
+	var hello protocol.HelloMessage
+	if err := json.Unmarshal(hellos[0], &hello); err != nil {
+		t.Fatal(err)
+	}
+	if hello.AudioParams != params {
+		t.Errorf("hello中的音频参数为 %+v，期望 %+v", hello.AudioParams, params)
+	}
+	if len(changed) != 1 || changed[0] != params {
+		t.Errorf("音频参数回调收到 %+v，期望一次 %+v", changed, params)
+	}
+	if rate := manager.SampleRate(); rate != params.SampleRate {
+		t.Errorf("本地编解码采样率为 %d，期望 %d", rate, params.SampleRate)
+	}
+	if !mock.IsConnected() {
+		t.Error("更新音频参数不应断开连接")
+	}
+}
+
+func TestUpdateAudioParamsRejected(t *testing.T) {
+	valid := protocol.AudioParams{Format: "opus", SampleRate: 24000, Channels: 1, FrameDuration: 60}
+
+	t.Run("服务器不支持", func(t *testing.T) {
+		c, mock := newAudioParamsClient(t, false)
+		called := false
+		c.SetOnAudioParamsChanged(func(protocol.AudioParams) error {
+			called = true
+			return nil
+		})
+		if err := c.UpdateAudioParams(valid); err == nil {
+			t.Fatal("服务器不支持时应返回错误")
+		}
+		if sent := mock.SentJSONOfType("hello"); len(sent) != 0 {
+			t.Errorf("服务器不支持时不应发送hello: %s", sent)
+		}
+		if called {
+			t.Error("服务器不支持时不应重新配置本地音频")
+		}
+	})
+
+	t.Run("参数不合法", func(t *testing.T) {
+		c, mock := newAudioParamsClient(t, true)
+		invalid := valid
+		invalid.FrameDuration = 7
+		if err := c.UpdateAudioParams(invalid); err == nil {
+			t.Fatal("帧时长不合法时应返回错误")
+		}
+		if sent := mock.SentJSONOfType("hello"); len(sent) != 0 {
+			t.Errorf("参数不合法时不应发送hello: %s", sent)
+		}
+	})
+
+	t.Run("未连接", func(t *testing.T) {
+		c, mock := newAudioParamsClient(t, true)
+		mock.InjectDisconnect(nil)
+		if err := c.UpdateAudioParams(valid); !errors.Is(err, ErrNotConnected) {
+			t.Fatalf("期望 ErrNotConnected，实际: %v", err)
+		}
+	})
+
+	t.Run("本地重新配置失败", func(t *testing.T) {
+		c, _ := newAudioParamsClient(t, true)
+		reconfigureErr := errors.New("设备不支持")
+		c.SetOnAudioParamsChanged(func(protocol.AudioParams) error { return reconfigureErr })
+		if err := c.UpdateAudioParams(valid); err == nil {
+			t.Fatal("本地重新配置失败时应返回错误")
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+// newSpeakingClient 创建正在播放TTS的客户端，状态由服务器的tts消息驱动
+func newSpeakingClient(t *testing.T, grace time.Duration) (*Client, chan struct{}, func() int) {
+	t.Helper()
+	c, mock := newOpenClient(t)
+	c.SetBargeInGrace(grace)
+	bargedIn := make(chan struct{}, 1)
+	c.SetOnBargeIn(func() {
+		bargedIn <- struct{}{}
+	})
+
+	mock.InjectJSON(`{"type":"tts","state":"start"}`)
+	if state := c.GetState(); state != StateSpeaking {
+		t.Fatalf("TTS开始后状态为 %s，期望 %s", state, StateSpeaking)
+	}
+	aborts := func() int {
+		return len(mock.SentJSONOfType("abort"))
+	}
+	return c, bargedIn, aborts
+}
+
+func TestBargeInDelayedByGrace(t *testing.T) {
+	const grace = 100 * time.Millisecond
+	c, bargedIn, aborts := newSpeakingClient(t, grace)
+
+	start := time.Now()
+	if !c.BeginBargeIn("wake_word_detected") {
+		t.Fatal("播放状态下 BeginBargeIn 应返回true")
+	}
+	if n := aborts(); n != 0 {
+		t.Fatalf("宽限期内已发送%d条abort", n)
+	}
+
+	select {
+	case <-bargedIn:
+	case <-time.After(time.Second):
+		t.Fatal("宽限期结束后未触发打断")
+	}
+	if elapsed := time.Since(start); elapsed < grace {
+		t.Errorf("打断在%v后生效，早于宽限期%v", elapsed, grace)
+	}
+	if n := aborts(); n != 1 {
+		t.Errorf("发送了%d条abort，期望1条", n)
+	}
+}
+
+func TestBargeInCancelledWithinGrace(t *testing.T) {
+	const grace = 100 * time.Millisecond
+	c, bargedIn, aborts := newSpeakingClient(t, grace)
+
+	c.BeginBargeIn("wake_word_detected")
+	time.Sleep(grace / 4)
+	if !c.CancelBargeIn() {
+		t.Fatal("宽限期内停止说话应取消打断")
+	}
+
+	select {
+	case <-bargedIn:
+		t.Fatal("打断已取消，不应触发打断回调")
+	case <-time.After(2 * grace):
+	}
+	if n := aborts(); n != 0 {
+		t.Errorf("打断已取消，却发送了%d条abort", n)
+	}
+	if state := c.GetState(); state != StateSpeaking {
+		t.Errorf("取消打断后状态为 %s，期望继续播放", state)
+	}
+}
+
+func TestBargeInWithoutGraceIsImmediate(t *testing.T) {
+	c, bargedIn, aborts := newSpeakingClient(t, 0)
+
+	c.BeginBargeIn("wake_word_detected")
+	select {
+	case <-bargedIn:
+	default:
+		t.Fatal("宽限期为0时应立即打断")
+	}
+	if n := aborts(); n != 1 {
+		t.Errorf("发送了%d条abort，期望1条", n)
+	}
+}
+
+func TestBargeInIgnoredWhenNotSpeaking(t *testing.T) {
+	c, _ := newOpenClient(t)
+	if c.BeginBargeIn("wake_word_detected") {
+		t.Error("未处于播放状态时 BeginBargeIn 应返回false")
+	}
+}
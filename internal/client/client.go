@@ -1,16 +1,20 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/justa-cai/xiaozhi-go/internal/audio"
+	"github.com/justa-cai/xiaozhi-go/internal/iot"
 	"github.com/justa-cai/xiaozhi-go/internal/protocol"
-	"github.com/sirupsen/logrus"
 )
 
 // 客户端状态常量
@@ -21,6 +25,18 @@ const (
 	StateSpeaking   = "speaking"   // 播放状态（播放TTS）
 )
 
+// 监听期间音频预缓冲默认值
+const (
+	DefaultPreBufferFrames = 5           // 最多保留的帧数
+	DefaultPreBufferMaxAge = time.Second // 帧的最长保留时间，超时视为上一轮的残留音频
+)
+
+// bufferedFrame 监听期间暂存的下行音频帧
+type bufferedFrame struct {
+	frame    protocol.AudioFrame
+	received time.Time
+}
+
 // 监听模式常量
 const (
 	ListenModeAuto     = "auto"     // 自动模式
@@ -32,9 +48,50 @@ const (
 const (
 	DefaultWebSocketURL      = "wss://api.tenclass.net/xiaozhi/v1/"
 	DefaultHelloTimeout      = 10 * time.Second
+	DefaultConnectTimeout    = 15 * time.Second
 	DefaultOpusFrameDuration = 60 // 毫秒
+	// DefaultCloseHandshakeTimeout Close时等待服务器确认关闭WebSocket连接的最长时间，受ctx截止时间限制
+	DefaultCloseHandshakeTimeout = time.Second
+	// DefaultPingTimeout Ping 的ctx没有截止时间时等待pong的最长时间
+	DefaultPingTimeout = 5 * time.Second
+)
+
+// 客户端方法返回的错误，调用方可用 errors.Is 判断并自行决定提示文本；
+// 返回时多数用 %w 包装并附加具体操作的说明
+var (
+	// ErrClientClosed 客户端已调用 Close 后再打开音频通道时返回
+	ErrClientClosed = errors.New("客户端已关闭")
+	// ErrNotConnected 未连接到服务器
+	ErrNotConnected = errors.New("未连接到服务器")
+	// ErrInvalidState 当前状态不允许该操作，如非空闲状态下打开音频通道
+	ErrInvalidState = errors.New("客户端状态不允许该操作")
+	// ErrNotListening 操作要求处于监听状态，errors.Is(ErrNotListening, ErrInvalidState) 为true
+	ErrNotListening = fmt.Errorf("客户端不在监听状态: %w", ErrInvalidState)
+	// ErrConnectTimeout 打开音频通道时连接服务器超时，见 SetConnectTimeout
+	ErrConnectTimeout = errors.New("连接WebSocket服务器超时")
+	// ErrHelloTimeout 打开音频通道时等待服务器hello超时，见 SetHelloTimeout
+	ErrHelloTimeout = errors.New("等待服务器Hello响应超时")
+	// ErrPingTimeout Ping 在截止时间内未收到pong
+	ErrPingTimeout = errors.New("等待服务器pong超时")
+	// ErrVersionMismatch 服务器hello声明的协议版本不兼容，具体版本见 VersionMismatchError
+	ErrVersionMismatch = errors.New("服务器协议版本不兼容")
 )
 
+// VersionMismatchError 服务器hello声明的协议版本不在 protocol.SupportedProtocolVersions 中时
+// OpenAudioChannel 返回的错误，errors.Is(err, ErrVersionMismatch) 为true
+type VersionMismatchError struct {
+	ServerVersion int   // 服务器声明的协议版本
+	Supported     []int // 客户端支持的协议版本
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("服务器协议版本%d不兼容，客户端支持: %v", e.ServerVersion, e.Supported)
+}
+
+func (e *VersionMismatchError) Unwrap() error {
+	return ErrVersionMismatch
+}
+
 // Client 定义小知客户端结构
 type Client struct {
 	// 协议实现
@@ -47,40 +104,148 @@ type Client struct {
 	deviceID   string
 	clientID   string
 	token      string
-	listenMode string
+	listenMode string // 当前（或最近一轮）监听使用的模式
+	// SetListenMode 设置的模式，下一次 SendStartListening 未指定模式时使用
+	nextListenMode string
+
+	response responseAssembler // 拼接本轮回复的文本
+
+	lastDisconnectReason protocol.DisconnectReason // 最近一次断开的原因
+	everDisconnected     bool                      // 是否发生过断开，用于区分lastDisconnectReason的零值
 
 	// 事件回调
 	onStateChanged       func(oldState, newState string)
 	onNetworkError       func(err error)
+	onDisconnected       func(reason protocol.DisconnectReason, err error)
+	onServerClose        func(code int, text string)
 	onRecognizedText     func(text string)
+	onPartialText        func(text string)
+	onFinalText          func(text string)
 	onSpeakText          func(text string)
+	onAssistantPartial   func(text string)
+	onAssistantResponse  func(text string)
 	onAudioData          func(data []byte)
+	onAudioFrame         func(frame protocol.AudioFrame)
 	onEmotionChanged     func(emotion, text string)
+	onEmotion            func(e protocol.Emotion, raw string, emoji string)
+	onAudioParamsChanged func(params protocol.AudioParams) error
+	onBargeIn            func()
 	onIoTCommand         func(commands []interface{})
+	onIoTCommands        func(commands []protocol.IoTCommand)
 	onAudioChannelOpen   func()
 	onAudioChannelClosed func()
+	onTurnLatency        func(d time.Duration)
+	onVersionMismatch    func(serverVersion int)
+	onSpeakingFinished   func()
+	onUnknownMessage     func(msgType string, raw []byte)
+	onMCPRequest         func(req protocol.MCPRequest) (protocol.MCPResponse, error)
+	onRawJSON            func(raw []byte)
+
+	// 轮次延迟统计：停止监听到首个TTS响应之间的时间
+	stopListeningAt time.Time
+	now             func() time.Time
+
+	// 手动监听模式下的静音自动停止（默认关闭）
+	vad *audio.VAD
+
+	// 音频参数与服务器能力
+	audioParams     protocol.AudioParams
+	protocolVersion int // hello和Protocol-Version头中发送的协议版本，0表示 protocol.ProtocolVersion
+	serverFeatures  map[string]bool
+	serverVersion   int
+
+	// 打断（barge-in）宽限期
+	bargeInGrace time.Duration
+	bargeInTimer *time.Timer
+
+	// 播放结束检测：本轮TTS尚未通知播放结束 / TTS期间播放队列曾播空且之后未再收到音频
+	speakingFinishPending bool
+	drainedDuringTTS      bool
+
+	// 断线重连后沿用原会话ID（默认关闭）
+	sessionResume bool
+
+	// 手动监听中途断线时暂存上行音频、重连后补发（可选，为nil表示未启用）
+	reconnectAudio *reconnectBuffer
+
+	// 半双工音频：监听期间是否丢弃下行音频，以及为避免截断TTS开头而保留的预缓冲帧
+	dropAudioWhileListening bool
+	preBufferFrames         int
+	preBufferMaxAge         time.Duration
+	preBuffer               []bufferedFrame
+
+	// IoT设备注册表（可选）
+	iotRegistry *iot.Registry
+
+	// 音频管线统计来源（可选）
+	audioMetrics AudioMetricsProvider
+
+	// 以音频文件代替麦克风的回放设备（可选），以及是否正在进行回放的一轮发言
+	audioReplayer AudioReplayer
+	replaying     bool
+
+	// 下行音频帧到达抖动统计（可选，为nil表示未启用）
+	jitter *jitterTracker
+
+	// 二进制音频封装方式；framed模式下的发送序号、时间戳起点（连接后首次发送时记录）和期望的下一个接收序号
+	audioFraming  protocol.AudioFraming
+	sendSequence  uint16
+	sendEpoch     time.Time
+	recvSequence  uint16
+	recvSeqActive bool
+
+	// 音频通道是否已打开：收到服务器hello后为true，断开后为false；打开后客户端回到空闲状态
+	audioChannelOpen bool
 
 	// 内部控制
-	helloReceived chan struct{}
+	helloResult    chan error    // 握手结果，收到合法的服务器hello时为nil，容量为1
+	connectTimeout time.Duration // 打开音频通道时等待连接建立的最长时间
+	helloTimeout   time.Duration // 发送hello后等待服务器hello的最长时间
+
+	// 关闭：closed由mu保护，closeDone在关闭流程结束后关闭
+	closed    bool
+	closeOnce sync.Once
+	closeDone chan struct{}
+	closeErr  error
 }
 
 // New 创建一个新的客户端实例
 func New(protocol protocol.Protocol) *Client {
 	client := &Client{
-		protocol:      protocol,
-		state:         StateIdle,
-		helloReceived: make(chan struct{}),
+		protocol:    protocol,
+		state:       StateIdle,
+		now:         time.Now,
+		helloResult: make(chan error, 1),
+		closeDone:   make(chan struct{}),
+		audioParams: defaultAudioParams(),
+
+		connectTimeout: DefaultConnectTimeout,
+		helloTimeout:   DefaultHelloTimeout,
+
+		dropAudioWhileListening: true,
+		preBufferFrames:         DefaultPreBufferFrames,
+		preBufferMaxAge:         DefaultPreBufferMaxAge,
 	}
 
 	// 设置协议回调
 	protocol.SetOnJSONMessage(client.handleJSONMessage)
 	protocol.SetOnBinaryMessage(client.handleBinaryMessage)
-	protocol.SetOnDisconnected(client.handleDisconnected)
+	client.watchDisconnect()
 	protocol.SetOnConnected(client.handleConnected)
 
 	return client
 }
 
+// defaultAudioParams 返回hello中默认协商的音频参数
+func defaultAudioParams() protocol.AudioParams {
+	return protocol.AudioParams{
+		Format:        "opus",
+		SampleRate:    16000,
+		Channels:      1,
+		FrameDuration: DefaultOpusFrameDuration,
+	}
+}
+
 // SetDeviceID 设置设备ID
 func (c *Client) SetDeviceID(deviceID string) {
 	c.mu.Lock()
@@ -102,6 +267,77 @@ func (c *Client) SetToken(token string) {
 	c.token = token
 }
 
+// SetHelloParams 设置打开音频通道时hello中请求的音频参数，默认为opus/16000Hz/单声道/60ms
+// 参数不合法时返回错误且不修改；已连接时在下次打开音频通道时生效，会话中更新请使用 UpdateAudioParams
+func (c *Client) SetHelloParams(params protocol.AudioParams) error {
+	if err := validateAudioParams(params); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.audioParams = params
+	return nil
+}
+
+// SetConnectTimeout 设置 OpenAudioChannel 等待连接建立的最长时间，默认 DefaultConnectTimeout
+// 该时间包含协议自身的握手，协议的握手超时（如WebSocket的 SetHandshakeTimeout，默认30秒）同样限制连接，
+// 实际生效的是两者中较小的一个；要延长连接等待时间需要同时调大协议的握手超时。
+// 协议实现了 protocol.ContextConnector（如WebSocket）时超时会中止拨号和握手，不会在后台留下仍在进行的连接
+func (c *Client) SetConnectTimeout(timeout time.Duration) error {
+	if timeout <= 0 {
+		return fmt.Errorf("无效的连接超时: %v", timeout)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.connectTimeout = timeout
+	return nil
+}
+
+// SetHelloTimeout 设置 OpenAudioChannel 发送hello后等待服务器hello的最长时间，默认 DefaultHelloTimeout
+// 从连接建立、hello发出后开始计时，不包含连接和握手的时间
+func (c *Client) SetHelloTimeout(timeout time.Duration) error {
+	if timeout <= 0 {
+		return fmt.Errorf("无效的hello超时: %v", timeout)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.helloTimeout = timeout
+	return nil
+}
+
+// SetProtocolVersion 设置hello和Protocol-Version头中发送的协议版本，必须是 protocol.SupportedProtocolVersions 之一
+func (c *Client) SetProtocolVersion(version int) error {
+	if version <= 0 || !protocol.IsSupportedVersion(version) {
+		return fmt.Errorf("不支持的协议版本%d，客户端支持: %v", version, protocol.SupportedProtocolVersions)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.protocolVersion = version
+	return nil
+}
+
+// helloVersionLocked 返回发送给服务器的协议版本，调用方需持有c.mu
+func (c *Client) helloVersionLocked() int {
+	if c.protocolVersion != 0 {
+		return c.protocolVersion
+	}
+	return protocol.ProtocolVersion
+}
+
+// validateAudioParams 检查音频参数能否被本地Opus编解码器使用
+func validateAudioParams(params protocol.AudioParams) error {
+	if params.Format != "opus" {
+		return fmt.Errorf("不支持的音频格式: %q，仅支持opus", params.Format)
+	}
+	if params.Channels != 1 && params.Channels != 2 {
+		return fmt.Errorf("无效的声道数: %d", params.Channels)
+	}
+	if err := audio.ValidateFrameDuration(params.SampleRate, params.FrameDuration); err != nil {
+		return fmt.Errorf("无效的音频参数: %v", err)
+	}
+	return nil
+}
+
 // SetOnStateChanged 设置状态变更的回调
 func (c *Client) SetOnStateChanged(callback func(oldState, newState string)) {
 	c.mu.Lock()
@@ -116,53 +352,699 @@ func (c *Client) SetOnNetworkError(callback func(err error)) {
 	c.onNetworkError = callback
 }
 
-// SetOnRecognizedText 设置识别文本的回调
-func (c *Client) SetOnRecognizedText(callback func(text string)) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.onRecognizedText = callback
+// SetOnDisconnected 设置连接断开的回调，reason说明断开的原因，自动重连应只在 reason.ShouldReconnect() 时进行。
+// 协议实现了 protocol.DisconnectReasonNotifier 时每次断开都会触发，包括 CloseAudioChannel 等本端主动断开；
+// 否则只在意外断开时触发
+func (c *Client) SetOnDisconnected(callback func(reason protocol.DisconnectReason, err error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onDisconnected = callback
+}
+
+// SetOnServerClose 设置服务器通过关闭帧结束连接时的回调，code为WebSocket关闭码，text为服务器给出的原因。
+// 鉴权失败、令牌过期等通常表现为1008或4000-4999的关闭码，可用 protocol.CloseCodeMeaning 转换为可读的说明；
+// 回调在 SetOnDisconnected 设置的回调之前触发
+func (c *Client) SetOnServerClose(callback func(code int, text string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onServerClose = callback
+}
+
+// LastDisconnectReason 返回最近一次断开的原因，尚未断开过时ok为false
+func (c *Client) LastDisconnectReason() (reason protocol.DisconnectReason, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastDisconnectReason, c.everDisconnected
+}
+
+// SetOnRecognizedText 设置识别文本的回调，只在收到最终识别结果时触发
+func (c *Client) SetOnRecognizedText(callback func(text string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onRecognizedText = callback
+}
+
+// SetOnPartialText 设置识别中间结果的回调，每次触发的text是到目前为止的完整识别文本，
+// 界面可以用它实时刷新，收到最终结果后以 SetOnFinalText 的文本为准
+func (c *Client) SetOnPartialText(callback func(text string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onPartialText = callback
+}
+
+// SetOnFinalText 设置最终识别结果的回调，服务器未标记结果类型时所有识别结果都按最终结果处理
+func (c *Client) SetOnFinalText(callback func(text string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onFinalText = callback
+}
+
+// SetOnAssistantPartial 设置回复文本增长时的回调，text为本轮到目前为止拼接好的回复，
+// 每收到一句tts的sentence_start或一段llm文本时触发，可用于界面实时显示
+func (c *Client) SetOnAssistantPartial(callback func(text string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onAssistantPartial = callback
+}
+
+// SetOnAssistantResponse 设置完整回复的回调，在tts stop时以本轮拼接好的完整回复触发，本轮没有文本时不触发。
+// 上一轮回复未结束就开始新一轮（开始监听、发送文本或断线）时，未完成的回复被丢弃
+func (c *Client) SetOnAssistantResponse(callback func(text string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onAssistantResponse = callback
+}
+
+// SetOnSpeakText 设置朗读文本的回调
+func (c *Client) SetOnSpeakText(callback func(text string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onSpeakText = callback
+}
+
+// SetOnAudioData 设置音频数据的回调
+func (c *Client) SetOnAudioData(callback func(data []byte)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onAudioData = callback
+}
+
+// SetOnAudioFrame 设置下行音频帧的回调，在 OnAudioData 之后以同样的顺序调用
+// framed模式下可从中取得帧头的序号和时间戳，plain模式下二者为0
+func (c *Client) SetOnAudioFrame(callback func(frame protocol.AudioFrame)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onAudioFrame = callback
+}
+
+// SetAudioFraming 设置二进制音频的封装方式，收发两个方向同时生效，需要服务器支持相同的格式。
+// 默认为 protocol.AudioFramingPlain，即裸Opus数据包；帧头格式见 protocol.AudioFrameHeaderSize
+func (c *Client) SetAudioFraming(mode protocol.AudioFraming) error {
+	if mode != protocol.AudioFramingPlain && mode != protocol.AudioFramingHeader {
+		return fmt.Errorf("不支持的音频封装方式: %s", mode)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.audioFraming = mode
+	c.resetAudioFramingLocked()
+	return nil
+}
+
+// AudioFraming 返回当前的二进制音频封装方式
+func (c *Client) AudioFraming() protocol.AudioFraming {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.audioFraming
+}
+
+// resetAudioFramingLocked 重置收发序号和时间戳起点，调用方需持有c.mu
+func (c *Client) resetAudioFramingLocked() {
+	c.sendSequence = 0
+	c.sendEpoch = time.Time{}
+	c.recvSeqActive = false
+}
+
+// frameAudioLocked 按当前封装方式封装一帧上行音频，调用方需持有c.mu
+func (c *Client) frameAudioLocked(data []byte) []byte {
+	if c.audioFraming != protocol.AudioFramingHeader {
+		return data
+	}
+	now := c.now()
+	if c.sendEpoch.IsZero() {
+		c.sendEpoch = now
+	}
+	frame := protocol.AudioFrame{
+		Sequence:  c.sendSequence,
+		Timestamp: uint32(now.Sub(c.sendEpoch).Milliseconds()),
+		Payload:   data,
+	}
+	c.sendSequence++
+	return protocol.EncodeAudioFrame(frame)
+}
+
+// unframeAudio 按当前封装方式解析一帧下行音频，framed模式下序号不连续时记录丢帧或乱序，丢帧数记入 AudioFrame.Lost
+func (c *Client) unframeAudio(data []byte) (protocol.AudioFrame, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.audioFraming != protocol.AudioFramingHeader {
+		return protocol.AudioFrame{Payload: data}, nil
+	}
+	frame, err := protocol.DecodeAudioFrame(data)
+	if err != nil {
+		return frame, err
+	}
+	if c.recvSeqActive && frame.Sequence != c.recvSequence {
+		if gap := frame.Sequence - c.recvSequence; gap < 0x8000 {
+			logger.Warnf("下行音频丢失 %d 帧: 期望序号%d, 收到%d", gap, c.recvSequence, frame.Sequence)
+			frame.Lost = int(gap)
+		} else {
+			logger.Warnf("下行音频乱序: 期望序号%d, 收到%d", c.recvSequence, frame.Sequence)
+			return frame, nil
+		}
+	}
+	c.recvSequence = frame.Sequence + 1
+	c.recvSeqActive = true
+	return frame, nil
+}
+
+// SetOnEmotionChanged 设置情感变更的回调
+func (c *Client) SetOnEmotionChanged(callback func(emotion, text string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onEmotionChanged = callback
+}
+
+// SetOnEmotion 设置类型化情感的回调
+// e为解析后的情感，未知情感为 protocol.EmotionUnknown；raw为服务器原始字符串，emoji为表情文本
+func (c *Client) SetOnEmotion(callback func(e protocol.Emotion, raw string, emoji string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onEmotion = callback
+}
+
+// SetOnAudioParamsChanged 设置音频参数更新的回调，用于重新配置本地编解码器和播放器
+func (c *Client) SetOnAudioParamsChanged(callback func(params protocol.AudioParams) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onAudioParamsChanged = callback
+}
+
+// SetOnIoTCommand 设置IoT命令的回调，回调参数为原始的commands数组
+// 保留用于兼容，新代码建议使用 SetOnIoTCommands
+func (c *Client) SetOnIoTCommand(callback func(commands []interface{})) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onIoTCommand = callback
+}
+
+// SetOnIoTCommands 设置类型化IoT命令的回调（推荐）
+// 非对象或缺少name/method的命令会被跳过并记录警告
+func (c *Client) SetOnIoTCommands(callback func(commands []protocol.IoTCommand)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onIoTCommands = callback
+}
+
+// SetIoTRegistry 设置IoT设备注册表
+// 设置后音频通道打开时自动发送设备描述符和状态，收到的IoT命令自动分发给注册的方法，执行后上报最新状态
+func (c *Client) SetIoTRegistry(registry *iot.Registry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.iotRegistry = registry
+}
+
+// SetBargeInGrace 设置打断宽限期
+// 在播放TTS时开始说话，发送中断消息和停止播放会延迟d，期间用户停止说话可通过 CancelBargeIn 取消
+func (c *Client) SetBargeInGrace(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bargeInGrace = d
+}
+
+// SetOnBargeIn 设置打断生效时的回调，通常用于停止本地播放
+func (c *Client) SetOnBargeIn(callback func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onBargeIn = callback
+}
+
+// SetAudioJitterTracking 启用或关闭下行音频帧到达抖动统计，重新启用时会清空之前的统计
+func (c *Client) SetAudioJitterTracking(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if enabled {
+		c.jitter = &jitterTracker{lastLog: c.now()}
+	} else {
+		c.jitter = nil
+	}
+}
+
+// SetListenMode 设置 SendStartListening 未指定模式时使用的监听模式
+// 在监听或播放回复期间调用时不影响当前这一轮，从下一次开始监听起生效；空闲时 ListenMode 立即返回新模式
+//
+// ListenModeRealtime 为全双工模式：监听期间照常接收并播放服务器音频，播放TTS（Speaking）期间
+// 也允许继续发送录音，TTS结束后回到监听状态而不是空闲，从而支持说话即打断。
+// 此时扬声器的声音会被麦克风采回并发送给服务器，可能被识别为用户语音而误打断，
+// 应在具备回声消除（AEC）的设备上使用，或使用耳机。
+func (c *Client) SetListenMode(mode string) error {
+	if err := validateListenMode(mode); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nextListenMode = mode
+	if c.state != StateIdle && c.state != StateConnecting && mode != c.listenMode {
+		logger.Debugf("监听模式将在下一轮监听时切换为: %s", mode)
+	}
+	return nil
+}
+
+// validateListenMode 检查监听模式是否为已知的模式常量
+func validateListenMode(mode string) error {
+	switch mode {
+	case ListenModeAuto, ListenModeManual, ListenModeRealtime:
+		return nil
+	default:
+		return fmt.Errorf("未知的监听模式: %s", mode)
+	}
+}
+
+// ListenMode 返回当前的监听模式：监听或播放回复期间为这一轮使用的模式，空闲时为下一轮将使用的模式
+func (c *Client) ListenMode() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if (c.state == StateIdle || c.state == StateConnecting) && c.nextListenMode != "" {
+		return c.nextListenMode
+	}
+	return c.listenMode
+}
+
+// IsFullDuplex 返回当前（或最近一轮）监听是否为全双工（实时）模式，此时播放期间录音不应停止
+func (c *Client) IsFullDuplex() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.listenMode == ListenModeRealtime
+}
+
+// canSendAudioLocked 判断当前能否发送录音数据：监听状态，或实时模式下的播放状态，调用方需持有c.mu
+func (c *Client) canSendAudioLocked() bool {
+	if c.state == StateListening {
+		return true
+	}
+	return c.state == StateSpeaking && c.listenMode == ListenModeRealtime
+}
+
+// SetDropAudioWhileListening 设置监听期间是否丢弃下行音频（默认开启）。
+//
+// 客户端按半双工工作：录音（Listening）时不播放服务器音频，避免扬声器声音被麦克风采回。
+// 开启时监听期间收到的音频不会立即交给 OnAudioData，只保留最近的少量帧（见 SetAudioPreBuffer），
+// 离开监听状态后收到下一帧音频或TTS开始时先补发这些帧，防止状态切换前后到达的TTS开头被截断；
+// 关闭时监听期间的音频照常回调，由调用方自行决定如何处理。实时监听模式（全双工）下不丢弃音频，不受此设置影响。
+func (c *Client) SetDropAudioWhileListening(drop bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dropAudioWhileListening = drop
+	if !drop {
+		c.preBuffer = nil
+	}
+}
+
+// SetAudioPreBuffer 设置监听期间保留的音频帧数和最长保留时间，frames为0时不保留，
+// maxAge为0时使用 DefaultPreBufferMaxAge
+func (c *Client) SetAudioPreBuffer(frames int, maxAge time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if frames < 0 {
+		frames = 0
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultPreBufferMaxAge
+	}
+	c.preBufferFrames = frames
+	c.preBufferMaxAge = maxAge
+	if len(c.preBuffer) > frames {
+		c.preBuffer = c.preBuffer[len(c.preBuffer)-frames:]
+	}
+}
+
+// bufferAudioLocked 暂存监听期间收到的音频帧，仅保留最近的 preBufferFrames 帧，调用方需持有c.mu
+func (c *Client) bufferAudioLocked(frame protocol.AudioFrame) {
+	if c.preBufferFrames == 0 {
+		return
+	}
+	frame.Payload = append([]byte(nil), frame.Payload...)
+	c.preBuffer = append(c.preBuffer, bufferedFrame{frame: frame, received: c.now()})
+	if len(c.preBuffer) > c.preBufferFrames {
+		c.preBuffer = c.preBuffer[len(c.preBuffer)-c.preBufferFrames:]
+	}
+}
+
+// takePreBufferLocked 取出未过期的预缓冲帧并清空缓冲，调用方需持有c.mu
+func (c *Client) takePreBufferLocked() []protocol.AudioFrame {
+	if len(c.preBuffer) == 0 {
+		return nil
+	}
+	now := c.now()
+	frames := make([]protocol.AudioFrame, 0, len(c.preBuffer))
+	for _, buffered := range c.preBuffer {
+		if now.Sub(buffered.received) <= c.preBufferMaxAge {
+			frames = append(frames, buffered.frame)
+		}
+	}
+	c.preBuffer = nil
+	return frames
+}
+
+// flushPreBuffer 将预缓冲的音频帧交给音频回调
+func (c *Client) flushPreBuffer() {
+	c.mu.Lock()
+	frames := c.takePreBufferLocked()
+	onAudioData := c.onAudioData
+	onAudioFrame := c.onAudioFrame
+	c.mu.Unlock()
+
+	if len(frames) > 0 {
+		logger.Debugf("补发监听期间缓冲的 %d 帧音频", len(frames))
+	}
+	for _, frame := range frames {
+		deliverAudioFrame(onAudioData, onAudioFrame, frame)
+	}
+}
+
+// deliverAudioFrame 依次把一帧下行音频交给音频数据回调和音频帧回调
+func deliverAudioFrame(onAudioData func([]byte), onAudioFrame func(protocol.AudioFrame), frame protocol.AudioFrame) {
+	if onAudioData != nil {
+		onAudioData(frame.Payload)
+	}
+	if onAudioFrame != nil {
+		onAudioFrame(frame)
+	}
+}
+
+// AudioJitterStats 返回下行音频帧到达间隔统计，未启用时返回零值
+func (c *Client) AudioJitterStats() JitterStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.jitter == nil {
+		return JitterStats{}
+	}
+	return c.jitter.stats()
+}
+
+// AudioMetricsProvider 提供音频管线统计，*audio.AudioManagerNew 实现了该接口
+type AudioMetricsProvider interface {
+	Metrics() audio.AudioMetrics
+}
+
+// SetAudioMetricsProvider 设置音频管线统计的来源，传nil清除
+func (c *Client) SetAudioMetricsProvider(provider AudioMetricsProvider) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.audioMetrics = provider
+}
+
+// AudioMetrics 返回音频管线统计快照，可在监控goroutine中定期调用；未设置统计来源时返回零值
+func (c *Client) AudioMetrics() audio.AudioMetrics {
+	c.mu.Lock()
+	provider := c.audioMetrics
+	c.mu.Unlock()
+
+	if provider == nil {
+		return audio.AudioMetrics{}
+	}
+	return provider.Metrics()
+}
+
+// SetOnAudioChannelOpen 设置音频通道打开的回调
+func (c *Client) SetOnAudioChannelOpen(callback func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onAudioChannelOpen = callback
+}
+
+// SetOnAudioChannelClosed 设置音频通道关闭的回调
+func (c *Client) SetOnAudioChannelClosed(callback func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onAudioChannelClosed = callback
+}
+
+// SetOnVersionMismatch 设置服务器协议版本不兼容时的回调，回调后连接会被断开
+func (c *Client) SetOnVersionMismatch(callback func(serverVersion int)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onVersionMismatch = callback
+}
+
+// SetOnMCPRequest 设置处理服务器MCP（工具调用）请求的回调，传nil清除
+//
+// 设置后hello中声明支持 protocol.FeatureMCP，服务器发来的每个JSON-RPC请求都交给回调处理，
+// 回调返回的结果或错误（*protocol.MCPError 原样发回，其他错误按内部错误发回）以"mcp"消息回复给服务器；
+// 通知没有ID，回调的返回值被忽略。回调在单独的goroutine中执行，耗时的工具不会阻塞消息接收，
+// 多个请求可能并发处理。可用 MCPRequest.ToolCall 解析tools/call的参数。对已打开的音频通道，hello中的能力声明在下次连接时生效
+func (c *Client) SetOnMCPRequest(callback func(req protocol.MCPRequest) (protocol.MCPResponse, error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onMCPRequest = callback
+}
+
+// helloFeaturesLocked 返回hello中声明的客户端能力，没有时为nil，调用方需持有c.mu
+func (c *Client) helloFeaturesLocked() map[string]bool {
+	if c.onMCPRequest == nil {
+		return nil
+	}
+	return map[string]bool{protocol.FeatureMCP: true}
+}
+
+// SetOnUnknownMessage 设置收到客户端未处理的JSON消息类型时的回调，
+// 便于在不修改客户端的情况下支持服务器新增的消息（如"system"）；未设置 SetOnMCPRequest 时"mcp"消息也交给该回调
+func (c *Client) SetOnUnknownMessage(callback func(msgType string, raw []byte)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onUnknownMessage = callback
+}
+
+// SetOnRawJSON 设置原始JSON消息回调，每条JSON消息在分发处理之前都会回调，可用于日志记录或调试
+// 回调在接收循环中同步执行，不应修改raw或长时间阻塞
+func (c *Client) SetOnRawJSON(callback func(raw []byte)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onRawJSON = callback
+}
+
+// SetOnSpeakingFinished 设置TTS音频实际播放完毕时的回调。
+// 与TTS stop消息不同，该回调在本地播放队列播空后才触发，每轮TTS最多触发一次
+func (c *Client) SetOnSpeakingFinished(callback func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onSpeakingFinished = callback
+}
+
+// PlaybackDrained 通知客户端本地播放队列已播空，通常接到 AudioManagerNew.SetOnQueueDrained。
+// TTS仍在进行时仅做记录，待TTS结束后再判断是否播放完毕
+func (c *Client) PlaybackDrained() {
+	c.mu.Lock()
+	if c.state == StateSpeaking {
+		c.drainedDuringTTS = true
+		c.mu.Unlock()
+		return
+	}
+	callback := c.takeSpeakingFinishedLocked()
+	c.mu.Unlock()
+
+	if callback != nil {
+		callback()
+	}
+}
+
+// takeSpeakingFinishedLocked 返回本轮待触发的播放结束回调并清除标记，调用方需持有c.mu
+func (c *Client) takeSpeakingFinishedLocked() func() {
+	if !c.speakingFinishPending {
+		return nil
+	}
+	c.speakingFinishPending = false
+	c.drainedDuringTTS = false
+	return c.onSpeakingFinished
+}
+
+// SetSessionResume 设置连接意外断开后是否保留会话ID（默认关闭）。
+// 开启后重新打开音频通道时在hello中携带原会话ID，后续listen等消息也继续使用它，
+// 便于服务器保留对话上下文；需要服务器支持。主动调用 CloseAudioChannel 仍会结束会话
+func (c *Client) SetSessionResume(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sessionResume = enabled
+}
+
+// SessionID 返回当前会话ID，尚未开始会话时为空
+func (c *Client) SessionID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sessionID
+}
+
+// SetReconnectAudioBuffer 设置手动监听中途意外断线时是否暂存上行音频（默认关闭），maxAge为0时关闭。
+//
+// 开启后，若连接在手动监听（按住说话）期间因错误断开，客户端进入等待重连状态：录音不停止，
+// SendAudioData 不再报错而是把数据包暂存起来，只保留最近maxAge时长、不超过maxBytes字节（为0时使用
+// DefaultReconnectBufferBytes）的音频。调用方重新打开音频通道后客户端自动以手动模式恢复监听，
+// 按顺序补发暂存的音频，用户可继续说话；等待期间调用 SendStopListening（松开按键）或 CloseAudioChannel 则丢弃暂存的音频。
+// 配合 SetSessionResume 时补发的音频属于原会话，服务器可将其接在断线前的音频之后；否则属于新会话的一轮新发言
+func (c *Client) SetReconnectAudioBuffer(maxAge time.Duration, maxBytes int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if maxAge <= 0 {
+		c.reconnectAudio = nil
+		return
+	}
+	c.reconnectAudio = newReconnectBuffer(maxAge, maxBytes)
+}
+
+// IsReconnectBuffering 返回是否正在暂存断线期间的上行音频、等待重连后恢复监听
+func (c *Client) IsReconnectBuffering() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reconnectHoldingLocked()
+}
+
+// reconnectHoldingLocked 返回是否正在等待重连，调用方需持有c.mu
+func (c *Client) reconnectHoldingLocked() bool {
+	return c.reconnectAudio != nil && c.reconnectAudio.holding
+}
+
+// discardReconnectAudioLocked 放弃等待重连，丢弃暂存的上行音频，调用方需持有c.mu
+func (c *Client) discardReconnectAudioLocked(reason string) {
+	if !c.reconnectHoldingLocked() {
+		return
+	}
+	logger.Infof("%s，丢弃断线期间暂存的%d帧音频", reason, len(c.reconnectAudio.frames))
+	c.reconnectAudio.reset()
+}
+
+// flushReconnectAudio 恢复监听后按顺序补发断线期间暂存的上行音频
+// 补发期间新采集的音频仍追加到缓冲末尾，直到缓冲取空才结束暂存，之后的音频直接发送
+func (c *Client) flushReconnectAudio() {
+	sent := 0
+	for {
+		c.mu.Lock()
+		if !c.reconnectHoldingLocked() {
+			c.mu.Unlock()
+			return
+		}
+		data, ok := c.reconnectAudio.pop(c.now())
+		if !ok {
+			dropped := c.reconnectAudio.dropped
+			c.reconnectAudio.reset()
+			c.mu.Unlock()
+			if dropped > 0 {
+				logger.Warnf("断线期间暂存的音频超出上限，已丢弃最早的%d帧", dropped)
+			}
+			logger.Infof("恢复监听，已补发断线期间暂存的%d帧音频", sent)
+			return
+		}
+		data = c.frameAudioLocked(data)
+		c.mu.Unlock()
+
+		if err := wrapSendAudioError(c.protocol.SendBinary(data)); err != nil {
+			c.mu.Lock()
+			c.discardReconnectAudioLocked(fmt.Sprintf("补发暂存的音频失败: %v", err))
+			c.mu.Unlock()
+			return
+		}
+		sent++
+	}
+}
+
+// clearSessionOnDisconnectLocked 连接断开时清除会话ID，开启会话恢复时保留，调用方需持有c.mu
+func (c *Client) clearSessionOnDisconnectLocked() {
+	if c.sessionResume && c.sessionID != "" {
+		logger.Debugf("保留会话ID以便重连后恢复: %s", c.sessionID)
+		return
+	}
+	c.sessionID = ""
+}
+
+// Stats 返回底层连接的收发统计，协议不支持统计时返回零值
+func (c *Client) Stats() protocol.ProtocolStats {
+	if provider, ok := c.protocol.(protocol.StatsProvider); ok {
+		return provider.Stats()
+	}
+	return protocol.ProtocolStats{}
+}
+
+// Ping 向服务器发送ping并等待对应的pong，返回往返时延，可用于健康检查和显示连接质量。
+// ctx没有截止时间时最多等待 DefaultPingTimeout；到期未收到pong返回 ErrPingTimeout，
+// 未连接时返回 ErrNotConnected，协议不支持测量往返时延时返回错误
+func (c *Client) Ping(ctx context.Context) (time.Duration, error) {
+	pinger, ok := c.protocol.(protocol.Pinger)
+	if !ok {
+		return 0, errors.New("协议不支持测量往返时延")
+	}
+	if !c.protocol.IsConnected() {
+		return 0, fmt.Errorf("无法发送ping: %w", ErrNotConnected)
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultPingTimeout)
+		defer cancel()
+	}
+
+	rtt, err := pinger.PingContext(ctx)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return 0, ErrPingTimeout
+		}
+		return 0, err
+	}
+	return rtt, nil
 }
 
-// SetOnSpeakText 设置朗读文本的回调
-func (c *Client) SetOnSpeakText(callback func(text string)) {
+// ProtocolVersion 返回当前连接协商的协议版本，服务器未声明版本时为客户端版本
+func (c *Client) ProtocolVersion() int {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.onSpeakText = callback
-}
 
-// SetOnAudioData 设置音频数据的回调
-func (c *Client) SetOnAudioData(callback func(data []byte)) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.onAudioData = callback
+	if c.serverVersion != 0 {
+		return c.serverVersion
+	}
+	return c.helloVersionLocked()
 }
 
-// SetOnEmotionChanged 设置情感变更的回调
-func (c *Client) SetOnEmotionChanged(callback func(emotion, text string)) {
+// SetOnTurnLatency 设置轮次延迟的回调
+// 延迟为发送停止监听消息到收到首个TTS start或音频帧之间的时间，每轮只回调一次
+func (c *Client) SetOnTurnLatency(callback func(d time.Duration)) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.onEmotionChanged = callback
+	c.onTurnLatency = callback
 }
 
-// SetOnIoTCommand 设置IoT命令的回调
-func (c *Client) SetOnIoTCommand(callback func(commands []interface{})) {
+// SetVADAutoStop 设置手动监听模式下的静音自动停止
+// 启用后需通过 ProcessPCM 送入录音数据，说话结束且静音持续silenceDuration后自动发送停止监听消息
+// 能量阈值在首次监听开始时根据噪声基底自动校准
+func (c *Client) SetVADAutoStop(enabled bool, silenceDuration time.Duration) {
+	var vad *audio.VAD
+	if enabled {
+		vad = audio.NewVAD(audio.VADOptions{Hangover: silenceDuration})
+		vad.SetOnEvent(c.handleVADEvent)
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.onIoTCommand = callback
+	c.vad = vad
 }
 
-// SetOnAudioChannelOpen 设置音频通道打开的回调
-func (c *Client) SetOnAudioChannelOpen(callback func()) {
+// ProcessPCM 将录音的PCM帧送入语音活动检测，仅在启用静音自动停止且处于监听状态时生效
+func (c *Client) ProcessPCM(pcm []int16) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.onAudioChannelOpen = callback
+	vad := c.vad
+	active := c.state == StateListening && c.listenMode == ListenModeManual
+	c.mu.Unlock()
+
+	if vad != nil && active {
+		vad.Process(pcm)
+	}
 }
 
-// SetOnAudioChannelClosed 设置音频通道关闭的回调
-func (c *Client) SetOnAudioChannelClosed(callback func()) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.onAudioChannelClosed = callback
+// handleVADEvent 处理语音活动检测事件
+func (c *Client) handleVADEvent(event audio.VADEvent) {
+	logger.Debugf("语音活动检测事件: %s", event)
+	if event != audio.SpeechEnded {
+		return
+	}
+
+	// 在独立的goroutine中发送，避免阻塞录音线程
+	go func() {
+		logger.Infof("检测到说话结束，自动停止监听")
+		if err := c.SendStopListening(); err != nil {
+			logger.Warnf("自动停止监听失败: %v", err)
+		}
+	}()
 }
 
 // GetState 获取当前状态
@@ -172,39 +1054,43 @@ func (c *Client) GetState() string {
 	return c.state
 }
 
-// SetState 内部方法，用于更新状态并触发回调
-func (c *Client) SetState(newState string) {
-	c.mu.Lock()
-	oldState := c.state
-	c.state = newState
-	onStateChanged := c.onStateChanged
-	c.mu.Unlock()
-
-	if oldState != newState && onStateChanged != nil {
-		onStateChanged(oldState, newState)
-	}
+// SetState 更新状态并触发回调，不在转换表中的非法转换会被拒绝，
+// 返回包装了 ErrIllegalStateTransition 的错误
+func (c *Client) SetState(newState string) error {
+	return c.transition(newState)
 }
 
-// OpenAudioChannel 打开音频通道
+// OpenAudioChannel 打开音频通道：设置请求头并连接服务器，发送hello后等待服务器hello，
+// 成功后客户端回到空闲状态；连接或握手失败时断开连接并返回错误
 func (c *Client) OpenAudioChannel(url string) error {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClientClosed
+	}
 	if c.state != StateIdle {
 		c.mu.Unlock()
-		return errors.New("客户端不在空闲状态，无法打开音频通道")
+		return fmt.Errorf("客户端不在空闲状态(%s)，无法打开音频通道: %w", c.state, ErrInvalidState)
+	}
+	if c.audioChannelOpen {
+		c.mu.Unlock()
+		return fmt.Errorf("音频通道已打开: %w", ErrInvalidState)
 	}
-	c.SetState(StateConnecting)
+	c.transitionLocked(StateConnecting)
+	onStateChanged := c.onStateChanged
 
 	// 准备请求头 - 确保请求头设置完整
 	if c.token != "" {
 		c.protocol.SetHeader("Authorization", fmt.Sprintf("Bearer %s", c.token))
-		logrus.Debugf("设置Authorization头: %s", fmt.Sprintf("Bearer %s", c.token))
+		logger.Debugf("设置Authorization头: %s", fmt.Sprintf("Bearer %s", c.token))
 	}
-	c.protocol.SetHeader("Protocol-Version", "1")
-	logrus.Debug("设置Protocol-Version头: 1")
+	version := c.helloVersionLocked()
+	c.protocol.SetHeader("Protocol-Version", strconv.Itoa(version))
+	logger.Debugf("设置Protocol-Version头: %d", version)
 
 	if c.deviceID != "" {
 		c.protocol.SetHeader("Device-Id", c.deviceID)
-		logrus.Debugf("设置Device-Id头: %s", c.deviceID)
+		logger.Debugf("设置Device-Id头: %s", c.deviceID)
 	} else {
 		// 尝试获取MAC地址作为设备ID
 		interfaces, err := net.Interfaces()
@@ -213,7 +1099,7 @@ func (c *Client) OpenAudioChannel(url string) error {
 				if i.HardwareAddr != nil && len(i.HardwareAddr) > 0 {
 					c.deviceID = i.HardwareAddr.String()
 					c.protocol.SetHeader("Device-Id", c.deviceID)
-					logrus.Debugf("设置Device-Id头(MAC): %s", c.deviceID)
+					logger.Debugf("设置Device-Id头(MAC): %s", c.deviceID)
 					break
 				}
 			}
@@ -222,106 +1108,198 @@ func (c *Client) OpenAudioChannel(url string) error {
 
 	if c.clientID != "" {
 		c.protocol.SetHeader("Client-Id", c.clientID)
-		logrus.Debugf("设置Client-Id头: %s", c.clientID)
+		logger.Debugf("设置Client-Id头: %s", c.clientID)
 	} else {
 		// 生成UUID作为客户端ID
 		c.clientID = uuid.New().String()
 		c.protocol.SetHeader("Client-Id", c.clientID)
-		logrus.Debugf("设置Client-Id头(新生成): %s", c.clientID)
+		logger.Debugf("设置Client-Id头(新生成): %s", c.clientID)
 	}
 
 	// 打印请求头和WebSocket地址
 	headers := c.protocol.GetHeaders()
-	logrus.Infof("WebSocket请求头: %v", headers)
+	logger.Infof("WebSocket请求头: %v", headers)
 
 	// 重置hello接收通道
-	c.helloReceived = make(chan struct{})
+	c.helloResult = make(chan error, 1)
+	helloResult := c.helloResult
 	c.mu.Unlock()
 
+	c.notifyStateChanged(onStateChanged, StateIdle, StateConnecting)
+
 	// 如果URL为空，使用默认URL
 	if url == "" {
 		url = DefaultWebSocketURL
 	}
 
 	// 打印WebSocket地址
-	logrus.Infof("WebSocket地址: %s", url)
+	logger.Infof("WebSocket地址: %s", url)
 
 	// 连接WebSocket服务器
 	var err error
 
-	// 使用更短的连接超时，与测试模式保持一致
-	connectDone := make(chan error, 1)
-	go func() {
-		logrus.Debug("开始尝试WebSocket连接...")
-		connectStart := time.Now()
-		connErr := c.protocol.Connect(url)
-		elapsed := time.Since(connectStart)
-		logrus.Debugf("WebSocket连接尝试完成，耗时: %v, 结果: %v", elapsed, connErr)
-		connectDone <- connErr
-	}()
+	c.mu.Lock()
+	connectTimeout := c.connectTimeout
+	helloTimeout := c.helloTimeout
+	c.mu.Unlock()
 
-	// 更短的连接超时 (15秒)
-	select {
-	case err = <-connectDone:
-		if err != nil {
-			logrus.Errorf("WebSocket连接失败: %v", err)
-			c.SetState(StateIdle)
-			return err
+	// 协议支持 ConnectContext 时超时会中止拨号和握手；否则只能停止等待，连接尝试在后台自行结束
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+	logger.Debugf("开始尝试WebSocket连接...")
+	connectStart := time.Now()
+	if connector, ok := c.protocol.(protocol.ContextConnector); ok {
+		err = connector.ConnectContext(ctx, url)
+	} else {
+		connectDone := make(chan error, 1)
+		go func() {
+			connectDone <- c.protocol.Connect(url)
+		}()
+		select {
+		case err = <-connectDone:
+		case <-ctx.Done():
+			err = ctx.Err()
 		}
-		logrus.Info("WebSocket连接成功，准备发送hello消息")
-	case <-time.After(15 * time.Second):
-		logrus.Error("WebSocket连接超时 (15秒)")
+	}
+	logger.Debugf("WebSocket连接尝试完成，耗时: %v, 结果: %v", time.Since(connectStart), err)
+
+	if err != nil {
 		c.SetState(StateIdle)
-		return errors.New("连接WebSocket服务器超时")
+		if ctx.Err() == context.DeadlineExceeded {
+			logger.Errorf("WebSocket连接超时 (%v)", connectTimeout)
+			return ErrConnectTimeout
+		}
+		logger.Errorf("WebSocket连接失败: %v", err)
+		return err
 	}
+	logger.Infof("WebSocket连接成功，准备发送hello消息")
 
 	// 发送Hello消息
+	c.mu.Lock()
 	hello := protocol.HelloMessage{
-		Type:      "hello",
-		Version:   1,
-		Transport: "websocket",
-		AudioParams: protocol.AudioParams{
-			Format:        "opus",
-			SampleRate:    16000,
-			Channels:      1,
-			FrameDuration: DefaultOpusFrameDuration,
-		},
+		Type:        "hello",
+		Version:     c.helloVersionLocked(),
+		Transport:   c.transport(),
+		AudioParams: c.audioParams,
+		Features:    c.helloFeaturesLocked(),
+	}
+	if c.sessionResume && c.sessionID != "" {
+		// 请求服务器恢复断线前的会话
+		hello.SessionID = c.sessionID
+		logger.Infof("请求恢复会话: %s", c.sessionID)
 	}
+	c.mu.Unlock()
 
 	// 发送hello前记录日志
 	logJSON, _ := json.Marshal(hello)
-	logrus.Debugf("发送hello消息: %s", string(logJSON))
+	logger.Debugf("发送hello消息: %s", string(logJSON))
 
 	// 发送hello消息
 	err = c.protocol.SendJSON(hello)
 	if err != nil {
-		logrus.Errorf("发送hello消息失败: %v", err)
+		logger.Errorf("发送hello消息失败: %v", err)
 		c.protocol.Disconnect()
 		c.SetState(StateIdle)
 		return err
 	}
-	logrus.Info("已成功发送hello消息，等待服务器响应")
+	logger.Infof("已成功发送hello消息，等待服务器响应")
 
 	// 等待服务器Hello响应
 	select {
-	case <-c.helloReceived:
+	case err := <-helloResult:
+		if err != nil {
+			// 服务器hello不可用（例如协议版本不兼容），处理hello时已断开连接
+			c.SetState(StateIdle)
+			return err
+		}
 		// 成功接收到服务器Hello响应
-		logrus.Info("成功接收到服务器hello响应！")
+		logger.Infof("成功接收到服务器hello响应！")
 		c.mu.Lock()
+		c.audioChannelOpen = true
 		onAudioChannelOpen := c.onAudioChannelOpen
+		registry := c.iotRegistry
+		resumeListening := c.reconnectHoldingLocked()
 		c.mu.Unlock()
 
+		// 握手完成，回到空闲状态等待开始监听、发送文本或服务器的回复
+		c.SetState(StateIdle)
+
+		// 上报IoT设备描述符和初始状态
+		if registry != nil {
+			if err := c.SendIoTDescriptors(registry.Descriptors()); err != nil {
+				logger.Warnf("发送IoT设备描述符失败: %v", err)
+			}
+			if err := c.SendIoTState(registry.States()); err != nil {
+				logger.Warnf("发送IoT设备状态失败: %v", err)
+			}
+		}
+
 		if onAudioChannelOpen != nil {
 			onAudioChannelOpen()
 		}
+
+		// 断线前用户仍在按住说话：恢复监听并补发断线期间暂存的音频
+		if resumeListening {
+			if err := c.SendStartListening(ListenModeManual); err != nil {
+				logger.Warnf("重连后恢复监听失败: %v", err)
+				c.mu.Lock()
+				c.discardReconnectAudioLocked("未能恢复监听")
+				c.mu.Unlock()
+			}
+		}
 		return nil
-	case <-time.After(DefaultHelloTimeout):
+	case <-time.After(helloTimeout):
 		// 超时未收到Hello响应
-		logrus.Error("等待服务器hello响应超时")
+		logger.Errorf("等待服务器hello响应超时")
 		c.protocol.Disconnect()
 		c.SetState(StateIdle)
-		return errors.New("等待服务器Hello响应超时")
+		return ErrHelloTimeout
+	}
+}
+
+// UpdateAudioParams 在不断开连接的情况下更新音频参数
+// 仅当服务器hello声明支持 protocol.FeatureAudioParamsUpdate 时可用：重发带新参数的hello，
+// 然后通过 SetOnAudioParamsChanged 设置的回调重新配置本地编解码器和播放器
+func (c *Client) UpdateAudioParams(params protocol.AudioParams) error {
+	if err := validateAudioParams(params); err != nil {
+		return err
+	}
+	if !c.protocol.IsConnected() {
+		return ErrNotConnected
+	}
+
+	c.mu.Lock()
+	supported := c.serverFeatures[protocol.FeatureAudioParamsUpdate]
+	onAudioParamsChanged := c.onAudioParamsChanged
+	version := c.helloVersionLocked()
+	features := c.helloFeaturesLocked()
+	c.mu.Unlock()
+
+	if !supported {
+		return errors.New("服务器不支持会话中更新音频参数，请重新连接")
+	}
+
+	hello := protocol.HelloMessage{
+		Type:        "hello",
+		Version:     version,
+		Transport:   c.transport(),
+		AudioParams: params,
+		Features:    features,
+	}
+	if err := c.protocol.SendJSON(hello); err != nil {
+		return fmt.Errorf("发送音频参数更新失败: %v", err)
+	}
+
+	c.mu.Lock()
+	c.audioParams = params
+	c.mu.Unlock()
+
+	if onAudioParamsChanged != nil {
+		if err := onAudioParamsChanged(params); err != nil {
+			return fmt.Errorf("重新配置本地音频失败: %v", err)
+		}
 	}
+	return nil
 }
 
 // CloseAudioChannel 关闭音频通道
@@ -329,12 +1307,15 @@ func (c *Client) CloseAudioChannel() error {
 	// 添加恢复机制，防止任何可能的异常
 	defer func() {
 		if r := recover(); r != nil {
-			logrus.Errorf("关闭音频通道时发生异常: %v", r)
+			logger.Errorf("关闭音频通道时发生异常: %v", r)
 		}
 	}()
 
+	// 主动关闭时结束会话，即使开启了会话恢复也不保留会话ID
 	c.mu.Lock()
-	if c.state == StateIdle {
+	c.sessionID = ""
+	c.discardReconnectAudioLocked("音频通道已关闭")
+	if c.state == StateIdle && !c.audioChannelOpen {
 		c.mu.Unlock()
 		return nil
 	}
@@ -346,7 +1327,7 @@ func (c *Client) CloseAudioChannel() error {
 		defer func() {
 			if r := recover(); r != nil {
 				err = fmt.Errorf("断开连接时发生异常: %v", r)
-				logrus.Error(err)
+				logger.Errorf("%v", err)
 			}
 		}()
 
@@ -354,7 +1335,12 @@ func (c *Client) CloseAudioChannel() error {
 	}()
 
 	// 无论是否出错，都调用断开连接处理程序
-	c.handleDisconnected(err)
+	c.handleDisconnected(protocol.DisconnectUserInitiated, err)
+
+	c.mu.Lock()
+	c.sessionID = ""
+	c.discardReconnectAudioLocked("音频通道已关闭")
+	c.mu.Unlock()
 
 	// 确保状态设置为空闲
 	c.SetState(StateIdle)
@@ -362,12 +1348,105 @@ func (c *Client) CloseAudioChannel() error {
 	return err
 }
 
-// SendStartListening 发送开始监听的消息
+// Close 关闭客户端：监听中时先发送停止监听消息，完成WebSocket关闭握手后关闭音频通道并断开连接，
+// 连接断开后保活随之停止；关闭后 OpenAudioChannel 返回 ErrClientClosed，调用方的重连逻辑应通过 IsClosed 判断是否继续。
+// 关闭完成或ctx到期时返回，ctx到期时强制断开连接并返回ctx.Err()。
+// 可重复调用，也可在信号处理中调用，后续调用等待同一次关闭流程
+func (c *Client) Close(ctx context.Context) error {
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		c.closed = true
+		if c.bargeInTimer != nil {
+			c.bargeInTimer.Stop()
+			c.bargeInTimer = nil
+		}
+		c.mu.Unlock()
+
+		go func() {
+			defer close(c.closeDone)
+			c.closeErr = c.shutdown(ctx)
+		}()
+	})
+
+	select {
+	case <-c.closeDone:
+		return c.closeErr
+	case <-ctx.Done():
+		// 关闭流程可能卡在网络操作上，强制断开让它尽快结束
+		if fp, ok := c.protocol.(interface{ ForceDisconnect() }); ok {
+			fp.ForceDisconnect()
+		}
+		return ctx.Err()
+	}
+}
+
+// IsClosed 返回是否已调用 Close
+func (c *Client) IsClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// shutdown 执行 Close 的关闭流程
+func (c *Client) shutdown(ctx context.Context) error {
+	c.mu.Lock()
+	state := c.state
+	sessionID := c.sessionID
+	c.mu.Unlock()
+
+	// 让服务器结束本轮识别，失败不影响后续关闭
+	if state == StateListening && c.protocol.IsConnected() {
+		listen := protocol.ListenMessage{
+			SessionID: sessionID,
+			Type:      "listen",
+			State:     "stop",
+		}
+		if err := c.protocol.SendJSONContext(ctx, listen); err != nil {
+			logger.Warnf("关闭时发送停止监听消息失败: %v", err)
+		}
+	}
+
+	// 先完成关闭握手，让服务器及时结束会话
+	if gp, ok := c.protocol.(interface {
+		DisconnectGraceful(timeout time.Duration) error
+	}); ok && c.protocol.IsConnected() {
+		timeout := DefaultCloseHandshakeTimeout
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+			timeout = time.Until(deadline)
+		}
+		if timeout > 0 {
+			if err := gp.DisconnectGraceful(timeout); err != nil {
+				logger.Warnf("WebSocket关闭握手未完成: %v", err)
+			}
+		}
+	}
+
+	err := c.CloseAudioChannel()
+
+	// 调用方可能直接驱动协议连接，客户端处于空闲状态而连接仍然存在
+	if c.protocol.IsConnected() {
+		if disconnectErr := c.protocol.Disconnect(); disconnectErr != nil && err == nil {
+			err = disconnectErr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("关闭客户端失败: %v", err)
+	}
+	return nil
+}
+
+// SendStartListening 发送开始监听的消息，mode为空时使用 SetListenMode 设置的模式，未设置时沿用上一轮的模式，默认手动模式
 func (c *Client) SendStartListening(mode string) error {
+	if mode != "" {
+		if err := validateListenMode(mode); err != nil {
+			return err
+		}
+	}
+
 	c.mu.Lock()
 	if c.state != StateConnecting && c.state != StateIdle && c.state != StateSpeaking {
 		c.mu.Unlock()
-		return errors.New("客户端状态不允许开始监听")
+		return fmt.Errorf("当前状态(%s)无法开始监听: %w", c.state, ErrInvalidState)
 	}
 
 	// 设置会话ID和监听模式
@@ -375,7 +1454,13 @@ func (c *Client) SendStartListening(mode string) error {
 		c.sessionID = uuid.New().String()
 	}
 
-	// 设置监听模式
+	// 设置监听模式，未指定时使用 SetListenMode 设置的模式，再沿用上一轮的模式
+	if mode == "" {
+		mode = c.nextListenMode
+	}
+	if mode == "" {
+		mode = c.listenMode
+	}
 	if mode == "" {
 		mode = ListenModeManual
 	}
@@ -397,17 +1482,106 @@ func (c *Client) SendStartListening(mode string) error {
 		return err
 	}
 
+	// 新一轮监听，清除上一轮的语音活动状态
+	c.mu.Lock()
+	vad := c.vad
+	c.response.reset()
+	// 丢弃上一轮残留的预缓冲音频
+	c.preBuffer = nil
+	c.mu.Unlock()
+	if vad != nil {
+		vad.Reset()
+	}
+
 	// 更新状态
 	c.SetState(StateListening)
+
+	// 重连后恢复监听时补发断线期间暂存的音频
+	c.flushReconnectAudio()
 	return nil
 }
 
+// AudioReplayer 能把音频文件按实时节奏送入录音处理流程的音频设备，*audio.AudioManagerNew 实现了该接口
+type AudioReplayer interface {
+	ReplayFile(ctx context.Context, path string) error
+}
+
+// SetAudioReplayer 设置 StartReplay 使用的回放设备，传nil清除
+func (c *Client) SetAudioReplayer(replayer AudioReplayer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.audioReplayer = replayer
+}
+
+// StartReplay 以音频文件代替麦克风完成一轮发言，见 StartReplayContext
+func (c *Client) StartReplay(path string) error {
+	return c.StartReplayContext(context.Background(), path)
+}
+
+// StartReplayContext 以音频文件代替麦克风完成一轮发言，用于复现对话和端到端测试：
+// 以手动模式开始监听，把文件按实时节奏送入回放设备的录音处理流程，文件读完后停止监听，之后服务器照常识别和回复。
+// 编码后的音频经回放设备的音频数据回调发出，与麦克风录音相同，调用方需像录音时一样把该回调接到 SendAudioData。
+// 阻塞直到文件送完；ctx取消时同样停止监听并返回ctx的错误
+func (c *Client) StartReplayContext(ctx context.Context, path string) error {
+	c.mu.Lock()
+	replayer := c.audioReplayer
+	if replayer == nil {
+		c.mu.Unlock()
+		return errors.New("未设置音频回放设备")
+	}
+	if c.replaying {
+		c.mu.Unlock()
+		return errors.New("已有音频文件正在回放")
+	}
+	c.replaying = true
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.replaying = false
+		c.mu.Unlock()
+	}()
+
+	if !c.protocol.IsConnected() {
+		return ErrNotConnected
+	}
+
+	if c.GetState() != StateListening {
+		if err := c.SendStartListening(ListenModeManual); err != nil {
+			return fmt.Errorf("开始监听失败: %w", err)
+		}
+	}
+
+	replayErr := replayer.ReplayFile(ctx, path)
+
+	// 无论文件是否完整送出都结束本轮发言，避免服务器一直等待音频
+	if c.GetState() == StateListening {
+		if err := c.SendStopListening(); err != nil && replayErr == nil {
+			return fmt.Errorf("停止监听失败: %w", err)
+		}
+	}
+	return replayErr
+}
+
+// IsReplaying 返回是否正在以音频文件代替麦克风发言，此时不应再开始录音
+func (c *Client) IsReplaying() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.replaying
+}
+
 // SendStopListening 发送停止监听的消息
 func (c *Client) SendStopListening() error {
 	c.mu.Lock()
 	if c.state != StateListening {
+		// 等待重连期间松开按键，这一轮发言作废
+		if c.reconnectHoldingLocked() {
+			c.discardReconnectAudioLocked("等待重连期间停止监听")
+			c.mu.Unlock()
+			return nil
+		}
 		c.mu.Unlock()
-		return errors.New("客户端不在监听状态，无法停止监听")
+		return fmt.Errorf("无法停止监听: %w", ErrNotListening)
 	}
 
 	sessionID := c.sessionID
@@ -420,39 +1594,150 @@ func (c *Client) SendStopListening() error {
 		State:     "stop",
 	}
 
-	return c.protocol.SendJSON(listen)
+	if err := c.protocol.SendJSON(listen); err != nil {
+		return err
+	}
+
+	// 记录停止监听的时间，用于计算轮次延迟
+	c.mu.Lock()
+	c.stopListeningAt = c.now()
+	c.mu.Unlock()
+	return nil
+}
+
+// SendWakeWordDetected 发送唤醒词检测到的消息
+func (c *Client) SendWakeWordDetected(text string) error {
+	c.mu.Lock()
+	if c.state != StateListening && c.state != StateSpeaking && c.state != StateIdle {
+		c.mu.Unlock()
+		return fmt.Errorf("当前状态(%s)无法发送唤醒词检测: %w", c.state, ErrInvalidState)
+	}
+
+	// 如果当前不在监听状态，先开始监听
+	if c.state != StateListening {
+		c.mu.Unlock()
+		err := c.SendStartListening(ListenModeAuto)
+		if err != nil {
+			return err
+		}
+		c.mu.Lock()
+	}
+
+	sessionID := c.sessionID
+	c.mu.Unlock()
+
+	// 发送wake word detected消息
+	listen := protocol.ListenMessage{
+		SessionID: sessionID,
+		Type:      "listen",
+		State:     "detect",
+		Text:      text,
+	}
+
+	return c.protocol.SendJSON(listen)
+}
+
+// HandleWakeWord 处理本地检测到的唤醒词，通常接到 AudioManagerNew.SetWakeWordDetector 的回调
+// 空闲时开始监听并通知服务器唤醒词；正在播放回复时先打断（受打断宽限期影响）；已在监听时忽略
+func (c *Client) HandleWakeWord(keyword string) {
+	switch c.GetState() {
+	case StateListening, StateConnecting:
+		return
+	case StateSpeaking:
+		c.BeginBargeIn("wake_word_detected")
+	}
+
+	if err := c.SendWakeWordDetected(keyword); err != nil {
+		logger.Errorf("发送唤醒词检测消息失败: %v", err)
+	}
+}
+
+// SendTranscript 发送本地语音识别的最终结果，服务器将其作为本轮监听的识别结果处理、跳过自己的语音识别，
+// 而不是像 SendWakeWordDetected 那样当作唤醒词。必须在监听状态下调用，识别结果归属当前监听会话；
+// 发送后本轮监听即告结束，不需要再调用 SendStopListening，之后的上行音频服务器可忽略
+func (c *Client) SendTranscript(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return errors.New("识别结果不能为空")
+	}
+	if !c.protocol.IsConnected() {
+		return fmt.Errorf("无法发送识别结果: %w", ErrNotConnected)
+	}
+
+	c.mu.Lock()
+	if c.state != StateListening || c.sessionID == "" {
+		c.mu.Unlock()
+		return fmt.Errorf("无法发送识别结果: %w", ErrNotListening)
+	}
+	sessionID := c.sessionID
+	mode := c.listenMode
+	c.mu.Unlock()
+
+	listen := protocol.ListenMessage{
+		SessionID: sessionID,
+		Type:      "listen",
+		State:     "transcript",
+		Mode:      mode,
+		Text:      text,
+	}
+	if err := c.protocol.SendJSON(listen); err != nil {
+		return err
+	}
+
+	// 识别结果相当于停止监听，从此刻开始计算轮次延迟
+	c.mu.Lock()
+	c.stopListeningAt = c.now()
+	c.mu.Unlock()
+	return nil
 }
 
-// SendWakeWordDetected 发送唤醒词检测到的消息
-func (c *Client) SendWakeWordDetected(text string) error {
-	c.mu.Lock()
-	if c.state != StateListening && c.state != StateSpeaking && c.state != StateIdle {
-		c.mu.Unlock()
-		return errors.New("客户端状态不允许发送唤醒词检测")
+// SendText 以文本方式发起一轮对话，不依赖麦克风采集。
+// 正在监听时先停止监听，正在播放时先打断当前回复；服务器随后返回的stt/llm/tts消息按语音对话同样处理
+func (c *Client) SendText(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return errors.New("文本内容不能为空")
+	}
+	if !c.protocol.IsConnected() {
+		return fmt.Errorf("无法发送文本: %w", ErrNotConnected)
 	}
 
-	// 如果当前不在监听状态，先开始监听
-	if c.state != StateListening {
-		c.mu.Unlock()
-		err := c.SendStartListening(ListenModeAuto)
-		if err != nil {
+	c.mu.Lock()
+	state := c.state
+	c.mu.Unlock()
+
+	switch state {
+	case StateConnecting:
+		return fmt.Errorf("客户端正在连接，无法发送文本: %w", ErrInvalidState)
+	case StateListening:
+		if err := c.SendStopListening(); err != nil {
 			return err
 		}
-		c.mu.Lock()
+	case StateSpeaking:
+		c.abortForBargeIn("text_input")
 	}
 
+	c.mu.Lock()
+	if c.sessionID == "" {
+		c.sessionID = uuid.New().String()
+	}
 	sessionID := c.sessionID
+	// 以文本开始新一轮对话，丢弃上一轮未完成的回复
+	c.response.reset()
 	c.mu.Unlock()
 
-	// 发送wake word detected消息
-	listen := protocol.ListenMessage{
+	message := protocol.TextMessage{
 		SessionID: sessionID,
-		Type:      "listen",
-		State:     "detect",
+		Type:      "text",
 		Text:      text,
 	}
+	if err := c.protocol.SendJSON(message); err != nil {
+		return err
+	}
 
-	return c.protocol.SendJSON(listen)
+	// 与停止监听一样，从发出文本开始计算轮次延迟
+	c.mu.Lock()
+	c.stopListeningAt = c.now()
+	c.mu.Unlock()
+	return nil
 }
 
 // SendAbortSpeaking 发送终止当前会话的消息
@@ -476,12 +1761,75 @@ func (c *Client) SendAbortSpeaking(reason string) error {
 	return c.protocol.SendJSON(abort)
 }
 
+// BeginBargeIn 在播放TTS时开始打断，宽限期结束后发送中断消息并触发打断回调
+// 未处于播放状态时返回false；宽限期为0时立即打断
+func (c *Client) BeginBargeIn(reason string) bool {
+	c.mu.Lock()
+	if c.state != StateSpeaking {
+		c.mu.Unlock()
+		return false
+	}
+	if c.bargeInTimer != nil {
+		// 已有待执行的打断
+		c.mu.Unlock()
+		return true
+	}
+	grace := c.bargeInGrace
+	if grace > 0 {
+		logger.Debugf("打断将在%v后生效", grace)
+		c.bargeInTimer = time.AfterFunc(grace, func() {
+			c.mu.Lock()
+			c.bargeInTimer = nil
+			c.mu.Unlock()
+			c.abortForBargeIn(reason)
+		})
+		c.mu.Unlock()
+		return true
+	}
+	c.mu.Unlock()
+
+	c.abortForBargeIn(reason)
+	return true
+}
+
+// CancelBargeIn 取消宽限期内尚未生效的打断，返回是否有打断被取消
+func (c *Client) CancelBargeIn() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.bargeInTimer == nil {
+		return false
+	}
+	cancelled := c.bargeInTimer.Stop()
+	c.bargeInTimer = nil
+	if cancelled {
+		logger.Debugf("宽限期内停止说话，已取消打断")
+	}
+	return cancelled
+}
+
+// abortForBargeIn 发送中断消息并触发打断回调
+func (c *Client) abortForBargeIn(reason string) {
+	logger.Infof("正在中断AI回复...")
+	if err := c.SendAbortSpeaking(reason); err != nil {
+		logger.Errorf("发送中断消息失败: %v", err)
+	}
+
+	c.mu.Lock()
+	onBargeIn := c.onBargeIn
+	c.mu.Unlock()
+
+	if onBargeIn != nil {
+		onBargeIn()
+	}
+}
+
 // SendIoTState 发送IoT状态消息
 func (c *Client) SendIoTState(states interface{}) error {
 	c.mu.Lock()
 	if !c.protocol.IsConnected() {
 		c.mu.Unlock()
-		return errors.New("未连接到服务器")
+		return ErrNotConnected
 	}
 
 	sessionID := c.sessionID
@@ -502,7 +1850,7 @@ func (c *Client) SendIoTDescriptors(descriptors interface{}) error {
 	c.mu.Lock()
 	if !c.protocol.IsConnected() {
 		c.mu.Unlock()
-		return errors.New("未连接到服务器")
+		return ErrNotConnected
 	}
 
 	sessionID := c.sessionID
@@ -518,27 +1866,169 @@ func (c *Client) SendIoTDescriptors(descriptors interface{}) error {
 	return c.protocol.SendJSON(iotDesc)
 }
 
+// SendRawJSON 发送库尚未建模的JSON消息，用于兼容服务器新增的消息类型。
+// v序列化后必须是带非空"type"字段的JSON对象；未设置"session_id"时自动填入当前会话ID。
+// 除此之外不校验消息结构，消息内容是否符合服务器协议由调用方负责。
+// 未连接时返回 ErrNotConnected，hello握手完成前返回 ErrInvalidState
+func (c *Client) SendRawJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("序列化消息失败: %v", err)
+	}
+	var message map[string]json.RawMessage
+	if err := json.Unmarshal(data, &message); err != nil || message == nil {
+		return errors.New("原始消息必须是JSON对象")
+	}
+	var messageType string
+	if err := json.Unmarshal(message["type"], &messageType); err != nil || messageType == "" {
+		return errors.New("原始消息缺少type字段")
+	}
+
+	c.mu.Lock()
+	if err := c.checkRawSendLocked(); err != nil {
+		c.mu.Unlock()
+		return fmt.Errorf("无法发送%s消息: %w", messageType, err)
+	}
+	sessionID := c.sessionID
+	c.mu.Unlock()
+
+	if _, ok := message["session_id"]; !ok && sessionID != "" {
+		message["session_id"], _ = json.Marshal(sessionID)
+	}
+	return c.protocol.SendJSON(message)
+}
+
+// SendRawBinary 原样发送一个二进制帧，不添加协议版本2/3的帧头，也不要求处于监听状态。
+// 库不校验帧的内容；超过协议的二进制帧大小上限时返回 *protocol.FrameTooLargeError。
+// 未连接时返回 ErrNotConnected，hello握手完成前返回 ErrInvalidState
+func (c *Client) SendRawBinary(data []byte) error {
+	c.mu.Lock()
+	if err := c.checkRawSendLocked(); err != nil {
+		c.mu.Unlock()
+		return fmt.Errorf("无法发送二进制消息: %w", err)
+	}
+	c.mu.Unlock()
+
+	return c.protocol.SendBinary(data)
+}
+
+// checkRawSendLocked 检查是否可以发送原始消息，调用方需持有c.mu
+func (c *Client) checkRawSendLocked() error {
+	if !c.protocol.IsConnected() {
+		return ErrNotConnected
+	}
+	if c.state == StateConnecting {
+		return ErrInvalidState
+	}
+	return nil
+}
+
 // SendAudioData 发送音频数据
 func (c *Client) SendAudioData(data []byte) error {
 	c.mu.Lock()
-	if c.state != StateListening {
+	if c.bufferForReconnectLocked(data) {
 		c.mu.Unlock()
-		return errors.New("客户端不在监听状态，无法发送音频数据")
+		return nil
+	}
+	if !c.canSendAudioLocked() {
+		c.mu.Unlock()
+		return fmt.Errorf("无法发送音频数据: %w", ErrNotListening)
 	}
+	data = c.frameAudioLocked(data)
 	c.mu.Unlock()
 
-	return c.protocol.SendBinary(data)
+	return wrapSendAudioError(c.protocol.SendBinary(data))
+}
+
+// SendAudioDataContext 发送音频数据，写入受ctx的截止时间和取消控制
+// 音频通路可借此为每一帧设置严格的截止时间，避免卡住的写入阻塞后续帧
+func (c *Client) SendAudioDataContext(ctx context.Context, data []byte) error {
+	c.mu.Lock()
+	if c.bufferForReconnectLocked(data) {
+		c.mu.Unlock()
+		return nil
+	}
+	if !c.canSendAudioLocked() {
+		c.mu.Unlock()
+		return fmt.Errorf("无法发送音频数据: %w", ErrNotListening)
+	}
+	data = c.frameAudioLocked(data)
+	c.mu.Unlock()
+
+	return wrapSendAudioError(c.protocol.SendBinaryContext(ctx, data))
+}
+
+// bufferForReconnectLocked 等待重连和补发期间暂存上行音频，返回是否已暂存，调用方需持有c.mu
+func (c *Client) bufferForReconnectLocked(data []byte) bool {
+	if !c.reconnectHoldingLocked() {
+		return false
+	}
+	c.reconnectAudio.push(c.now(), data)
+	return true
+}
+
+// wrapSendAudioError 为超过大小上限的音频帧补充说明，仍可通过 errors.As 取得 *protocol.FrameTooLargeError
+func wrapSendAudioError(err error) error {
+	var tooLarge *protocol.FrameTooLargeError
+	if errors.As(err, &tooLarge) {
+		return fmt.Errorf("音频帧未发送，连接未受影响: %w", err)
+	}
+	return err
 }
 
 // 内部事件处理方法
 
 // handleConnected 处理连接成功事件
 func (c *Client) handleConnected() {
-	logrus.Info("WebSocket已连接")
+	logger.Infof("WebSocket已连接")
+}
+
+// watchDisconnect 注册断开回调，协议能报告断开原因时使用带原因的回调
+func (c *Client) watchDisconnect() {
+	if notifier, ok := c.protocol.(protocol.DisconnectReasonNotifier); ok {
+		notifier.SetOnDisconnectReason(c.handleDisconnectReason)
+		return
+	}
+	// 协议不报告原因时只能收到意外断开，按是否带错误区分网络错误和服务器关闭
+	c.protocol.SetOnDisconnected(func(err error) {
+		reason := protocol.DisconnectServerClosed
+		if err != nil {
+			reason = protocol.DisconnectNetworkError
+		}
+		c.handleDisconnectReason(reason, err)
+	})
+}
+
+// handleDisconnectReason 处理协议报告的断开事件：记录原因，意外断开时更新客户端状态，最后触发断开回调。
+// 本端主动断开时状态由发起断开的代码（例如 CloseAudioChannel）负责处理
+func (c *Client) handleDisconnectReason(reason protocol.DisconnectReason, err error) {
+	c.mu.Lock()
+	c.lastDisconnectReason = reason
+	c.everDisconnected = true
+	onDisconnected := c.onDisconnected
+	onServerClose := c.onServerClose
+	c.mu.Unlock()
+
+	if reason != protocol.DisconnectUserInitiated {
+		logger.Infof("连接断开（%s）: %v", reason, err)
+		c.handleDisconnected(reason, err)
+	}
+
+	var closeErr *protocol.ServerCloseError
+	if errors.As(err, &closeErr) {
+		logger.Infof("服务器关闭码 %d: %s，原因: %q", closeErr.Code, protocol.CloseCodeMeaning(closeErr.Code), closeErr.Text)
+		if onServerClose != nil {
+			onServerClose(closeErr.Code, closeErr.Text)
+		}
+	}
+
+	if onDisconnected != nil {
+		onDisconnected(reason, err)
+	}
 }
 
 // handleDisconnected 处理连接断开事件
-func (c *Client) handleDisconnected(err error) {
+func (c *Client) handleDisconnected(reason protocol.DisconnectReason, err error) {
 	// 添加超时保护
 	done := make(chan struct{})
 
@@ -546,15 +2036,26 @@ func (c *Client) handleDisconnected(err error) {
 		defer close(done)
 
 		c.mu.Lock()
-		oldState := c.state
-		c.state = StateIdle
+		// 手动监听中途意外断线：录音继续，暂存上行音频等待重连
+		if reason.ShouldReconnect() && c.reconnectAudio != nil && c.state == StateListening && c.listenMode == ListenModeManual {
+			c.reconnectAudio.hold()
+			logger.Infof("监听中途连接断开，暂存上行音频等待重连")
+		}
+		oldState, _ := c.transitionLocked(StateIdle)
+		wasOpen := c.audioChannelOpen
+		c.audioChannelOpen = false
+		onStateChanged := c.onStateChanged
 		onAudioChannelClosed := c.onAudioChannelClosed
 		onNetworkError := c.onNetworkError
-		c.sessionID = ""
+		c.clearSessionOnDisconnectLocked()
+		c.resetAudioFramingLocked()
+		c.response.reset()
 		c.mu.Unlock()
 
-		// 如果之前不是空闲状态，触发通道关闭回调
-		if oldState != StateIdle && onAudioChannelClosed != nil {
+		c.notifyStateChanged(onStateChanged, oldState, StateIdle)
+
+		// 音频通道已打开或之前不是空闲状态，触发通道关闭回调
+		if (wasOpen || oldState != StateIdle) && onAudioChannelClosed != nil {
 			onAudioChannelClosed()
 		}
 
@@ -571,108 +2072,246 @@ func (c *Client) handleDisconnected(err error) {
 		return
 	case <-time.After(2 * time.Second):
 		// 处理超时
-		logrus.Warn("处理连接断开事件超时")
+		logger.Warnf("处理连接断开事件超时")
 
 		// 强制设置状态为空闲
 		c.mu.Lock()
-		c.state = StateIdle
-		c.sessionID = ""
+		c.transitionLocked(StateIdle)
+		c.audioChannelOpen = false
+		c.clearSessionOnDisconnectLocked()
+		c.resetAudioFramingLocked()
 		c.mu.Unlock()
 	}
 }
 
+// serverMessage 服务器下发的JSON消息，包含各类型消息用到的全部字段
+// 接收时只解析一次，再按Type交给对应的处理函数，避免先解析类型、再按具体类型重复解析整条消息
+type serverMessage struct {
+	Type string `json:"type"`
+
+	// hello
+	Version     int                   `json:"version"`
+	Transport   string                `json:"transport"`
+	AudioParams *protocol.AudioParams `json:"audio_params"`
+	Features    map[string]bool       `json:"features"`
+
+	// stt / tts / llm
+	State   string `json:"state"`
+	Text    string `json:"text"`
+	Emotion string `json:"emotion"`
+
+	// stt 中间结果或最终结果的标记
+	protocol.STTResultFlags
+
+	// iot
+	Commands []interface{} `json:"commands"`
+
+	// error
+	Code  int    `json:"code"`
+	Error string `json:"error"`
+
+	// mcp
+	Payload json.RawMessage `json:"payload"`
+}
+
 // handleJSONMessage 处理JSON消息
 func (c *Client) handleJSONMessage(data []byte) {
 	// 记录收到的JSON消息，但不记录太大的数据
 	if len(data) < 1000 {
-		logrus.Debugf("收到WebSocket JSON消息: %s", string(data))
+		logger.Debugf("收到WebSocket JSON消息: %s", string(data))
 	} else {
-		logrus.Debugf("收到WebSocket JSON消息，长度: %d字节", len(data))
+		logger.Debugf("收到WebSocket JSON消息，长度: %d字节", len(data))
 	}
 
-	// 解析消息类型
-	var message struct {
-		Type string `json:"type"`
+	c.mu.Lock()
+	onRawJSON := c.onRawJSON
+	onUnknownMessage := c.onUnknownMessage
+	onMCPRequest := c.onMCPRequest
+	c.mu.Unlock()
+
+	if onRawJSON != nil {
+		onRawJSON(data)
 	}
 
+	// 只解析一次，各处理函数直接使用解析结果
+	var message serverMessage
 	if err := json.Unmarshal(data, &message); err != nil {
-		logrus.Errorf("解析WebSocket消息失败: %v", err)
+		logger.Errorf("解析WebSocket消息失败: %v", err)
 		return
 	}
 
 	// 根据消息类型分别处理
 	switch message.Type {
 	case "hello":
-		logrus.Info("识别到服务器hello消息，进行处理")
-		c.handleHelloMessage(data)
+		logger.Infof("识别到服务器hello消息，进行处理")
+		c.handleHelloMessage(&message)
 	case "stt":
-		c.handleSTTMessage(data)
+		c.handleSTTMessage(&message)
 	case "tts":
-		c.handleTTSMessage(data)
+		c.handleTTSMessage(&message)
 	case "llm":
-		c.handleLLMMessage(data)
+		c.handleLLMMessage(&message)
 	case "iot":
-		c.handleIoTMessage(data)
+		c.handleIoTMessage(&message)
 	case "error":
-		c.handleErrorMessage(data)
+		c.handleErrorMessage(&message)
+	case "mcp":
+		if onMCPRequest != nil {
+			c.handleMCPMessage(&message, onMCPRequest)
+			return
+		}
+		fallthrough
 	default:
-		logrus.Warnf("收到未知类型的WebSocket消息: %s", message.Type)
+		if onUnknownMessage != nil {
+			onUnknownMessage(message.Type, data)
+			return
+		}
+		logger.Warnf("收到未知类型的WebSocket消息: %s", message.Type)
 	}
 }
 
 // handleBinaryMessage 处理接收到的二进制消息
 func (c *Client) handleBinaryMessage(data []byte) {
+	frame, err := c.unframeAudio(data)
+	if err != nil {
+		logger.Warnf("丢弃无法解析的音频帧: %v", err)
+		return
+	}
+
 	c.mu.Lock()
-	// 如果是在监听状态，忽略收到的音频数据
-	if c.state == StateListening {
+	// 半双工：监听状态下不播放音频，只保留最近几帧以免切换状态时截断TTS开头；实时模式为全双工，不丢弃
+	if c.state == StateListening && c.dropAudioWhileListening && c.listenMode != ListenModeRealtime {
+		c.bufferAudioLocked(frame)
 		c.mu.Unlock()
+		// 停止监听后服务器可能先于tts start下发音频，此时状态仍为监听，同样计入轮次延迟
+		c.reportTurnLatency()
 		return
 	}
 
 	onAudioData := c.onAudioData
+	onAudioFrame := c.onAudioFrame
+	c.drainedDuringTTS = false
+	pending := c.takePreBufferLocked()
+
+	var jitterStats *JitterStats
+	if c.jitter != nil {
+		now := c.now()
+		c.jitter.observe(now)
+		if c.jitter.shouldLog(now) {
+			stats := c.jitter.stats()
+			jitterStats = &stats
+		}
+	}
 	c.mu.Unlock()
 
-	// 调用音频数据回调
-	if onAudioData != nil {
-		onAudioData(data)
+	if jitterStats != nil {
+		logger.Debugf("音频帧到达抖动: 帧间隔数=%d, 平均=%v, 标准差=%v, 最大间隔=%v",
+			jitterStats.Count, jitterStats.Mean, jitterStats.StdDev, jitterStats.MaxGap)
+	}
+
+	c.reportTurnLatency()
+
+	// 调用音频数据回调，先补发监听期间缓冲的帧
+	for _, buffered := range pending {
+		deliverAudioFrame(onAudioData, onAudioFrame, buffered)
 	}
+	deliverAudioFrame(onAudioData, onAudioFrame, frame)
 }
 
-// handleHelloMessage 处理Hello消息
-func (c *Client) handleHelloMessage(data []byte) {
-	var hello protocol.ServerHelloMessage
-	if err := json.Unmarshal(data, &hello); err != nil {
-		logrus.Errorf("解析Hello消息失败: %v", err)
+// reportTurnLatency 在停止监听后的首个TTS响应到达时计算并上报轮次延迟
+func (c *Client) reportTurnLatency() {
+	c.mu.Lock()
+	if c.stopListeningAt.IsZero() {
+		c.mu.Unlock()
 		return
 	}
+	latency := c.now().Sub(c.stopListeningAt)
+	c.stopListeningAt = time.Time{}
+	onTurnLatency := c.onTurnLatency
+	c.mu.Unlock()
+
+	logger.Infof("轮次延迟（停止监听到首个TTS响应）: %v", latency)
+	if onTurnLatency != nil {
+		onTurnLatency(latency)
+	}
+}
 
+// handleHelloMessage 处理Hello消息
+func (c *Client) handleHelloMessage(hello *serverMessage) {
 	// 验证消息格式
-	if hello.Type != "hello" || hello.Transport != "websocket" {
-		logrus.Errorf("服务器返回的Hello消息格式不正确")
+	// 服务器未声明传输方式时视为与客户端一致
+	if transport := c.transport(); hello.Transport != "" && hello.Transport != transport {
+		logger.Errorf("服务器hello的传输方式%q与客户端使用的%q不一致", hello.Transport, transport)
+		c.protocol.Disconnect()
+		c.finishHello(fmt.Errorf("服务器hello的传输方式%q与客户端使用的%q不一致", hello.Transport, transport))
+		return
+	}
+
+	// 检查服务器协议版本
+	if !protocol.IsSupportedVersion(hello.Version) {
+		mismatch := &VersionMismatchError{ServerVersion: hello.Version, Supported: protocol.SupportedProtocolVersions}
+		logger.Errorf("%v", mismatch)
+		c.mu.Lock()
+		onVersionMismatch := c.onVersionMismatch
+		c.mu.Unlock()
+
+		if onVersionMismatch != nil {
+			onVersionMismatch(hello.Version)
+		}
 		c.protocol.Disconnect()
+		// 让等待hello的 OpenAudioChannel 立即返回，而不是等到hello超时
+		c.finishHello(mismatch)
 		return
 	}
 
+	// 记录服务器声明的协议版本和扩展能力
+	c.mu.Lock()
+	c.serverVersion = hello.Version
+	c.serverFeatures = hello.Features
+	c.mu.Unlock()
+
 	// 通知等待的goroutine已收到Hello消息
-	select {
-	case c.helloReceived <- struct{}{}:
-	default:
-		// 通道已关闭或已经有值了，不需要发送
+	c.finishHello(nil)
+}
+
+// transport 返回当前协议的传输方式，用于hello的transport字段
+func (c *Client) transport() string {
+	if namer, ok := c.protocol.(protocol.TransportNamer); ok {
+		return namer.Transport()
 	}
+	return protocol.TransportWebSocket
 }
 
-// handleSTTMessage 处理STT消息
-func (c *Client) handleSTTMessage(data []byte) {
-	var stt protocol.STTMessage
-	if err := json.Unmarshal(data, &stt); err != nil {
-		logrus.Errorf("解析STT消息失败: %v", err)
-		return
+// finishHello 把握手结果交给等待hello的 OpenAudioChannel，没有等待者或已有结果时丢弃
+func (c *Client) finishHello(err error) {
+	c.mu.Lock()
+	helloResult := c.helloResult
+	c.mu.Unlock()
+
+	select {
+	case helloResult <- err:
+	default:
 	}
+}
 
+// handleSTTMessage 处理STT消息，按中间结果和最终结果分别回调
+func (c *Client) handleSTTMessage(stt *serverMessage) {
 	c.mu.Lock()
 	onRecognizedText := c.onRecognizedText
+	onPartialText := c.onPartialText
+	onFinalText := c.onFinalText
 	c.mu.Unlock()
 
+	if !stt.IsFinalResult(stt.State) {
+		if onPartialText != nil {
+			onPartialText(stt.Text)
+		}
+		return
+	}
+
+	if onFinalText != nil {
+		onFinalText(stt.Text)
+	}
 	// 调用识别文本回调
 	if onRecognizedText != nil {
 		onRecognizedText(stt.Text)
@@ -680,20 +2319,44 @@ func (c *Client) handleSTTMessage(data []byte) {
 }
 
 // handleTTSMessage 处理TTS消息
-func (c *Client) handleTTSMessage(data []byte) {
-	var tts protocol.TTSMessage
-	if err := json.Unmarshal(data, &tts); err != nil {
-		logrus.Errorf("解析TTS消息失败: %v", err)
-		return
-	}
-
+func (c *Client) handleTTSMessage(tts *serverMessage) {
 	switch tts.State {
 	case "start":
 		// TTS开始，切换到播放状态
+		c.reportTurnLatency()
+		c.mu.Lock()
+		c.speakingFinishPending = true
+		c.drainedDuringTTS = false
+		c.mu.Unlock()
 		c.SetState(StateSpeaking)
+		c.flushPreBuffer()
 	case "stop":
 		// TTS结束，切换到空闲状态
-		c.SetState(StateIdle)
+		c.mu.Lock()
+		if c.jitter != nil {
+			c.jitter.pause()
+		}
+		// 播放队列已在TTS期间播空且之后没有新音频，说明已经播放完毕
+		var onSpeakingFinished func()
+		if c.drainedDuringTTS {
+			onSpeakingFinished = c.takeSpeakingFinishedLocked()
+		}
+		// 实时模式下录音在播放期间一直进行，回复结束后回到监听状态
+		nextState := StateIdle
+		if c.listenMode == ListenModeRealtime && c.state == StateSpeaking {
+			nextState = StateListening
+		}
+		response := c.response.take()
+		onAssistantResponse := c.onAssistantResponse
+		c.mu.Unlock()
+		c.SetState(nextState)
+
+		if onSpeakingFinished != nil {
+			onSpeakingFinished()
+		}
+		if onAssistantResponse != nil && response != "" {
+			onAssistantResponse(response)
+		}
 	case "sentence_start":
 		// 句子开始，调用文本回调
 		c.mu.Lock()
@@ -703,62 +2366,152 @@ func (c *Client) handleTTSMessage(data []byte) {
 		if onSpeakText != nil && tts.Text != "" {
 			onSpeakText(tts.Text)
 		}
+		c.appendResponse(tts.Text)
 	}
 }
 
 // handleLLMMessage 处理LLM消息
-func (c *Client) handleLLMMessage(data []byte) {
-	var llm protocol.LLMMessage
-	if err := json.Unmarshal(data, &llm); err != nil {
-		logrus.Errorf("解析LLM消息失败: %v", err)
-		return
-	}
-
+func (c *Client) handleLLMMessage(llm *serverMessage) {
 	c.mu.Lock()
 	onEmotionChanged := c.onEmotionChanged
+	onEmotion := c.onEmotion
 	c.mu.Unlock()
 
 	// 调用情感变更回调
 	if onEmotionChanged != nil {
 		onEmotionChanged(llm.Emotion, llm.Text)
 	}
+	if onEmotion != nil {
+		onEmotion(protocol.ParseEmotion(llm.Emotion), llm.Emotion, llm.Text)
+	}
+
+	// 带情绪的llm消息文本是对应的表情，只有纯文本片段属于回复内容
+	if llm.Emotion == "" {
+		c.appendResponse(llm.Text)
+	}
 }
 
-// handleIoTMessage 处理IoT消息
-func (c *Client) handleIoTMessage(data []byte) {
-	var msg map[string]interface{}
-	if err := json.Unmarshal(data, &msg); err != nil {
-		logrus.Errorf("解析IoT消息失败: %v", err)
+// appendResponse 将一段回复文本拼接到本轮回复中，并以拼接后的文本触发回复增长回调
+func (c *Client) appendResponse(chunk string) {
+	if strings.TrimSpace(chunk) == "" {
 		return
 	}
+	c.mu.Lock()
+	partial := c.response.append(chunk)
+	onAssistantPartial := c.onAssistantPartial
+	c.mu.Unlock()
+
+	if onAssistantPartial != nil {
+		onAssistantPartial(partial)
+	}
+}
 
+// handleIoTMessage 处理IoT消息
+func (c *Client) handleIoTMessage(msg *serverMessage) {
 	// 检查是否包含commands字段
-	if commands, ok := msg["commands"].([]interface{}); ok {
+	if commands := msg.Commands; commands != nil {
 		c.mu.Lock()
 		onIoTCommand := c.onIoTCommand
+		onIoTCommands := c.onIoTCommands
+		registry := c.iotRegistry
 		c.mu.Unlock()
 
+		if registry != nil {
+			c.dispatchIoTCommands(registry, commands)
+		}
+
 		// 调用IoT命令回调
 		if onIoTCommand != nil {
 			onIoTCommand(commands)
 		}
+		if onIoTCommands != nil {
+			parsed, err := protocol.ParseIoTCommands(commands)
+			if err != nil {
+				logger.Warnf("部分IoT命令无法解析: %v", err)
+			}
+			if len(parsed) > 0 {
+				onIoTCommands(parsed)
+			}
+		}
 	}
 }
 
-// handleErrorMessage 处理错误消息
-func (c *Client) handleErrorMessage(data []byte) {
-	var errMsg struct {
-		Type  string `json:"type"`
-		Code  int    `json:"code"`
-		Error string `json:"error"`
+// handleMCPMessage 解析mcp消息中的JSON-RPC请求，在单独的goroutine中交给回调处理并回复结果
+func (c *Client) handleMCPMessage(msg *serverMessage, handler func(req protocol.MCPRequest) (protocol.MCPResponse, error)) {
+	request, err := protocol.ParseMCPRequest(msg.Payload)
+	if err != nil {
+		logger.Warnf("无法解析的MCP请求: %v", err)
+		// 能确定是请求时回复错误，避免服务器一直等待；没有method的可能是对客户端请求的响应，不回复
+		if !request.IsNotification() && request.Method != "" {
+			c.sendMCPResponse(request.ID, protocol.MCPResponse{}, err)
+		}
+		return
+	}
+
+	go func() {
+		logger.Debugf("处理MCP请求: %s", request.Method)
+		response, err := handler(request)
+		if request.IsNotification() {
+			if err != nil {
+				logger.Warnf("处理MCP通知%s失败: %v", request.Method, err)
+			}
+			return
+		}
+		if err != nil {
+			logger.Warnf("处理MCP请求%s失败: %v", request.Method, err)
+		}
+		c.sendMCPResponse(request.ID, response, err)
+	}()
+}
+
+// sendMCPResponse 以mcp消息回复JSON-RPC请求
+func (c *Client) sendMCPResponse(id json.RawMessage, response protocol.MCPResponse, handlerErr error) {
+	payload, err := protocol.EncodeMCPResponse(id, response, handlerErr)
+	if err != nil {
+		logger.Errorf("编码MCP响应失败: %v", err)
+		payload, _ = protocol.EncodeMCPResponse(id, protocol.MCPResponse{}, fmt.Errorf("编码响应失败: %v", err))
+	}
+
+	c.mu.Lock()
+	sessionID := c.sessionID
+	c.mu.Unlock()
+
+	message := protocol.MCPMessage{
+		SessionID: sessionID,
+		Type:      "mcp",
+		Payload:   payload,
+	}
+	if err := c.protocol.SendJSON(message); err != nil {
+		logger.Warnf("发送MCP响应失败: %v", err)
 	}
+}
 
-	if err := json.Unmarshal(data, &errMsg); err != nil {
-		logrus.Errorf("解析错误消息失败: %v", err)
+// dispatchIoTCommands 将IoT命令分发给注册表中的设备方法，并上报执行后的状态
+func (c *Client) dispatchIoTCommands(registry *iot.Registry, commands []interface{}) {
+	parsed, err := protocol.ParseIoTCommands(commands)
+	if err != nil {
+		logger.Warnf("部分IoT命令无法解析: %v", err)
+	}
+	if len(parsed) == 0 {
 		return
 	}
 
-	logrus.Errorf("收到服务器错误: 代码=%d, 消息=%s", errMsg.Code, errMsg.Error)
+	for _, cmd := range parsed {
+		if err := registry.Invoke(cmd); err != nil {
+			logger.Errorf("执行IoT命令 %s.%s 失败: %v", cmd.Name, cmd.Method, err)
+		} else {
+			logger.Infof("已执行IoT命令: %s.%s", cmd.Name, cmd.Method)
+		}
+	}
+
+	if err := c.SendIoTState(registry.States()); err != nil {
+		logger.Warnf("上报IoT设备状态失败: %v", err)
+	}
+}
+
+// handleErrorMessage 处理错误消息
+func (c *Client) handleErrorMessage(errMsg *serverMessage) {
+	logger.Errorf("收到服务器错误: 代码=%d, 消息=%s", errMsg.Code, errMsg.Error)
 
 	// 调用网络错误回调
 	c.mu.Lock()
@@ -0,0 +1,210 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/justa-cai/xiaozhi-go/internal/protocol"
+)
+
+// newMockServer 创建回复hello的测试协议，serverHello为收到客户端hello后注入的服务器hello
+func newMockServer(serverHello string) *protocol.MockProtocol {
+	mock := protocol.NewMockProtocol()
+	mock.OnSendJSON = func(data []byte) {
+		if protocol.MessageType(data) == "hello" {
+			mock.InjectJSON(serverHello)
+		}
+	}
+	return mock
+}
+
+func TestOpenAudioChannelVersionMismatch(t *testing.T) {
+	mock := newMockServer(`{"type":"hello","version":99,"transport":"websocket"}`)
+	c := New(mock)
+	if err := c.SetHelloTimeout(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	var mismatched int
+	c.SetOnVersionMismatch(func(serverVersion int) {
+		mismatched = serverVersion
+	})
+
+	start := time.Now()
+	err := c.OpenAudioChannel("ws://test")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("版本不兼容时应立即返回，实际等待了%v", elapsed)
+	}
+
+	if !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("期望 ErrVersionMismatch，实际: %v", err)
+	}
+	var mismatch *VersionMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("期望 *VersionMismatchError，实际: %T", err)
+	}
+	if mismatch.ServerVersion != 99 {
+		t.Errorf("ServerVersion = %d，期望 99", mismatch.ServerVersion)
+	}
+	if mismatched != 99 {
+		t.Errorf("版本不兼容回调收到 %d，期望 99", mismatched)
+	}
+	if mock.IsConnected() {
+		t.Error("版本不兼容时应断开连接")
+	}
+	if state := c.GetState(); state != StateIdle {
+		t.Errorf("状态为 %s，期望 %s", state, StateIdle)
+	}
+}
+
+func TestOpenAudioChannelSupportedVersion(t *testing.T) {
+	mock := newMockServer(`{"type":"hello","version":1,"transport":"websocket","features":{"mcp":true}}`)
+	c := New(mock)
+
+	if err := c.OpenAudioChannel("ws://test"); err != nil {
+		t.Fatalf("打开音频通道失败: %v", err)
+	}
+	if !mock.IsConnected() {
+		t.Error("握手成功后应保持连接")
+	}
+}
+
+// newOpenClient 创建已通过 OpenAudioChannel 完成握手的客户端
+func newOpenClient(t testing.TB) (*Client, *protocol.MockProtocol) {
+	t.Helper()
+	mock := newMockServer(`{"type":"hello","version":1,"transport":"websocket"}`)
+	c := New(mock)
+	if err := c.OpenAudioChannel("ws://test"); err != nil {
+		t.Fatalf("打开音频通道失败: %v", err)
+	}
+	mock.Reset()
+	return c, mock
+}
+
+func TestOpenAudioChannelSendsConfiguredHello(t *testing.T) {
+	mock := newMockServer(`{"type":"hello","version":1,"transport":"websocket"}`)
+	c := New(mock)
+	c.SetToken("secret")
+	c.SetDeviceID("aa:bb:cc:dd:ee:ff")
+	c.SetClientID("client-1")
+	params := protocol.AudioParams{Format: "opus", SampleRate: 48000, Channels: 1, FrameDuration: 20}
+	if err := c.SetHelloParams(params); err != nil {
+		t.Fatal(err)
+	}
+	c.SetOnMCPRequest(func(req protocol.MCPRequest) (protocol.MCPResponse, error) {
+		return protocol.MCPResponse{}, nil
+	})
+
+	if err := c.OpenAudioChannel("ws://test"); err != nil {
+		t.Fatalf("打开音频通道失败: %v", err)
+	}
+
+	hellos := mock.SentJSONOfType("hello")
+	if len(hellos) != 1 {
+		t.Fatalf("发送了%d条hello，期望1条", len(hellos))
+	}
+	var hello protocol.HelloMessage
+	if err := json.Unmarshal(hellos[0], &hello); err != nil {
+		t.Fatal(err)
+	}
+	if hello.AudioParams != params {
+		t.Errorf("hello音频参数为 %+v，期望 %+v", hello.AudioParams, params)
+	}
+	if hello.Version != protocol.ProtocolVersion {
+		t.Errorf("hello版本为 %d，期望 %d", hello.Version, protocol.ProtocolVersion)
+	}
+	if !hello.Features[protocol.FeatureMCP] {
+		t.Errorf("hello未声明 %s 能力: %v", protocol.FeatureMCP, hello.Features)
+	}
+
+	headers := mock.GetHeaders()
+	want := map[string]string{
+		"Authorization":    "Bearer secret",
+		"Device-Id":        "aa:bb:cc:dd:ee:ff",
+		"Client-Id":        "client-1",
+		"Protocol-Version": "1",
+	}
+	for key, value := range want {
+		if headers[key] != value {
+			t.Errorf("请求头 %s = %q，期望 %q", key, headers[key], value)
+		}
+	}
+}
+
+func TestOpenAudioChannelReturnsToIdle(t *testing.T) {
+	c, mock := newOpenClient(t)
+
+	if state := c.GetState(); state != StateIdle {
+		t.Fatalf("握手完成后状态为 %s，期望 %s", state, StateIdle)
+	}
+	if err := c.OpenAudioChannel("ws://test"); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("重复打开音频通道应返回 ErrInvalidState，实际: %v", err)
+	}
+	if err := c.SendText("你好"); err != nil {
+		t.Fatalf("握手完成后发送文本失败: %v", err)
+	}
+	if sent := mock.SentJSONOfType("text"); len(sent) != 1 {
+		t.Errorf("发送了%d条文本消息，期望1条", len(sent))
+	}
+
+	closed := false
+	c.SetOnAudioChannelClosed(func() {
+		closed = true
+	})
+	mock.InjectDisconnect(errors.New("连接被重置"))
+	if !closed {
+		t.Error("空闲状态下断开已打开的音频通道时应触发通道关闭回调")
+	}
+}
+
+// mqttMock 声明MQTT传输方式的测试协议
+type mqttMock struct {
+	*protocol.MockProtocol
+}
+
+func (mqttMock) Transport() string {
+	return protocol.TransportMQTT
+}
+
+func TestHelloTransportFollowsProtocol(t *testing.T) {
+	mock := newMockServer(`{"type":"hello","version":1,"transport":"mqtt"}`)
+	c := New(mqttMock{mock})
+
+	if err := c.OpenAudioChannel("mqtt.example.com:8883"); err != nil {
+		t.Fatalf("打开音频通道失败: %v", err)
+	}
+	var hello protocol.HelloMessage
+	if err := json.Unmarshal(mock.SentJSONOfType("hello")[0], &hello); err != nil {
+		t.Fatal(err)
+	}
+	if hello.Transport != protocol.TransportMQTT {
+		t.Errorf("hello的传输方式为 %q，期望 %q", hello.Transport, protocol.TransportMQTT)
+	}
+}
+
+func TestHelloTransportMismatch(t *testing.T) {
+	mock := newMockServer(`{"type":"hello","version":1,"transport":"websocket"}`)
+	c := New(mqttMock{mock})
+
+	start := time.Now()
+	err := c.OpenAudioChannel("mqtt.example.com:8883")
+	if err == nil {
+		t.Fatal("服务器hello的传输方式不一致时应返回错误")
+	}
+	if errors.Is(err, ErrHelloTimeout) || time.Since(start) > time.Second {
+		t.Errorf("传输方式不一致时应立即返回，实际: %v", err)
+	}
+	if mock.IsConnected() {
+		t.Error("传输方式不一致时应断开连接")
+	}
+}
+
+func TestHelloWithoutTransportAccepted(t *testing.T) {
+	mock := newMockServer(`{"type":"hello","version":1}`)
+	c := New(mock)
+	if err := c.OpenAudioChannel("ws://test"); err != nil {
+		t.Fatalf("服务器未声明传输方式时应视为一致: %v", err)
+	}
+}
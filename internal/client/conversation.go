@@ -0,0 +1,292 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/justa-cai/xiaozhi-go/internal/protocol"
+)
+
+// DefaultConversationEventBuffer 对话事件通道的默认容量
+const DefaultConversationEventBuffer = 64
+
+// ConversationEventType 对话事件类型
+type ConversationEventType int
+
+const (
+	EventUserSpeechRecognized    ConversationEventType = iota // 用户语音的识别结果，Text为识别文本
+	EventAssistantTextChunk                                   // 助手回复的一句文本，Text为句子内容
+	EventAssistantSpeechStarted                               // 助手开始回复（TTS开始）
+	EventAssistantSpeechFinished                              // 助手回复播放完毕；未设置音频设备时为TTS结束
+	EventInterrupted                                          // 助手回复被打断
+	EventError                                                // 对话过程中出错，Err为错误
+)
+
+// String 返回事件类型名称，用于日志
+func (t ConversationEventType) String() string {
+	switch t {
+	case EventUserSpeechRecognized:
+		return "UserSpeechRecognized"
+	case EventAssistantTextChunk:
+		return "AssistantTextChunk"
+	case EventAssistantSpeechStarted:
+		return "AssistantSpeechStarted"
+	case EventAssistantSpeechFinished:
+		return "AssistantSpeechFinished"
+	case EventInterrupted:
+		return "Interrupted"
+	case EventError:
+		return "Error"
+	default:
+		return fmt.Sprintf("ConversationEventType(%d)", int(t))
+	}
+}
+
+// ConversationEvent 对话事件
+type ConversationEvent struct {
+	Type ConversationEventType
+	Text string // 识别文本或回复文本，其他事件为空
+	Err  error  // 仅 EventError 有效
+}
+
+// ConversationAudio 对话使用的音频设备，*audio.AudioManagerNew 实现了该接口
+type ConversationAudio interface {
+	SetAudioDataCallback(callback func([]byte))
+	StartRecording() error
+	StopRecording() error
+	IsRecording() bool
+	StartPlaying() error
+	StopPlaying() error
+	PlayAudio(data []byte)
+	SetOnQueueDrained(callback func())
+}
+
+// AudioLossConcealer 能在下行音频丢帧后补偿播放的音频设备，*audio.AudioManagerNew 实现了该接口
+type AudioLossConcealer interface {
+	PlayAudioAfterLoss(data []byte, lost int)
+}
+
+// ConversationOptions 对话选项
+type ConversationOptions struct {
+	Audio       ConversationAudio // 音频设备（可选），设置后自动录音、上传、播放回复，打断时停止播放
+	ListenMode  string            // 监听模式，为空时沿用客户端的监听模式
+	EventBuffer int               // 事件通道容量，为0时使用 DefaultConversationEventBuffer
+}
+
+// Conversation 在 Client 之上管理"听→说"的对话循环
+// 通过 StartTurn/EndTurn/Interrupt 控制对话轮次，录音的开始停止、播放和打断由内部根据客户端状态协调，
+// 识别结果、回复文本和播放进度以事件的形式从 Events 通道发出。
+// 创建后接管客户端的状态变更、识别文本、回复文本、播放结束、打断、音频数据和音频帧回调，不应再单独设置这些回调
+type Conversation struct {
+	client     *Client
+	audio      ConversationAudio
+	listenMode string
+	events     chan ConversationEvent
+
+	mu     sync.Mutex // 保护closed，保证关闭后不再向events发送
+	closed bool
+}
+
+// NewConversation 创建对话并接管客户端回调
+func NewConversation(c *Client, options ConversationOptions) *Conversation {
+	if options.EventBuffer <= 0 {
+		options.EventBuffer = DefaultConversationEventBuffer
+	}
+
+	cv := &Conversation{
+		client:     c,
+		audio:      options.Audio,
+		listenMode: options.ListenMode,
+		events:     make(chan ConversationEvent, options.EventBuffer),
+	}
+
+	c.SetOnStateChanged(cv.handleStateChanged)
+	c.SetOnRecognizedText(func(text string) {
+		cv.emit(ConversationEvent{Type: EventUserSpeechRecognized, Text: text})
+	})
+	c.SetOnSpeakText(func(text string) {
+		cv.emit(ConversationEvent{Type: EventAssistantTextChunk, Text: text})
+	})
+	c.SetOnBargeIn(cv.handleBargeIn)
+
+	if cv.audio != nil {
+		// 有音频设备时以本地播放队列播空作为回复结束
+		c.SetOnSpeakingFinished(func() {
+			cv.emit(ConversationEvent{Type: EventAssistantSpeechFinished})
+		})
+		if concealer, ok := cv.audio.(AudioLossConcealer); ok {
+			// 帧头模式下序号不连续时先补偿丢失的帧
+			c.SetOnAudioFrame(func(frame protocol.AudioFrame) {
+				if frame.Lost > 0 {
+					concealer.PlayAudioAfterLoss(frame.Payload, frame.Lost)
+					return
+				}
+				cv.audio.PlayAudio(frame.Payload)
+			})
+		} else {
+			c.SetOnAudioData(cv.audio.PlayAudio)
+		}
+		cv.audio.SetOnQueueDrained(c.PlaybackDrained)
+		cv.audio.SetAudioDataCallback(func(data []byte) {
+			if err := c.SendAudioData(data); err != nil {
+				logger.Debugf("发送音频数据失败: %v", err)
+			}
+		})
+		if provider, ok := cv.audio.(AudioMetricsProvider); ok {
+			c.SetAudioMetricsProvider(provider)
+		}
+		if replayer, ok := cv.audio.(AudioReplayer); ok {
+			c.SetAudioReplayer(replayer)
+		}
+	}
+	return cv
+}
+
+// Events 返回对话事件通道，Close 后关闭
+// 事件在接收循环中发出，通道已满时丢弃新事件，调用方应及时读取
+func (cv *Conversation) Events() <-chan ConversationEvent {
+	return cv.events
+}
+
+// StartTurn 开始一轮用户发言：助手正在回复时先打断（受打断宽限期影响），然后开始监听
+// 设置了音频设备时进入监听状态后自动开始录音并上传
+func (cv *Conversation) StartTurn() error {
+	if cv.isClosed() {
+		return errors.New("对话已关闭")
+	}
+	if !cv.client.GetProtocol().IsConnected() {
+		return ErrNotConnected
+	}
+
+	state := cv.client.GetState()
+	if state == StateSpeaking {
+		cv.client.BeginBargeIn("start_recording")
+	}
+	if state == StateListening {
+		return nil
+	}
+	if err := cv.client.SendStartListening(cv.listenMode); err != nil {
+		return fmt.Errorf("开始监听失败: %w", err)
+	}
+	return nil
+}
+
+// EndTurn 结束本轮用户发言：停止录音并通知服务器停止监听，之后等待助手回复
+// 打断宽限期内结束发言时取消打断，助手继续回复
+func (cv *Conversation) EndTurn() error {
+	if cv.client.CancelBargeIn() {
+		logger.Debugf("宽限期内结束发言，助手继续回复")
+	}
+	if cv.client.GetState() != StateListening {
+		return nil
+	}
+
+	// 先停止采集，让尾部音频帧在停止监听之前发出
+	cv.stopRecording()
+	if err := cv.client.SendStopListening(); err != nil {
+		return fmt.Errorf("停止监听失败: %w", err)
+	}
+	return nil
+}
+
+// Interrupt 立即打断助手回复，不等待打断宽限期；未在回复时直接返回
+func (cv *Conversation) Interrupt() error {
+	cv.client.CancelBargeIn()
+	if cv.client.GetState() != StateSpeaking {
+		return nil
+	}
+	cv.client.abortForBargeIn("user_interrupt")
+	return nil
+}
+
+// Close 停止录音并关闭事件通道，不断开客户端连接；可重复调用
+func (cv *Conversation) Close() {
+	cv.mu.Lock()
+	if cv.closed {
+		cv.mu.Unlock()
+		return
+	}
+	cv.closed = true
+	close(cv.events)
+	cv.mu.Unlock()
+
+	cv.stopRecording()
+}
+
+// handleStateChanged 根据客户端状态协调录音和播放
+func (cv *Conversation) handleStateChanged(oldState, newState string) {
+	// 全双工时录音在监听和回复期间都保持进行
+	fullDuplex := cv.client.IsFullDuplex()
+
+	switch {
+	case newState == StateSpeaking:
+		if cv.audio != nil {
+			if err := cv.audio.StartPlaying(); err != nil {
+				cv.emit(ConversationEvent{Type: EventError, Err: fmt.Errorf("开始播放失败: %v", err)})
+			}
+			if oldState == StateListening && !fullDuplex {
+				cv.stopRecording()
+			}
+		}
+		cv.emit(ConversationEvent{Type: EventAssistantSpeechStarted})
+	case newState == StateListening:
+		// 回放音频文件时由回放代替麦克风
+		if cv.audio != nil && !cv.audio.IsRecording() && !cv.client.IsReplaying() {
+			if err := cv.audio.StartRecording(); err != nil {
+				cv.emit(ConversationEvent{Type: EventError, Err: fmt.Errorf("开始录音失败: %v", err)})
+			}
+		}
+	case oldState == StateListening && cv.client.IsReconnectBuffering():
+		// 监听中途断线，录音继续，音频由客户端暂存到重连后补发
+	case oldState == StateListening || (fullDuplex && oldState == StateSpeaking):
+		cv.stopRecording()
+	}
+
+	// 没有音频设备时无法得知本地播放进度，以TTS结束作为回复结束
+	if cv.audio == nil && oldState == StateSpeaking && newState != StateSpeaking {
+		cv.emit(ConversationEvent{Type: EventAssistantSpeechFinished})
+	}
+}
+
+// handleBargeIn 打断生效后停止本地播放
+func (cv *Conversation) handleBargeIn() {
+	if cv.audio != nil {
+		if err := cv.audio.StopPlaying(); err != nil {
+			logger.Warnf("打断时停止播放失败: %v", err)
+		}
+	}
+	cv.emit(ConversationEvent{Type: EventInterrupted})
+}
+
+// stopRecording 停止录音，未设置音频设备或未在录音时直接返回
+func (cv *Conversation) stopRecording() {
+	if cv.audio == nil || !cv.audio.IsRecording() {
+		return
+	}
+	if err := cv.audio.StopRecording(); err != nil {
+		logger.Warnf("停止录音失败: %v", err)
+	}
+}
+
+// emit 发出事件，通道已满或对话已关闭时丢弃
+func (cv *Conversation) emit(event ConversationEvent) {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+
+	if cv.closed {
+		return
+	}
+	select {
+	case cv.events <- event:
+	default:
+		logger.Warnf("对话事件通道已满，丢弃事件: %s", event.Type)
+	}
+}
+
+// isClosed 返回对话是否已关闭
+func (cv *Conversation) isClosed() bool {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	return cv.closed
+}
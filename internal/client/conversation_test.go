@@ -0,0 +1,106 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/justa-cai/xiaozhi-go/internal/audio"
+)
+
+// nextEvent 读取下一个对话事件，超时则测试失败
+func nextEvent(t *testing.T, cv *Conversation) ConversationEvent {
+	t.Helper()
+	select {
+	case event := <-cv.Events():
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("等待对话事件超时")
+		return ConversationEvent{}
+	}
+}
+
+// expectEvent 读取下一个事件并检查类型和文本
+func expectEvent(t *testing.T, cv *Conversation, eventType ConversationEventType, text string) {
+	t.Helper()
+	event := nextEvent(t, cv)
+	if event.Type != eventType || event.Text != text {
+		t.Fatalf("收到事件 %s(%q)，期望 %s(%q)", event.Type, event.Text, eventType, text)
+	}
+}
+
+func TestConversationTurnWithMockDevices(t *testing.T) {
+	c, mock := newOpenClient(t)
+
+	recorder := audio.NewMockRecorder(audio.RecorderOptions{
+		SampleRate:    audio.DefaultSampleRate,
+		ChannelCount:  audio.DefaultChannelCount,
+		FrameDuration: audio.DefaultFrameDuration,
+	}, nil)
+	manager, err := audio.NewAudioManagerWithOptions(audio.AudioManagerOptions{Recorder: recorder, HeadlessOutput: true})
+	if err != nil {
+		t.Fatalf("创建音频管理器失败: %v", err)
+	}
+	defer manager.Close()
+
+	cv := NewConversation(c, ConversationOptions{Audio: manager})
+	defer cv.Close()
+
+	// 用户发言：开始监听后自动录音，录到的音频上传到服务器
+	if err := cv.StartTurn(); err != nil {
+		t.Fatalf("开始发言失败: %v", err)
+	}
+	if !manager.IsRecording() {
+		t.Fatal("开始监听后没有开始录音")
+	}
+	for i := 0; i < 3; i++ {
+		recorder.Emit()
+	}
+	if err := cv.EndTurn(); err != nil {
+		t.Fatalf("结束发言失败: %v", err)
+	}
+	if manager.IsRecording() {
+		t.Error("结束发言后仍在录音")
+	}
+	if sent := len(mock.SentBinary()); sent < 3 {
+		t.Errorf("上传了%d帧音频，期望至少3帧", sent)
+	}
+	if listens := len(mock.SentJSONOfType("listen")); listens != 2 {
+		t.Errorf("发送了%d条listen消息，期望开始和停止各一条", listens)
+	}
+
+	// 助手回复：识别文本、回复文本和回复音频
+	mock.InjectJSON(`{"type":"stt","text":"今天天气怎么样"}`)
+	expectEvent(t, cv, EventUserSpeechRecognized, "今天天气怎么样")
+	mock.InjectJSON(`{"type":"tts","state":"start"}`)
+	expectEvent(t, cv, EventAssistantSpeechStarted, "")
+	mock.InjectJSON(`{"type":"tts","state":"sentence_start","text":"今天晴。"}`)
+	expectEvent(t, cv, EventAssistantTextChunk, "今天晴。")
+
+	frame := make([]byte, audio.DefaultSampleRate*audio.DefaultFrameDuration/1000*2)
+	mock.InjectBinary(frame)
+	if manager.GetQueueLength() == 0 {
+		t.Fatal("回复音频没有进入播放队列")
+	}
+	mock.InjectJSON(`{"type":"tts","state":"stop"}`)
+
+	// 拉取播放队列直到播放完毕
+	player := manager.Player()
+	pcm := make([]int16, 1024)
+	deadline := time.After(2 * time.Second)
+	for {
+		player.Read(pcm)
+		select {
+		case event := <-cv.Events():
+			if event.Type != EventAssistantSpeechFinished {
+				t.Fatalf("收到事件 %s，期望 %s", event.Type, EventAssistantSpeechFinished)
+			}
+			if state := c.GetState(); state != StateIdle {
+				t.Errorf("回复结束后状态为 %s，期望 %s", state, StateIdle)
+			}
+			return
+		case <-deadline:
+			t.Fatal("等待回复播放完毕超时")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
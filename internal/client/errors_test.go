@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/justa-cai/xiaozhi-go/internal/protocol"
+)
+
+// silentPinger 实现 protocol.Pinger，发出的ping永远收不到pong
+type silentPinger struct {
+	*protocol.MockProtocol
+}
+
+func (sp *silentPinger) PingContext(ctx context.Context) (time.Duration, error) {
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func TestOpenAudioChannelSentinelErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T) *Client
+		want  error
+	}{
+		{
+			name: "关闭后打开",
+			setup: func(t *testing.T) *Client {
+				c := New(newMockServer(`{"type":"hello","version":1,"transport":"websocket"}`))
+				if err := c.Close(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+				return c
+			},
+			want: ErrClientClosed,
+		},
+		{
+			name: "重复打开",
+			setup: func(t *testing.T) *Client {
+				c, _ := newOpenClient(t)
+				return c
+			},
+			want: ErrInvalidState,
+		},
+		{
+			name: "监听中打开",
+			setup: func(t *testing.T) *Client {
+				c, _ := newOpenClient(t)
+				if err := c.SendStartListening(ListenModeManual); err != nil {
+					t.Fatal(err)
+				}
+				return c
+			},
+			want: ErrInvalidState,
+		},
+		{
+			name: "连接超时",
+			setup: func(t *testing.T) *Client {
+				hp := &hangingProtocol{MockProtocol: protocol.NewMockProtocol(), release: make(chan struct{})}
+				t.Cleanup(func() { close(hp.release) })
+				c := New(hp)
+				if err := c.SetConnectTimeout(20 * time.Millisecond); err != nil {
+					t.Fatal(err)
+				}
+				return c
+			},
+			want: ErrConnectTimeout,
+		},
+		{
+			name: "hello超时",
+			setup: func(t *testing.T) *Client {
+				// 服务器不回复hello
+				c := New(protocol.NewMockProtocol())
+				if err := c.SetHelloTimeout(20 * time.Millisecond); err != nil {
+					t.Fatal(err)
+				}
+				return c
+			},
+			want: ErrHelloTimeout,
+		},
+		{
+			name: "版本不兼容",
+			setup: func(t *testing.T) *Client {
+				return New(newMockServer(`{"type":"hello","version":99,"transport":"websocket"}`))
+			},
+			want: ErrVersionMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.setup(t)
+			err := c.OpenAudioChannel("ws://test")
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("期望 errors.Is(err, %v)，实际: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestListeningSentinelErrors(t *testing.T) {
+	c, _ := newOpenClient(t)
+
+	err := c.SendStopListening()
+	if !errors.Is(err, ErrNotListening) || !errors.Is(err, ErrInvalidState) {
+		t.Errorf("空闲时停止监听期望 ErrNotListening 且属于 ErrInvalidState，实际: %v", err)
+	}
+	err = c.SendAudioData([]byte{1, 2, 3})
+	if !errors.Is(err, ErrNotListening) {
+		t.Errorf("空闲时发送音频期望 ErrNotListening，实际: %v", err)
+	}
+
+	if err := c.SendStartListening(ListenModeManual); err != nil {
+		t.Fatalf("开始监听失败: %v", err)
+	}
+	err = c.SendStartListening(ListenModeManual)
+	if !errors.Is(err, ErrInvalidState) {
+		t.Errorf("监听中再次开始监听期望 ErrInvalidState，实际: %v", err)
+	}
+	if errors.Is(err, ErrNotListening) {
+		t.Errorf("监听中再次开始监听不应是 ErrNotListening: %v", err)
+	}
+	if err := c.SendAudioData([]byte{1, 2, 3}); err != nil {
+		t.Errorf("监听中发送音频失败: %v", err)
+	}
+	if err := c.SendStopListening(); err != nil {
+		t.Errorf("停止监听失败: %v", err)
+	}
+
+	err = c.SendStartListening("invalid")
+	if errors.Is(err, ErrInvalidState) {
+		t.Errorf("无效的监听模式不应是 ErrInvalidState: %v", err)
+	}
+}
+
+func TestPingSentinelErrors(t *testing.T) {
+	sp := &silentPinger{MockProtocol: protocol.NewMockProtocol()}
+	c := New(sp)
+
+	if _, err := c.Ping(context.Background()); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("未连接时 Ping 期望 ErrNotConnected，实际: %v", err)
+	}
+
+	if err := sp.Connect("ws://test"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.Ping(ctx); !errors.Is(err, ErrPingTimeout) {
+		t.Errorf("收不到pong时 Ping 期望 ErrPingTimeout，实际: %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/justa-cai/xiaozhi-go/internal/audio"
+	"github.com/justa-cai/xiaozhi-go/internal/protocol"
+)
+
+func TestAudioFramingSendAndReceive(t *testing.T) {
+	c, mock := newOpenClient(t)
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c.now = clock.Now
+	if err := c.SetAudioFraming(protocol.AudioFramingHeader); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SendStartListening(ListenModeManual); err != nil {
+		t.Fatalf("开始监听失败: %v", err)
+	}
+
+	payloads := [][]byte{{0x01}, {0x02, 0x03}, {0x04}}
+	for _, payload := range payloads {
+		if err := c.SendAudioData(payload); err != nil {
+			t.Fatalf("发送音频失败: %v", err)
+		}
+		clock.Advance(60 * time.Millisecond)
+	}
+	sent := mock.SentBinary()
+	if len(sent) != len(payloads) {
+		t.Fatalf("发送了%d帧，期望%d帧", len(sent), len(payloads))
+	}
+	for i, data := range sent {
+		frame, err := protocol.DecodeAudioFrame(data)
+		if err != nil {
+			t.Fatalf("第%d帧无法解析: %v", i, err)
+		}
+		if frame.Sequence != uint16(i) || frame.Timestamp != uint32(i*60) {
+			t.Errorf("第%d帧序号%d时间戳%d，期望序号%d时间戳%d", i, frame.Sequence, frame.Timestamp, i, i*60)
+		}
+		if !bytes.Equal(frame.Payload, payloads[i]) {
+			t.Errorf("第%d帧数据包为% x，期望% x", i, frame.Payload, payloads[i])
+		}
+	}
+
+	if err := c.SendStopListening(); err != nil {
+		t.Fatalf("停止监听失败: %v", err)
+	}
+	mock.InjectJSON(`{"type":"tts","state":"start"}`)
+	var frames []protocol.AudioFrame
+	var audio [][]byte
+	c.SetOnAudioFrame(func(frame protocol.AudioFrame) { frames = append(frames, frame) })
+	c.SetOnAudioData(func(data []byte) { audio = append(audio, data) })
+	mock.InjectBinary(protocol.EncodeAudioFrame(protocol.AudioFrame{Sequence: 9, Timestamp: 540, Payload: []byte{0xaa}}))
+	mock.InjectBinary(protocol.EncodeAudioFrame(protocol.AudioFrame{Sequence: 10, Timestamp: 600, Payload: []byte{0xbb}}))
+	// 无法解析的帧被丢弃，不交给回调
+	mock.InjectBinary([]byte{0xff})
+
+	if len(frames) != 2 || len(audio) != 2 {
+		t.Fatalf("收到%d个音频帧回调和%d个音频数据回调，期望各2个", len(frames), len(audio))
+	}
+	if frames[0].Sequence != 9 || frames[0].Timestamp != 540 || frames[1].Sequence != 10 || frames[1].Timestamp != 600 {
+		t.Errorf("音频帧回调收到%+v", frames)
+	}
+	if !bytes.Equal(audio[0], []byte{0xaa}) || !bytes.Equal(audio[1], []byte{0xbb}) {
+		t.Errorf("音频数据回调收到的不是去掉帧头的数据包: % x", audio)
+	}
+}
+
+func TestAudioFramingPlainPassthrough(t *testing.T) {
+	c, mock := newOpenClient(t)
+	if got := c.AudioFraming(); got != protocol.AudioFramingPlain {
+		t.Fatalf("默认封装方式为%s，期望plain", got)
+	}
+	if err := c.SetAudioFraming(protocol.AudioFraming(9)); err == nil {
+		t.Error("未知的封装方式应被拒绝")
+	}
+	if err := c.SendStartListening(ListenModeManual); err != nil {
+		t.Fatalf("开始监听失败: %v", err)
+	}
+	if err := c.SendAudioData([]byte{0x01, 0x02}); err != nil {
+		t.Fatalf("发送音频失败: %v", err)
+	}
+	if sent := mock.SentBinary(); len(sent) != 1 || !bytes.Equal(sent[0], []byte{0x01, 0x02}) {
+		t.Errorf("plain模式发送了% x，期望原样发送", sent)
+	}
+
+	if err := c.SendStopListening(); err != nil {
+		t.Fatalf("停止监听失败: %v", err)
+	}
+	mock.InjectJSON(`{"type":"tts","state":"start"}`)
+	var frames []protocol.AudioFrame
+	c.SetOnAudioFrame(func(frame protocol.AudioFrame) { frames = append(frames, frame) })
+	mock.InjectBinary([]byte{0xff})
+	if len(frames) != 1 || !bytes.Equal(frames[0].Payload, []byte{0xff}) || frames[0].Sequence != 0 {
+		t.Errorf("plain模式收到%+v，期望只有数据包", frames)
+	}
+}
+
+func TestFramedAudioGapConcealed(t *testing.T) {
+	c, mock := newOpenClient(t)
+	if err := c.SetAudioFraming(protocol.AudioFramingHeader); err != nil {
+		t.Fatal(err)
+	}
+	manager, err := audio.NewAudioManagerWithOptions(audio.AudioManagerOptions{
+		Recorder:       audio.NewMockRecorder(audio.RecorderOptions{}, nil),
+		HeadlessOutput: true,
+	})
+	if err != nil {
+		t.Fatalf("创建音频管理器失败: %v", err)
+	}
+	defer manager.Close()
+	cv := NewConversation(c, ConversationOptions{Audio: manager})
+	defer cv.Close()
+
+	mock.InjectJSON(`{"type":"tts","state":"start"}`)
+	payload := make([]byte, audio.DefaultSampleRate*audio.DefaultFrameDuration/1000*2)
+	inject := func(sequence uint16) {
+		mock.InjectBinary(protocol.EncodeAudioFrame(protocol.AudioFrame{Sequence: sequence, Payload: payload}))
+	}
+
+	player := manager.Player()
+	inject(0)
+	inject(1)
+	if concealed := player.ConcealedFrames(); concealed != 0 {
+		t.Fatalf("序号连续时补偿了%d帧", concealed)
+	}
+	// 跳过序号2和3
+	inject(4)
+	if concealed := player.ConcealedFrames(); concealed != 2 {
+		t.Errorf("丢失2帧后补偿了%d帧，期望2帧", concealed)
+	}
+	if queued := manager.GetQueueLength(); queued != 5 {
+		t.Errorf("播放队列有%d帧，期望3帧音频加2帧补偿", queued)
+	}
+
+	// 长时间中断只补偿有限的帧数
+	inject(1000)
+	if concealed := player.ConcealedFrames(); concealed != 2+5 {
+		t.Errorf("长时间中断后共补偿了%d帧，期望%d帧", concealed, 2+5)
+	}
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"math"
+	"time"
+)
+
+// jitterLogInterval 抖动统计日志的最小输出间隔
+const jitterLogInterval = 10 * time.Second
+
+// JitterStats 下行音频帧到达间隔统计
+type JitterStats struct {
+	// 参与统计的到达间隔数
+	Count int
+	// 平均到达间隔
+	Mean time.Duration
+	// 到达间隔标准差，即抖动
+	StdDev time.Duration
+	// 最大到达间隔
+	MaxGap time.Duration
+}
+
+// jitterTracker 使用Welford算法增量统计帧到达间隔，不保存历史数据
+type jitterTracker struct {
+	last    time.Time
+	count   int
+	mean    float64
+	m2      float64
+	maxGap  time.Duration
+	lastLog time.Time
+}
+
+// observe 记录一帧的到达时间
+func (t *jitterTracker) observe(at time.Time) {
+	if t.last.IsZero() {
+		t.last = at
+		return
+	}
+	gap := at.Sub(t.last)
+	t.last = at
+
+	t.count++
+	x := float64(gap)
+	delta := x - t.mean
+	t.mean += delta / float64(t.count)
+	t.m2 += delta * (x - t.mean)
+	if gap > t.maxGap {
+		t.maxGap = gap
+	}
+}
+
+// pause 在TTS段落结束时调用，避免把段落之间的空档计入抖动
+func (t *jitterTracker) pause() {
+	t.last = time.Time{}
+}
+
+// shouldLog 判断距上次输出日志是否已超过 jitterLogInterval
+func (t *jitterTracker) shouldLog(now time.Time) bool {
+	if t.count == 0 || now.Sub(t.lastLog) < jitterLogInterval {
+		return false
+	}
+	t.lastLog = now
+	return true
+}
+
+// stats 返回当前统计结果
+func (t *jitterTracker) stats() JitterStats {
+	stats := JitterStats{
+		Count:  t.count,
+		Mean:   time.Duration(t.mean),
+		MaxGap: t.maxGap,
+	}
+	if t.count > 1 {
+		stats.StdDev = time.Duration(math.Sqrt(t.m2 / float64(t.count-1)))
+	}
+	return stats
+}
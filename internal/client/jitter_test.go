@@ -0,0 +1,80 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJitterTrackerIrregularIntervals(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var tracker jitterTracker
+	at := start
+	tracker.observe(at)
+	for _, gap := range []time.Duration{60, 80, 40, 120, 60} {
+		at = at.Add(gap * time.Millisecond)
+		tracker.observe(at)
+	}
+
+	stats := tracker.stats()
+	if stats.Count != 5 {
+		t.Errorf("Count = %d，期望 5", stats.Count)
+	}
+	if stats.Mean != 72*time.Millisecond {
+		t.Errorf("Mean = %v，期望 72ms", stats.Mean)
+	}
+	// 样本方差 (144+64+1024+2304+144)/4 = 920ms²
+	if want := 30331 * time.Microsecond; stats.StdDev < want-time.Microsecond || stats.StdDev > want+time.Microsecond {
+		t.Errorf("StdDev = %v，期望约 %v", stats.StdDev, want)
+	}
+	if stats.MaxGap != 120*time.Millisecond {
+		t.Errorf("MaxGap = %v，期望 120ms", stats.MaxGap)
+	}
+
+	// 段落之间的空档不计入统计
+	tracker.pause()
+	at = at.Add(5 * time.Second)
+	tracker.observe(at)
+	at = at.Add(72 * time.Millisecond)
+	tracker.observe(at)
+	stats = tracker.stats()
+	if stats.Count != 6 || stats.MaxGap != 120*time.Millisecond {
+		t.Errorf("暂停后统计为 %+v，期望不计入段落间隔", stats)
+	}
+}
+
+func TestJitterTrackerRegularIntervals(t *testing.T) {
+	var tracker jitterTracker
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 20; i++ {
+		tracker.observe(at)
+		at = at.Add(60 * time.Millisecond)
+	}
+	stats := tracker.stats()
+	if stats.Mean != 60*time.Millisecond || stats.StdDev != 0 || stats.MaxGap != 60*time.Millisecond {
+		t.Errorf("等间隔到达的统计为 %+v，期望均值60ms且无抖动", stats)
+	}
+}
+
+func TestAudioJitterStatsFromDownlinkFrames(t *testing.T) {
+	c, mock := newOpenClient(t)
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c.now = clock.Now
+	c.SetAudioJitterTracking(true)
+
+	mock.InjectJSON(`{"type":"tts","state":"start"}`)
+	mock.InjectBinary([]byte{1})
+	for _, gap := range []time.Duration{60, 80, 40, 120, 60} {
+		clock.Advance(gap * time.Millisecond)
+		mock.InjectBinary([]byte{1})
+	}
+
+	stats := c.AudioJitterStats()
+	if stats.Count != 5 || stats.Mean != 72*time.Millisecond || stats.MaxGap != 120*time.Millisecond {
+		t.Errorf("下行帧抖动统计为 %+v", stats)
+	}
+
+	c.SetAudioJitterTracking(false)
+	if stats := c.AudioJitterStats(); stats != (JitterStats{}) {
+		t.Errorf("关闭统计后返回 %+v，期望零值", stats)
+	}
+}
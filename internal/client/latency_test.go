@@ -0,0 +1,91 @@
+package client
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock 可手动推进的时钟，替换 Client.now
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (fc *fakeClock) Now() time.Time {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.now
+}
+
+func (fc *fakeClock) Advance(d time.Duration) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.now = fc.now.Add(d)
+}
+
+func TestTurnLatencyStopToTTSStart(t *testing.T) {
+	tests := []struct {
+		name    string
+		respond func(c *Client, inject func(string))
+	}{
+		{"tts start", func(c *Client, inject func(string)) {
+			inject(`{"type":"tts","state":"start"}`)
+		}},
+		{"音频帧", func(c *Client, inject func(string)) {
+			c.handleBinaryMessage([]byte{1, 2, 3})
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, mock := newOpenClient(t)
+			clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+			c.now = clock.Now
+
+			var latencies []time.Duration
+			c.SetOnTurnLatency(func(d time.Duration) {
+				latencies = append(latencies, d)
+			})
+			inject := func(msg string) { mock.InjectJSON(msg) }
+
+			if err := c.SendStartListening(ListenModeManual); err != nil {
+				t.Fatal(err)
+			}
+			clock.Advance(2 * time.Second)
+			if err := c.SendStopListening(); err != nil {
+				t.Fatal(err)
+			}
+			clock.Advance(730 * time.Millisecond)
+			tt.respond(c, inject)
+
+			// 同一轮的后续响应不再上报
+			clock.Advance(time.Second)
+			inject(`{"type":"tts","state":"start"}`)
+			c.handleBinaryMessage([]byte{4, 5, 6})
+
+			if len(latencies) != 1 {
+				t.Fatalf("上报了%d次轮次延迟，期望1次: %v", len(latencies), latencies)
+			}
+			if latencies[0] != 730*time.Millisecond {
+				t.Errorf("轮次延迟为%v，期望730ms", latencies[0])
+			}
+		})
+	}
+}
+
+func TestTurnLatencyNotReportedWithoutStop(t *testing.T) {
+	c, mock := newOpenClient(t)
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c.now = clock.Now
+
+	reported := false
+	c.SetOnTurnLatency(func(d time.Duration) { reported = true })
+
+	// 服务器主动下发的回复（没有先停止监听）不计入轮次延迟
+	clock.Advance(time.Second)
+	mock.InjectJSON(`{"type":"tts","state":"start"}`)
+	if reported {
+		t.Error("未停止监听时不应上报轮次延迟")
+	}
+}
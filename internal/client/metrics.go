@@ -0,0 +1,31 @@
+//go:build prometheus
+
+package client
+
+import (
+	"fmt"
+
+	"github.com/justa-cai/xiaozhi-go/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RegisterMetrics 在reg中注册客户端的Prometheus指标（-tags prometheus），每次抓取时读取 Stats 和 AudioMetrics 的快照。
+// reg为nil时使用 prometheus.DefaultRegisterer；设置了设备ID时指标带有device_id标签，
+// 因此同一个reg中可以注册多个设备ID不同的客户端，应在 SetDeviceID 之后调用
+func (c *Client) RegisterMetrics(reg prometheus.Registerer) error {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	c.mu.Lock()
+	deviceID := c.deviceID
+	c.mu.Unlock()
+
+	var labels prometheus.Labels
+	if deviceID != "" {
+		labels = prometheus.Labels{"device_id": deviceID}
+	}
+	if err := reg.Register(metrics.NewCollector(c, labels)); err != nil {
+		return fmt.Errorf("注册客户端指标失败: %w", err)
+	}
+	return nil
+}
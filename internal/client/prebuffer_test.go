@@ -0,0 +1,85 @@
+package client
+
+import (
+	"testing"
+)
+
+// recordAudio 记录交给音频数据回调的每一帧
+func recordAudio(c *Client) *[][]byte {
+	var received [][]byte
+	c.SetOnAudioData(func(data []byte) {
+		received = append(received, append([]byte(nil), data...))
+	})
+	return &received
+}
+
+func TestTTSAudioPlaysAfterServerMessages(t *testing.T) {
+	c, mock := newOpenClient(t)
+	received := recordAudio(c)
+
+	if err := c.SendStartListening(ListenModeManual); err != nil {
+		t.Fatal(err)
+	}
+	// 监听期间到达的TTS开头被缓冲，不立即播放
+	mock.InjectBinary([]byte{1})
+	mock.InjectBinary([]byte{2})
+	if len(*received) != 0 {
+		t.Fatalf("监听期间播放了%d帧音频", len(*received))
+	}
+
+	if err := c.SendStopListening(); err != nil {
+		t.Fatal(err)
+	}
+	mock.InjectJSON(`{"type":"tts","state":"start"}`)
+	if state := c.GetState(); state != StateSpeaking {
+		t.Fatalf("TTS开始后状态为 %s，期望 %s", state, StateSpeaking)
+	}
+	mock.InjectBinary([]byte{3})
+
+	want := [][]byte{{1}, {2}, {3}}
+	if len(*received) != len(want) {
+		t.Fatalf("播放了%d帧音频，期望%d帧", len(*received), len(want))
+	}
+	for i := range want {
+		if (*received)[i][0] != want[i][0] {
+			t.Errorf("第%d帧为 %v，期望 %v", i, (*received)[i], want[i])
+		}
+	}
+
+	mock.InjectJSON(`{"type":"tts","state":"stop"}`)
+	if state := c.GetState(); state != StateIdle {
+		t.Errorf("TTS结束后状态为 %s，期望 %s", state, StateIdle)
+	}
+}
+
+func TestPreBufferKeepsOnlyRecentFrames(t *testing.T) {
+	c, mock := newOpenClient(t)
+	c.SetAudioPreBuffer(2, 0)
+	received := recordAudio(c)
+
+	if err := c.SendStartListening(ListenModeManual); err != nil {
+		t.Fatal(err)
+	}
+	for i := byte(1); i <= 5; i++ {
+		mock.InjectBinary([]byte{i})
+	}
+	mock.InjectJSON(`{"type":"tts","state":"start"}`)
+
+	if len(*received) != 2 || (*received)[0][0] != 4 || (*received)[1][0] != 5 {
+		t.Errorf("补发的帧为 %v，期望最近的两帧 [4] [5]", *received)
+	}
+}
+
+func TestDropAudioWhileListeningDisabled(t *testing.T) {
+	c, mock := newOpenClient(t)
+	c.SetDropAudioWhileListening(false)
+	received := recordAudio(c)
+
+	if err := c.SendStartListening(ListenModeManual); err != nil {
+		t.Fatal(err)
+	}
+	mock.InjectBinary([]byte{1})
+	if len(*received) != 1 {
+		t.Errorf("关闭丢弃后监听期间的音频应照常回调，实际回调%d帧", len(*received))
+	}
+}
@@ -0,0 +1,80 @@
+package client
+
+import "time"
+
+// 断线期间上行音频缓冲的默认参数
+const (
+	DefaultReconnectBufferBytes = 64 * 1024 // 缓冲的最大字节数
+)
+
+// uplinkFrame 断线期间暂存的一帧上行音频（未封装帧头的Opus数据包）
+type uplinkFrame struct {
+	data     []byte
+	captured time.Time
+}
+
+// reconnectBuffer 手动监听中途意外断线时暂存仍在采集的上行音频，重新连接、恢复监听后按顺序补发，
+// 补发完之前新采集的音频继续排在后面，保证上行顺序。
+// 只保留最近maxAge时长、不超过maxBytes字节的帧，超出时丢弃最早的帧；由Client.mu保护
+type reconnectBuffer struct {
+	maxAge   time.Duration
+	maxBytes int
+
+	holding bool // 是否正在等待重连（断线时处于手动监听）
+	frames  []uplinkFrame
+	bytes   int
+	dropped int // 本次等待期间因超出上限丢弃的帧数
+}
+
+func newReconnectBuffer(maxAge time.Duration, maxBytes int) *reconnectBuffer {
+	if maxBytes <= 0 {
+		maxBytes = DefaultReconnectBufferBytes
+	}
+	return &reconnectBuffer{maxAge: maxAge, maxBytes: maxBytes}
+}
+
+// hold 开始暂存，丢弃上一次残留的帧
+func (b *reconnectBuffer) hold() {
+	b.reset()
+	b.holding = true
+}
+
+// push 暂存一帧，之后按时长和字节数上限丢弃最早的帧
+func (b *reconnectBuffer) push(now time.Time, data []byte) {
+	b.frames = append(b.frames, uplinkFrame{data: append([]byte(nil), data...), captured: now})
+	b.bytes += len(data)
+	b.trim(now)
+}
+
+// trim 丢弃超过时长或字节数上限的最早的帧
+func (b *reconnectBuffer) trim(now time.Time) {
+	n := 0
+	for n < len(b.frames) && (b.bytes > b.maxBytes || now.Sub(b.frames[n].captured) > b.maxAge) {
+		b.bytes -= len(b.frames[n].data)
+		n++
+	}
+	if n > 0 {
+		b.frames = b.frames[n:]
+		b.dropped += n
+	}
+}
+
+// pop 取出最早的一帧，已没有帧时返回false
+func (b *reconnectBuffer) pop(now time.Time) ([]byte, bool) {
+	b.trim(now)
+	if len(b.frames) == 0 {
+		return nil, false
+	}
+	data := b.frames[0].data
+	b.frames = b.frames[1:]
+	b.bytes -= len(data)
+	return data, true
+}
+
+// reset 结束暂存并清空缓冲
+func (b *reconnectBuffer) reset() {
+	b.holding = false
+	b.frames = nil
+	b.bytes = 0
+	b.dropped = 0
+}
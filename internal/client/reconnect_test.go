@@ -0,0 +1,102 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/justa-cai/xiaozhi-go/internal/protocol"
+)
+
+func TestReconnectResumesSessionAndFlushesAudio(t *testing.T) {
+	c, mock := newOpenClient(t)
+	c.SetSessionResume(true)
+	c.SetReconnectAudioBuffer(time.Second, 0)
+
+	if err := c.SendStartListening(ListenModeManual); err != nil {
+		t.Fatal(err)
+	}
+	sessionID := c.SessionID()
+
+	mock.InjectDisconnect(errors.New("连接被重置"))
+	if !c.IsReconnectBuffering() {
+		t.Fatal("按住说话时断线应进入等待重连状态")
+	}
+	frames := [][]byte{{1}, {2}, {3}}
+	for _, frame := range frames {
+		if err := c.SendAudioData(frame); err != nil {
+			t.Fatalf("等待重连期间发送音频失败: %v", err)
+		}
+	}
+
+	mock.Reset()
+	if err := c.OpenAudioChannel("ws://test"); err != nil {
+		t.Fatalf("重新打开音频通道失败: %v", err)
+	}
+
+	hellos := mock.SentJSONOfType("hello")
+	if len(hellos) != 1 {
+		t.Fatalf("发送了%d条hello，期望1条", len(hellos))
+	}
+	var hello protocol.HelloMessage
+	if err := json.Unmarshal(hellos[0], &hello); err != nil {
+		t.Fatal(err)
+	}
+	if hello.SessionID != sessionID {
+		t.Errorf("重连hello的会话ID为 %q，期望 %q", hello.SessionID, sessionID)
+	}
+
+	listens := mock.SentJSONOfType("listen")
+	if len(listens) != 1 {
+		t.Fatalf("重连后发送了%d条listen消息，期望1条", len(listens))
+	}
+	var listen protocol.ListenMessage
+	if err := json.Unmarshal(listens[0], &listen); err != nil {
+		t.Fatal(err)
+	}
+	if listen.State != "start" || listen.SessionID != sessionID {
+		t.Errorf("重连后的listen消息为 %+v，期望以原会话开始监听", listen)
+	}
+
+	sent := mock.SentBinary()
+	if len(sent) != len(frames) {
+		t.Fatalf("补发了%d帧音频，期望%d帧", len(sent), len(frames))
+	}
+	for i := range frames {
+		if !bytes.Equal(sent[i], frames[i]) {
+			t.Errorf("第%d帧为 %v，期望 %v", i, sent[i], frames[i])
+		}
+	}
+	if state := c.GetState(); state != StateListening {
+		t.Errorf("重连后状态为 %s，期望 %s", state, StateListening)
+	}
+	if c.IsReconnectBuffering() {
+		t.Error("补发完成后应结束暂存")
+	}
+}
+
+func TestReconnectWithoutResumeStartsNewSession(t *testing.T) {
+	c, mock := newOpenClient(t)
+	if err := c.SendStartListening(ListenModeManual); err != nil {
+		t.Fatal(err)
+	}
+
+	mock.InjectDisconnect(errors.New("连接被重置"))
+	if c.SessionID() != "" {
+		t.Error("未开启会话恢复时断线应清除会话ID")
+	}
+
+	mock.Reset()
+	if err := c.OpenAudioChannel("ws://test"); err != nil {
+		t.Fatalf("重新打开音频通道失败: %v", err)
+	}
+	var hello protocol.HelloMessage
+	if err := json.Unmarshal(mock.SentJSONOfType("hello")[0], &hello); err != nil {
+		t.Fatal(err)
+	}
+	if hello.SessionID != "" {
+		t.Errorf("未开启会话恢复时hello不应携带会话ID，实际: %q", hello.SessionID)
+	}
+}
@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/justa-cai/xiaozhi-go/internal/protocol"
+)
+
+// fakeReplayer 回放时调用replay，模拟把文件逐帧送入录音处理流程
+type fakeReplayer struct {
+	replay func(ctx context.Context, path string) error
+}
+
+func (r *fakeReplayer) ReplayFile(ctx context.Context, path string) error {
+	return r.replay(ctx, path)
+}
+
+// listenStates 返回依次发送的listen消息的state
+func listenStates(t *testing.T, mock *protocol.MockProtocol) []string {
+	t.Helper()
+	var states []string
+	for _, data := range mock.SentJSONOfType("listen") {
+		var listen protocol.ListenMessage
+		if err := json.Unmarshal(data, &listen); err != nil {
+			t.Fatal(err)
+		}
+		states = append(states, listen.State)
+	}
+	return states
+}
+
+func TestStartReplayDrivesListenTurn(t *testing.T) {
+	c, mock := newOpenClient(t)
+	var path string
+	c.SetAudioReplayer(&fakeReplayer{replay: func(ctx context.Context, p string) error {
+		path = p
+		if !c.IsReplaying() {
+			t.Error("回放期间IsReplaying应为true")
+		}
+		if state := c.GetState(); state != StateListening {
+			t.Errorf("回放期间状态为%s，期望%s", state, StateListening)
+		}
+		for i := 0; i < 3; i++ {
+			if err := c.SendAudioData([]byte{byte(i)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}})
+
+	if err := c.StartReplay("turn.wav"); err != nil {
+		t.Fatalf("回放失败: %v", err)
+	}
+	if path != "turn.wav" {
+		t.Errorf("回放设备收到路径%q", path)
+	}
+	if c.IsReplaying() {
+		t.Error("回放结束后IsReplaying仍为true")
+	}
+	if sent := len(mock.SentBinary()); sent != 3 {
+		t.Errorf("发送了%d帧音频，期望3帧", sent)
+	}
+	states := listenStates(t, mock)
+	if len(states) != 2 || states[0] != "start" || states[1] != "stop" {
+		t.Errorf("listen消息依次为%v，期望[start stop]", states)
+	}
+}
+
+func TestStartReplayStopsListeningOnError(t *testing.T) {
+	c, mock := newOpenClient(t)
+	replayErr := errors.New("读取回放文件失败")
+	c.SetAudioReplayer(&fakeReplayer{replay: func(ctx context.Context, path string) error {
+		return replayErr
+	}})
+
+	if err := c.StartReplay("broken.wav"); !errors.Is(err, replayErr) {
+		t.Fatalf("返回%v，期望回放设备的错误", err)
+	}
+	states := listenStates(t, mock)
+	if len(states) != 2 || states[1] != "stop" {
+		t.Errorf("回放出错后listen消息为%v，期望以stop结束", states)
+	}
+}
+
+func TestStartReplayCancel(t *testing.T) {
+	c, mock := newOpenClient(t)
+	c.SetAudioReplayer(&fakeReplayer{replay: func(ctx context.Context, path string) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.StartReplayContext(ctx, "turn.wav"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("返回%v，期望context.Canceled", err)
+	}
+	if states := listenStates(t, mock); len(states) != 2 || states[1] != "stop" {
+		t.Errorf("取消后listen消息为%v，期望以stop结束", states)
+	}
+}
+
+func TestStartReplayRejected(t *testing.T) {
+	t.Run("未设置回放设备", func(t *testing.T) {
+		c, _ := newOpenClient(t)
+		if err := c.StartReplay("turn.wav"); err == nil {
+			t.Error("未设置回放设备时应返回错误")
+		}
+	})
+	t.Run("未连接", func(t *testing.T) {
+		c, mock := newOpenClient(t)
+		c.SetAudioReplayer(&fakeReplayer{replay: func(ctx context.Context, path string) error {
+			t.Error("未连接时不应开始回放")
+			return nil
+		}})
+		mock.InjectDisconnect(nil)
+		if err := c.StartReplay("turn.wav"); !errors.Is(err, ErrNotConnected) {
+			t.Errorf("返回%v，期望ErrNotConnected", err)
+		}
+	})
+	t.Run("重复回放", func(t *testing.T) {
+		c, _ := newOpenClient(t)
+		c.SetAudioReplayer(&fakeReplayer{replay: func(ctx context.Context, path string) error {
+			if err := c.StartReplay("again.wav"); err == nil {
+				t.Error("回放期间应拒绝第二次回放")
+			}
+			return nil
+		}})
+		if err := c.StartReplay("turn.wav"); err != nil {
+			t.Fatalf("回放失败: %v", err)
+		}
+	})
+}
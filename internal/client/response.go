@@ -0,0 +1,52 @@
+package client
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// responseAssembler 将一轮回复中逐句下发的文本拼接成完整回复，由Client.mu保护
+// 句子来自tts的sentence_start消息，以及不带情绪的llm文本片段（带情绪的llm消息文本是表情，不计入回复）
+type responseAssembler struct {
+	text strings.Builder
+}
+
+// append 追加一段文本，返回到目前为止的完整文本
+func (a *responseAssembler) append(chunk string) string {
+	chunk = strings.TrimSpace(chunk)
+	if chunk == "" {
+		return a.text.String()
+	}
+	if a.text.Len() > 0 && needsSpace(a.text.String(), chunk) {
+		a.text.WriteByte(' ')
+	}
+	a.text.WriteString(chunk)
+	return a.text.String()
+}
+
+// take 取出完整回复并清空，开始下一轮
+func (a *responseAssembler) take() string {
+	text := a.text.String()
+	a.reset()
+	return text
+}
+
+// reset 丢弃尚未完成的回复
+func (a *responseAssembler) reset() {
+	a.text.Reset()
+}
+
+// needsSpace 返回拼接两段文本时是否需要空格：中文等不以空格分词的文字直接拼接，英文等单词之间补一个空格
+func needsSpace(prev, next string) bool {
+	last, _ := utf8.DecodeLastRuneInString(prev)
+	first, _ := utf8.DecodeRuneInString(next)
+	if unicode.Is(unicode.Han, last) || unicode.Is(unicode.Han, first) {
+		return false
+	}
+	// 全角标点之后不需要空格
+	if last >= 0x3000 && last <= 0x303F || last >= 0xFF00 && last <= 0xFFEF {
+		return false
+	}
+	return !unicode.IsSpace(last) && !unicode.IsPunct(first)
+}
@@ -0,0 +1,75 @@
+package client
+
+import "testing"
+
+func TestSpeakingFinishedAfterQueueDrains(t *testing.T) {
+	c, mock := newOpenClient(t)
+	finished := 0
+	c.SetOnSpeakingFinished(func() {
+		finished++
+	})
+
+	mock.InjectJSON(`{"type":"tts","state":"start"}`)
+	mock.InjectBinary([]byte{1})
+	c.PlaybackDrained()
+	if finished != 0 {
+		t.Fatal("TTS进行中播放队列播空不应触发播放结束")
+	}
+
+	// 播空之后又收到音频，TTS结束时仍在播放
+	mock.InjectBinary([]byte{2})
+	mock.InjectJSON(`{"type":"tts","state":"stop"}`)
+	if finished != 0 {
+		t.Fatal("TTS结束时仍有音频未播放，不应触发播放结束")
+	}
+
+	c.PlaybackDrained()
+	if finished != 1 {
+		t.Fatalf("播放队列播空后触发了%d次播放结束，期望1次", finished)
+	}
+	c.PlaybackDrained()
+	if finished != 1 {
+		t.Errorf("每轮TTS最多触发一次播放结束，实际%d次", finished)
+	}
+}
+
+func TestSpeakingFinishedOnTTSStopWhenAlreadyDrained(t *testing.T) {
+	c, mock := newOpenClient(t)
+	finished := 0
+	c.SetOnSpeakingFinished(func() {
+		finished++
+	})
+
+	mock.InjectJSON(`{"type":"tts","state":"start"}`)
+	mock.InjectBinary([]byte{1})
+	c.PlaybackDrained()
+	mock.InjectJSON(`{"type":"tts","state":"stop"}`)
+	if finished != 1 {
+		t.Errorf("TTS结束前已播空，结束时应触发一次播放结束，实际%d次", finished)
+	}
+}
+
+// BenchmarkTTSSentenceStart 处理一条tts sentence_start消息的开销：解析、文本回调和回复拼接
+func BenchmarkTTSSentenceStart(b *testing.B) {
+	c, mock := newOpenClient(b)
+	var spoken int
+	c.SetOnSpeakText(func(text string) { spoken++ })
+	mock.InjectJSON(`{"type":"tts","state":"start"}`)
+	message := []byte(`{"type":"tts","state":"sentence_start","text":"从前有座山，山里有座庙。","session_id":"f4c1b2a0-5d3e-4b7a-9c21-3e8f6a1d2b4c"}`)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mock.InjectJSON(message)
+		// 定期清空拼接的回复，避免回复文本随迭代次数无限增长
+		if i%256 == 255 {
+			c.mu.Lock()
+			c.response.reset()
+			c.mu.Unlock()
+		}
+	}
+	b.StopTimer()
+	if spoken != b.N {
+		b.Fatalf("文本回调触发%d次，期望%d次", spoken, b.N)
+	}
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrIllegalStateTransition 请求的状态转换不在允许的转换表中
+var ErrIllegalStateTransition = errors.New("非法的状态转换")
+
+// stateTransitions 合法的状态转换表，键为当前状态，值为允许转换到的状态
+// 转换到当前所在的状态总是允许的，但不触发状态变更回调
+var stateTransitions = map[string][]string{
+	StateIdle:       {StateConnecting, StateListening, StateSpeaking},
+	StateConnecting: {StateIdle, StateListening},
+	StateListening:  {StateIdle, StateSpeaking},
+	StateSpeaking:   {StateIdle, StateListening},
+}
+
+// canTransition 判断能否从from转换到to
+func canTransition(from, to string) bool {
+	if from == to {
+		_, known := stateTransitions[to]
+		return known
+	}
+	for _, allowed := range stateTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transitionLocked 校验并更新状态，返回原状态，调用方需持有c.mu并在释放锁后触发状态变更回调
+func (c *Client) transitionLocked(to string) (string, error) {
+	from := c.state
+	if !canTransition(from, to) {
+		return from, fmt.Errorf("%w: %s -> %s", ErrIllegalStateTransition, from, to)
+	}
+	c.state = to
+	return from, nil
+}
+
+// transition 校验并更新状态，合法且状态发生变化时触发状态变更回调，非法转换记录日志并返回错误
+func (c *Client) transition(to string) error {
+	c.mu.Lock()
+	from, err := c.transitionLocked(to)
+	onStateChanged := c.onStateChanged
+	c.mu.Unlock()
+
+	if err != nil {
+		logger.Warnf("拒绝状态转换: %v", err)
+		return err
+	}
+	c.notifyStateChanged(onStateChanged, from, to)
+	return nil
+}
+
+// notifyStateChanged 状态发生变化时调用状态变更回调
+func (c *Client) notifyStateChanged(onStateChanged func(oldState, newState string), from, to string) {
+	if from != to && onStateChanged != nil {
+		onStateChanged(from, to)
+	}
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/justa-cai/xiaozhi-go/internal/protocol"
+)
+
+func TestStateTransitions(t *testing.T) {
+	tests := []struct {
+		from, to string
+		legal    bool
+	}{
+		{StateIdle, StateIdle, true},
+		{StateIdle, StateConnecting, true},
+		{StateIdle, StateListening, true},
+		{StateIdle, StateSpeaking, true},
+
+		{StateConnecting, StateIdle, true},
+		{StateConnecting, StateConnecting, true},
+		{StateConnecting, StateListening, true},
+		{StateConnecting, StateSpeaking, false},
+
+		{StateListening, StateIdle, true},
+		{StateListening, StateConnecting, false},
+		{StateListening, StateListening, true},
+		{StateListening, StateSpeaking, true},
+
+		{StateSpeaking, StateIdle, true},
+		{StateSpeaking, StateConnecting, false},
+		{StateSpeaking, StateListening, true},
+		{StateSpeaking, StateSpeaking, true},
+
+		// 未知状态既不能作为起点也不能作为目标
+		{StateIdle, "unknown", false},
+		{"unknown", StateIdle, false},
+		{"unknown", "unknown", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			if got := canTransition(tt.from, tt.to); got != tt.legal {
+				t.Fatalf("canTransition(%s, %s) = %v，期望 %v", tt.from, tt.to, got, tt.legal)
+			}
+
+			c := New(protocol.NewMockProtocol())
+			c.state = tt.from
+			var changes [][2]string
+			c.SetOnStateChanged(func(oldState, newState string) {
+				changes = append(changes, [2]string{oldState, newState})
+			})
+
+			err := c.transition(tt.to)
+			if tt.legal {
+				if err != nil {
+					t.Fatalf("合法转换返回错误: %v", err)
+				}
+				if c.state != tt.to {
+					t.Errorf("转换后状态为 %s，期望 %s", c.state, tt.to)
+				}
+				wantChanges := 1
+				if tt.from == tt.to {
+					wantChanges = 0
+				}
+				if len(changes) != wantChanges {
+					t.Errorf("状态变更回调触发%d次，期望%d次", len(changes), wantChanges)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrIllegalStateTransition) {
+				t.Fatalf("非法转换期望 ErrIllegalStateTransition，实际: %v", err)
+			}
+			if c.state != tt.from {
+				t.Errorf("非法转换后状态变为 %s，期望保持 %s", c.state, tt.from)
+			}
+			if len(changes) != 0 {
+				t.Errorf("非法转换触发了状态变更回调: %v", changes)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/justa-cai/xiaozhi-go/internal/protocol"
+)
+
+func TestSendTextReplyCallbacks(t *testing.T) {
+	c, mock := newOpenClient(t)
+
+	var recognized, spoken []string
+	var response string
+	c.SetOnRecognizedText(func(text string) {
+		recognized = append(recognized, text)
+	})
+	c.SetOnSpeakText(func(text string) {
+		spoken = append(spoken, text)
+	})
+	c.SetOnAssistantResponse(func(text string) {
+		response = text
+	})
+
+	if err := c.SendText("今天天气怎么样"); err != nil {
+		t.Fatalf("发送文本失败: %v", err)
+	}
+	texts := mock.SentJSONOfType("text")
+	if len(texts) != 1 {
+		t.Fatalf("发送了%d条文本消息，期望1条", len(texts))
+	}
+	var message protocol.TextMessage
+	if err := json.Unmarshal(texts[0], &message); err != nil {
+		t.Fatal(err)
+	}
+	if message.Text != "今天天气怎么样" || message.SessionID == "" {
+		t.Errorf("文本消息为 %+v", message)
+	}
+
+	// 服务器按语音对话同样回复
+	mock.InjectJSON(`{"type":"stt","text":"今天天气怎么样"}`)
+	mock.InjectJSON(`{"type":"tts","state":"start"}`)
+	mock.InjectJSON(`{"type":"tts","state":"sentence_start","text":"今天晴，"}`)
+	mock.InjectJSON(`{"type":"tts","state":"sentence_start","text":"气温25度。"}`)
+	mock.InjectJSON(`{"type":"tts","state":"stop"}`)
+
+	if len(recognized) != 1 || recognized[0] != "今天天气怎么样" {
+		t.Errorf("识别文本回调收到 %v", recognized)
+	}
+	if len(spoken) != 2 {
+		t.Errorf("朗读文本回调收到 %v，期望2句", spoken)
+	}
+	if response != "今天晴，气温25度。" {
+		t.Errorf("完整回复为 %q", response)
+	}
+	if state := c.GetState(); state != StateIdle {
+		t.Errorf("回复结束后状态为 %s，期望 %s", state, StateIdle)
+	}
+}
+
+func TestSendTextAbortsCurrentReply(t *testing.T) {
+	c, mock := newOpenClient(t)
+	mock.InjectJSON(`{"type":"tts","state":"start"}`)
+
+	if err := c.SendText("换个话题"); err != nil {
+		t.Fatalf("播放时发送文本失败: %v", err)
+	}
+	if n := len(mock.SentJSONOfType("abort")); n != 1 {
+		t.Errorf("播放时发送文本应先打断当前回复，发送了%d条abort", n)
+	}
+}
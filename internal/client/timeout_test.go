@@ -0,0 +1,188 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/justa-cai/xiaozhi-go/internal/protocol"
+)
+
+// hangingProtocol Connect 一直阻塞到release关闭，模拟无响应的服务器
+type hangingProtocol struct {
+	*protocol.MockProtocol
+	release chan struct{}
+}
+
+func (hp *hangingProtocol) Connect(url string) error {
+	<-hp.release
+	return errors.New("连接已放弃")
+}
+
+// contextProtocol 实现 protocol.ContextConnector，拨号一直阻塞到ctx结束
+type contextProtocol struct {
+	*protocol.MockProtocol
+	cancelled chan struct{}
+}
+
+func (cp *contextProtocol) ConnectContext(ctx context.Context, url string) error {
+	<-ctx.Done()
+	close(cp.cancelled)
+	return ctx.Err()
+}
+
+func TestOpenAudioChannelConnectTimeout(t *testing.T) {
+	hp := &hangingProtocol{MockProtocol: protocol.NewMockProtocol(), release: make(chan struct{})}
+	defer close(hp.release)
+	c := New(hp)
+	if err := c.SetConnectTimeout(50 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	err := c.OpenAudioChannel("ws://test")
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, ErrConnectTimeout) {
+		t.Fatalf("期望 ErrConnectTimeout，实际: %v", err)
+	}
+	if elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Errorf("连接超时后等待了%v，期望约50ms", elapsed)
+	}
+	if state := c.GetState(); state != StateIdle {
+		t.Errorf("连接超时后状态为 %s，期望 %s", state, StateIdle)
+	}
+}
+
+func TestOpenAudioChannelConnectTimeoutCancelsDial(t *testing.T) {
+	cp := &contextProtocol{MockProtocol: protocol.NewMockProtocol(), cancelled: make(chan struct{})}
+	c := New(cp)
+	if err := c.SetConnectTimeout(50 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.OpenAudioChannel("ws://test"); !errors.Is(err, ErrConnectTimeout) {
+		t.Fatalf("期望 ErrConnectTimeout，实际: %v", err)
+	}
+	select {
+	case <-cp.cancelled:
+	default:
+		t.Error("连接超时后应取消拨号")
+	}
+}
+
+// newHangingListener 启动只接受TCP连接、从不回复握手的服务器，返回ws地址
+func newHangingListener(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			// 读到客户端关闭连接为止，不发送任何响应
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer conn.Close()
+				buf := make([]byte, 1024)
+				for {
+					if _, err := conn.Read(buf); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	t.Cleanup(func() {
+		ln.Close()
+		wg.Wait()
+	})
+	return "ws://" + ln.Addr().String()
+}
+
+func TestOpenAudioChannelConnectTimeoutNoGoroutineLeak(t *testing.T) {
+	url := newHangingListener(t)
+	ws := protocol.NewWebsocketProtocol()
+	// 握手超时远大于连接超时，只有连接超时能中止拨号
+	ws.SetHandshakeTimeout(time.Minute)
+	c := New(ws)
+	if err := c.SetConnectTimeout(100 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 5; i++ {
+		start := time.Now()
+		if err := c.OpenAudioChannel(url); !errors.Is(err, ErrConnectTimeout) {
+			t.Fatalf("第%d次连接期望 ErrConnectTimeout，实际: %v", i+1, err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Fatalf("第%d次连接超时后等待了%v，拨号没有被中止", i+1, elapsed)
+		}
+	}
+
+	// 被中止的拨号关闭连接后，客户端和服务器两端的goroutine都应退出
+	deadline := time.Now().Add(2 * time.Second)
+	after := runtime.NumGoroutine()
+	for after > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		after = runtime.NumGoroutine()
+	}
+	if after > before {
+		buf := make([]byte, 1<<16)
+		t.Errorf("连接超时后goroutine从%d个增加到%d个:\n%s", before, after, buf[:runtime.Stack(buf, true)])
+	}
+	if ws.IsConnected() {
+		t.Error("连接超时后协议不应处于已连接状态")
+	}
+}
+
+func TestOpenAudioChannelHelloTimeout(t *testing.T) {
+	// 服务器不回复hello
+	mock := protocol.NewMockProtocol()
+	c := New(mock)
+	if err := c.SetHelloTimeout(50 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	err := c.OpenAudioChannel("ws://test")
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, ErrHelloTimeout) {
+		t.Fatalf("期望 ErrHelloTimeout，实际: %v", err)
+	}
+	if elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Errorf("hello超时后等待了%v，期望约50ms", elapsed)
+	}
+	if mock.IsConnected() {
+		t.Error("hello超时后应断开连接")
+	}
+	if state := c.GetState(); state != StateIdle {
+		t.Errorf("hello超时后状态为 %s，期望 %s", state, StateIdle)
+	}
+}
+
+func TestSetTimeoutsRejectNonPositive(t *testing.T) {
+	c := New(protocol.NewMockProtocol())
+	for _, d := range []time.Duration{0, -time.Second} {
+		if err := c.SetConnectTimeout(d); err == nil {
+			t.Errorf("SetConnectTimeout(%v) 应返回错误", d)
+		}
+		if err := c.SetHelloTimeout(d); err == nil {
+			t.Errorf("SetHelloTimeout(%v) 应返回错误", d)
+		}
+	}
+}
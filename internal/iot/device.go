@@ -0,0 +1,184 @@
+package iot
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ValueType 属性或参数的取值类型
+type ValueType string
+
+const (
+	TypeNumber  ValueType = "number"
+	TypeString  ValueType = "string"
+	TypeBoolean ValueType = "boolean"
+)
+
+// Parameter 方法参数定义
+type Parameter struct {
+	Name        string
+	Description string
+	Type        ValueType
+}
+
+// Property 设备属性，取值由Getter在生成状态时实时读取
+type Property struct {
+	Name        string
+	Description string
+	Type        ValueType
+	Getter      func() interface{}
+}
+
+// Method 设备方法，服务器下发对应的IoT命令时调用Handler
+type Method struct {
+	Name        string
+	Description string
+	Parameters  []Parameter
+	Handler     func(params map[string]interface{}) error
+}
+
+// Device 一个可被服务器控制的IoT设备
+type Device struct {
+	name        string
+	description string
+
+	mu         sync.Mutex
+	properties []*Property
+	methods    []*Method
+}
+
+// NewDevice 创建设备
+func NewDevice(name, description string) *Device {
+	return &Device{
+		name:        name,
+		description: description,
+	}
+}
+
+// Name 返回设备名称
+func (d *Device) Name() string {
+	return d.name
+}
+
+// AddProperty 注册属性，同名属性会被替换
+func (d *Device) AddProperty(name, description string, typ ValueType, getter func() interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	prop := &Property{Name: name, Description: description, Type: typ, Getter: getter}
+	for i, p := range d.properties {
+		if p.Name == name {
+			d.properties[i] = prop
+			return
+		}
+	}
+	d.properties = append(d.properties, prop)
+}
+
+// AddMethod 注册方法，同名方法会被替换
+func (d *Device) AddMethod(name, description string, params []Parameter, handler func(params map[string]interface{}) error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	method := &Method{Name: name, Description: description, Parameters: params, Handler: handler}
+	for i, m := range d.methods {
+		if m.Name == name {
+			d.methods[i] = method
+			return
+		}
+	}
+	d.methods = append(d.methods, method)
+}
+
+// descriptor 生成设备描述符
+func (d *Device) descriptor() Descriptor {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	desc := Descriptor{
+		Name:        d.name,
+		Description: d.description,
+		Properties:  make(map[string]PropertyDescriptor, len(d.properties)),
+		Methods:     make(map[string]MethodDescriptor, len(d.methods)),
+	}
+	for _, p := range d.properties {
+		desc.Properties[p.Name] = PropertyDescriptor{Description: p.Description, Type: p.Type}
+	}
+	for _, m := range d.methods {
+		params := make(map[string]PropertyDescriptor, len(m.Parameters))
+		for _, param := range m.Parameters {
+			params[param.Name] = PropertyDescriptor{Description: param.Description, Type: param.Type}
+		}
+		desc.Methods[m.Name] = MethodDescriptor{Description: m.Description, Parameters: params}
+	}
+	return desc
+}
+
+// state 读取设备当前状态
+func (d *Device) state() State {
+	d.mu.Lock()
+	properties := make([]*Property, len(d.properties))
+	copy(properties, d.properties)
+	d.mu.Unlock()
+
+	values := make(map[string]interface{}, len(properties))
+	for _, p := range properties {
+		if p.Getter != nil {
+			values[p.Name] = p.Getter()
+		}
+	}
+	return State{Name: d.name, State: values}
+}
+
+// invoke 校验参数后调用方法
+func (d *Device) invoke(methodName string, params map[string]interface{}) error {
+	d.mu.Lock()
+	var method *Method
+	for _, m := range d.methods {
+		if m.Name == methodName {
+			method = m
+			break
+		}
+	}
+	d.mu.Unlock()
+
+	if method == nil {
+		return fmt.Errorf("设备 %s 不支持方法 %s", d.name, methodName)
+	}
+
+	for _, param := range method.Parameters {
+		value, ok := params[param.Name]
+		if !ok {
+			return fmt.Errorf("方法 %s.%s 缺少参数 %s", d.name, methodName, param.Name)
+		}
+		if !param.Type.matches(value) {
+			return fmt.Errorf("方法 %s.%s 参数 %s 类型错误，期望 %s，实际 %T",
+				d.name, methodName, param.Name, param.Type, value)
+		}
+	}
+
+	if method.Handler == nil {
+		return nil
+	}
+	return method.Handler(params)
+}
+
+// matches 检查JSON解码后的值是否符合类型
+func (t ValueType) matches(value interface{}) bool {
+	switch t {
+	case TypeNumber:
+		switch value.(type) {
+		case float64, float32, int, int64, int32:
+			return true
+		}
+		return false
+	case TypeString:
+		_, ok := value.(string)
+		return ok
+	case TypeBoolean:
+		_, ok := value.(bool)
+		return ok
+	default:
+		return true
+	}
+}
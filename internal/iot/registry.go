@@ -0,0 +1,110 @@
+package iot
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/justa-cai/xiaozhi-go/internal/protocol"
+)
+
+// PropertyDescriptor 属性或参数的描述
+type PropertyDescriptor struct {
+	Description string    `json:"description"`
+	Type        ValueType `json:"type"`
+}
+
+// MethodDescriptor 方法的描述
+type MethodDescriptor struct {
+	Description string                        `json:"description"`
+	Parameters  map[string]PropertyDescriptor `json:"parameters"`
+}
+
+// Descriptor 发送给服务器的设备描述符，对应 SendIoTDescriptors 的格式
+type Descriptor struct {
+	Name        string                        `json:"name"`
+	Description string                        `json:"description"`
+	Properties  map[string]PropertyDescriptor `json:"properties"`
+	Methods     map[string]MethodDescriptor   `json:"methods"`
+}
+
+// State 发送给服务器的设备状态，对应 SendIoTState 的格式
+type State struct {
+	Name  string                 `json:"name"`
+	State map[string]interface{} `json:"state"`
+}
+
+// Registry 设备注册表
+type Registry struct {
+	mu      sync.Mutex
+	devices []*Device
+}
+
+// NewRegistry 创建设备注册表
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register 注册设备，设备名称不能重复
+func (r *Registry) Register(device *Device) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, d := range r.devices {
+		if d.Name() == device.Name() {
+			return fmt.Errorf("设备 %s 已注册", device.Name())
+		}
+	}
+	r.devices = append(r.devices, device)
+	return nil
+}
+
+// Device 按名称查找设备
+func (r *Registry) Device(name string) *Device {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, d := range r.devices {
+		if d.Name() == name {
+			return d
+		}
+	}
+	return nil
+}
+
+// Descriptors 生成所有设备的描述符
+func (r *Registry) Descriptors() []Descriptor {
+	devices := r.snapshot()
+	result := make([]Descriptor, 0, len(devices))
+	for _, d := range devices {
+		result = append(result, d.descriptor())
+	}
+	return result
+}
+
+// States 读取所有设备的当前状态
+func (r *Registry) States() []State {
+	devices := r.snapshot()
+	result := make([]State, 0, len(devices))
+	for _, d := range devices {
+		result = append(result, d.state())
+	}
+	return result
+}
+
+// Invoke 将IoT命令分发给对应设备的方法
+func (r *Registry) Invoke(cmd protocol.IoTCommand) error {
+	device := r.Device(cmd.Name)
+	if device == nil {
+		return fmt.Errorf("未注册的设备: %s", cmd.Name)
+	}
+	return device.invoke(cmd.Method, cmd.Parameters)
+}
+
+// snapshot 复制设备列表，避免在持锁时调用属性getter
+func (r *Registry) snapshot() []*Device {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	devices := make([]*Device, len(r.devices))
+	copy(devices, r.devices)
+	return devices
+}
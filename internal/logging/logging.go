@@ -0,0 +1,17 @@
+// Package logging 定义各库包共用的日志接口，嵌入方可据此接入自己的日志系统
+package logging
+
+import "github.com/sirupsen/logrus"
+
+// Logger 库内部使用的日志接口
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// Default 返回默认的日志实现，即logrus的全局Logger
+func Default() Logger {
+	return logrus.StandardLogger()
+}
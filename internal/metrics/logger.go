@@ -0,0 +1,16 @@
+//go:build prometheus
+
+package metrics
+
+import "github.com/justa-cai/xiaozhi-go/internal/logging"
+
+// logger 本包使用的日志实现
+var logger = logging.Default()
+
+// SetLogger 设置本包的日志实现，传入nil时恢复默认的logrus；应在使用本包之前调用
+func SetLogger(l logging.Logger) {
+	if l == nil {
+		l = logging.Default()
+	}
+	logger = l
+}
@@ -0,0 +1,113 @@
+//go:build prometheus
+
+// Package metrics 以Prometheus格式导出客户端的连接和音频统计。
+// 本包只在 -tags prometheus 构建时编译，不使用该标签时程序不依赖Prometheus客户端库
+package metrics
+
+import (
+	"github.com/justa-cai/xiaozhi-go/internal/audio"
+	"github.com/justa-cai/xiaozhi-go/internal/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Namespace 指标名称的前缀
+const Namespace = "xiaozhi"
+
+// Source 统计的来源，*client.Client 实现了该接口
+type Source interface {
+	Stats() protocol.ProtocolStats
+	AudioMetrics() audio.AudioMetrics
+}
+
+// snapshot 一次抓取时读取的统计快照
+type snapshot struct {
+	conn  protocol.ProtocolStats
+	audio audio.AudioMetrics
+}
+
+// metric 一个指标的描述和取值方法
+type metric struct {
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	value     func(s *snapshot) float64
+}
+
+// Collector 在每次抓取时读取 Source 的统计快照并转换为指标。
+// 快照中的计数自协议实例或音频管理器创建起累计，跨重连不清零，直接作为counter导出
+type Collector struct {
+	source  Source
+	metrics []metric
+}
+
+// NewCollector 创建读取source统计的Collector，labels为附加到所有指标上的固定标签，可为nil
+func NewCollector(source Source, labels prometheus.Labels) *Collector {
+	c := &Collector{source: source}
+	counter := func(subsystem, name, help string, value func(s *snapshot) float64) {
+		c.add(subsystem, name, help, prometheus.CounterValue, labels, value)
+	}
+	gauge := func(subsystem, name, help string, value func(s *snapshot) float64) {
+		c.add(subsystem, name, help, prometheus.GaugeValue, labels, value)
+	}
+
+	// 连接
+	counter("protocol", "sent_bytes_total", "已发送的消息负载字节数", func(s *snapshot) float64 { return float64(s.conn.BytesSent) })
+	counter("protocol", "received_bytes_total", "已接收的消息负载字节数", func(s *snapshot) float64 { return float64(s.conn.BytesReceived) })
+	counter("protocol", "sent_frames_total", "已发送的消息帧数", func(s *snapshot) float64 { return float64(s.conn.FramesSent) })
+	counter("protocol", "received_frames_total", "已接收的消息帧数", func(s *snapshot) float64 { return float64(s.conn.FramesReceived) })
+	counter("protocol", "reconnects_total", "首次连接之后再次建立连接的次数", func(s *snapshot) float64 { return float64(s.conn.Reconnects) })
+	gauge("protocol", "rtt_seconds", "最近一次ping/pong测得的往返时延，尚未测量时为0", func(s *snapshot) float64 { return s.conn.RTT.Seconds() })
+
+	// 录音
+	counter("audio", "captured_frames_total", "录音器送来的帧数", func(s *snapshot) float64 { return float64(s.audio.Capture.FramesCaptured) })
+	counter("audio", "encoded_frames_total", "编码成功的帧数", func(s *snapshot) float64 { return float64(s.audio.Capture.FramesEncoded) })
+	counter("audio", "encode_errors_total", "编码失败的帧数", func(s *snapshot) float64 { return float64(s.audio.Capture.EncodeErrors) })
+	counter("audio", "uploaded_frames_total", "交给上传的音频数据包数", func(s *snapshot) float64 { return float64(s.audio.Capture.FramesSent) })
+	counter("audio", "suppressed_frames_total", "静音抑制丢弃的帧数", func(s *snapshot) float64 { return float64(s.audio.Capture.FramesSuppressed) })
+	counter("audio", "capture_overflows_total", "录音缓冲区已满时丢弃的帧数", func(s *snapshot) float64 { return float64(s.audio.Capture.Device.Overflows) })
+	counter("audio", "capture_underflows_total", "录音中长时间没有从设备读到数据的次数", func(s *snapshot) float64 { return float64(s.audio.Capture.Device.Underflows) })
+	gauge("audio", "recording", "是否正在录音", func(s *snapshot) float64 { return boolValue(s.audio.Recording) })
+
+	// 播放
+	counter("audio", "playback_received_frames_total", "送入播放器的Opus数据包数", func(s *snapshot) float64 { return float64(s.audio.Playback.FramesReceived) })
+	counter("audio", "decoded_frames_total", "解码成功的数据包数", func(s *snapshot) float64 { return float64(s.audio.Playback.FramesDecoded) })
+	counter("audio", "decode_errors_total", "解码失败的数据包数", func(s *snapshot) float64 { return float64(s.audio.Playback.DecodeErrors) })
+	counter("audio", "concealed_frames_total", "丢包补偿生成的帧数", func(s *snapshot) float64 { return float64(s.audio.Playback.FramesConcealed) })
+	counter("audio", "played_frames_total", "从播放队列取出播放的帧数", func(s *snapshot) float64 { return float64(s.audio.Playback.FramesPlayed) })
+	counter("audio", "dropped_frames_total", "因播放队列已满丢弃的帧数", func(s *snapshot) float64 { return float64(s.audio.Playback.FramesDropped) })
+	gauge("audio", "playback_queue_length", "当前播放队列长度", func(s *snapshot) float64 { return float64(s.audio.Playback.QueueLength) })
+	gauge("audio", "jitter_buffer_depth_seconds", "抖动缓冲中排队等待播放的音频时长", func(s *snapshot) float64 { return s.audio.Playback.JitterBuffer.Depth.Seconds() })
+	counter("audio", "jitter_buffer_underruns_total", "抖动缓冲播放中途播空的次数", func(s *snapshot) float64 { return float64(s.audio.Playback.JitterBuffer.Underruns) })
+	counter("audio", "jitter_buffer_overruns_total", "抖动缓冲超过上限丢弃旧帧的次数", func(s *snapshot) float64 { return float64(s.audio.Playback.JitterBuffer.Overruns) })
+	gauge("audio", "playing", "播放器是否已启动", func(s *snapshot) float64 { return boolValue(s.audio.Playing) })
+	return c
+}
+
+func (c *Collector) add(subsystem, name, help string, valueType prometheus.ValueType, labels prometheus.Labels, value func(s *snapshot) float64) {
+	c.metrics = append(c.metrics, metric{
+		desc:      prometheus.NewDesc(prometheus.BuildFQName(Namespace, subsystem, name), help, nil, labels),
+		valueType: valueType,
+		value:     value,
+	})
+}
+
+// Describe 实现 prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range c.metrics {
+		ch <- m.desc
+	}
+}
+
+// Collect 实现 prometheus.Collector，每次抓取读取一次统计快照
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	s := &snapshot{conn: c.source.Stats(), audio: c.source.AudioMetrics()}
+	for _, m := range c.metrics {
+		ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, m.value(s))
+	}
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,41 @@
+//go:build prometheus
+
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Serve 在addr上启动HTTP服务，以 /metrics 导出gatherer中的指标，gatherer为nil时使用 prometheus.DefaultGatherer。
+// 监听成功后在后台提供服务，返回的服务器的Addr为实际监听地址，可通过 Shutdown 关闭
+func Serve(addr string, gatherer prometheus.Gatherer) (*http.Server, error) {
+	if gatherer == nil {
+		gatherer = prometheus.DefaultGatherer
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("监听指标地址失败: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))
+	server := &http.Server{
+		Addr:              listener.Addr().String(),
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Errorf("指标服务异常退出: %v", err)
+		}
+	}()
+
+	logger.Infof("指标服务已启动: http://%s/metrics", server.Addr)
+	return server, nil
+}
@@ -0,0 +1,27 @@
+package ota
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrActivationPending 设备尚未激活，需要用户在控制台输入激活码
+var ErrActivationPending = errors.New("设备尚未激活")
+
+// ErrNetwork 网络错误（连接失败、超时等），重试后仍失败时通过 errors.Is 判断
+var ErrNetwork = errors.New("网络错误")
+
+// ErrServerError 服务器返回非成功状态码
+type ErrServerError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *ErrServerError) Error() string {
+	return fmt.Sprintf("服务器返回错误状态码: %d, 响应: %s", e.StatusCode, e.Body)
+}
+
+// Temporary 5xx错误可以重试
+func (e *ErrServerError) Temporary() bool {
+	return e.StatusCode >= 500
+}
@@ -0,0 +1,158 @@
+package ota
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrFirmwareChecksum 下载的固件SHA256与服务器下发的不一致
+var ErrFirmwareChecksum = errors.New("固件校验失败")
+
+// firmwareCopyBufferSize 下载固件时的读缓冲区大小
+const firmwareCopyBufferSize = 32 * 1024
+
+// resumableFile 支持断点续传的下载目标，*os.File 满足该接口
+type resumableFile interface {
+	io.ReadWriteSeeker
+	Truncate(size int64) error
+}
+
+// DownloadFirmware 下载服务器下发的新固件并写入dest，progress可为nil
+// 服务器下发了SHA256时会校验下载内容；dest为可读写的文件且已有内容时，通过Range请求从已有长度处续传
+func (c *OTAClient) DownloadFirmware(ctx context.Context, dest io.Writer, progress func(done, total int64)) error {
+	resp, err := c.RequestActivationContext(ctx)
+	if err != nil {
+		return fmt.Errorf("获取固件信息失败: %w", err)
+	}
+	if resp.Firmware.URL == "" {
+		return errors.New("服务器未下发固件下载地址")
+	}
+
+	return c.downloadFirmware(ctx, resp.Firmware, dest, progress)
+}
+
+// downloadFirmware 按固件信息下载固件
+func (c *OTAClient) downloadFirmware(ctx context.Context, firmware FirmwareInfo, dest io.Writer, progress func(done, total int64)) error {
+	hash := sha256.New()
+
+	// 已有部分内容时计入校验并尝试续传
+	var offset int64
+	file, resumable := dest.(resumableFile)
+	if resumable {
+		size, err := file.Seek(0, io.SeekEnd)
+		if err != nil {
+			return fmt.Errorf("定位固件文件失败: %v", err)
+		}
+		if size > 0 {
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				return fmt.Errorf("定位固件文件失败: %v", err)
+			}
+			if _, err := io.CopyN(hash, file, size); err != nil {
+				return fmt.Errorf("读取已下载的固件失败: %v", err)
+			}
+			offset = size
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", firmware.URL, nil)
+	if err != nil {
+		return fmt.Errorf("创建固件下载请求失败: %v", err)
+	}
+	req.Header.Set("User-Agent", "XiaoZhi-go/1.0")
+	req.Header.Set("Device-Id", c.DeviceInfo.MACAddress)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	// 固件较大，不使用请求超时，由ctx控制下载时长
+	httpClient := *c.HTTPClient
+	httpClient.Timeout = 0
+
+	httpResp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: 下载固件失败: %v", ErrNetwork, err)
+	}
+	defer httpResp.Body.Close()
+
+	switch httpResp.StatusCode {
+	case http.StatusPartialContent:
+		logger.Infof("从%d字节处继续下载固件", offset)
+	case http.StatusOK:
+		// 服务器不支持续传，从头开始
+		if offset > 0 {
+			logger.Infof("服务器不支持断点续传，重新下载固件")
+			if err := file.Truncate(0); err != nil {
+				return fmt.Errorf("清空固件文件失败: %v", err)
+			}
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				return fmt.Errorf("定位固件文件失败: %v", err)
+			}
+			hash.Reset()
+			offset = 0
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// 已下载完整，直接校验
+		if offset == 0 {
+			return &ErrServerError{StatusCode: httpResp.StatusCode}
+		}
+		return verifyFirmware(firmware, hash.Sum(nil))
+	default:
+		body, _ := io.ReadAll(io.LimitReader(httpResp.Body, 1024))
+		return &ErrServerError{StatusCode: httpResp.StatusCode, Body: string(body)}
+	}
+
+	total := int64(-1)
+	if httpResp.ContentLength >= 0 {
+		total = offset + httpResp.ContentLength
+	}
+	done := offset
+	if progress != nil {
+		progress(done, total)
+	}
+
+	buf := make([]byte, firmwareCopyBufferSize)
+	writer := io.MultiWriter(dest, hash)
+	for {
+		n, readErr := httpResp.Body.Read(buf)
+		if n > 0 {
+			if _, err := writer.Write(buf[:n]); err != nil {
+				return fmt.Errorf("写入固件失败: %v", err)
+			}
+			done += int64(n)
+			if progress != nil {
+				progress(done, total)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("%w: 下载固件中断: %v", ErrNetwork, readErr)
+		}
+	}
+
+	if total >= 0 && done != total {
+		return fmt.Errorf("%w: 固件下载不完整: %d/%d字节", ErrNetwork, done, total)
+	}
+	logger.Infof("固件下载完成，共%d字节", done)
+	return verifyFirmware(firmware, hash.Sum(nil))
+}
+
+// verifyFirmware 服务器下发了SHA256时校验固件摘要
+func verifyFirmware(firmware FirmwareInfo, sum []byte) error {
+	if firmware.SHA256 == "" {
+		return nil
+	}
+	actual := hex.EncodeToString(sum)
+	if !strings.EqualFold(actual, firmware.SHA256) {
+		return fmt.Errorf("%w: 期望%s，实际%s", ErrFirmwareChecksum, firmware.SHA256, actual)
+	}
+	logger.Infof("固件SHA256校验通过")
+	return nil
+}
@@ -2,14 +2,20 @@ package ota
 
 import (
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"runtime"
+	"strings"
 	"time"
 
-	"github.com/sirupsen/logrus"
+	"github.com/justa-cai/xiaozhi-go/internal/protocol"
 )
 
 const (
@@ -18,6 +24,27 @@ const (
 
 	// 超时设置
 	DefaultTimeout = 10 * time.Second
+
+	// DefaultMaxRetries 网络错误或5xx时的默认重试次数
+	DefaultMaxRetries = 3
+
+	// DefaultRetryBackoff 首次重试前的等待时间，之后每次翻倍
+	DefaultRetryBackoff = time.Second
+
+	// DefaultActivationTimeout 服务器未下发超时时间时，等待激活确认的默认时长
+	DefaultActivationTimeout = 5 * time.Minute
+
+	// activationPollInterval 激活未确认时重新提交签名的间隔
+	activationPollInterval = 3 * time.Second
+
+	// DefaultActivationPollInterval 等待用户输入激活码时的默认轮询间隔
+	DefaultActivationPollInterval = 5 * time.Second
+
+	// maxActivationBackoff 请求失败时轮询间隔退避的上限
+	maxActivationBackoff = time.Minute
+
+	// activationAlgorithm 激活挑战使用的签名算法
+	activationAlgorithm = "hmac-sha256"
 )
 
 // ChipInfo 芯片信息结构
@@ -63,25 +90,59 @@ type DeviceInfo struct {
 type MQTTConfig struct {
 	Endpoint       string `json:"endpoint"`
 	ClientID       string `json:"client_id"`
+	Username       string `json:"username"`
+	Password       string `json:"password"`
 	PublishTopic   string `json:"publish_topic"`
 	SubscribeTopic string `json:"subscribe_topic"`
 }
 
+// ProtocolOptions 转换为创建 protocol.MQTTProtocol 所需的参数
+func (c MQTTConfig) ProtocolOptions() protocol.MQTTOptions {
+	return protocol.MQTTOptions{
+		Endpoint:       c.Endpoint,
+		ClientID:       c.ClientID,
+		Username:       c.Username,
+		Password:       c.Password,
+		PublishTopic:   c.PublishTopic,
+		SubscribeTopic: c.SubscribeTopic,
+	}
+}
+
+// WebsocketConfig WebSocket配置结构
+type WebsocketConfig struct {
+	URL   string `json:"url"`
+	Token string `json:"token"`
+}
+
 // FirmwareInfo 固件信息结构
 type FirmwareInfo struct {
 	Version string `json:"version"`
+	URL     string `json:"url,omitempty"`
+	SHA256  string `json:"sha256,omitempty"`
 }
 
 // ActivationInfo 激活信息结构
 type ActivationInfo struct {
-	Code string `json:"code"`
+	Code      string `json:"code"`
+	Message   string `json:"message,omitempty"`
+	Challenge string `json:"challenge,omitempty"`
+	TimeoutMs int    `json:"timeout_ms,omitempty"`
+}
+
+// ActivationRequest 激活挑战应答，提交到激活接口
+type ActivationRequest struct {
+	Algorithm    string `json:"algorithm"`
+	SerialNumber string `json:"serial_number"`
+	Challenge    string `json:"challenge"`
+	HMAC         string `json:"hmac"`
 }
 
 // OTAResponse OTA响应结构
 type OTAResponse struct {
-	MQTT       MQTTConfig     `json:"mqtt"`
-	Firmware   FirmwareInfo   `json:"firmware"`
-	Activation ActivationInfo `json:"activation"`
+	MQTT       MQTTConfig      `json:"mqtt"`
+	Websocket  WebsocketConfig `json:"websocket"`
+	Firmware   FirmwareInfo    `json:"firmware"`
+	Activation ActivationInfo  `json:"activation"`
 }
 
 // OTAClient OTA客户端结构
@@ -89,19 +150,34 @@ type OTAClient struct {
 	Endpoint   string
 	HTTPClient *http.Client
 	DeviceInfo DeviceInfo
+
+	// 网络错误或5xx时的重试次数及首次重试等待时间（之后每次翻倍），为0时不重试
+	MaxRetries   int
+	RetryBackoff time.Duration
+
+	// 激活挑战签名使用的设备密钥
+	hmacKey []byte
 }
 
-// NewOTAClient 创建新的OTA客户端
-func NewOTAClient(deviceMAC, appVersion, boardType string) *OTAClient {
-	// 创建HTTP客户端
-	httpClient := &http.Client{
-		Timeout: DefaultTimeout,
-	}
+// OTAClientOptions OTA客户端选项，未设置的字段使用默认值
+type OTAClientOptions struct {
+	Endpoint     string        // OTA服务器地址，为空时使用 DefaultOTAEndpoint
+	Timeout      time.Duration // HTTP请求超时，为0时使用 DefaultTimeout
+	MaxRetries   int           // 网络错误或5xx时的重试次数，为0时使用 DefaultMaxRetries，小于0时不重试
+	RetryBackoff time.Duration // 首次重试前的等待时间，为0时使用 DefaultRetryBackoff
+	DeviceMAC    string        // 设备MAC地址
+	AppVersion   string        // 应用版本号
+	BoardType    string        // 设备板型号
+	// DeviceInfo 完整的设备信息，非nil时原样使用，忽略上面的设备字段且不探测本机资源
+	DeviceInfo *DeviceInfo
+}
 
-	// 初始化设备信息
+// DefaultDeviceInfo 返回默认的设备信息，内存和存储大小使用本机探测值（探测失败时使用固定默认值）
+// 可在此基础上修改后通过 OTAClientOptions.DeviceInfo 传入
+func DefaultDeviceInfo(deviceMAC, appVersion, boardType string) DeviceInfo {
 	deviceInfo := DeviceInfo{
-		FlashSize:           16777216, // 16MB
-		MinimumFreeHeapSize: 8318916,  // 8MB
+		FlashSize:           defaultFlashSize,
+		MinimumFreeHeapSize: defaultMinimumFreeHeapSize,
 		MACAddress:          deviceMAC,
 		ChipModelName:       "generic",
 		ChipInfo: ChipInfo{
@@ -125,15 +201,108 @@ func NewOTAClient(deviceMAC, appVersion, boardType string) *OTAClient {
 		},
 	}
 
+	// 尽量使用本机真实资源
+	if res, err := ProbeSystemResources(); err != nil {
+		logger.Debugf("探测设备资源失败，使用默认值: %v", err)
+	} else {
+		res.ApplyTo(&deviceInfo)
+	}
+
+	return deviceInfo
+}
+
+// NewOTAClient 创建新的OTA客户端
+func NewOTAClient(deviceMAC, appVersion, boardType string) *OTAClient {
+	return NewOTAClientWithOptions(OTAClientOptions{
+		DeviceMAC:  deviceMAC,
+		AppVersion: appVersion,
+		BoardType:  boardType,
+	})
+}
+
+// NewOTAClientWithOptions 使用指定选项创建OTA客户端
+func NewOTAClientWithOptions(options OTAClientOptions) *OTAClient {
+	if options.Endpoint == "" {
+		options.Endpoint = DefaultOTAEndpoint
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultTimeout
+	}
+	if options.MaxRetries == 0 {
+		options.MaxRetries = DefaultMaxRetries
+	} else if options.MaxRetries < 0 {
+		options.MaxRetries = 0
+	}
+	if options.RetryBackoff <= 0 {
+		options.RetryBackoff = DefaultRetryBackoff
+	}
+
+	var deviceInfo DeviceInfo
+	if options.DeviceInfo != nil {
+		deviceInfo = *options.DeviceInfo
+	} else {
+		deviceInfo = DefaultDeviceInfo(options.DeviceMAC, options.AppVersion, options.BoardType)
+	}
+
 	return &OTAClient{
-		Endpoint:   DefaultOTAEndpoint,
-		HTTPClient: httpClient,
-		DeviceInfo: deviceInfo,
+		Endpoint: options.Endpoint,
+		HTTPClient: &http.Client{
+			Timeout: options.Timeout,
+		},
+		DeviceInfo:   deviceInfo,
+		MaxRetries:   options.MaxRetries,
+		RetryBackoff: options.RetryBackoff,
 	}
 }
 
+// SetEndpoint 设置OTA服务器地址
+func (c *OTAClient) SetEndpoint(endpoint string) {
+	c.Endpoint = endpoint
+}
+
+// SetHTTPClient 设置发送请求使用的HTTP客户端，可用于测试或自定义传输层
+func (c *OTAClient) SetHTTPClient(client *http.Client) {
+	c.HTTPClient = client
+}
+
+// SetHMACKey 设置激活挑战签名使用的设备密钥
+func (c *OTAClient) SetHMACKey(key []byte) {
+	c.hmacKey = append([]byte(nil), key...)
+}
+
+// ActivationEndpoint 返回激活接口地址，即OTA地址下的 activate
+func (c *OTAClient) ActivationEndpoint() string {
+	return strings.TrimSuffix(c.Endpoint, "/") + "/activate"
+}
+
+// setHeaders 设置OTA请求的公共请求头
+func (c *OTAClient) setHeaders(req *http.Request) {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Device-Id", c.DeviceInfo.MACAddress)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "XiaoZhi-go/1.0")
+	req.Header.Set("App-Version", c.DeviceInfo.Application.Version)
+	req.Header.Set("Chip-Model", c.DeviceInfo.ChipModelName)
+	req.Header.Set("Board-Type", c.DeviceInfo.Board.Type)
+}
+
+// SignChallenge 使用设备密钥计算激活挑战的HMAC-SHA256签名（十六进制）
+func (c *OTAClient) SignChallenge(challenge string) (string, error) {
+	if len(c.hmacKey) == 0 {
+		return "", errors.New("未设置HMAC密钥")
+	}
+	mac := hmac.New(sha256.New, c.hmacKey)
+	mac.Write([]byte(challenge))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
 // RequestActivation 向服务器请求设备激活码
 func (c *OTAClient) RequestActivation() (*OTAResponse, error) {
+	return c.RequestActivationContext(context.Background())
+}
+
+// RequestActivationContext 与 RequestActivation 相同，但可通过ctx取消请求
+func (c *OTAClient) RequestActivationContext(ctx context.Context) (*OTAResponse, error) {
 	// 将设备信息编码为JSON
 	jsonData, err := json.Marshal(c.DeviceInfo)
 	if err != nil {
@@ -141,72 +310,108 @@ func (c *OTAClient) RequestActivation() (*OTAResponse, error) {
 	}
 
 	// 打印发送报文
-	logrus.Debugf("发送请求体: %s", string(jsonData))
+	logger.Debugf("发送请求体: %s", string(jsonData))
+
+	// 发送请求，网络错误和5xx时自动重试
+	status, body, err := c.post(ctx, c.Endpoint, jsonData)
+	if err != nil {
+		return nil, err
+	}
+
+	// 检查响应状态码
+	if status != http.StatusOK {
+		return nil, &ErrServerError{StatusCode: status, Body: string(body)}
+	}
+
+	// 解析响应JSON
+	var otaResp OTAResponse
+	if err := json.Unmarshal(body, &otaResp); err != nil {
+		return nil, fmt.Errorf("解析服务器响应失败: %v", err)
+	}
+
+	if otaResp.Activation.Code == "" {
+		logger.Infof("设备已激活")
+	} else {
+		logger.Infof("获取到设备激活码: %s", otaResp.Activation.Code)
+	}
+	return &otaResp, nil
+}
 
+// post 发送POST请求并读取响应，网络错误和5xx状态码按 MaxRetries 和 RetryBackoff 指数退避重试
+// 返回的错误为包装了 ErrNetwork 的网络错误、*ErrServerError 或ctx错误；4xx等状态码原样返回由调用方处理
+func (c *OTAClient) post(ctx context.Context, url string, payload []byte) (int, []byte, error) {
+	backoff := c.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		status, body, err := c.postOnce(ctx, url, payload)
+		if err == nil && status < 500 {
+			return status, body, nil
+		}
+		if err == nil {
+			err = &ErrServerError{StatusCode: status, Body: string(body)}
+		}
+		if ctx.Err() != nil {
+			return 0, nil, ctx.Err()
+		}
+		if attempt >= c.MaxRetries {
+			return 0, nil, err
+		}
+
+		logger.Warnf("OTA请求失败，%v后进行第%d次重试: %v", backoff, attempt+1, err)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, nil, ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// postOnce 发送一次POST请求并读取响应
+func (c *OTAClient) postOnce(ctx context.Context, url string, payload []byte) (int, []byte, error) {
 	// 创建HTTP请求
-	req, err := http.NewRequest("POST", c.Endpoint, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(payload))
 	if err != nil {
-		return nil, fmt.Errorf("创建HTTP请求失败: %v", err)
+		return 0, nil, fmt.Errorf("创建HTTP请求失败: %v", err)
 	}
 
 	// 设置请求头
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Device-Id", c.DeviceInfo.MACAddress)
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("User-Agent", "XiaoZhi-go/1.0")
-	req.Header.Set("App-Version", c.DeviceInfo.Application.Version)
-	req.Header.Set("Chip-Model", c.DeviceInfo.ChipModelName)
-	req.Header.Set("Board-Type", c.DeviceInfo.Board.Type)
+	c.setHeaders(req)
 
 	// 打印请求头信息
-	logrus.Debugf("请求URL: %s", req.URL.String())
-	logrus.Debugf("请求头信息:")
+	logger.Debugf("请求URL: %s", req.URL.String())
+	logger.Debugf("请求头信息:")
 	for key, values := range req.Header {
 		for _, value := range values {
-			logrus.Debugf("  %s: %s", key, value)
+			logger.Debugf("  %s: %s", key, value)
 		}
 	}
 
 	// 发送请求
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("发送HTTP请求失败: %v", err)
+		return 0, nil, fmt.Errorf("%w: 发送HTTP请求失败: %v", ErrNetwork, err)
 	}
 	defer resp.Body.Close()
 
 	// 读取响应体
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应体失败: %v", err)
+		return 0, nil, fmt.Errorf("%w: 读取响应体失败: %v", ErrNetwork, err)
 	}
 
 	// 打印服务器应答
-	logrus.Debugf("服务器状态码: %d", resp.StatusCode)
-	logrus.Debugf("服务器响应头:")
+	logger.Debugf("服务器状态码: %d", resp.StatusCode)
+	logger.Debugf("服务器响应头:")
 	for key, values := range resp.Header {
 		for _, value := range values {
-			logrus.Debugf("  %s: %s", key, value)
+			logger.Debugf("  %s: %s", key, value)
 		}
 	}
-	logrus.Debugf("服务器响应体: %s", string(body))
-
-	// 检查响应状态码
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("服务器返回错误状态码: %d, 响应: %s", resp.StatusCode, string(body))
-	}
-
-	// 解析响应JSON
-	var otaResp OTAResponse
-	if err := json.Unmarshal(body, &otaResp); err != nil {
-		return nil, fmt.Errorf("解析服务器响应失败: %v", err)
-	}
+	logger.Debugf("服务器响应体: %s", string(body))
 
-	if otaResp.Activation.Code == "" {
-		logrus.Infof("设备已激活")
-	} else {
-		logrus.Infof("获取到设备激活码: %s", otaResp.Activation.Code)
-	}
-	return &otaResp, nil
+	return resp.StatusCode, body, nil
 }
 
 // GetActivationCode 获取设备激活码
@@ -231,11 +436,11 @@ func (c *OTAClient) CheckFirmwareUpdate() (string, bool, error) {
 
 	// 检查版本号是否相同
 	if currentVersion == latestVersion {
-		logrus.Infof("当前固件版本已是最新: %s", currentVersion)
+		logger.Infof("当前固件版本已是最新: %s", currentVersion)
 		return latestVersion, false, nil
 	}
 
-	logrus.Infof("发现新版本固件: %s，当前版本: %s", latestVersion, currentVersion)
+	logger.Infof("发现新版本固件: %s，当前版本: %s", latestVersion, currentVersion)
 	return latestVersion, true, nil
 }
 
@@ -249,14 +454,148 @@ func (c *OTAClient) GetMQTTConfig() (*MQTTConfig, error) {
 	return &resp.MQTT, nil
 }
 
+// GetWebSocketConfig 获取WebSocket服务器地址和访问令牌
+// 服务器未下发地址时返回 protocol.DefaultWebSocketURL，未下发令牌时返回空字符串
+func (c *OTAClient) GetWebSocketConfig() (url, token string, err error) {
+	resp, err := c.RequestActivation()
+	if err != nil {
+		return "", "", err
+	}
+
+	url, token = resp.Websocket.Endpoint()
+	return url, token, nil
+}
+
+// Endpoint 返回WebSocket地址和令牌，地址为空时使用默认地址
+func (w WebsocketConfig) Endpoint() (url, token string) {
+	if w.URL == "" {
+		return protocol.DefaultWebSocketURL, w.Token
+	}
+	return w.URL, w.Token
+}
+
 // CheckActivationStatus 检查设备激活状态
+// 设备尚未激活时返回 ErrActivationPending，其他错误为网络或服务器错误，可通过 errors.Is/errors.As 区分
 func (c *OTAClient) CheckActivationStatus() (bool, error) {
 	// 尝试获取激活信息
 	resp, err := c.RequestActivation()
 	if err != nil {
-		return false, fmt.Errorf("获取激活信息失败: %v", err)
+		return false, fmt.Errorf("获取激活信息失败: %w", err)
 	}
 
 	// 如果激活码为空，则认为已激活
-	return resp.Activation.Code == "", nil
+	if resp.Activation.Code != "" {
+		return false, ErrActivationPending
+	}
+	return true, nil
+}
+
+// WaitForActivation 轮询OTA服务器直到设备激活或ctx结束，成功时返回包含固件和MQTT信息的响应
+// 请求失败时轮询间隔按指数退避，最长不超过1分钟；请求成功后恢复为pollInterval
+func (c *OTAClient) WaitForActivation(ctx context.Context, pollInterval time.Duration) (*OTAResponse, error) {
+	if pollInterval <= 0 {
+		pollInterval = DefaultActivationPollInterval
+	}
+	interval := pollInterval
+
+	for {
+		resp, err := c.RequestActivationContext(ctx)
+		if err == nil && resp.Activation.Code == "" {
+			return resp, nil
+		}
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			interval *= 2
+			if interval > maxActivationBackoff {
+				interval = maxActivationBackoff
+			}
+			logger.Warnf("检查激活状态失败，%v后重试: %v", interval, err)
+		} else {
+			interval = pollInterval
+			logger.Debugf("设备尚未激活，%v后重新检查", interval)
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// CompleteActivation 完成激活挑战：对服务器下发的挑战签名并提交，直到服务器确认激活或超时
+// 返回true表示设备已激活
+func (c *OTAClient) CompleteActivation() (bool, error) {
+	resp, err := c.RequestActivation()
+	if err != nil {
+		return false, fmt.Errorf("获取激活信息失败: %v", err)
+	}
+
+	activation := resp.Activation
+	if activation.Code == "" && activation.Challenge == "" {
+		return true, nil
+	}
+	if activation.Challenge == "" {
+		return false, errors.New("服务器未下发激活挑战")
+	}
+
+	signature, err := c.SignChallenge(activation.Challenge)
+	if err != nil {
+		return false, err
+	}
+
+	payload, err := json.Marshal(ActivationRequest{
+		Algorithm:    activationAlgorithm,
+		SerialNumber: c.DeviceInfo.MACAddress,
+		Challenge:    activation.Challenge,
+		HMAC:         signature,
+	})
+	if err != nil {
+		return false, fmt.Errorf("编码激活请求失败: %v", err)
+	}
+
+	timeout := DefaultActivationTimeout
+	if activation.TimeoutMs > 0 {
+		timeout = time.Duration(activation.TimeoutMs) * time.Millisecond
+	}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		activated, err := c.postActivation(payload)
+		if err != nil {
+			return false, err
+		}
+		if activated {
+			logger.Infof("设备激活成功")
+			return true, nil
+		}
+
+		if time.Now().Add(activationPollInterval).After(deadline) {
+			return false, fmt.Errorf("等待激活确认超时: %v", timeout)
+		}
+		logger.Debugf("激活尚未确认，%v后重试", activationPollInterval)
+		time.Sleep(activationPollInterval)
+	}
+}
+
+// postActivation 提交一次激活签名，200表示已激活，202表示等待用户确认
+func (c *OTAClient) postActivation(payload []byte) (bool, error) {
+	status, body, err := c.post(context.Background(), c.ActivationEndpoint(), payload)
+	if err != nil {
+		return false, fmt.Errorf("发送激活请求失败: %w", err)
+	}
+
+	switch status {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusAccepted:
+		return false, nil
+	default:
+		return false, &ErrServerError{StatusCode: status, Body: string(body)}
+	}
 }
@@ -0,0 +1,238 @@
+package ota
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestOTAClient 创建指向endpoint、不重试、不探测本机资源的OTA客户端
+func newTestOTAClient(endpoint string) *OTAClient {
+	info := DeviceInfo{MACAddress: "aa:bb:cc:dd:ee:ff"}
+	return NewOTAClientWithOptions(OTAClientOptions{
+		Endpoint:   endpoint,
+		MaxRetries: -1,
+		DeviceInfo: &info,
+	})
+}
+
+// newActivationServer 模拟OTA服务器：OTA地址下发激活挑战，/activate 用key校验签名，
+// 签名正确返回200，否则返回403；activations统计收到的激活请求数
+func newActivationServer(t *testing.T, key []byte, challenge string, activations *atomic.Int32) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ota/", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(OTAResponse{
+			Activation: ActivationInfo{Code: "123456", Challenge: challenge, TimeoutMs: 1000},
+		})
+	})
+	mux.HandleFunc("/ota/activate", func(w http.ResponseWriter, r *http.Request) {
+		activations.Add(1)
+		var req ActivationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Algorithm != "hmac-sha256" || req.Challenge != challenge || req.SerialNumber != "aa:bb:cc:dd:ee:ff" {
+			t.Errorf("激活请求字段不正确: %+v", req)
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(req.Challenge))
+		signature, err := hex.DecodeString(req.HMAC)
+		if err != nil || !hmac.Equal(signature, mac.Sum(nil)) {
+			http.Error(w, "签名无效", http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCompleteActivationValidSignature(t *testing.T) {
+	key := []byte("device-secret")
+	var activations atomic.Int32
+	srv := newActivationServer(t, key, "c0ffee", &activations)
+
+	c := newTestOTAClient(srv.URL + "/ota/")
+	c.SetHMACKey(key)
+	activated, err := c.CompleteActivation()
+	if err != nil {
+		t.Fatalf("激活失败: %v", err)
+	}
+	if !activated {
+		t.Error("签名正确时应激活成功")
+	}
+	if n := activations.Load(); n != 1 {
+		t.Errorf("提交了%d次激活请求，期望1次", n)
+	}
+}
+
+func TestCompleteActivationWrongKey(t *testing.T) {
+	var activations atomic.Int32
+	srv := newActivationServer(t, []byte("device-secret"), "c0ffee", &activations)
+
+	c := newTestOTAClient(srv.URL + "/ota/")
+	c.SetHMACKey([]byte("other-secret"))
+	activated, err := c.CompleteActivation()
+	if activated {
+		t.Error("签名错误时不应激活成功")
+	}
+	var serverErr *ErrServerError
+	if !errors.As(err, &serverErr) || serverErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("期望403的 *ErrServerError，实际: %v", err)
+	}
+}
+
+func TestCompleteActivationWithoutKey(t *testing.T) {
+	var activations atomic.Int32
+	srv := newActivationServer(t, []byte("device-secret"), "c0ffee", &activations)
+
+	c := newTestOTAClient(srv.URL + "/ota/")
+	if _, err := c.CompleteActivation(); err == nil {
+		t.Fatal("未设置HMAC密钥时应返回错误")
+	}
+	if n := activations.Load(); n != 0 {
+		t.Errorf("未设置密钥时提交了%d次激活请求", n)
+	}
+}
+
+func TestSignChallenge(t *testing.T) {
+	// RFC 4231 测试用例2
+	c := newTestOTAClient("")
+	c.SetHMACKey([]byte("Jefe"))
+	got, err := c.SignChallenge("what do ya want for nothing?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"; got != want {
+		t.Errorf("签名为 %s，期望 %s", got, want)
+	}
+}
+
+// newStatusServer 依次按statuses回复，超出后重复最后一个状态码；200时下发activation，attempts统计请求数
+func newStatusServer(t *testing.T, activation ActivationInfo, attempts *atomic.Int32, statuses ...int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(attempts.Add(1))
+		status := statuses[min(n, len(statuses))-1]
+		if status != http.StatusOK {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		json.NewEncoder(w).Encode(OTAResponse{Activation: activation})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newRetryOTAClient 创建重试maxRetries次、退避1ms的OTA客户端
+func newRetryOTAClient(endpoint string, maxRetries int) *OTAClient {
+	info := DeviceInfo{MACAddress: "aa:bb:cc:dd:ee:ff"}
+	return NewOTAClientWithOptions(OTAClientOptions{
+		Endpoint:     endpoint,
+		MaxRetries:   maxRetries,
+		RetryBackoff: time.Millisecond,
+		DeviceInfo:   &info,
+	})
+}
+
+func TestRequestActivationRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantAttempts int32
+		wantStatus   int // 期望的 *ErrServerError 状态码，0表示成功
+	}{
+		{"5xx后成功", []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK}, 3, 0},
+		{"持续5xx用完重试", []int{http.StatusInternalServerError}, 3, http.StatusInternalServerError},
+		{"4xx不重试", []int{http.StatusNotFound}, 1, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			srv := newStatusServer(t, ActivationInfo{}, &attempts, tt.statuses...)
+			c := newRetryOTAClient(srv.URL, 2)
+
+			_, err := c.RequestActivation()
+			if n := attempts.Load(); n != tt.wantAttempts {
+				t.Errorf("请求了%d次，期望%d次", n, tt.wantAttempts)
+			}
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("请求失败: %v", err)
+				}
+				return
+			}
+			var serverErr *ErrServerError
+			if !errors.As(err, &serverErr) || serverErr.StatusCode != tt.wantStatus {
+				t.Fatalf("期望状态码%d的 *ErrServerError，实际: %v", tt.wantStatus, err)
+			}
+			if serverErr.Temporary() != (tt.wantStatus >= 500) {
+				t.Errorf("状态码%d的 Temporary() = %v", tt.wantStatus, serverErr.Temporary())
+			}
+		})
+	}
+}
+
+func TestRequestActivationNetworkError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	c := newRetryOTAClient(url, 1)
+	if _, err := c.RequestActivation(); !errors.Is(err, ErrNetwork) {
+		t.Fatalf("期望 ErrNetwork，实际: %v", err)
+	}
+}
+
+func TestRequestActivationContextCancelDuringBackoff(t *testing.T) {
+	var attempts atomic.Int32
+	srv := newStatusServer(t, ActivationInfo{}, &attempts, http.StatusServiceUnavailable)
+	c := newRetryOTAClient(srv.URL, 5)
+	c.RetryBackoff = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.RequestActivationContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("期望 context.DeadlineExceeded，实际: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("ctx结束后%v才返回，期望在退避等待中立即返回", elapsed)
+	}
+	if n := attempts.Load(); n != 1 {
+		t.Errorf("请求了%d次，期望1次", n)
+	}
+}
+
+func TestCheckActivationStatus(t *testing.T) {
+	var attempts atomic.Int32
+	pending := newStatusServer(t, ActivationInfo{Code: "123456"}, &attempts, http.StatusOK)
+	if _, err := newRetryOTAClient(pending.URL, -1).CheckActivationStatus(); !errors.Is(err, ErrActivationPending) {
+		t.Errorf("下发激活码时期望 ErrActivationPending，实际: %v", err)
+	}
+
+	activated := newStatusServer(t, ActivationInfo{}, &attempts, http.StatusOK)
+	ok, err := newRetryOTAClient(activated.URL, -1).CheckActivationStatus()
+	if err != nil || !ok {
+		t.Errorf("未下发激活码时应已激活，实际: %v, %v", ok, err)
+	}
+
+	failing := newStatusServer(t, ActivationInfo{}, &attempts, http.StatusBadGateway)
+	_, err = newRetryOTAClient(failing.URL, -1).CheckActivationStatus()
+	var serverErr *ErrServerError
+	if !errors.As(err, &serverErr) || errors.Is(err, ErrActivationPending) {
+		t.Errorf("服务器错误应可通过 errors.As 区分，实际: %v", err)
+	}
+}
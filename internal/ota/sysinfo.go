@@ -0,0 +1,27 @@
+package ota
+
+// 未能探测到真实值时使用的默认设备资源
+const (
+	defaultFlashSize           = 16777216 // 16MB
+	defaultMinimumFreeHeapSize = 8318916  // 8MB
+)
+
+// SystemResources 探测到的本机资源信息
+type SystemResources struct {
+	// 存储容量（字节），对应设备的Flash大小
+	StorageSize int
+	// 物理内存总量（字节）
+	TotalMemory int
+	// 当前可用内存（字节），对应设备的最小剩余堆大小
+	AvailableMemory int
+}
+
+// ApplyTo 将探测到的非零值写入设备信息，未探测到的字段保持原值
+func (r SystemResources) ApplyTo(info *DeviceInfo) {
+	if r.StorageSize > 0 {
+		info.FlashSize = r.StorageSize
+	}
+	if r.AvailableMemory > 0 {
+		info.MinimumFreeHeapSize = r.AvailableMemory
+	}
+}
@@ -0,0 +1,72 @@
+//go:build linux
+
+package ota
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ProbeSystemResources 从 /proc/meminfo 和根文件系统探测本机资源
+func ProbeSystemResources() (SystemResources, error) {
+	var res SystemResources
+
+	total, available, err := readMeminfo("/proc/meminfo")
+	if err != nil {
+		return res, err
+	}
+	res.TotalMemory = total
+	res.AvailableMemory = available
+
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs("/", &fs); err != nil {
+		return res, fmt.Errorf("获取文件系统信息失败: %v", err)
+	}
+	// 32位平台上int可能溢出，超出范围时取最大值
+	size := uint64(fs.Blocks) * uint64(fs.Bsize)
+	if size > math.MaxInt {
+		size = math.MaxInt
+	}
+	res.StorageSize = int(size)
+
+	return res, nil
+}
+
+// readMeminfo 解析 meminfo 中的 MemTotal 和 MemAvailable（单位kB）
+func readMeminfo(path string) (total, available int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("读取内存信息失败: %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		value, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			total = value * 1024
+		case "MemAvailable:":
+			available = value * 1024
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, fmt.Errorf("读取内存信息失败: %v", err)
+	}
+	if total == 0 {
+		return 0, 0, fmt.Errorf("内存信息中缺少MemTotal")
+	}
+	return total, available, nil
+}
@@ -0,0 +1,85 @@
+//go:build linux
+
+package ota
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProbeSystemResources(t *testing.T) {
+	res, err := ProbeSystemResources()
+	if err != nil {
+		t.Fatalf("探测本机资源失败: %v", err)
+	}
+	if res.TotalMemory <= 0 || res.AvailableMemory <= 0 || res.StorageSize <= 0 {
+		t.Fatalf("探测到的资源存在零值: %+v", res)
+	}
+	if res.AvailableMemory > res.TotalMemory {
+		t.Errorf("可用内存%d大于内存总量%d", res.AvailableMemory, res.TotalMemory)
+	}
+
+	info := DefaultDeviceInfo("aa:bb:cc:dd:ee:ff", "1.0.0", "test")
+	if info.FlashSize != res.StorageSize {
+		t.Errorf("FlashSize 为 %d，期望探测值 %d", info.FlashSize, res.StorageSize)
+	}
+	if info.MinimumFreeHeapSize <= 0 {
+		t.Errorf("MinimumFreeHeapSize 为 %d", info.MinimumFreeHeapSize)
+	}
+}
+
+func TestReadMeminfo(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	total, available, err := readMeminfo(write("meminfo", "MemTotal:        2048 kB\nMemFree:  100 kB\nMemAvailable:    1024 kB\nbad line\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2048*1024 || available != 1024*1024 {
+		t.Errorf("解析结果为 total=%d available=%d", total, available)
+	}
+
+	if _, _, err := readMeminfo(write("nototal", "MemAvailable: 1024 kB\n")); err == nil {
+		t.Error("缺少MemTotal时应返回错误")
+	}
+	if _, _, err := readMeminfo(filepath.Join(dir, "missing")); err == nil {
+		t.Error("文件不存在时应返回错误")
+	}
+}
+
+func TestDeviceInfoOverridesProbedValues(t *testing.T) {
+	received := make(chan DeviceInfo, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var info DeviceInfo
+		if err := json.NewDecoder(r.Body).Decode(&info); err != nil {
+			t.Errorf("解析设备信息失败: %v", err)
+		}
+		received <- info
+		json.NewEncoder(w).Encode(OTAResponse{})
+	}))
+	defer srv.Close()
+
+	info := DefaultDeviceInfo("aa:bb:cc:dd:ee:ff", "1.0.0", "test")
+	info.FlashSize = 4 << 20
+	info.MinimumFreeHeapSize = 300000
+	c := NewOTAClientWithOptions(OTAClientOptions{Endpoint: srv.URL, MaxRetries: -1, DeviceInfo: &info})
+	if _, err := c.RequestActivation(); err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+
+	got := <-received
+	if got.FlashSize != 4<<20 || got.MinimumFreeHeapSize != 300000 {
+		t.Errorf("上报的 FlashSize=%d MinimumFreeHeapSize=%d，期望使用覆盖值", got.FlashSize, got.MinimumFreeHeapSize)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package ota
+
+import "errors"
+
+// ProbeSystemResources 当前平台不支持资源探测
+func ProbeSystemResources() (SystemResources, error) {
+	return SystemResources{}, errors.New("当前平台不支持探测设备资源")
+}
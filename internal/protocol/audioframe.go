@@ -0,0 +1,74 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// AudioFraming 二进制音频消息的封装方式
+type AudioFraming int
+
+const (
+	// AudioFramingPlain 每条二进制消息就是一个裸Opus数据包，与现有服务器兼容（默认）
+	AudioFramingPlain AudioFraming = iota
+	// AudioFramingHeader 每条二进制消息以 AudioFrameHeaderSize 字节的帧头开始，后接Opus数据包
+	AudioFramingHeader
+)
+
+// String 返回封装方式名称
+func (f AudioFraming) String() string {
+	switch f {
+	case AudioFramingPlain:
+		return "plain"
+	case AudioFramingHeader:
+		return "framed"
+	default:
+		return fmt.Sprintf("AudioFraming(%d)", int(f))
+	}
+}
+
+// 带帧头的二进制音频格式，多字节字段均为大端序（网络字节序）：
+//
+//	偏移  长度  字段
+//	0     1     版本，当前为 AudioFrameVersion
+//	1     2     序号，每发送一帧加1，溢出后回到0
+//	3     4     时间戳，发送方自音频通道打开起的毫秒数
+//	7     N     Opus数据包
+//
+// 接收方可根据序号发现丢帧和乱序，根据时间戳对齐收发两端的时间
+const (
+	AudioFrameVersion    = 1
+	AudioFrameHeaderSize = 7
+)
+
+// AudioFrame 一帧二进制音频；plain模式下只有Payload有效
+type AudioFrame struct {
+	Sequence  uint16
+	Timestamp uint32 // 毫秒
+	Payload   []byte
+	Lost      int // 接收方根据序号推算的、本帧之前丢失的帧数，不参与编解码
+}
+
+// EncodeAudioFrame 按带帧头的格式编码一帧音频
+func EncodeAudioFrame(frame AudioFrame) []byte {
+	data := make([]byte, AudioFrameHeaderSize, AudioFrameHeaderSize+len(frame.Payload))
+	data[0] = AudioFrameVersion
+	binary.BigEndian.PutUint16(data[1:3], frame.Sequence)
+	binary.BigEndian.PutUint32(data[3:7], frame.Timestamp)
+	return append(data, frame.Payload...)
+}
+
+// DecodeAudioFrame 解析带帧头的二进制音频，返回的Payload引用data的内存
+func DecodeAudioFrame(data []byte) (AudioFrame, error) {
+	if len(data) < AudioFrameHeaderSize {
+		return AudioFrame{}, fmt.Errorf("音频帧过短: %d字节", len(data))
+	}
+	if data[0] != AudioFrameVersion {
+		return AudioFrame{}, fmt.Errorf("不支持的音频帧版本: %d", data[0])
+	}
+	return AudioFrame{
+		Sequence:  binary.BigEndian.Uint16(data[1:3]),
+		Timestamp: binary.BigEndian.Uint32(data[3:7]),
+		Payload:   data[AudioFrameHeaderSize:],
+	}, nil
+}
@@ -0,0 +1,74 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAudioFrameRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame AudioFrame
+	}{
+		{"首帧", AudioFrame{Sequence: 0, Timestamp: 0, Payload: []byte{0xfc, 0xff, 0xfe}}},
+		{"普通帧", AudioFrame{Sequence: 42, Timestamp: 2520, Payload: bytes.Repeat([]byte{0x5a}, 120)}},
+		{"序号和时间戳最大值", AudioFrame{Sequence: 0xffff, Timestamp: 0xffffffff, Payload: []byte{1}}},
+		{"空数据包", AudioFrame{Sequence: 7, Timestamp: 60}},
+		{"大数据包", AudioFrame{Sequence: 1, Timestamp: 1, Payload: bytes.Repeat([]byte{0, 1, 2, 3}, 4000)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := EncodeAudioFrame(tt.frame)
+			if len(data) != AudioFrameHeaderSize+len(tt.frame.Payload) {
+				t.Fatalf("编码长度为%d，期望%d", len(data), AudioFrameHeaderSize+len(tt.frame.Payload))
+			}
+			got, err := DecodeAudioFrame(data)
+			if err != nil {
+				t.Fatalf("解码失败: %v", err)
+			}
+			if got.Sequence != tt.frame.Sequence || got.Timestamp != tt.frame.Timestamp {
+				t.Errorf("解码得到序号%d时间戳%d，期望序号%d时间戳%d",
+					got.Sequence, got.Timestamp, tt.frame.Sequence, tt.frame.Timestamp)
+			}
+			if !bytes.Equal(got.Payload, tt.frame.Payload) {
+				t.Errorf("解码得到%d字节数据包，与原始的%d字节不一致", len(got.Payload), len(tt.frame.Payload))
+			}
+		})
+	}
+}
+
+func TestEncodeAudioFrameWireFormat(t *testing.T) {
+	data := EncodeAudioFrame(AudioFrame{Sequence: 0x0102, Timestamp: 0x03040506, Payload: []byte{0xaa, 0xbb}})
+	want := []byte{AudioFrameVersion, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0xaa, 0xbb}
+	if !bytes.Equal(data, want) {
+		t.Errorf("编码结果为% x，期望% x", data, want)
+	}
+}
+
+func TestDecodeAudioFrameErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"空消息", nil},
+		{"帧头不完整", []byte{AudioFrameVersion, 0, 1, 0, 0, 0}},
+		{"未知版本", []byte{2, 0, 1, 0, 0, 0, 60, 0xfc}},
+		{"版本为0", []byte{0, 0, 1, 0, 0, 0, 60, 0xfc}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeAudioFrame(tt.data); err == nil {
+				t.Errorf("DecodeAudioFrame(% x) 应返回错误", tt.data)
+			}
+		})
+	}
+
+	// 只有帧头时数据包为空而不是错误
+	frame, err := DecodeAudioFrame([]byte{AudioFrameVersion, 0, 1, 0, 0, 0, 60})
+	if err != nil {
+		t.Fatalf("解码只有帧头的消息失败: %v", err)
+	}
+	if frame.Sequence != 1 || frame.Timestamp != 60 || len(frame.Payload) != 0 {
+		t.Errorf("解码结果为%+v", frame)
+	}
+}
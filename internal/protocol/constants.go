@@ -4,4 +4,29 @@ package protocol
 const (
 	// DefaultWebSocketURL 默认的WebSocket服务器地址
 	DefaultWebSocketURL = "wss://api.tenclass.net/xiaozhi/v1/"
+
+	// ProtocolVersion 客户端使用的协议版本，通过 Protocol-Version 头和hello发送
+	ProtocolVersion = 1
+)
+
+// hello中声明的传输方式
+const (
+	TransportWebSocket = "websocket"
+	TransportMQTT      = "mqtt"
 )
+
+// SupportedProtocolVersions 客户端可兼容的服务器协议版本
+var SupportedProtocolVersions = []int{ProtocolVersion}
+
+// IsSupportedVersion 判断服务器声明的协议版本是否兼容，0表示服务器未声明，视为兼容
+func IsSupportedVersion(version int) bool {
+	if version == 0 {
+		return true
+	}
+	for _, v := range SupportedProtocolVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
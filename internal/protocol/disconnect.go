@@ -0,0 +1,93 @@
+package protocol
+
+import "fmt"
+
+// DisconnectReason 连接断开的原因
+type DisconnectReason int
+
+const (
+	// DisconnectUserInitiated 本端调用 Disconnect、DisconnectGraceful 或 ForceDisconnect 主动断开
+	DisconnectUserInitiated DisconnectReason = iota
+	// DisconnectNetworkError 读写出错、TCP连接中断等网络故障
+	DisconnectNetworkError
+	// DisconnectServerClosed 服务器发送关闭帧结束了连接
+	DisconnectServerClosed
+	// DisconnectReadTimeout 超过读取超时未收到服务器任何数据（包括pong），连接被视为失效
+	DisconnectReadTimeout
+)
+
+// String 返回断开原因的名称
+func (r DisconnectReason) String() string {
+	switch r {
+	case DisconnectUserInitiated:
+		return "user_initiated"
+	case DisconnectNetworkError:
+		return "network_error"
+	case DisconnectServerClosed:
+		return "server_closed"
+	case DisconnectReadTimeout:
+		return "read_timeout"
+	default:
+		return fmt.Sprintf("DisconnectReason(%d)", int(r))
+	}
+}
+
+// ShouldReconnect 返回按该原因断开后是否适合自动重连：本端主动断开时不应重连
+func (r DisconnectReason) ShouldReconnect() bool {
+	return r != DisconnectUserInitiated
+}
+
+// DisconnectReasonNotifier 能报告断开原因的协议实现
+// 带原因的回调在每次断开时都会触发，包括本端主动断开（DisconnectUserInitiated，err为nil）；
+// Protocol.SetOnDisconnected 设置的回调不受影响，仍只在意外断开时触发
+type DisconnectReasonNotifier interface {
+	SetOnDisconnectReason(callback func(reason DisconnectReason, err error))
+}
+
+// ServerCloseError 服务器通过关闭帧结束连接时携带的关闭码和原因，
+// 以 DisconnectServerClosed 断开时可通过 errors.As 从断开回调的err中取出
+type ServerCloseError struct {
+	Code int    // WebSocket关闭码，例如1008表示违反策略
+	Text string // 服务器给出的关闭原因，可能为空
+}
+
+func (e *ServerCloseError) Error() string {
+	if e.Text == "" {
+		return fmt.Sprintf("服务器关闭连接: %d（%s）", e.Code, CloseCodeMeaning(e.Code))
+	}
+	return fmt.Sprintf("服务器关闭连接: %d（%s）: %s", e.Code, CloseCodeMeaning(e.Code), e.Text)
+}
+
+// closeCodeMeanings RFC 6455 及IANA登记的关闭码含义
+var closeCodeMeanings = map[int]string{
+	1000: "正常关闭",
+	1001: "服务器下线或离开",
+	1002: "协议错误",
+	1003: "不支持的数据类型",
+	1005: "未提供关闭码",
+	1006: "连接异常中断",
+	1007: "数据格式无效",
+	1008: "违反策略，常见于鉴权失败",
+	1009: "消息过大",
+	1010: "缺少必需的扩展",
+	1011: "服务器内部错误",
+	1012: "服务器重启",
+	1013: "服务器繁忙，请稍后重试",
+	1014: "网关错误",
+	1015: "TLS握手失败",
+}
+
+// CloseCodeMeaning 返回WebSocket关闭码的中文含义，4000-4999为服务器自定义的关闭码
+func CloseCodeMeaning(code int) string {
+	if meaning, ok := closeCodeMeanings[code]; ok {
+		return meaning
+	}
+	switch {
+	case code >= 4000 && code <= 4999:
+		return "服务器自定义原因，例如令牌无效或过期"
+	case code >= 3000 && code <= 3999:
+		return "框架或库定义的原因"
+	default:
+		return "未知关闭码"
+	}
+}
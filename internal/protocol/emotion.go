@@ -0,0 +1,56 @@
+package protocol
+
+import "strings"
+
+// Emotion 服务器llm消息中的情感类型
+type Emotion string
+
+// 小智服务器下发的已知情感
+const (
+	EmotionUnknown     Emotion = "unknown" // 未知情感，原始字符串需另行保留
+	EmotionNeutral     Emotion = "neutral"
+	EmotionHappy       Emotion = "happy"
+	EmotionLaughing    Emotion = "laughing"
+	EmotionFunny       Emotion = "funny"
+	EmotionSad         Emotion = "sad"
+	EmotionAngry       Emotion = "angry"
+	EmotionCrying      Emotion = "crying"
+	EmotionLoving      Emotion = "loving"
+	EmotionEmbarrassed Emotion = "embarrassed"
+	EmotionSurprised   Emotion = "surprised"
+	EmotionShocked     Emotion = "shocked"
+	EmotionThinking    Emotion = "thinking"
+	EmotionWinking     Emotion = "winking"
+	EmotionCool        Emotion = "cool"
+	EmotionRelaxed     Emotion = "relaxed"
+	EmotionDelicious   Emotion = "delicious"
+	EmotionKissy       Emotion = "kissy"
+	EmotionConfident   Emotion = "confident"
+	EmotionSleepy      Emotion = "sleepy"
+	EmotionSilly       Emotion = "silly"
+	EmotionConfused    Emotion = "confused"
+)
+
+// knownEmotions 已知情感集合
+var knownEmotions = map[Emotion]struct{}{
+	EmotionNeutral: {}, EmotionHappy: {}, EmotionLaughing: {}, EmotionFunny: {},
+	EmotionSad: {}, EmotionAngry: {}, EmotionCrying: {}, EmotionLoving: {},
+	EmotionEmbarrassed: {}, EmotionSurprised: {}, EmotionShocked: {}, EmotionThinking: {},
+	EmotionWinking: {}, EmotionCool: {}, EmotionRelaxed: {}, EmotionDelicious: {},
+	EmotionKissy: {}, EmotionConfident: {}, EmotionSleepy: {}, EmotionSilly: {},
+	EmotionConfused: {},
+}
+
+// ParseEmotion 将服务器下发的情感字符串解析为Emotion，未知值返回 EmotionUnknown
+func ParseEmotion(s string) Emotion {
+	e := Emotion(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := knownEmotions[e]; ok {
+		return e
+	}
+	return EmotionUnknown
+}
+
+// String 返回情感字符串
+func (e Emotion) String() string {
+	return string(e)
+}
@@ -0,0 +1,45 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+)
+
+// IoTCommand 服务器下发的单条IoT命令
+type IoTCommand struct {
+	Name       string                 `json:"name"`       // 目标设备名称
+	Method     string                 `json:"method"`     // 调用的方法
+	Parameters map[string]interface{} `json:"parameters"` // 方法参数，可能为空
+}
+
+// ParseIoTCommands 将iot消息中的commands数组转换为[]IoTCommand
+// 非对象条目或缺少name/method的条目会被跳过，并在返回的错误中说明；其余条目照常返回
+func ParseIoTCommands(commands []interface{}) ([]IoTCommand, error) {
+	result := make([]IoTCommand, 0, len(commands))
+	var errs []error
+
+	for i, raw := range commands {
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			errs = append(errs, fmt.Errorf("第%d条IoT命令不是对象: %T", i, raw))
+			continue
+		}
+
+		name, _ := obj["name"].(string)
+		method, _ := obj["method"].(string)
+		if name == "" || method == "" {
+			errs = append(errs, fmt.Errorf("第%d条IoT命令缺少name或method字段", i))
+			continue
+		}
+
+		cmd := IoTCommand{Name: name, Method: method}
+		if params, ok := obj["parameters"].(map[string]interface{}); ok {
+			cmd.Parameters = params
+		} else {
+			cmd.Parameters = map[string]interface{}{}
+		}
+		result = append(result, cmd)
+	}
+
+	return result, errors.Join(errs...)
+}
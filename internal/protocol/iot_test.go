@@ -0,0 +1,76 @@
+package protocol
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseIoTCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands string
+		want     []IoTCommand
+		wantErr  bool
+	}{
+		{
+			name:     "完整命令",
+			commands: `[{"name":"Speaker","method":"SetVolume","parameters":{"volume":80}}]`,
+			want:     []IoTCommand{{Name: "Speaker", Method: "SetVolume", Parameters: map[string]interface{}{"volume": float64(80)}}},
+		},
+		{
+			name:     "缺少parameters",
+			commands: `[{"name":"Lamp","method":"TurnOn"}]`,
+			want:     []IoTCommand{{Name: "Lamp", Method: "TurnOn", Parameters: map[string]interface{}{}}},
+		},
+		{
+			name:     "parameters不是对象",
+			commands: `[{"name":"Lamp","method":"TurnOn","parameters":[1,2]}]`,
+			want:     []IoTCommand{{Name: "Lamp", Method: "TurnOn", Parameters: map[string]interface{}{}}},
+		},
+		{
+			name:     "多余字段被忽略",
+			commands: `[{"name":"Lamp","method":"TurnOff","parameters":{},"id":7,"extra":{"a":1}}]`,
+			want:     []IoTCommand{{Name: "Lamp", Method: "TurnOff", Parameters: map[string]interface{}{}}},
+		},
+		{
+			name:     "缺少name",
+			commands: `[{"method":"TurnOn"}]`,
+			want:     []IoTCommand{},
+			wantErr:  true,
+		},
+		{
+			name:     "method不是字符串",
+			commands: `[{"name":"Lamp","method":1}]`,
+			want:     []IoTCommand{},
+			wantErr:  true,
+		},
+		{
+			name:     "非对象条目被跳过，其余照常返回",
+			commands: `["TurnOn",42,null,{"name":"Lamp","method":"TurnOn"},[1]]`,
+			want:     []IoTCommand{{Name: "Lamp", Method: "TurnOn", Parameters: map[string]interface{}{}}},
+			wantErr:  true,
+		},
+		{
+			name:     "空数组",
+			commands: `[]`,
+			want:     []IoTCommand{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var commands []interface{}
+			if err := json.Unmarshal([]byte(tt.commands), &commands); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParseIoTCommands(commands)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("错误为 %v，期望出错: %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("解析结果为 %+v，期望 %+v", got, tt.want)
+			}
+		})
+	}
+}
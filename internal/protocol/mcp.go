@@ -0,0 +1,149 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// MCP（Model Context Protocol）消息：服务器通过"mcp"消息调用设备提供的工具，
+// payload为JSON-RPC 2.0格式的请求、通知或响应，客户端的响应同样放在"mcp"消息的payload中发回
+const (
+	JSONRPCVersion = "2.0"
+
+	// 常用的MCP方法
+	MCPMethodInitialize = "initialize"
+	MCPMethodToolsList  = "tools/list"
+	MCPMethodToolsCall  = "tools/call"
+)
+
+// JSON-RPC 2.0 定义的错误码
+const (
+	MCPErrorParse          = -32700 // 无法解析的JSON
+	MCPErrorInvalidRequest = -32600 // 不是合法的请求对象
+	MCPErrorMethodNotFound = -32601 // 方法不存在
+	MCPErrorInvalidParams  = -32602 // 参数无效
+	MCPErrorInternal       = -32603 // 内部错误
+)
+
+// MCPMessage 定义mcp消息，双向使用
+type MCPMessage struct {
+	SessionID string          `json:"session_id,omitempty"` // 会话ID
+	Type      string          `json:"type"`                 // 消息类型，必须为"mcp"
+	Payload   json.RawMessage `json:"payload"`              // JSON-RPC 2.0 负载
+}
+
+// MCPRequest 服务器发来的JSON-RPC请求或通知
+type MCPRequest struct {
+	ID     json.RawMessage // 请求ID，响应时原样带回；为空表示通知，不需要响应
+	Method string          // 方法名，例如 MCPMethodToolsCall
+	Params json.RawMessage // 方法参数，可能为空
+}
+
+// IsNotification 返回是否为通知（没有ID，不需要响应）
+func (r MCPRequest) IsNotification() bool {
+	return len(r.ID) == 0
+}
+
+// BindParams 将参数解析到v中，没有参数时不修改v
+func (r MCPRequest) BindParams(v interface{}) error {
+	if len(r.Params) == 0 || bytes.Equal(r.Params, []byte("null")) {
+		return nil
+	}
+	if err := json.Unmarshal(r.Params, v); err != nil {
+		return &MCPError{Code: MCPErrorInvalidParams, Message: fmt.Sprintf("参数无效: %v", err)}
+	}
+	return nil
+}
+
+// ToolCall 解析tools/call请求的参数，方法不是 MCPMethodToolsCall 或缺少工具名时返回错误
+func (r MCPRequest) ToolCall() (MCPToolCall, error) {
+	if r.Method != MCPMethodToolsCall {
+		return MCPToolCall{}, &MCPError{Code: MCPErrorMethodNotFound, Message: fmt.Sprintf("不是工具调用: %s", r.Method)}
+	}
+	var call MCPToolCall
+	if err := r.BindParams(&call); err != nil {
+		return MCPToolCall{}, err
+	}
+	if call.Name == "" {
+		return MCPToolCall{}, &MCPError{Code: MCPErrorInvalidParams, Message: "工具调用缺少name"}
+	}
+	return call, nil
+}
+
+// MCPToolCall tools/call请求的参数
+type MCPToolCall struct {
+	Name      string                 `json:"name"`      // 工具名称
+	Arguments map[string]interface{} `json:"arguments"` // 工具参数，可能为空
+}
+
+// MCPResponse 对请求的响应，Result编码为JSON-RPC响应的result字段，为nil时编码为空对象
+type MCPResponse struct {
+	Result interface{}
+}
+
+// MCPError JSON-RPC错误对象；处理函数返回该类型的错误时原样发回，其他错误按 MCPErrorInternal 发回
+type MCPError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *MCPError) Error() string {
+	return fmt.Sprintf("MCP错误 %d: %s", e.Code, e.Message)
+}
+
+// mcpResponse JSON-RPC 2.0 响应对象
+type mcpResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *MCPError       `json:"error,omitempty"`
+}
+
+// ParseMCPRequest 解析mcp消息的payload；payload是响应而不是请求时返回错误
+func ParseMCPRequest(payload []byte) (MCPRequest, error) {
+	var envelope struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Method  string          `json:"method"`
+		Params  json.RawMessage `json:"params"`
+	}
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return MCPRequest{}, &MCPError{Code: MCPErrorParse, Message: fmt.Sprintf("解析MCP负载失败: %v", err)}
+	}
+	if bytes.Equal(envelope.ID, []byte("null")) {
+		envelope.ID = nil
+	}
+	request := MCPRequest{ID: envelope.ID, Method: envelope.Method, Params: envelope.Params}
+	if envelope.JSONRPC != JSONRPCVersion {
+		return request, &MCPError{Code: MCPErrorInvalidRequest, Message: fmt.Sprintf("不支持的JSON-RPC版本: %q", envelope.JSONRPC)}
+	}
+	if envelope.Method == "" {
+		return request, &MCPError{Code: MCPErrorInvalidRequest, Message: "MCP请求缺少method"}
+	}
+	return request, nil
+}
+
+// EncodeMCPResponse 编码对id请求的响应负载：err为nil时发送result，否则发送error
+func EncodeMCPResponse(id json.RawMessage, response MCPResponse, err error) (json.RawMessage, error) {
+	envelope := mcpResponse{JSONRPC: JSONRPCVersion, ID: id}
+	if len(id) == 0 {
+		// 无法确定请求ID时按JSON-RPC的约定使用null
+		envelope.ID = json.RawMessage("null")
+	}
+	if err != nil {
+		var mcpErr *MCPError
+		if !errors.As(err, &mcpErr) {
+			mcpErr = &MCPError{Code: MCPErrorInternal, Message: err.Error()}
+		}
+		envelope.Error = mcpErr
+	} else {
+		envelope.Result = response.Result
+		if envelope.Result == nil {
+			envelope.Result = struct{}{}
+		}
+	}
+	return json.Marshal(envelope)
+}
@@ -1,5 +1,10 @@
 package protocol
 
+import (
+	"encoding/json"
+	"strings"
+)
+
 // AudioParams 定义音频参数结构
 type AudioParams struct {
 	Format        string `json:"format"`         // 音频编码格式，例如"opus"
@@ -10,26 +15,38 @@ type AudioParams struct {
 
 // HelloMessage 定义客户端初始hello消息
 type HelloMessage struct {
-	Type        string      `json:"type"`         // 消息类型，必须为"hello"
-	Version     int         `json:"version"`      // 协议版本号
-	Transport   string      `json:"transport"`    // 传输方式，必须为"websocket"
-	AudioParams AudioParams `json:"audio_params"` // 音频参数
+	Type        string          `json:"type"`                 // 消息类型，必须为"hello"
+	Version     int             `json:"version"`              // 协议版本号
+	Transport   string          `json:"transport"`            // 传输方式，TransportWebSocket 或 TransportMQTT
+	AudioParams AudioParams     `json:"audio_params"`         // 音频参数
+	SessionID   string          `json:"session_id,omitempty"` // 可选，请求恢复的会话ID
+	Features    map[string]bool `json:"features,omitempty"`   // 可选，客户端支持的扩展能力
 }
 
 // ServerHelloMessage 定义服务器响应的hello消息
 type ServerHelloMessage struct {
-	Type        string       `json:"type"`                   // 消息类型，必须为"hello"
-	Transport   string       `json:"transport"`              // 传输方式，必须为"websocket"
-	AudioParams *AudioParams `json:"audio_params,omitempty"` // 可选，服务器音频参数
+	Type        string          `json:"type"`                   // 消息类型，必须为"hello"
+	Version     int             `json:"version,omitempty"`      // 可选，服务器使用的协议版本
+	Transport   string          `json:"transport"`              // 传输方式，与客户端hello一致
+	AudioParams *AudioParams    `json:"audio_params,omitempty"` // 可选，服务器音频参数
+	Features    map[string]bool `json:"features,omitempty"`     // 可选，服务器支持的扩展能力
 }
 
+// hello中声明的扩展能力
+const (
+	// FeatureAudioParamsUpdate 服务器支持在会话中重发hello更新音频参数，无需重新连接
+	FeatureAudioParamsUpdate = "audio_params_update"
+	// FeatureMCP 客户端能响应"mcp"消息中的工具调用
+	FeatureMCP = "mcp"
+)
+
 // ListenMessage 定义开始/停止录音的消息
 type ListenMessage struct {
 	SessionID string `json:"session_id"`     // 会话ID
 	Type      string `json:"type"`           // 消息类型，必须为"listen"
-	State     string `json:"state"`          // 状态: "start", "stop", "detect"
+	State     string `json:"state"`          // 状态: "start", "stop", "detect", "transcript"
 	Mode      string `json:"mode"`           // 模式: "auto", "manual", "realtime"
-	Text      string `json:"text,omitempty"` // 可选，当state为"detect"时，包含检测到的唤醒词
+	Text      string `json:"text,omitempty"` // 可选，state为"detect"时为检测到的唤醒词，为"transcript"时为本地识别的最终结果
 }
 
 // AbortMessage 定义终止消息的结构
@@ -39,10 +56,86 @@ type AbortMessage struct {
 	Reason    string `json:"reason"`     // 原因，例如"wake_word_detected"等
 }
 
+// TextMessage 定义文本输入消息，用文字代替语音发起一轮对话，
+// 服务器按语音识别结果处理，随后同样返回stt/llm/tts消息
+type TextMessage struct {
+	SessionID string `json:"session_id"` // 会话ID
+	Type      string `json:"type"`       // 消息类型，必须为"text"
+	Text      string `json:"text"`       // 用户输入的文本
+}
+
 // STTMessage 定义语音识别结果消息
 type STTMessage struct {
-	Type string `json:"type"` // 消息类型，必须为"stt"
-	Text string `json:"text"` // 识别到的文本
+	Type    string `json:"type"`     // 消息类型，必须为"stt"
+	Text    string `json:"text"`     // 识别到的文本
+	IsFinal bool   `json:"is_final"` // 是否为最终结果，false表示识别过程中的中间结果
+}
+
+// UnmarshalJSON 解析STT消息，按 STTResultFlags 兼容各种中间结果标记，服务器未标记时视为最终结果
+func (m *STTMessage) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Type  string `json:"type"`
+		Text  string `json:"text"`
+		State string `json:"state"`
+		STTResultFlags
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	m.Type = raw.Type
+	m.Text = raw.Text
+	m.IsFinal = raw.IsFinalResult(raw.State)
+	return nil
+}
+
+// STTResultFlags 服务器标记识别结果是中间结果还是最终结果的字段，不同服务器的写法不同
+type STTResultFlags struct {
+	IsFinal   FlexBool `json:"is_final"`
+	Final     FlexBool `json:"final"`
+	IsPartial FlexBool `json:"is_partial"`
+	Partial   FlexBool `json:"partial"`
+	Interim   FlexBool `json:"interim"`
+}
+
+// IsFinalResult 返回是否为最终结果，state为stt消息的state字段。
+// is_final/final为false、is_partial/partial/interim为true，或state为"partial"/"interim"时是中间结果；
+// 都没有标记时视为最终结果，兼容只发送最终结果的服务器
+func (f STTResultFlags) IsFinalResult(state string) bool {
+	switch {
+	case f.IsFinal.Set:
+		return f.IsFinal.Value
+	case f.Final.Set:
+		return f.Final.Value
+	case f.IsPartial.Set:
+		return !f.IsPartial.Value
+	case f.Partial.Set:
+		return !f.Partial.Value
+	case f.Interim.Set:
+		return !f.Interim.Value
+	}
+	switch strings.ToLower(state) {
+	case "partial", "interim", "intermediate":
+		return false
+	}
+	return true
+}
+
+// FlexBool 兼容 true/false、"true"/"false"、1/0 等写法的布尔字段
+// 字段缺失、为null或无法识别时Set为false，不会导致整条消息解析失败
+type FlexBool struct {
+	Value bool // 字段的值
+	Set   bool // 消息中是否有可识别的值
+}
+
+func (b *FlexBool) UnmarshalJSON(data []byte) error {
+	*b = FlexBool{}
+	switch strings.ToLower(strings.Trim(string(data), `"`)) {
+	case "true", "1", "yes":
+		*b = FlexBool{Value: true, Set: true}
+	case "false", "0", "no":
+		*b = FlexBool{Value: false, Set: true}
+	}
+	return nil
 }
 
 // TTSMessage 定义文本转语音控制消息
@@ -73,21 +166,139 @@ type IoTStateMessage struct {
 	Descriptors interface{} `json:"descriptors,omitempty"` // 设备描述信息
 }
 
-// MessageType 从JSON数据中提取消息类型
+// MessageType 从JSON数据中提取顶层对象的"type"字段，不是对象或没有该字段时返回空字符串
+// 只做词法扫描而不完整解析：跳过字符串内容和嵌套的对象、数组，因此字符串值或嵌套对象中的"type"不会被误认，
+// 冒号前后允许空白；字段名或值含转义字符时才做一次完整的字符串解码
 func MessageType(data []byte) string {
-	// 简单查找"type"字段，这不是一个完全可靠的JSON解析
-	// 但对于快速判断消息类型足够了
-	for i := 0; i < len(data)-8; i++ {
-		if data[i] == '"' && data[i+1] == 't' && data[i+2] == 'y' && data[i+3] == 'p' &&
-			data[i+4] == 'e' && data[i+5] == '"' && data[i+6] == ':' && data[i+7] == '"' {
-			j := i + 8
-			for j < len(data) && data[j] != '"' {
-				j++
+	i := skipJSONSpace(data, 0)
+	if i >= len(data) || data[i] != '{' {
+		return ""
+	}
+	i++
+
+	for {
+		i = skipJSONSpace(data, i)
+		if i >= len(data) || data[i] != '"' {
+			return ""
+		}
+		keyStart := i + 1
+		keyEnd, escaped := scanJSONString(data, i)
+		if keyEnd < 0 {
+			return ""
+		}
+		isType := string(data[keyStart:keyEnd]) == "type"
+		if escaped {
+			var key string
+			isType = json.Unmarshal(data[keyStart-1:keyEnd+1], &key) == nil && key == "type"
+		}
+
+		i = skipJSONSpace(data, keyEnd+1)
+		if i >= len(data) || data[i] != ':' {
+			return ""
+		}
+		i = skipJSONSpace(data, i+1)
+		if i >= len(data) {
+			return ""
+		}
+
+		if isType {
+			if data[i] != '"' {
+				return ""
+			}
+			end, escaped := scanJSONString(data, i)
+			if end < 0 {
+				return ""
+			}
+			if !escaped {
+				return string(data[i+1 : end])
+			}
+			var value string
+			if err := json.Unmarshal(data[i:end+1], &value); err != nil {
+				return ""
 			}
-			if j < len(data) {
-				return string(data[i+8 : j])
+			return value
+		}
+
+		i = skipJSONValue(data, i)
+		if i < 0 {
+			return ""
+		}
+		i = skipJSONSpace(data, i)
+		if i >= len(data) || data[i] != ',' {
+			// 对象结束或格式错误，都说明没有顶层type字段
+			return ""
+		}
+		i++
+	}
+}
+
+// skipJSONSpace 返回从i开始第一个非空白字符的位置
+func skipJSONSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch data[i] {
+		case ' ', '\t', '\n', '\r':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// scanJSONString 从data[i]处的左引号开始扫描字符串，返回右引号的位置和是否含有转义字符，未结束时返回-1
+func scanJSONString(data []byte, i int) (int, bool) {
+	escaped := false
+	for j := i + 1; j < len(data); j++ {
+		switch data[j] {
+		case '\\':
+			escaped = true
+			j++
+		case '"':
+			return j, escaped
+		}
+	}
+	return -1, escaped
+}
+
+// skipJSONValue 跳过从data[i]开始的一个值，返回值之后的位置，格式错误时返回-1
+func skipJSONValue(data []byte, i int) int {
+	switch data[i] {
+	case '"':
+		end, _ := scanJSONString(data, i)
+		if end < 0 {
+			return -1
+		}
+		return end + 1
+	case '{', '[':
+		depth := 0
+		for i < len(data) {
+			switch data[i] {
+			case '"':
+				end, _ := scanJSONString(data, i)
+				if end < 0 {
+					return -1
+				}
+				i = end
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return i + 1
+				}
+			}
+			i++
+		}
+		return -1
+	default:
+		// 数字、true、false、null：读到分隔符为止
+		for i < len(data) {
+			switch data[i] {
+			case ',', '}', ']', ' ', '\t', '\n', '\r':
+				return i
 			}
+			i++
 		}
+		return i
 	}
-	return ""
 }
@@ -0,0 +1,81 @@
+package protocol
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMessageType(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"普通消息", `{"type":"tts","state":"start"}`, "tts"},
+		{"type不在首位", `{"session_id":"abc","type":"stt","text":"你好"}`, "stt"},
+		{"冒号前后有空白", "{ \"type\" :\t\"hello\" }", "hello"},
+		{"换行缩进", "{\n  \"state\": \"stop\",\n  \"type\":\n    \"tts\"\n}", "tts"},
+		{"值中含转义引号", `{"type":"a\"b"}`, `a"b`},
+		{"值为Unicode转义", `{"type":"\u0074ts"}`, "tts"},
+		{"键为Unicode转义", `{"\u0074ype":"llm"}`, "llm"},
+		{"字符串值中伪造type", `{"text":"type\":\"hack","type":"stt"}`, "stt"},
+		{"字符串值中以转义引号伪造type", `{"text":"\"type\":\"hack\"","type":"stt"}`, "stt"},
+		{"只有伪造的type", `{"text":"\"type\":\"hack\""}`, ""},
+		{"嵌套对象中的type", `{"payload":{"type":"hack"},"type":"iot"}`, "iot"},
+		{"数组中的type", `{"list":[{"type":"hack"},"]"],"type":"iot"}`, "iot"},
+		{"只有嵌套的type", `{"payload":{"type":"hack"}}`, ""},
+		{"数字和布尔值", `{"n":-1.5e3,"ok":true,"x":null,"type":"mcp"}`, "mcp"},
+		{"type为数字", `{"type":1}`, ""},
+		{"type为对象", `{"type":{"type":"hack"}}`, ""},
+		{"相似的键", `{"types":"hack","xtype":"hack","type":"goodbye"}`, "goodbye"},
+		{"顶层为数组", `[{"type":"hack"}]`, ""},
+		{"顶层为字符串", `"\"type\":\"hack\""`, ""},
+		{"空输入", ``, ""},
+		{"空对象", `{}`, ""},
+		{"未结束的字符串", `{"text":"abc`, ""},
+		{"未结束的type值", `{"type":"tts`, ""},
+		{"缺少冒号", `{"type" "tts"}`, ""},
+		{"缺少逗号", `{"a":1 "type":"tts"}`, ""},
+		{"值以反斜杠结尾", `{"a":"\\","type":"tts"}`, "tts"},
+		{"非法转义", `{"type":"\x"}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MessageType([]byte(tt.data)); got != tt.want {
+				t.Errorf("MessageType(%s) = %q，期望%q", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageTypeTruncated(t *testing.T) {
+	// 任意截断位置都不能越界，截断在type值之前时必须返回空字符串
+	data := `{"session_id":"abc","payload":{"type":"x","list":[1,"]",{}]},"type":"tts"}`
+	for n := 0; n < len(data); n++ {
+		got := MessageType([]byte(data[:n]))
+		if got != "" && got != "tts" {
+			t.Errorf("截断到%d字节时返回%q", n, got)
+		}
+	}
+}
+
+func TestMessageTypeAllocations(t *testing.T) {
+	data := []byte(`{"session_id":"abc","payload":{"type":"x"},"type":"tts","state":"start"}`)
+	allocs := testing.AllocsPerRun(100, func() {
+		if MessageType(data) != "tts" {
+			t.Fatal("类型解析错误")
+		}
+	})
+	// 只允许为返回的字符串分配一次
+	if allocs > 1 {
+		t.Errorf("每次调用分配%.0f次，期望不超过1次", allocs)
+	}
+}
+
+func BenchmarkMessageType(b *testing.B) {
+	data := []byte(`{"session_id":"abc","text":"` + strings.Repeat("你好", 64) + `","type":"stt"}`)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		MessageType(data)
+	}
+}
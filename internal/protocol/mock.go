@@ -0,0 +1,284 @@
+package protocol
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// MockProtocol 用于测试的内存协议实现，不进行任何网络通信
+// 发送的消息被记录下来供断言；通过 InjectJSON / InjectBinary / InjectDisconnect 模拟服务器下发的消息和断线
+type MockProtocol struct {
+	mu                 sync.Mutex
+	connected          bool
+	url                string
+	headers            map[string]string
+	sentJSON           [][]byte
+	sentBinary         [][]byte
+	onJSONMessage      func(data []byte)
+	onBinaryMessage    func(data []byte)
+	onDisconnected     func(err error)
+	onDisconnectReason func(reason DisconnectReason, err error)
+	onConnected        func()
+
+	// ConnectError 不为nil时 Connect 返回该错误，用于模拟连接失败
+	ConnectError error
+	// OnSendJSON 每次发送JSON消息后调用，可用于按请求回复，例如收到hello后注入服务器hello
+	OnSendJSON func(data []byte)
+}
+
+// NewMockProtocol 创建一个未连接的测试协议实例
+func NewMockProtocol() *MockProtocol {
+	return &MockProtocol{
+		headers: make(map[string]string),
+	}
+}
+
+// Connect 实现Protocol接口，标记为已连接并触发连接成功回调
+func (mp *MockProtocol) Connect(url string) error {
+	mp.mu.Lock()
+	if mp.ConnectError != nil {
+		err := mp.ConnectError
+		mp.mu.Unlock()
+		return err
+	}
+	mp.connected = true
+	mp.url = url
+	onConnected := mp.onConnected
+	mp.mu.Unlock()
+
+	if onConnected != nil {
+		onConnected()
+	}
+	return nil
+}
+
+// Disconnect 实现Protocol接口，标记为已断开，不触发断开回调；
+// 原本处于连接状态时以 DisconnectUserInitiated 触发带原因的回调
+func (mp *MockProtocol) Disconnect() error {
+	mp.mu.Lock()
+	wasConnected := mp.connected
+	mp.connected = false
+	onDisconnectReason := mp.onDisconnectReason
+	mp.mu.Unlock()
+
+	if wasConnected && onDisconnectReason != nil {
+		onDisconnectReason(DisconnectUserInitiated, nil)
+	}
+	return nil
+}
+
+// SendJSON 实现Protocol接口，记录序列化后的JSON消息
+func (mp *MockProtocol) SendJSON(data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("序列化JSON消息失败: %v", err)
+	}
+
+	mp.mu.Lock()
+	if !mp.connected {
+		mp.mu.Unlock()
+		return errors.New("未连接到服务器")
+	}
+	mp.sentJSON = append(mp.sentJSON, payload)
+	onSendJSON := mp.OnSendJSON
+	mp.mu.Unlock()
+
+	if onSendJSON != nil {
+		onSendJSON(payload)
+	}
+	return nil
+}
+
+// SendBinary 实现Protocol接口，记录二进制数据的副本
+func (mp *MockProtocol) SendBinary(data []byte) error {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	if !mp.connected {
+		return errors.New("未连接到服务器")
+	}
+	mp.sentBinary = append(mp.sentBinary, append([]byte(nil), data...))
+	return nil
+}
+
+// SendJSONContext 实现Protocol接口，ctx已取消时返回其错误
+func (mp *MockProtocol) SendJSONContext(ctx context.Context, data interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return mp.SendJSON(data)
+}
+
+// SendBinaryContext 实现Protocol接口，ctx已取消时返回其错误
+func (mp *MockProtocol) SendBinaryContext(ctx context.Context, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return mp.SendBinary(data)
+}
+
+// SetOnJSONMessage 实现Protocol接口
+func (mp *MockProtocol) SetOnJSONMessage(callback func(data []byte)) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.onJSONMessage = callback
+}
+
+// SetOnBinaryMessage 实现Protocol接口
+func (mp *MockProtocol) SetOnBinaryMessage(callback func(data []byte)) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.onBinaryMessage = callback
+}
+
+// SetOnDisconnected 实现Protocol接口
+func (mp *MockProtocol) SetOnDisconnected(callback func(err error)) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.onDisconnected = callback
+}
+
+// SetOnDisconnectReason 实现DisconnectReasonNotifier接口
+func (mp *MockProtocol) SetOnDisconnectReason(callback func(reason DisconnectReason, err error)) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.onDisconnectReason = callback
+}
+
+// SetOnConnected 实现Protocol接口
+func (mp *MockProtocol) SetOnConnected(callback func()) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.onConnected = callback
+}
+
+// IsConnected 实现Protocol接口
+func (mp *MockProtocol) IsConnected() bool {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	return mp.connected
+}
+
+// SetHeader 实现Protocol接口
+func (mp *MockProtocol) SetHeader(key, value string) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.headers[key] = value
+}
+
+// GetHeaders 实现Protocol接口，返回请求头的副本
+func (mp *MockProtocol) GetHeaders() map[string]string {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	headersCopy := make(map[string]string, len(mp.headers))
+	for k, v := range mp.headers {
+		headersCopy[k] = v
+	}
+	return headersCopy
+}
+
+// URL 返回最近一次 Connect 使用的地址
+func (mp *MockProtocol) URL() string {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	return mp.url
+}
+
+// SentJSON 返回已发送的JSON消息
+func (mp *MockProtocol) SentJSON() [][]byte {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	return append([][]byte(nil), mp.sentJSON...)
+}
+
+// SentJSONOfType 返回已发送的指定type的JSON消息
+func (mp *MockProtocol) SentJSONOfType(messageType string) [][]byte {
+	var matched [][]byte
+	for _, data := range mp.SentJSON() {
+		if MessageType(data) == messageType {
+			matched = append(matched, data)
+		}
+	}
+	return matched
+}
+
+// SentBinary 返回已发送的二进制数据
+func (mp *MockProtocol) SentBinary() [][]byte {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	return append([][]byte(nil), mp.sentBinary...)
+}
+
+// Reset 清空已记录的发送消息
+func (mp *MockProtocol) Reset() {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.sentJSON = nil
+	mp.sentBinary = nil
+}
+
+// InjectJSON 模拟收到服务器的JSON消息，data可以是[]byte、string或任意可序列化的值
+func (mp *MockProtocol) InjectJSON(data interface{}) error {
+	var payload []byte
+	switch v := data.(type) {
+	case []byte:
+		payload = v
+	case string:
+		payload = []byte(v)
+	default:
+		var err error
+		if payload, err = json.Marshal(v); err != nil {
+			return fmt.Errorf("序列化JSON消息失败: %v", err)
+		}
+	}
+
+	mp.mu.Lock()
+	onJSONMessage := mp.onJSONMessage
+	mp.mu.Unlock()
+
+	if onJSONMessage != nil {
+		onJSONMessage(payload)
+	}
+	return nil
+}
+
+// InjectBinary 模拟收到服务器的二进制消息
+func (mp *MockProtocol) InjectBinary(data []byte) {
+	mp.mu.Lock()
+	onBinaryMessage := mp.onBinaryMessage
+	mp.mu.Unlock()
+
+	if onBinaryMessage != nil {
+		onBinaryMessage(data)
+	}
+}
+
+// InjectDisconnect 模拟连接意外断开，标记为未连接并触发断开回调；
+// err不为nil时按 DisconnectNetworkError 报告原因，否则按 DisconnectServerClosed
+func (mp *MockProtocol) InjectDisconnect(err error) {
+	reason := DisconnectServerClosed
+	if err != nil {
+		reason = DisconnectNetworkError
+	}
+	mp.InjectDisconnectReason(reason, err)
+}
+
+// InjectDisconnectReason 以指定原因模拟连接意外断开，依次触发带原因的回调和断开回调
+func (mp *MockProtocol) InjectDisconnectReason(reason DisconnectReason, err error) {
+	mp.mu.Lock()
+	mp.connected = false
+	onDisconnected := mp.onDisconnected
+	onDisconnectReason := mp.onDisconnectReason
+	mp.mu.Unlock()
+
+	if onDisconnectReason != nil {
+		onDisconnectReason(reason, err)
+	}
+	if onDisconnected != nil {
+		onDisconnected(err)
+	}
+}
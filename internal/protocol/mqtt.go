@@ -0,0 +1,352 @@
+package protocol
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// MQTTOptions MQTT连接参数，通常来自OTA激活响应中的mqtt字段
+type MQTTOptions struct {
+	Endpoint       string // 服务器地址，例如 "mqtt.xiaozhi.me:8883" 或 "ssl://host:8883"
+	ClientID       string
+	Username       string
+	Password       string
+	PublishTopic   string // 客户端发送消息的主题
+	SubscribeTopic string // 接收服务器消息的主题
+}
+
+// MQTT连接默认参数
+const (
+	DefaultMQTTPort    = "8883"
+	DefaultMQTTTimeout = 10 * time.Second
+	DefaultMQTTQoS     = 1
+)
+
+// MQTTProtocol 实现了Protocol接口，通过MQTT服务器收发消息
+// JSON和二进制数据都发布到PublishTopic；从SubscribeTopic收到的消息中，
+// 合法的JSON对象交给JSON回调，其余交给二进制回调
+type MQTTProtocol struct {
+	options            MQTTOptions
+	client             mqtt.Client
+	mu                 sync.Mutex
+	connected          bool
+	onJSONMessage      func(data []byte)
+	onBinaryMessage    func(data []byte)
+	onDisconnected     func(err error)
+	onDisconnectReason func(reason DisconnectReason, err error)
+	onConnected        func()
+	headers            map[string]string
+	timeout            time.Duration
+	skipTLSVerify      bool
+}
+
+// NewMQTTProtocol 创建一个新的MQTT协议实例
+func NewMQTTProtocol(options MQTTOptions) *MQTTProtocol {
+	return &MQTTProtocol{
+		options: options,
+		headers: make(map[string]string),
+		timeout: DefaultMQTTTimeout,
+	}
+}
+
+// SetHeader 设置连接参数，支持 Client-Id、Username、Password 三项，其余忽略
+func (mp *MQTTProtocol) SetHeader(key, value string) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.headers[key] = value
+}
+
+// GetHeaders 获取所有设置的连接参数
+func (mp *MQTTProtocol) GetHeaders() map[string]string {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	headersCopy := make(map[string]string)
+	for k, v := range mp.headers {
+		headersCopy[k] = v
+	}
+	return headersCopy
+}
+
+// SetTimeout 设置连接和发布的超时时间
+func (mp *MQTTProtocol) SetTimeout(timeout time.Duration) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.timeout = timeout
+}
+
+// SetSkipTLSVerify 设置是否跳过TLS证书验证
+func (mp *MQTTProtocol) SetSkipTLSVerify(skip bool) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.skipTLSVerify = skip
+}
+
+// brokerURL 将endpoint转换为paho可识别的服务器地址
+func brokerURL(endpoint string) string {
+	if strings.Contains(endpoint, "://") {
+		return endpoint
+	}
+	if !strings.Contains(endpoint, ":") {
+		endpoint += ":" + DefaultMQTTPort
+	}
+	return "ssl://" + endpoint
+}
+
+// Connect 实现Protocol接口，连接MQTT服务器并订阅SubscribeTopic
+// url不为空时覆盖MQTTOptions中的Endpoint
+func (mp *MQTTProtocol) Connect(url string) error {
+	mp.mu.Lock()
+	if mp.connected {
+		mp.mu.Unlock()
+		return errors.New("已经连接到服务器")
+	}
+	if url != "" {
+		mp.options.Endpoint = url
+	}
+	options := mp.options
+	if v := mp.headers["Client-Id"]; v != "" && options.ClientID == "" {
+		options.ClientID = v
+	}
+	if v := mp.headers["Username"]; v != "" {
+		options.Username = v
+	}
+	if v := mp.headers["Password"]; v != "" {
+		options.Password = v
+	}
+	timeout := mp.timeout
+	skipTLSVerify := mp.skipTLSVerify
+	mp.mu.Unlock()
+
+	if options.Endpoint == "" {
+		return errors.New("未配置MQTT服务器地址")
+	}
+	if options.PublishTopic == "" || options.SubscribeTopic == "" {
+		return errors.New("未配置MQTT发布或订阅主题")
+	}
+
+	broker := brokerURL(options.Endpoint)
+	logger.Infof("开始连接MQTT服务器: %s, client_id=%s", broker, options.ClientID)
+
+	clientOptions := mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(options.ClientID).
+		SetUsername(options.Username).
+		SetPassword(options.Password).
+		SetConnectTimeout(timeout).
+		SetAutoReconnect(false).
+		SetTLSConfig(&tls.Config{InsecureSkipVerify: skipTLSVerify}).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			logger.Errorf("MQTT连接断开: %v", err)
+			mp.handleDisconnect(err)
+		})
+
+	client := mqtt.NewClient(clientOptions)
+	token := client.Connect()
+	if !token.WaitTimeout(timeout) {
+		client.Disconnect(0)
+		return errors.New("连接MQTT服务器超时")
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("连接MQTT服务器失败: %v", err)
+	}
+
+	subToken := client.Subscribe(options.SubscribeTopic, DefaultMQTTQoS, mp.handleMessage)
+	if !subToken.WaitTimeout(timeout) {
+		client.Disconnect(0)
+		return errors.New("订阅MQTT主题超时")
+	}
+	if err := subToken.Error(); err != nil {
+		client.Disconnect(0)
+		return fmt.Errorf("订阅MQTT主题失败: %v", err)
+	}
+
+	mp.mu.Lock()
+	mp.client = client
+	mp.connected = true
+	mp.options = options
+	onConnected := mp.onConnected
+	mp.mu.Unlock()
+
+	logger.Infof("MQTT连接成功，已订阅: %s", options.SubscribeTopic)
+	if onConnected != nil {
+		onConnected()
+	}
+	return nil
+}
+
+// Disconnect 实现Protocol接口，断开与MQTT服务器的连接
+func (mp *MQTTProtocol) Disconnect() error {
+	mp.mu.Lock()
+	if !mp.connected || mp.client == nil {
+		mp.mu.Unlock()
+		return nil
+	}
+	mp.connected = false
+	client := mp.client
+	mp.client = nil
+	onDisconnectReason := mp.onDisconnectReason
+	mp.mu.Unlock()
+
+	// 留250ms让正在发送的消息完成
+	client.Disconnect(250)
+	if onDisconnectReason != nil {
+		onDisconnectReason(DisconnectUserInitiated, nil)
+	}
+	return nil
+}
+
+// SendJSON 实现Protocol接口，发布JSON消息
+func (mp *MQTTProtocol) SendJSON(data interface{}) error {
+	return mp.SendJSONContext(context.Background(), data)
+}
+
+// SendBinary 实现Protocol接口，发布二进制数据
+func (mp *MQTTProtocol) SendBinary(data []byte) error {
+	return mp.SendBinaryContext(context.Background(), data)
+}
+
+// SendJSONContext 实现Protocol接口，发布JSON消息，等待确认受ctx控制
+func (mp *MQTTProtocol) SendJSONContext(ctx context.Context, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("序列化JSON消息失败: %v", err)
+	}
+	return mp.publish(ctx, payload)
+}
+
+// SendBinaryContext 实现Protocol接口，发布二进制数据，等待确认受ctx控制
+func (mp *MQTTProtocol) SendBinaryContext(ctx context.Context, data []byte) error {
+	return mp.publish(ctx, data)
+}
+
+// publish 发布消息并等待确认，ctx没有截止时间时使用默认超时
+func (mp *MQTTProtocol) publish(ctx context.Context, payload []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	mp.mu.Lock()
+	if !mp.connected || mp.client == nil {
+		mp.mu.Unlock()
+		return errors.New("未连接到服务器")
+	}
+	client := mp.client
+	topic := mp.options.PublishTopic
+	timeout := mp.timeout
+	mp.mu.Unlock()
+
+	token := client.Publish(topic, DefaultMQTTQoS, false, payload)
+
+	var timer <-chan time.Time
+	if _, ok := ctx.Deadline(); !ok {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		timer = t.C
+	}
+
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer:
+		return errors.New("发布MQTT消息超时")
+	}
+}
+
+// handleMessage 分发订阅主题上收到的消息
+func (mp *MQTTProtocol) handleMessage(_ mqtt.Client, msg mqtt.Message) {
+	payload := msg.Payload()
+
+	mp.mu.Lock()
+	onJSONMessage := mp.onJSONMessage
+	onBinaryMessage := mp.onBinaryMessage
+	mp.mu.Unlock()
+
+	if len(payload) > 0 && payload[0] == '{' && json.Valid(payload) {
+		if onJSONMessage != nil {
+			onJSONMessage(payload)
+		}
+		return
+	}
+	if onBinaryMessage != nil {
+		onBinaryMessage(payload)
+	}
+}
+
+// handleDisconnect 处理连接意外断开，MQTT客户端只报告连接丢失，统一按网络错误处理
+func (mp *MQTTProtocol) handleDisconnect(err error) {
+	mp.mu.Lock()
+	if !mp.connected {
+		mp.mu.Unlock()
+		return
+	}
+	mp.connected = false
+	mp.client = nil
+	onDisconnected := mp.onDisconnected
+	onDisconnectReason := mp.onDisconnectReason
+	mp.mu.Unlock()
+
+	if onDisconnectReason != nil {
+		onDisconnectReason(DisconnectNetworkError, err)
+	}
+	if onDisconnected != nil {
+		onDisconnected(err)
+	}
+}
+
+// SetOnJSONMessage 实现Protocol接口，设置接收JSON消息的回调
+func (mp *MQTTProtocol) SetOnJSONMessage(callback func(data []byte)) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.onJSONMessage = callback
+}
+
+// SetOnBinaryMessage 实现Protocol接口，设置接收二进制消息的回调
+func (mp *MQTTProtocol) SetOnBinaryMessage(callback func(data []byte)) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.onBinaryMessage = callback
+}
+
+// SetOnDisconnected 实现Protocol接口，设置连接断开的回调
+func (mp *MQTTProtocol) SetOnDisconnected(callback func(err error)) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.onDisconnected = callback
+}
+
+// SetOnDisconnectReason 实现DisconnectReasonNotifier接口，设置带断开原因的回调
+func (mp *MQTTProtocol) SetOnDisconnectReason(callback func(reason DisconnectReason, err error)) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.onDisconnectReason = callback
+}
+
+// SetOnConnected 实现Protocol接口，设置连接成功的回调
+func (mp *MQTTProtocol) SetOnConnected(callback func()) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.onConnected = callback
+}
+
+// Transport 实现TransportNamer接口
+func (mp *MQTTProtocol) Transport() string {
+	return TransportMQTT
+}
+
+// IsConnected 实现Protocol接口，返回当前连接状态
+func (mp *MQTTProtocol) IsConnected() bool {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	return mp.connected
+}
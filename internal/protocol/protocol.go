@@ -1,5 +1,10 @@
 package protocol
 
+import (
+	"context"
+	"time"
+)
+
 // Protocol 定义了客户端与服务器通信的基本接口
 type Protocol interface {
 	// Connect 建立与服务器的连接
@@ -14,10 +19,17 @@ type Protocol interface {
 	// SendBinary 发送二进制数据到服务器
 	SendBinary(data []byte) error
 
+	// SendJSONContext 发送JSON消息，写入受ctx的截止时间和取消控制
+	SendJSONContext(ctx context.Context, data interface{}) error
+
+	// SendBinaryContext 发送二进制数据，写入受ctx的截止时间和取消控制
+	SendBinaryContext(ctx context.Context, data []byte) error
+
 	// SetOnJSONMessage 设置接收JSON消息的回调
 	SetOnJSONMessage(callback func(data []byte))
 
 	// SetOnBinaryMessage 设置接收二进制消息的回调
+	// 回调默认可以保留data；实现允许复用读取缓冲区时（例如 WebsocketProtocol.SetReuseReadBuffer），data只在回调返回前有效
 	SetOnBinaryMessage(callback func(data []byte))
 
 	// SetOnDisconnected 设置连接断开的回调
@@ -35,3 +47,21 @@ type Protocol interface {
 	// GetHeaders 获取所有设置的请求头
 	GetHeaders() map[string]string
 }
+
+// ContextConnector 支持取消连接的协议实现，ctx取消或到期时中止拨号和握手并返回错误，
+// 不会在返回后继续建立连接
+type ContextConnector interface {
+	ConnectContext(ctx context.Context, url string) error
+}
+
+// TransportNamer 能报告自身传输方式的协议实现，客户端据此填写hello中的transport并校验服务器hello；
+// 未实现该接口的协议视为 TransportWebSocket
+type TransportNamer interface {
+	Transport() string
+}
+
+// Pinger 支持测量往返时延的协议实现，PingContext 发送一次ping并等待与之对应的pong，
+// 返回往返时延；ctx取消或到期、连接断开时返回错误
+type Pinger interface {
+	PingContext(ctx context.Context) (time.Duration, error)
+}
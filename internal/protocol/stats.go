@@ -0,0 +1,73 @@
+package protocol
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ProtocolStats 连接统计信息，计数器自协议实例创建起累计，跨重连不清零
+type ProtocolStats struct {
+	BytesSent      uint64        // 已发送的消息负载字节数
+	BytesReceived  uint64        // 已接收的消息负载字节数
+	FramesSent     uint64        // 已发送的消息帧数
+	FramesReceived uint64        // 已接收的消息帧数
+	Reconnects     uint64        // 首次连接之后再次建立连接的次数
+	RTT            time.Duration // 最近一次ping/pong测得的往返时延，尚未测量时为0
+}
+
+// StatsProvider 由支持连接统计的协议实现，监控时可通过类型断言获取
+type StatsProvider interface {
+	Stats() ProtocolStats
+}
+
+// connStats 连接统计计数器，均为原子操作，可与收发并发读取
+type connStats struct {
+	bytesSent      atomic.Uint64
+	bytesReceived  atomic.Uint64
+	framesSent     atomic.Uint64
+	framesReceived atomic.Uint64
+	connects       atomic.Uint64
+	rtt            atomic.Int64 // 纳秒
+	pingSentAt     atomic.Int64 // 最近一次发送ping的时间（UnixNano），0表示没有待响应的ping
+}
+
+// recordSent 记录发送了一帧n字节的消息
+func (s *connStats) recordSent(n int) {
+	s.framesSent.Add(1)
+	s.bytesSent.Add(uint64(n))
+}
+
+// recordReceived 记录收到了一帧n字节的消息
+func (s *connStats) recordReceived(n int) {
+	s.framesReceived.Add(1)
+	s.bytesReceived.Add(uint64(n))
+}
+
+// recordPing 记录ping的发送时间
+func (s *connStats) recordPing(at time.Time) {
+	s.pingSentAt.Store(at.UnixNano())
+}
+
+// recordPong 收到pong时根据对应ping的发送时间更新往返时延
+func (s *connStats) recordPong(at time.Time) {
+	sentAt := s.pingSentAt.Swap(0)
+	if sentAt == 0 {
+		return
+	}
+	s.rtt.Store(at.UnixNano() - sentAt)
+}
+
+// snapshot 返回当前统计信息
+func (s *connStats) snapshot() ProtocolStats {
+	stats := ProtocolStats{
+		BytesSent:      s.bytesSent.Load(),
+		BytesReceived:  s.bytesReceived.Load(),
+		FramesSent:     s.framesSent.Load(),
+		FramesReceived: s.framesReceived.Load(),
+		RTT:            time.Duration(s.rtt.Load()),
+	}
+	if connects := s.connects.Load(); connects > 1 {
+		stats.Reconnects = connects - 1
+	}
+	return stats
+}
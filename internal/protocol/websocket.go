@@ -1,37 +1,81 @@
 package protocol
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
-	"github.com/sirupsen/logrus"
 )
 
 // WebsocketProtocol 实现了Protocol接口，使用WebSocket作为通信方式
 type WebsocketProtocol struct {
-	conn             *websocket.Conn
-	url              string
-	mu               sync.Mutex
-	connected        bool
-	onJSONMessage    func(data []byte)
-	onBinaryMessage  func(data []byte)
-	onDisconnected   func(err error)
-	onConnected      func()
-	headers          map[string]string
-	readTimeout      time.Duration
-	writeTimeout     time.Duration
-	handshakeTimeout time.Duration
-	skipTLSVerify    bool
-	stopChan         chan struct{}
+	conn               *websocket.Conn
+	netConn            *cancelableConn // conn底层的TCP连接，用于中断 writeContext 正在进行的写入
+	url                string
+	mu                 sync.Mutex
+	connected          bool
+	onJSONMessage      func(data []byte)
+	onBinaryMessage    func(data []byte)
+	onDisconnected     func(err error)
+	onDisconnectReason func(reason DisconnectReason, err error)
+	onConnected        func()
+	headers            map[string]string
+	readTimeout        time.Duration
+	writeTimeout       time.Duration
+	handshakeTimeout   time.Duration
+	skipTLSVerify      bool
+	stopChan           chan struct{}
+	lastMessageAt      atomic.Int64              // 最近一次收到消息（或建立连接）的时间（UnixNano），读取循环不必获取mu
+	lastPongAt         atomic.Int64              // 最近一次收到pong的时间（UnixNano），0表示尚未收到
+	preConnectQueue    bool                      // 未连接时是否缓存待发送的消息
+	pending            []queuedMessage           // 连接建立前缓存的消息
+	stats              connStats                 // 收发统计
+	keepAlive          time.Duration             // 发送ping保活的间隔，0表示不发送
+	readDone           chan struct{}             // 当前连接的读取循环退出时关闭
+	tlsConfig          *tls.Config               // 自定义TLS配置，设置后优先于skipTLSVerify
+	maxBinaryFrame     int                       // 发送二进制消息的最大字节数，0表示不限制
+	reuseReadBuffer    bool                      // 二进制消息回调是否直接使用读取缓冲区，不复制
+	pingMu             sync.Mutex                // 保护pingSeq和pendingPings，pong处理不必等待mu上的写入
+	pingSeq            uint64                    // PingContext 的序号，作为ping的负载
+	pendingPings       map[string]chan time.Time // 等待pong的PingContext，键为ping的负载
 }
 
+// DefaultMaxBinaryFrameSize 默认的二进制消息大小上限
+// 单个Opus数据包最大1275字节/帧，60ms最多3帧，留出帧头后4096字节足够容纳正常的音频帧
+const DefaultMaxBinaryFrameSize = 4096
+
+// FrameTooLargeError 二进制消息超过大小上限时返回的错误，消息不会被发送，连接保持可用
+// 可通过 errors.As 判断，通常说明编码参数有误或误把PCM当作Opus发送
+type FrameTooLargeError struct {
+	Size  int // 消息字节数
+	Limit int // 大小上限
+}
+
+func (e *FrameTooLargeError) Error() string {
+	return fmt.Sprintf("二进制消息过大: %d字节，超过上限%d字节，请检查编码参数或通过 SetMaxBinaryFrameSize 调整上限", e.Size, e.Limit)
+}
+
+// queuedMessage 连接建立前缓存的一条消息
+type queuedMessage struct {
+	messageType int
+	data        []byte
+}
+
+// maxPreConnectQueue 连接前最多缓存的消息数
+const maxPreConnectQueue = 64
+
 // NewWebsocketProtocol 创建一个新的WebSocket协议实例
 func NewWebsocketProtocol() *WebsocketProtocol {
 	return &WebsocketProtocol{
@@ -41,6 +85,7 @@ func NewWebsocketProtocol() *WebsocketProtocol {
 		handshakeTimeout: 30 * time.Second,
 		skipTLSVerify:    false,
 		stopChan:         make(chan struct{}),
+		maxBinaryFrame:   DefaultMaxBinaryFrameSize,
 	}
 }
 
@@ -70,6 +115,86 @@ func (wp *WebsocketProtocol) SetReadTimeout(timeout time.Duration) {
 	wp.readTimeout = timeout
 }
 
+// SetKeepAlive 设置定时发送WebSocket ping的间隔，0表示关闭（默认）
+//
+// 开启后每次收到pong都会顺延读取截止时间，因此即使长时间没有业务消息，只要链路正常、
+// pong持续返回，连接就不会因读取超时而断开；interval应小于读取超时时间
+func (wp *WebsocketProtocol) SetKeepAlive(interval time.Duration) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	if interval > 0 && interval >= wp.readTimeout {
+		logger.Warnf("保活间隔%v不小于读取超时%v，空闲时连接仍可能超时断开", interval, wp.readTimeout)
+	}
+	wp.keepAlive = interval
+}
+
+// KeepAlive 返回当前的保活ping间隔，0表示未开启
+func (wp *WebsocketProtocol) KeepAlive() time.Duration {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	return wp.keepAlive
+}
+
+// SetMaxBinaryFrameSize 设置发送二进制消息的最大字节数，默认 DefaultMaxBinaryFrameSize，小于等于0表示不限制
+// 超过上限的消息在写入前被拒绝并返回 *FrameTooLargeError，避免超过服务器的消息大小限制导致连接被断开
+func (wp *WebsocketProtocol) SetMaxBinaryFrameSize(size int) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	if size < 0 {
+		size = 0
+	}
+	wp.maxBinaryFrame = size
+}
+
+// SetReuseReadBuffer 设置二进制消息回调是否直接收到读取缓冲区，默认关闭，对之后建立的连接生效
+//
+// 读取循环总是把消息读入一个可复用的缓冲区。关闭时回调收到的是按消息大小复制出的切片，调用方可以保留；
+// 开启后不再复制，回调收到的切片只在回调返回前有效，下一条消息会覆盖其内容，需要保留数据时必须自行复制。
+// Client会在预缓冲和播放队列中保留音频帧，通过Client使用协议时不要开启
+func (wp *WebsocketProtocol) SetReuseReadBuffer(enabled bool) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.reuseReadBuffer = enabled
+}
+
+// maxRetainedReadBuffer 读取缓冲区保留的最大容量，偶尔收到的大消息读完后释放，不长期占用内存
+const maxRetainedReadBuffer = 64 * 1024
+
+// readMessage 将一条消息读入可复用的缓冲区buf；reuse为true时二进制消息直接返回缓冲区的内容，否则返回副本
+// 与 conn.ReadMessage 每条消息都重新分配并逐步扩容相比，只在复制时分配一次
+func readMessage(conn *websocket.Conn, buf *bytes.Buffer, reuse bool) (int, []byte, error) {
+	messageType, r, err := conn.NextReader()
+	if err != nil {
+		return messageType, nil, err
+	}
+	if buf.Cap() > maxRetainedReadBuffer {
+		*buf = bytes.Buffer{}
+	}
+	buf.Reset()
+	if _, err := buf.ReadFrom(r); err != nil {
+		return messageType, nil, err
+	}
+	if reuse && messageType == websocket.BinaryMessage {
+		return messageType, buf.Bytes(), nil
+	}
+	message := make([]byte, buf.Len())
+	copy(message, buf.Bytes())
+	return messageType, message, nil
+}
+
+// checkBinaryFrameSize 检查二进制消息是否超过大小上限
+func (wp *WebsocketProtocol) checkBinaryFrameSize(data []byte) error {
+	wp.mu.Lock()
+	limit := wp.maxBinaryFrame
+	wp.mu.Unlock()
+
+	if limit > 0 && len(data) > limit {
+		return &FrameTooLargeError{Size: len(data), Limit: limit}
+	}
+	return nil
+}
+
 // SetWriteTimeout 设置写入超时时间
 func (wp *WebsocketProtocol) SetWriteTimeout(timeout time.Duration) {
 	wp.writeTimeout = timeout
@@ -87,8 +212,104 @@ func (wp *WebsocketProtocol) SetSkipTLSVerify(skip bool) {
 	wp.skipTLSVerify = skip
 }
 
+// SetTLSConfig 设置连接使用的TLS配置，例如私有CA或双向TLS；设置后 SetSkipTLSVerify 不再生效
+// 传入nil恢复默认行为。配置会被复制，之后修改cfg不影响已保存的配置
+func (wp *WebsocketProtocol) SetTLSConfig(cfg *tls.Config) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	if cfg == nil {
+		wp.tlsConfig = nil
+		return
+	}
+	wp.tlsConfig = cfg.Clone()
+}
+
+// SetRootCAs 使用PEM编码的CA证书校验服务器证书，替代系统根证书
+func (wp *WebsocketProtocol) SetRootCAs(pemBytes []byte) error {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemBytes) {
+		return errors.New("解析CA证书失败：未找到有效的PEM证书")
+	}
+
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	cfg := wp.customTLSConfigLocked()
+	cfg.RootCAs = pool
+	return nil
+}
+
+// SetClientCert 设置双向TLS使用的客户端证书和私钥（PEM编码）
+func (wp *WebsocketProtocol) SetClientCert(certPEM, keyPEM []byte) error {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("加载客户端证书失败: %v", err)
+	}
+
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	cfg := wp.customTLSConfigLocked()
+	cfg.Certificates = []tls.Certificate{cert}
+	return nil
+}
+
+// customTLSConfigLocked 返回自定义TLS配置，尚未设置时新建一个，调用方需持有mu
+func (wp *WebsocketProtocol) customTLSConfigLocked() *tls.Config {
+	if wp.tlsConfig == nil {
+		wp.tlsConfig = &tls.Config{}
+	}
+	return wp.tlsConfig
+}
+
+// SetPreConnectQueue 设置未连接时是否缓存 SendJSON/SendBinary 及其Context版本的消息
+// 启用后消息在连接建立时按顺序发送；禁用时（默认）未连接直接返回错误，并丢弃已缓存的消息
+func (wp *WebsocketProtocol) SetPreConnectQueue(enabled bool) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.preConnectQueue = enabled
+	if !enabled {
+		wp.pending = nil
+	}
+}
+
+// enqueuePending 在未连接时缓存消息，调用方需持有mu
+func (wp *WebsocketProtocol) enqueuePending(messageType int, data []byte) error {
+	if !wp.preConnectQueue {
+		return errors.New("未连接到服务器")
+	}
+	if len(wp.pending) >= maxPreConnectQueue {
+		return fmt.Errorf("未连接到服务器，待发送消息已达上限(%d)", maxPreConnectQueue)
+	}
+	wp.pending = append(wp.pending, queuedMessage{messageType: messageType, data: data})
+	logger.Debugf("未连接到服务器，消息已缓存，待发送数: %d", len(wp.pending))
+	return nil
+}
+
+// flushPending 按顺序发送连接前缓存的消息，调用方需持有mu
+func (wp *WebsocketProtocol) flushPending() {
+	if len(wp.pending) == 0 {
+		return
+	}
+	logger.Infof("连接已建立，发送%d条缓存消息", len(wp.pending))
+	for i, msg := range wp.pending {
+		wp.conn.SetWriteDeadline(time.Now().Add(wp.writeTimeout))
+		if err := wp.conn.WriteMessage(msg.messageType, msg.data); err != nil {
+			logger.Errorf("发送缓存消息失败，丢弃剩余%d条: %v", len(wp.pending)-i, err)
+			break
+		}
+		wp.stats.recordSent(len(msg.data))
+	}
+	wp.pending = nil
+}
+
 // Connect 实现Protocol接口，连接到WebSocket服务器
 func (wp *WebsocketProtocol) Connect(url string) error {
+	return wp.ConnectContext(context.Background(), url)
+}
+
+// ConnectContext 连接到WebSocket服务器，DNS解析、拨号和握手受ctx的截止时间和取消控制，
+// 同时仍受 SetHandshakeTimeout 的握手超时限制
+func (wp *WebsocketProtocol) ConnectContext(ctx context.Context, url string) error {
 	wp.mu.Lock()
 	if wp.connected {
 		wp.mu.Unlock()
@@ -96,6 +317,11 @@ func (wp *WebsocketProtocol) Connect(url string) error {
 	}
 	wp.url = url
 	skipTLSVerify := wp.skipTLSVerify
+	tlsConfig := &tls.Config{InsecureSkipVerify: skipTLSVerify}
+	if wp.tlsConfig != nil {
+		tlsConfig = wp.tlsConfig.Clone()
+		skipTLSVerify = tlsConfig.InsecureSkipVerify
+	}
 	wp.mu.Unlock()
 
 	// 准备请求头
@@ -103,85 +329,116 @@ func (wp *WebsocketProtocol) Connect(url string) error {
 	wp.mu.Lock()
 	// 清晰地记录每个请求头
 	if len(wp.headers) > 0 {
-		logrus.Debug("WebSocket连接请求头:")
+		logger.Debugf("WebSocket连接请求头:")
 		for k, v := range wp.headers {
 			header[k] = []string{v}
-			logrus.Debugf("  %s: %s", k, v)
+			logger.Debugf("  %s: %s", k, v)
 		}
 	} else {
-		logrus.Warn("WebSocket连接没有设置任何请求头")
+		logger.Warnf("WebSocket连接没有设置任何请求头")
 	}
 	wp.mu.Unlock()
 
 	// 尝试解析主机名
-	logrus.Debug("准备解析WebSocket服务器地址...")
+	logger.Debugf("准备解析WebSocket服务器地址...")
 	parsedURL, err := parseWebSocketURL(url)
 	if err != nil {
-		logrus.Errorf("解析WebSocket URL失败: %v", err)
+		logger.Errorf("解析WebSocket URL失败: %v", err)
 		return err
 	}
 
 	// 尝试DNS解析
-	logrus.Debugf("尝试解析主机名: %s", parsedURL.Hostname)
-	ips, err := net.LookupIP(parsedURL.Hostname)
+	logger.Debugf("尝试解析主机名: %s", parsedURL.Hostname)
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, parsedURL.Hostname)
 	if err != nil {
-		logrus.Errorf("DNS解析失败: %v", err)
+		logger.Errorf("DNS解析失败: %v", err)
 		// 我们继续执行，因为Dial函数会再次尝试解析
 	} else {
-		logrus.Debugf("DNS解析成功，获取到IP地址: %v", ips)
+		logger.Debugf("DNS解析成功，获取到IP地址: %v", ips)
 	}
 
-	// 配置拨号器
+	// 配置拨号器，底层TCP连接包装为cancelableConn，TLS建立在包装之上
+	var netConn *cancelableConn
 	dialer := websocket.Dialer{
 		HandshakeTimeout: wp.handshakeTimeout,
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: skipTLSVerify,
+		TLSClientConfig:  tlsConfig,
+		NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			var d net.Dialer
+			c, err := d.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			netConn = &cancelableConn{Conn: c}
+			return netConn, nil
 		},
 	}
 
-	logrus.Debugf("开始WebSocket连接: %s", url)
-	logrus.Debugf("  跳过TLS验证: %v", skipTLSVerify)
-	logrus.Debugf("  握手超时: %v", wp.handshakeTimeout)
-	logrus.Debugf("  读取超时: %v", wp.readTimeout)
-	logrus.Debugf("  写入超时: %v", wp.writeTimeout)
+	logger.Debugf("开始WebSocket连接: %s", url)
+	logger.Debugf("  跳过TLS验证: %v", skipTLSVerify)
+	logger.Debugf("  握手超时: %v", wp.handshakeTimeout)
+	logger.Debugf("  读取超时: %v", wp.readTimeout)
+	logger.Debugf("  写入超时: %v", wp.writeTimeout)
 
 	// 建立连接
 	startTime := time.Now()
-	logrus.Debug("正在尝试建立WebSocket连接...")
-	conn, resp, err := dialer.Dial(url, header)
+	logger.Debugf("正在尝试建立WebSocket连接...")
+	conn, resp, err := dialer.DialContext(ctx, url, header)
 	elapsed := time.Since(startTime)
 
 	if err != nil {
 		if resp != nil {
-			logrus.Errorf("连接WebSocket服务器失败: %v", err)
-			logrus.Errorf("HTTP状态码: %d", resp.StatusCode)
-			logrus.Errorf("HTTP响应头: %v", resp.Header)
+			logger.Errorf("连接WebSocket服务器失败: %v", err)
+			logger.Errorf("HTTP状态码: %d", resp.StatusCode)
+			logger.Errorf("HTTP响应头: %v", resp.Header)
 			body := make([]byte, 1024)
 			n, readErr := resp.Body.Read(body)
 			if readErr != nil && readErr != io.EOF {
-				logrus.Errorf("读取响应体失败: %v", readErr)
+				logger.Errorf("读取响应体失败: %v", readErr)
 			} else if n > 0 {
-				logrus.Errorf("响应体: %s", string(body[:n]))
+				logger.Errorf("响应体: %s", string(body[:n]))
 			}
-			logrus.Errorf("连接用时: %v", elapsed)
+			logger.Errorf("连接用时: %v", elapsed)
 		} else {
-			logrus.Errorf("连接WebSocket服务器失败: %v", err)
-			logrus.Error("无HTTP响应")
-			logrus.Errorf("连接用时: %v", elapsed)
+			logger.Errorf("连接WebSocket服务器失败: %v", err)
+			logger.Errorf("无HTTP响应")
+			logger.Errorf("连接用时: %v", elapsed)
 		}
 		return err
 	}
 
-	logrus.Infof("WebSocket连接成功, 用时: %v", elapsed)
+	logger.Infof("WebSocket连接成功, 用时: %v", elapsed)
+
+	// 收到pong时计算往返时延，同时沿用默认行为刷新读取截止时间
+	conn.SetPongHandler(func(appData string) error {
+		now := time.Now()
+		wp.stats.recordPong(now)
+		wp.lastPongAt.Store(now.UnixNano())
+		wp.resolvePing(appData, now)
+		// 链路仍然可用，顺延读取截止时间
+		conn.SetReadDeadline(now.Add(wp.readTimeout))
+		return nil
+	})
+	wp.stats.connects.Add(1)
 
 	wp.mu.Lock()
 	wp.conn = conn
+	wp.netConn = netConn
 	wp.connected = true
 	wp.stopChan = make(chan struct{})
+	wp.readDone = make(chan struct{})
+	wp.lastMessageAt.Store(time.Now().UnixNano())
+	wp.flushPending()
+	keepAlive := wp.keepAlive
+	stopChan := wp.stopChan
+	readDone := wp.readDone
+	reuse := wp.reuseReadBuffer
 	wp.mu.Unlock()
 
 	// 启动读取循环
-	go wp.readPump()
+	go wp.readPump(conn, readDone, reuse)
+	if keepAlive > 0 {
+		go wp.keepAliveLoop(conn, stopChan, keepAlive)
+	}
 
 	// 触发连接成功回调
 	if wp.onConnected != nil {
@@ -261,14 +518,19 @@ func (wp *WebsocketProtocol) Disconnect() error {
 	default:
 		close(wp.stopChan)
 	}
+	onDisconnectReason := wp.onDisconnectReason
 	wp.mu.Unlock()
 
+	if onDisconnectReason != nil {
+		onDisconnectReason(DisconnectUserInitiated, nil)
+	}
+
 	// 启动一个goroutine来关闭连接，完全不阻塞当前操作
 	go func() {
 		// 捕获所有可能的异常
 		defer func() {
 			if r := recover(); r != nil {
-				logrus.Errorf("关闭WebSocket连接时发生异常: %v", r)
+				logger.Errorf("关闭WebSocket连接时发生异常: %v", r)
 			}
 		}()
 
@@ -286,30 +548,222 @@ func (wp *WebsocketProtocol) Disconnect() error {
 	return nil
 }
 
+// DisconnectGraceful 完成WebSocket关闭握手后断开连接
+// 发送关闭帧后等待服务器回复关闭帧，收到或超过timeout后再关闭TCP连接，使服务器能及时结束会话；
+// 等待超时时连接同样会被关闭并返回错误。需要立即退出时使用 ForceDisconnect
+func (wp *WebsocketProtocol) DisconnectGraceful(timeout time.Duration) error {
+	wp.mu.Lock()
+	if !wp.connected || wp.conn == nil {
+		wp.mu.Unlock()
+		return nil
+	}
+
+	// 标记为断开，读取循环继续运行以接收服务器的关闭确认
+	wp.connected = false
+	conn := wp.conn
+	wp.conn = nil
+	readDone := wp.readDone
+
+	select {
+	case <-wp.stopChan:
+	default:
+		close(wp.stopChan)
+	}
+	onDisconnectReason := wp.onDisconnectReason
+	wp.mu.Unlock()
+
+	if onDisconnectReason != nil {
+		onDisconnectReason(DisconnectUserInitiated, nil)
+	}
+
+	defer conn.Close()
+
+	closeMessage := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	if err := conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(timeout)); err != nil {
+		return fmt.Errorf("发送关闭帧失败: %v", err)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-readDone:
+		logger.Debugf("服务器已确认关闭WebSocket连接")
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("等待服务器确认关闭超时(%v)", timeout)
+	}
+}
+
 // SendJSON 实现Protocol接口，发送JSON消息
 func (wp *WebsocketProtocol) SendJSON(data interface{}) error {
+	return wp.SendJSONWithTimeout(data, 0)
+}
+
+// SendBinary 实现Protocol接口，发送二进制数据
+func (wp *WebsocketProtocol) SendBinary(data []byte) error {
+	return wp.SendBinaryWithTimeout(data, 0)
+}
+
+// SendJSONWithTimeout 发送JSON消息，本次写入使用timeout作为截止时间，不影响全局writeTimeout
+// timeout为0时使用全局writeTimeout
+func (wp *WebsocketProtocol) SendJSONWithTimeout(data interface{}, timeout time.Duration) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("序列化JSON消息失败: %v", err)
+	}
+	return wp.writeMessage(websocket.TextMessage, payload, timeout)
+}
+
+// SendBinaryWithTimeout 发送二进制数据，本次写入使用timeout作为截止时间，不影响全局writeTimeout
+// 实时音频可借此设置较短的截止时间（如100ms）快速失败，控制消息仍使用宽松的默认值
+func (wp *WebsocketProtocol) SendBinaryWithTimeout(data []byte, timeout time.Duration) error {
+	if err := wp.checkBinaryFrameSize(data); err != nil {
+		return err
+	}
+	return wp.writeMessage(websocket.BinaryMessage, data, timeout)
+}
+
+// writeMessage 以指定的截止时间写入一条消息，未连接时按配置缓存
+// 写入完成后清除写入截止时间，避免本次的截止时间影响后续写入
+func (wp *WebsocketProtocol) writeMessage(messageType int, data []byte, timeout time.Duration) error {
 	wp.mu.Lock()
 	defer wp.mu.Unlock()
 
 	if !wp.connected || wp.conn == nil {
-		return errors.New("未连接到服务器")
+		// 缓存的消息在连接建立后才发送，需要复制一份
+		return wp.enqueuePending(messageType, append([]byte(nil), data...))
+	}
+
+	if timeout <= 0 {
+		timeout = wp.writeTimeout
 	}
+	wp.conn.SetWriteDeadline(time.Now().Add(timeout))
+	defer wp.conn.SetWriteDeadline(time.Time{})
 
-	wp.conn.SetWriteDeadline(time.Now().Add(wp.writeTimeout))
-	return wp.conn.WriteJSON(data)
+	if err := wp.conn.WriteMessage(messageType, data); err != nil {
+		return err
+	}
+	wp.stats.recordSent(len(data))
+	return nil
 }
 
-// SendBinary 实现Protocol接口，发送二进制数据
-func (wp *WebsocketProtocol) SendBinary(data []byte) error {
+// SendJSONContext 实现Protocol接口，发送JSON消息
+// ctx带截止时间时用它代替全局writeTimeout作为写入截止时间，ctx取消时中断正在进行的写入
+func (wp *WebsocketProtocol) SendJSONContext(ctx context.Context, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("序列化JSON消息失败: %v", err)
+	}
+	return wp.writeContext(ctx, websocket.TextMessage, payload)
+}
+
+// SendBinaryContext 实现Protocol接口，发送二进制数据
+// ctx带截止时间时用它代替全局writeTimeout作为写入截止时间，ctx取消时中断正在进行的写入
+func (wp *WebsocketProtocol) SendBinaryContext(ctx context.Context, data []byte) error {
+	if err := wp.checkBinaryFrameSize(data); err != nil {
+		return err
+	}
+	return wp.writeContext(ctx, websocket.BinaryMessage, data)
+}
+
+// writeContext 在ctx控制下执行一次写入，未连接时与 SendJSON 一样按配置缓存
+// 写入被中断后WebSocket帧可能只写出一部分，此时连接已不可用，需要由调用方断开重连
+func (wp *WebsocketProtocol) writeContext(ctx context.Context, messageType int, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	wp.mu.Lock()
 	defer wp.mu.Unlock()
 
 	if !wp.connected || wp.conn == nil {
-		return errors.New("未连接到服务器")
+		// 缓存的消息在连接建立后才发送，需要复制一份
+		return wp.enqueuePending(messageType, append([]byte(nil), data...))
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(wp.writeTimeout)
+	}
+	conn := wp.conn
+	conn.SetWriteDeadline(deadline)
+	defer conn.SetWriteDeadline(time.Time{})
+
+	// ctx取消时让底层连接的写入立即超时；cancelableConn保证gorilla/websocket写下一帧时
+	// 设置的截止时间不会覆盖取消。等待监听goroutine退出后再恢复，取消不会影响之后的写入
+	if netConn := wp.netConn; netConn != nil {
+		done := make(chan struct{})
+		exited := make(chan struct{})
+		go func() {
+			defer close(exited)
+			select {
+			case <-ctx.Done():
+				netConn.cancelWrite()
+			case <-done:
+			}
+		}()
+		defer func() {
+			close(done)
+			<-exited
+			netConn.resetWrite()
+		}()
+	}
+
+	err := conn.WriteMessage(messageType, data)
+	if ctxErr := ctx.Err(); ctxErr != nil && err != nil {
+		logger.Warnf("写入被取消: %v", err)
+		return ctxErr
 	}
+	if err == nil {
+		wp.stats.recordSent(len(data))
+	}
+	return err
+}
 
-	wp.conn.SetWriteDeadline(time.Now().Add(wp.writeTimeout))
-	return wp.conn.WriteMessage(websocket.BinaryMessage, data)
+// cancelableConn 包装WebSocket底层的网络连接，使写入可以被取消
+// gorilla/websocket写每一帧前都会重新设置写入截止时间，直接修改底层连接的截止时间会被下一帧覆盖；
+// 取消后这里把之后设置的写入截止时间都替换为当前时间，直到 resetWrite
+type cancelableConn struct {
+	net.Conn
+	mu        sync.Mutex
+	cancelled bool
+}
+
+// SetWriteDeadline 取消期间忽略t，使写入立即超时
+func (c *cancelableConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancelled {
+		t = time.Now()
+	}
+	return c.Conn.SetWriteDeadline(t)
+}
+
+// SetDeadline 同时设置读写截止时间，写入部分同样受取消影响
+func (c *cancelableConn) SetDeadline(t time.Time) error {
+	if err := c.Conn.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.SetWriteDeadline(t)
+}
+
+// cancelWrite 中断正在进行的写入
+func (c *cancelableConn) cancelWrite() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cancelled = true
+	c.Conn.SetWriteDeadline(time.Now())
+}
+
+// resetWrite 结束取消状态并清除写入截止时间
+func (c *cancelableConn) resetWrite() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancelled {
+		c.cancelled = false
+		c.Conn.SetWriteDeadline(time.Time{})
+	}
 }
 
 // SetOnJSONMessage 实现Protocol接口，设置接收JSON消息的回调
@@ -319,7 +773,7 @@ func (wp *WebsocketProtocol) SetOnJSONMessage(callback func(data []byte)) {
 	wp.onJSONMessage = callback
 }
 
-// SetOnBinaryMessage 实现Protocol接口，设置接收二进制消息的回调
+// SetOnBinaryMessage 实现Protocol接口，设置接收二进制消息的回调，data的所有权见 SetReuseReadBuffer
 func (wp *WebsocketProtocol) SetOnBinaryMessage(callback func(data []byte)) {
 	wp.mu.Lock()
 	defer wp.mu.Unlock()
@@ -333,6 +787,13 @@ func (wp *WebsocketProtocol) SetOnDisconnected(callback func(err error)) {
 	wp.onDisconnected = callback
 }
 
+// SetOnDisconnectReason 实现DisconnectReasonNotifier接口，设置带断开原因的回调
+func (wp *WebsocketProtocol) SetOnDisconnectReason(callback func(reason DisconnectReason, err error)) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.onDisconnectReason = callback
+}
+
 // SetOnConnected 实现Protocol接口，设置连接成功的回调
 func (wp *WebsocketProtocol) SetOnConnected(callback func()) {
 	wp.mu.Lock()
@@ -340,6 +801,11 @@ func (wp *WebsocketProtocol) SetOnConnected(callback func()) {
 	wp.onConnected = callback
 }
 
+// Transport 实现TransportNamer接口
+func (wp *WebsocketProtocol) Transport() string {
+	return TransportWebSocket
+}
+
 // IsConnected 实现Protocol接口，返回当前连接状态
 func (wp *WebsocketProtocol) IsConnected() bool {
 	wp.mu.Lock()
@@ -347,15 +813,151 @@ func (wp *WebsocketProtocol) IsConnected() bool {
 	return wp.connected
 }
 
+// LastMessageTime 返回最近一次收到消息的时间，尚未收到消息时为建立连接的时间
+func (wp *WebsocketProtocol) LastMessageTime() time.Time {
+	at := wp.lastMessageAt.Load()
+	if at == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, at)
+}
+
+// LastPongTime 返回最近一次收到保活pong的时间，尚未收到时为零值
+// pong不是业务消息，不刷新LastMessageTime，但同样说明链路可用
+func (wp *WebsocketProtocol) LastPongTime() time.Time {
+	at := wp.lastPongAt.Load()
+	if at == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, at)
+}
+
+// Ping 发送一个WebSocket ping控制帧，收到pong后更新 Stats 中的往返时延
+func (wp *WebsocketProtocol) Ping() error {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	if !wp.connected || wp.conn == nil {
+		return errors.New("未连接到服务器")
+	}
+
+	now := time.Now()
+	wp.stats.recordPing(now)
+	return wp.conn.WriteControl(websocket.PingMessage, nil, now.Add(wp.writeTimeout))
+}
+
+// PingContext 实现Pinger接口，发送以序号为负载的ping控制帧，等待负载相同的pong并返回往返时延
+// 可与其他收发及 Ping 并发调用；同时更新 Stats 中的往返时延
+func (wp *WebsocketProtocol) PingContext(ctx context.Context) (time.Duration, error) {
+	wp.mu.Lock()
+	if !wp.connected || wp.conn == nil {
+		wp.mu.Unlock()
+		return 0, errors.New("未连接到服务器")
+	}
+	conn := wp.conn
+	readDone := wp.readDone
+	writeTimeout := wp.writeTimeout
+	wp.mu.Unlock()
+
+	wp.pingMu.Lock()
+	wp.pingSeq++
+	id := "ping-" + strconv.FormatUint(wp.pingSeq, 10)
+	pong := make(chan time.Time, 1)
+	if wp.pendingPings == nil {
+		wp.pendingPings = make(map[string]chan time.Time)
+	}
+	wp.pendingPings[id] = pong
+	wp.pingMu.Unlock()
+
+	defer func() {
+		wp.pingMu.Lock()
+		delete(wp.pendingPings, id)
+		wp.pingMu.Unlock()
+	}()
+
+	deadline := time.Now().Add(writeTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	sentAt := time.Now()
+	wp.stats.recordPing(sentAt)
+	if err := conn.WriteControl(websocket.PingMessage, []byte(id), deadline); err != nil {
+		return 0, fmt.Errorf("发送ping失败: %v", err)
+	}
+
+	select {
+	case receivedAt := <-pong:
+		return receivedAt.Sub(sentAt), nil
+	case <-readDone:
+		return 0, errors.New("等待pong时连接已断开")
+	case <-ctx.Done():
+		return 0, fmt.Errorf("等待pong失败: %w", ctx.Err())
+	}
+}
+
+// resolvePing 收到pong时唤醒等待该负载的 PingContext
+// 在读取循环中调用，只获取pingMu，不会被持有mu的阻塞写入拖住
+func (wp *WebsocketProtocol) resolvePing(appData string, at time.Time) {
+	wp.pingMu.Lock()
+	pong, ok := wp.pendingPings[appData]
+	delete(wp.pendingPings, appData)
+	wp.pingMu.Unlock()
+
+	if ok {
+		pong <- at
+	}
+}
+
+// Stats 返回连接统计信息，可与收发并发调用
+func (wp *WebsocketProtocol) Stats() ProtocolStats {
+	return wp.stats.snapshot()
+}
+
+// keepAliveLoop 按interval定时发送ping，连接断开或被替换后退出
+func (wp *WebsocketProtocol) keepAliveLoop(conn *websocket.Conn, stopChan chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-ticker.C:
+			wp.mu.Lock()
+			current := wp.conn
+			wp.mu.Unlock()
+			if current != conn {
+				return
+			}
+			if err := wp.Ping(); err != nil {
+				logger.Warnf("发送保活ping失败: %v", err)
+				return
+			}
+		}
+	}
+}
+
 // readPump 处理从WebSocket接收的消息
-func (wp *WebsocketProtocol) readPump() {
+// 读取截止时间在每次收到消息或pong时顺延，超时说明这段时间内链路上没有任何数据，
+// 连接被视为失效；gorilla/websocket的读取错误不可恢复，因此任何读取错误都会结束循环。
+// 循环结束时按读取错误区分断开原因：服务器关闭帧、读取超时或其他网络错误
+func (wp *WebsocketProtocol) readPump(conn *websocket.Conn, readDone chan struct{}, reuse bool) {
+	defer close(readDone)
+	var readBuffer bytes.Buffer
+	reason := DisconnectNetworkError
+	var cause error
 	defer func() {
 		wp.mu.Lock()
-		isConnected := wp.connected
+		// 连接已被替换（重连）时不影响新连接
+		isConnected := wp.connected && wp.conn == conn
 		wp.mu.Unlock()
 
 		if isConnected {
-			wp.handleDisconnect(errors.New("WebSocket读取循环结束"))
+			err := errors.New("WebSocket读取循环结束")
+			if cause != nil {
+				err = fmt.Errorf("WebSocket读取循环结束: %w", cause)
+			}
+			wp.handleDisconnect(reason, err)
 		}
 	}()
 
@@ -365,15 +967,37 @@ func (wp *WebsocketProtocol) readPump() {
 			return
 		default:
 			// 设置读取超时
-			wp.conn.SetReadDeadline(time.Now().Add(wp.readTimeout))
+			conn.SetReadDeadline(time.Now().Add(wp.readTimeout))
 
 			// 读取消息
-			messageType, message, err := wp.conn.ReadMessage()
+			messageType, message, err := readMessage(conn, &readBuffer, reuse)
 			if err != nil {
-				logrus.Errorf("读取WebSocket消息失败: %v", err)
+				cause = err
+				var netErr net.Error
+				var closeErr *websocket.CloseError
+				switch {
+				case errors.As(err, &closeErr):
+					// 保留服务器给出的关闭码和原因，便于诊断鉴权失败等问题
+					reason = DisconnectServerClosed
+					cause = &ServerCloseError{Code: closeErr.Code, Text: closeErr.Text}
+					if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+						logger.Infof("%v", cause)
+					} else {
+						logger.Errorf("%v", cause)
+					}
+				case errors.As(err, &netErr) && netErr.Timeout():
+					reason = DisconnectReadTimeout
+					logger.Errorf("超过%v未收到服务器任何数据（包括pong），连接已失效: %v", wp.readTimeout, err)
+				default:
+					logger.Errorf("读取WebSocket消息失败: %v", err)
+				}
 				return
 			}
 
+			// 写入期间mu被一直持有，这里使用原子操作，避免读取循环被阻塞的写入拖住
+			wp.lastMessageAt.Store(time.Now().UnixNano())
+			wp.stats.recordReceived(len(message))
+
 			// 根据消息类型调用不同的回调
 			switch messageType {
 			case websocket.TextMessage:
@@ -385,14 +1009,15 @@ func (wp *WebsocketProtocol) readPump() {
 					wp.onBinaryMessage(message)
 				}
 			case websocket.CloseMessage:
+				reason = DisconnectServerClosed
 				return
 			}
 		}
 	}
 }
 
-// handleDisconnect 处理连接断开
-func (wp *WebsocketProtocol) handleDisconnect(err error) {
+// handleDisconnect 处理意外断开，依次触发带原因的回调和断开回调
+func (wp *WebsocketProtocol) handleDisconnect(reason DisconnectReason, err error) {
 	wp.mu.Lock()
 	if !wp.connected {
 		wp.mu.Unlock()
@@ -404,9 +1029,13 @@ func (wp *WebsocketProtocol) handleDisconnect(err error) {
 		wp.conn = nil
 	}
 	onDisconnected := wp.onDisconnected
+	onDisconnectReason := wp.onDisconnectReason
 	wp.mu.Unlock()
 
 	// 触发断开连接回调
+	if onDisconnectReason != nil {
+		onDisconnectReason(reason, err)
+	}
 	if onDisconnected != nil {
 		onDisconnected(err)
 	}
@@ -416,10 +1045,10 @@ func (wp *WebsocketProtocol) handleDisconnect(err error) {
 // 这是为了支持程序快速退出而设计的方法
 func (wp *WebsocketProtocol) ForceDisconnect() {
 	wp.mu.Lock()
-	defer wp.mu.Unlock()
 
 	// 如果已经断开，直接返回
 	if !wp.connected || wp.conn == nil {
+		wp.mu.Unlock()
 		return
 	}
 
@@ -439,6 +1068,11 @@ func (wp *WebsocketProtocol) ForceDisconnect() {
 	default:
 		close(wp.stopChan)
 	}
+	onDisconnectReason := wp.onDisconnectReason
+	wp.mu.Unlock()
 
-	logrus.Debug("WebSocket连接已强制关闭")
+	logger.Debugf("WebSocket连接已强制关闭")
+	if onDisconnectReason != nil {
+		onDisconnectReason(DisconnectUserInitiated, nil)
+	}
 }
@@ -0,0 +1,575 @@
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestServer 启动一个WebSocket测试服务器，handle在升级后的连接上运行，返回ws://地址
+func newTestServer(t testing.TB, handle func(conn *websocket.Conn)) string {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("升级WebSocket连接失败: %v", err)
+			return
+		}
+		defer conn.Close()
+		handle(conn)
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// echoTextMessages 把收到的文本消息依次发送到out，连接关闭后返回
+func echoTextMessages(out chan<- string) func(conn *websocket.Conn) {
+	return func(conn *websocket.Conn) {
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if messageType == websocket.TextMessage {
+				out <- string(data)
+			}
+		}
+	}
+}
+
+// newTLSTestServer 启动要求客户端证书的WebSocket测试服务器，clientCAs为信任的客户端证书，
+// 返回wss://地址和PEM编码的服务器证书
+func newTLSTestServer(t *testing.T, clientCAs *x509.CertPool) (string, []byte) {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		echoTextMessages(make(chan string, 1))(conn)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	// 握手失败是测试预期的一部分，不输出服务器日志
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	return "wss" + strings.TrimPrefix(srv.URL, "https"), caPEM
+}
+
+// newClientCert 生成自签名的客户端证书，返回PEM编码的证书和私钥
+func newClientCert(t *testing.T) (certPEM, keyPEM []byte, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "xiaozhi-test-device"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, cert
+}
+
+func TestConnectWithCustomCAAndClientCert(t *testing.T) {
+	certPEM, keyPEM, cert := newClientCert(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(cert)
+	url, caPEM := newTLSTestServer(t, clientCAs)
+
+	tests := []struct {
+		name       string
+		caPEM      []byte
+		clientCert bool
+		wantErr    bool
+	}{
+		{"不信任服务器证书", nil, true, true},
+		{"缺少客户端证书", caPEM, false, true},
+		{"CA和客户端证书齐全", caPEM, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wp := NewWebsocketProtocol()
+			if tt.caPEM != nil {
+				if err := wp.SetRootCAs(tt.caPEM); err != nil {
+					t.Fatalf("设置CA证书失败: %v", err)
+				}
+			}
+			if tt.clientCert {
+				if err := wp.SetClientCert(certPEM, keyPEM); err != nil {
+					t.Fatalf("设置客户端证书失败: %v", err)
+				}
+			}
+
+			err := wp.Connect(url)
+			if tt.wantErr {
+				if err == nil {
+					wp.ForceDisconnect()
+					t.Fatal("期望连接失败")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("连接失败: %v", err)
+			}
+			defer wp.ForceDisconnect()
+			if err := wp.SendJSON(map[string]string{"type": "hello"}); err != nil {
+				t.Errorf("TLS连接上发送失败: %v", err)
+			}
+		})
+	}
+}
+
+func TestSetRootCAsRejectsInvalidPEM(t *testing.T) {
+	wp := NewWebsocketProtocol()
+	if err := wp.SetRootCAs([]byte("not a certificate")); err == nil {
+		t.Error("无效的PEM应返回错误")
+	}
+	if err := wp.SetClientCert([]byte("bad"), []byte("bad")); err == nil {
+		t.Error("无效的客户端证书应返回错误")
+	}
+}
+
+func TestSendBinaryContextCancelMidWrite(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	// 服务器从不读取，写入的数据填满TCP缓冲区后阻塞
+	url := newTestServer(t, func(conn *websocket.Conn) { <-release })
+
+	wp := NewWebsocketProtocol()
+	wp.SetMaxBinaryFrameSize(0)
+	if err := wp.Connect(url); err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer wp.ForceDisconnect()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := wp.SendBinaryContext(ctx, make([]byte, 64<<20))
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("期望 context.Canceled，实际: %v", err)
+	}
+	if elapsed > 5*time.Second {
+		t.Errorf("取消后写入%v才返回，期望立即中断", elapsed)
+	}
+}
+
+func TestSendJSONContextClearsDeadline(t *testing.T) {
+	received := make(chan string, 2)
+	url := newTestServer(t, echoTextMessages(received))
+
+	wp := NewWebsocketProtocol()
+	if err := wp.Connect(url); err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer wp.ForceDisconnect()
+
+	// 写入完成后才取消ctx，之后的写入不能受影响
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	if err := wp.SendJSONContext(ctx, map[string]string{"type": "first"}); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+	cancel()
+	time.Sleep(100 * time.Millisecond)
+
+	if err := wp.SendJSON(map[string]string{"type": "second"}); err != nil {
+		t.Fatalf("ctx结束后发送失败: %v", err)
+	}
+	for _, want := range []string{`{"type":"first"}`, `{"type":"second"}`} {
+		select {
+		case got := <-received:
+			if got != want {
+				t.Errorf("收到 %s，期望 %s", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("等待消息 %s 超时", want)
+		}
+	}
+}
+
+func TestSendJSONContextQueuesBeforeConnect(t *testing.T) {
+	received := make(chan string, 3)
+	url := newTestServer(t, echoTextMessages(received))
+
+	wp := NewWebsocketProtocol()
+	if err := wp.SendJSONContext(context.Background(), map[string]string{"type": "hello"}); err == nil {
+		t.Fatal("未启用缓存时未连接发送应返回错误")
+	}
+
+	wp.SetPreConnectQueue(true)
+	for _, msgType := range []string{"hello", "listen"} {
+		if err := wp.SendJSONContext(context.Background(), map[string]string{"type": msgType}); err != nil {
+			t.Fatalf("缓存消息失败: %v", err)
+		}
+	}
+	if err := wp.Connect(url); err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer wp.ForceDisconnect()
+	if err := wp.SendJSON(map[string]string{"type": "abort"}); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+
+	for _, want := range []string{`{"type":"hello"}`, `{"type":"listen"}`, `{"type":"abort"}`} {
+		select {
+		case got := <-received:
+			if got != want {
+				t.Errorf("收到 %s，期望 %s", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("等待消息 %s 超时", want)
+		}
+	}
+}
+
+func TestSendQueuesBeforeConnectInOrder(t *testing.T) {
+	type message struct {
+		messageType int
+		data        string
+	}
+	received := make(chan message, 4)
+	url := newTestServer(t, func(conn *websocket.Conn) {
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			received <- message{messageType, string(data)}
+		}
+	})
+
+	wp := NewWebsocketProtocol()
+	if err := wp.SendBinary([]byte{1}); err == nil {
+		t.Fatal("未启用缓存时未连接发送应返回错误")
+	}
+
+	wp.SetPreConnectQueue(true)
+	if err := wp.SendJSON(map[string]string{"type": "hello"}); err != nil {
+		t.Fatalf("缓存JSON消息失败: %v", err)
+	}
+	frame := []byte{1, 2, 3}
+	if err := wp.SendBinary(frame); err != nil {
+		t.Fatalf("缓存二进制消息失败: %v", err)
+	}
+	// 缓存的是副本，调用方复用缓冲区不影响待发送的数据
+	frame[0] = 9
+	if err := wp.SendJSON(map[string]string{"type": "listen"}); err != nil {
+		t.Fatalf("缓存JSON消息失败: %v", err)
+	}
+
+	if err := wp.Connect(url); err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer wp.ForceDisconnect()
+	if err := wp.SendJSON(map[string]string{"type": "abort"}); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+
+	want := []message{
+		{websocket.TextMessage, `{"type":"hello"}`},
+		{websocket.BinaryMessage, "\x01\x02\x03"},
+		{websocket.TextMessage, `{"type":"listen"}`},
+		{websocket.TextMessage, `{"type":"abort"}`},
+	}
+	for _, w := range want {
+		select {
+		case got := <-received:
+			if got != w {
+				t.Errorf("收到 %d %q，期望 %d %q", got.messageType, got.data, w.messageType, w.data)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("等待消息 %q 超时", w.data)
+		}
+	}
+}
+
+func TestPreConnectQueueLimitAndDisable(t *testing.T) {
+	wp := NewWebsocketProtocol()
+	wp.SetPreConnectQueue(true)
+	for i := 0; i < maxPreConnectQueue; i++ {
+		if err := wp.SendBinary([]byte{byte(i)}); err != nil {
+			t.Fatalf("缓存第%d条消息失败: %v", i+1, err)
+		}
+	}
+	if err := wp.SendBinary([]byte{0}); err == nil {
+		t.Fatal("缓存已满时应返回错误")
+	}
+
+	wp.SetPreConnectQueue(false)
+	wp.mu.Lock()
+	pending := len(wp.pending)
+	wp.mu.Unlock()
+	if pending != 0 {
+		t.Errorf("禁用缓存后仍有%d条待发送消息", pending)
+	}
+}
+
+func TestKeepAliveSurvivesIdlePastReadTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		keepAlive  time.Duration
+		wantAlive  bool
+		wantReason DisconnectReason
+	}{
+		{"开启保活", 50 * time.Millisecond, true, 0},
+		{"未开启保活", 0, false, DisconnectReadTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 服务器只读取不发送业务消息，读取时自动回复pong
+			url := newTestServer(t, echoTextMessages(make(chan string, 1)))
+
+			wp := NewWebsocketProtocol()
+			wp.SetReadTimeout(200 * time.Millisecond)
+			wp.SetKeepAlive(tt.keepAlive)
+			disconnected := make(chan DisconnectReason, 1)
+			wp.SetOnDisconnectReason(func(reason DisconnectReason, err error) {
+				disconnected <- reason
+			})
+			if err := wp.Connect(url); err != nil {
+				t.Fatalf("连接失败: %v", err)
+			}
+			defer wp.ForceDisconnect()
+
+			select {
+			case reason := <-disconnected:
+				if tt.wantAlive {
+					t.Fatalf("空闲超过读取超时后连接断开: %s", reason)
+				}
+				if reason != tt.wantReason {
+					t.Errorf("断开原因为 %s，期望 %s", reason, tt.wantReason)
+				}
+			case <-time.After(time.Second):
+				if !tt.wantAlive {
+					t.Fatal("未开启保活时空闲超过读取超时应断开")
+				}
+				if !wp.IsConnected() {
+					t.Error("开启保活后连接应保持")
+				}
+				if pong := wp.LastPongTime(); !pong.After(wp.LastMessageTime()) {
+					t.Errorf("最近pong时间 %v 应晚于最近消息时间 %v", pong, wp.LastMessageTime())
+				}
+			}
+		})
+	}
+}
+
+func TestServerCloseReasonReported(t *testing.T) {
+	url := newTestServer(t, func(conn *websocket.Conn) {
+		msg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "token无效")
+		conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+		conn.ReadMessage()
+	})
+
+	wp := NewWebsocketProtocol()
+	type result struct {
+		reason DisconnectReason
+		err    error
+	}
+	disconnected := make(chan result, 1)
+	wp.SetOnDisconnectReason(func(reason DisconnectReason, err error) {
+		disconnected <- result{reason, err}
+	})
+	if err := wp.Connect(url); err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer wp.ForceDisconnect()
+
+	select {
+	case got := <-disconnected:
+		if got.reason != DisconnectServerClosed {
+			t.Errorf("断开原因为 %s，期望 %s", got.reason, DisconnectServerClosed)
+		}
+		var closeErr *ServerCloseError
+		if !errors.As(got.err, &closeErr) || closeErr.Code != websocket.ClosePolicyViolation || closeErr.Text != "token无效" {
+			t.Errorf("断开错误为 %v，期望带关闭码1008和原因的 *ServerCloseError", got.err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("等待断开回调超时")
+	}
+}
+
+func TestReadPumpNotBlockedByPendingWrite(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	serverSend := make(chan struct{})
+	// 服务器不读取数据，只在收到信号后发送一条消息
+	url := newTestServer(t, func(conn *websocket.Conn) {
+		<-serverSend
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"tts"}`))
+		<-release
+	})
+
+	wp := NewWebsocketProtocol()
+	wp.SetMaxBinaryFrameSize(0)
+	received := make(chan struct{}, 1)
+	wp.SetOnJSONMessage(func(data []byte) { received <- struct{}{} })
+	if err := wp.Connect(url); err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer wp.ForceDisconnect()
+	connectedAt := wp.LastMessageTime()
+
+	// 写入阻塞在TCP缓冲区上，期间一直持有mu
+	writeDone := make(chan error, 1)
+	go func() { writeDone <- wp.SendBinaryWithTimeout(make([]byte, 64<<20), 3*time.Second) }()
+	time.Sleep(100 * time.Millisecond)
+	close(serverSend)
+
+	select {
+	case <-received:
+	case err := <-writeDone:
+		t.Fatalf("写入结束前未收到消息，写入结果: %v", err)
+	case <-time.After(time.Second):
+		t.Fatal("写入阻塞期间读取循环未处理消息")
+	}
+	if last := wp.LastMessageTime(); !last.After(connectedAt) {
+		t.Errorf("收到消息后 LastMessageTime 未更新: %v", last)
+	}
+}
+
+func TestPongResolvedDuringPendingWrite(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	sendPong := make(chan struct{})
+	// 服务器不读取数据，收到信号后直接回复负载为第一个ping序号的pong
+	url := newTestServer(t, func(conn *websocket.Conn) {
+		<-sendPong
+		conn.WriteControl(websocket.PongMessage, []byte("ping-1"), time.Now().Add(time.Second))
+		<-release
+	})
+
+	wp := NewWebsocketProtocol()
+	wp.SetMaxBinaryFrameSize(0)
+	if err := wp.Connect(url); err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer wp.ForceDisconnect()
+
+	pingDone := make(chan error, 1)
+	go func() {
+		_, err := wp.PingContext(context.Background())
+		pingDone <- err
+	}()
+	deadline := time.Now().Add(time.Second)
+	for {
+		wp.pingMu.Lock()
+		registered := len(wp.pendingPings) == 1
+		wp.pingMu.Unlock()
+		if registered {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("PingContext 未登记等待的ping")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// 写入阻塞在TCP缓冲区上，期间一直持有mu
+	go wp.SendBinaryWithTimeout(make([]byte, 64<<20), 3*time.Second)
+	time.Sleep(100 * time.Millisecond)
+	close(sendPong)
+
+	select {
+	case err := <-pingDone:
+		if err != nil {
+			t.Fatalf("PingContext 失败: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("写入阻塞期间pong未被处理")
+	}
+}
+
+// BenchmarkReceiveBinaryFrame 接收路径每帧的分配，帧为240字节的二进制消息（约60ms的Opus数据包）
+// copy为默认行为，回调收到按消息大小复制的切片；reuse开启 SetReuseReadBuffer，回调直接收到读取缓冲区
+func BenchmarkReceiveBinaryFrame(b *testing.B) {
+	for _, reuse := range []bool{false, true} {
+		name := "copy"
+		if reuse {
+			name = "reuse"
+		}
+		b.Run(name, func(b *testing.B) {
+			benchmarkReceiveBinaryFrame(b, reuse)
+		})
+	}
+}
+
+func benchmarkReceiveBinaryFrame(b *testing.B, reuse bool) {
+	// 服务器发送的帧不加掩码，负载超过125字节时帧头带两字节扩展长度；
+	// 预先生成全部数据一次写出，服务器端几乎不参与分配统计
+	frame := append([]byte{0x82, 126, 0, 240}, make([]byte, 240)...)
+	stream := bytes.Repeat(frame, b.N)
+	start := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	url := newTestServer(b, func(conn *websocket.Conn) {
+		<-start
+		conn.UnderlyingConn().Write(stream)
+		<-release
+	})
+
+	wp := NewWebsocketProtocol()
+	wp.SetReuseReadBuffer(reuse)
+	var received atomic.Int64
+	done := make(chan struct{})
+	wp.SetOnBinaryMessage(func(data []byte) {
+		if received.Add(1) == int64(b.N) {
+			close(done)
+		}
+	})
+	if err := wp.Connect(url); err != nil {
+		b.Fatalf("连接失败: %v", err)
+	}
+	defer wp.ForceDisconnect()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	close(start)
+	<-done
+	b.StopTimer()
+}